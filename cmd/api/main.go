@@ -1,13 +1,31 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"notes-service/internal/auth"
+	"notes-service/internal/collab"
 	"notes-service/internal/config"
+	"notes-service/internal/eventbus"
+	"notes-service/internal/events"
 	"notes-service/internal/handlers"
+	"notes-service/internal/importer"
+	"notes-service/internal/mail"
+	"notes-service/internal/password"
+	"notes-service/internal/ratelimit"
+	"notes-service/internal/reminder"
 	"notes-service/internal/repository"
+	"notes-service/internal/signedurl"
 	"notes-service/internal/spellcheck"
+	"notes-service/internal/storage"
+	"notes-service/internal/thumbnail"
+	"notes-service/internal/webhook"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -25,24 +43,240 @@ func main() {
 	}
 	defer postgresRepo.Close()
 
-	userRepo := repository.NewUserRepository(postgresRepo.GetDB())
+	hasher, err := password.NewHasher(cfg.PasswordHashAlgorithm, cfg.BcryptCost)
+	if err != nil {
+		log.Fatalf("Failed to configure password hashing: %v", err)
+	}
+	userRepo := repository.NewUserRepository(postgresRepo.GetDB(), repository.WithHasher(hasher))
 	spellchecker := spellcheck.NewYandexSpellchecker(cfg.YandexSpellcheckerURL)
-	authService := auth.NewAuthService(userRepo, cfg.JWTSecret)
+
+	var authOpts []auth.Option
+	if cfg.JWTPrivateKeyFile != "" {
+		keys, err := auth.LoadKeySet(cfg.JWTPrivateKeyFile, cfg.JWTVerificationKeyFiles)
+		if err != nil {
+			log.Fatalf("Failed to load signing keys: %v", err)
+		}
+		go reloadSigningKeyOnSIGHUP(keys, cfg.JWTPrivateKeyFile)
+		authOpts = append(authOpts, auth.WithKeySet(keys))
+	}
+
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimitStore == "postgres" {
+		pgStore := ratelimit.NewPostgresStore(postgresRepo.GetDB())
+		go pruneRateLimitBuckets(pgStore)
+		rateLimitStore = pgStore
+	}
+	rateLimiter := ratelimit.NewLimiter(rateLimitStore, cfg.LoginRatePerMinute, cfg.LoginRateBurst)
+	authOpts = append(authOpts,
+		auth.WithRateLimiter(rateLimiter),
+		auth.WithLockout(auth.LockoutPolicy{
+			Threshold:   cfg.LockoutThreshold,
+			Duration:    cfg.LockoutDuration,
+			MaxDuration: cfg.LockoutMaxDuration,
+		}),
+	)
+
+	var mailer mail.Sender = mail.NewLogSender()
+	if cfg.MailSender == "file" {
+		mailer = mail.NewFileSender(cfg.MailDir)
+	}
+	authOpts = append(authOpts,
+		auth.WithAPIKeys(repository.NewAPIKeyRepository(postgresRepo.GetDB())),
+		auth.WithPasswordReset(mailer, cfg.PasswordResetURL),
+		auth.WithEmailVerification(mailer, cfg.EmailVerifyURL),
+		auth.WithTOTPIssuer(cfg.TOTPIssuer),
+	)
+	if cfg.OIDCIssuer != "" {
+		authOpts = append(authOpts, auth.WithOIDC(auth.NewOIDCProvider(auth.OIDCConfig{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+		})))
+	}
+
+	authService := auth.NewAuthService(userRepo, cfg.JWTSecret, authOpts...)
 
 	r := chi.NewRouter()
 
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
-	noteHandler := handlers.NewNoteHandler(postgresRepo, spellchecker, authService)
+	blobStore, err := newBlobStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize blob storage: %v", err)
+	}
+	urlSecret := cfg.AttachmentURLSecret
+	if urlSecret == "" {
+		urlSecret = cfg.JWTSecret
+	}
+	signer := signedurl.NewSigner([]byte(urlSecret), cfg.PublicURL, cfg.AttachmentURLTTL)
+	attachmentLinks := handlers.NewAttachmentLinks(postgresRepo, signer)
+	thumbnailWorker := thumbnail.NewWorker(postgresRepo, blobStore)
+	go thumbnailWorker.Run(context.Background())
+
+	noteHandler := handlers.NewNoteHandler(postgresRepo, spellchecker, authService, handlers.WithNoteAttachments(attachmentLinks))
+	shareHandler := handlers.NewShareHandler(postgresRepo)
+	workspaceHandler := handlers.NewWorkspaceHandler(postgresRepo)
+	linkHandler := handlers.NewShareLinkHandler(postgresRepo, hasher, cfg.PublicURL,
+		handlers.WithShareLinkAttachments(attachmentLinks),
+		handlers.WithShareLinkRateLimiter(rateLimiter))
+	adminHandler := handlers.NewAdminHandler(userRepo)
+	notebookHandler := handlers.NewNotebookHandler(postgresRepo, postgresRepo)
+	exportHandler := handlers.NewExportHandler(postgresRepo, postgresRepo)
 
+	importWorker := importer.NewWorker(postgresRepo, postgresRepo, spellchecker)
+	go importWorker.Run(context.Background())
+	importHandler := handlers.NewImportHandler(postgresRepo, importWorker.Notify, cfg.ImportMaxBytes)
+
+	attachmentHandler := handlers.NewAttachmentHandler(postgresRepo, blobStore, cfg.AttachmentMaxBytes, cfg.AttachmentQuotaBytes,
+		handlers.WithThumbnailNotify(thumbnailWorker.Notify), handlers.WithSignedURLs(signer))
+
+	eventHub := events.NewHub()
+	go events.Listen(cfg.DatabaseURL, eventHub)
+	eventHandler := handlers.NewEventHandler(postgresRepo, eventHub)
+	syncHandler := handlers.NewSyncHandler(postgresRepo, postgresRepo, spellchecker)
+	collabHandler := handlers.NewCollabHandler(collab.NewManager(postgresRepo, cfg.CollabSnapshotInterval))
+
+	webhookWorker := webhook.NewWorker(postgresRepo, webhook.NewClient(cfg.WebhookAllowPrivate))
+	go webhookWorker.Run(context.Background())
+	webhookHandler := handlers.NewWebhookHandler(postgresRepo, webhookWorker.Notify)
+
+	eventSink, err := newEventSink(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize event sink: %v", err)
+	}
+	defer eventSink.Close()
+	go eventbus.NewPublisher(postgresRepo, eventSink).Run(context.Background())
+
+	go reminder.NewScheduler(postgresRepo, postgresRepo).Run(context.Background())
+	reminderHandler := handlers.NewReminderHandler(postgresRepo, postgresRepo)
+	checklistHandler := handlers.NewChecklistHandler(postgresRepo)
+	noteFlagHandler := handlers.NewNoteFlagHandler(postgresRepo, postgresRepo)
+
+	go purgeTrash(postgresRepo, cfg.TrashRetention)
+	go pruneNoteEvents(postgresRepo, cfg.NoteEventsRetention)
+	go pruneWebhookDeliveries(postgresRepo, cfg.WebhookDeliveryRetention)
+	go pruneOutbox(postgresRepo, cfg.OutboxRetention)
+	go removeOrphanedBlobs(postgresRepo, blobStore)
+
+	r.Get("/.well-known/jwks.json", authService.JWKS)
 	r.Post("/register", authService.Register)
 	r.Post("/login", authService.Login)
+	r.Post("/login/2fa", authService.LoginTwoFactor)
+	r.Get("/login/oidc", authService.StartOIDCLogin)
+	r.Get("/login/oidc/callback", authService.OIDCCallback)
+	r.Post("/password/forgot", authService.ForgotPassword)
+	r.Post("/password/reset", authService.ResetPassword)
+	r.Post("/email/verify", authService.VerifyEmail)
+	r.Get("/s/{token}", linkHandler.ViewSharedNote)
+	r.Post("/s/{token}", linkHandler.ViewSharedNote)
+	r.Get("/files/{id}", attachmentHandler.ServeSignedFile)
 
 	r.Group(func(r chi.Router) {
 		r.Use(authService.Authenticate)
-		r.Post("/notes", noteHandler.CreateNote)
-		r.Get("/notes", noteHandler.ListNotes)
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeAccount))
+			r.Post("/me/password", authService.ChangePassword)
+			r.Post("/me/email/verification", authService.SendEmailVerification)
+			r.Post("/me/2fa/setup", authService.SetupTwoFactor)
+			r.Post("/me/2fa/enable", authService.EnableTwoFactor)
+			r.Post("/me/2fa/disable", authService.DisableTwoFactor)
+			r.Post("/me/api-keys", authService.CreateAPIKey)
+			r.Get("/me/api-keys", authService.ListAPIKeys)
+			r.Delete("/me/api-keys/{id}", authService.RevokeAPIKey)
+			r.Post("/webhooks", webhookHandler.CreateWebhook)
+			r.Get("/webhooks", webhookHandler.ListWebhooks)
+			r.Get("/webhooks/{id}", webhookHandler.GetWebhook)
+			r.Put("/webhooks/{id}", webhookHandler.UpdateWebhook)
+			r.Delete("/webhooks/{id}", webhookHandler.DeleteWebhook)
+			r.Get("/webhooks/{id}/deliveries", webhookHandler.ListDeliveries)
+			r.Get("/webhooks/{id}/deliveries/{deliveryID}", webhookHandler.GetDelivery)
+			r.Post("/webhooks/{id}/deliveries/{deliveryID}/replay", webhookHandler.ReplayDelivery)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeAccount))
+			r.Use(auth.RequireRole(repository.RoleAdmin))
+			r.Get("/users", adminHandler.ListUsers)
+			r.Get("/users/{id}", adminHandler.GetUser)
+			r.Post("/users/{id}/disable", adminHandler.DisableUser)
+			r.Post("/users/{id}/enable", adminHandler.EnableUser)
+			r.Post("/users/{id}/logout", adminHandler.LogoutUser)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeNotesRead))
+			r.Get("/notes", noteHandler.ListNotes)
+			r.Get("/notes/shared-with-me", shareHandler.ListSharedWithMe)
+			r.Get("/notes/{id}", noteHandler.GetNote)
+			r.Get("/notes/{id}/shares", shareHandler.ListShares)
+			r.Get("/notes/{id}/links", linkHandler.ListShareLinks)
+			r.Get("/notes/{id}/attachments", attachmentHandler.ListAttachments)
+			r.Get("/notes/{id}/checklist", checklistHandler.ListChecklist)
+			r.Get("/notes/{id}/collab", collabHandler.Connect)
+			r.Get("/notes/{id}/attachments/{attachmentID}", attachmentHandler.DownloadAttachment)
+			r.Get("/notes/{id}/attachments/{attachmentID}/thumbnails/{size}", attachmentHandler.DownloadThumbnail)
+			r.Get("/workspaces", workspaceHandler.ListWorkspaces)
+			r.Get("/workspaces/{id}", workspaceHandler.GetWorkspace)
+			r.Get("/workspaces/{id}/members", workspaceHandler.ListMembers)
+			r.Get("/invitations", workspaceHandler.ListInvitations)
+			r.Get("/notebooks", notebookHandler.ListNotebooks)
+			r.Get("/notebooks/{id}", notebookHandler.GetNotebook)
+			r.Get("/trash", notebookHandler.ListTrash)
+			r.Get("/export", exportHandler.Export)
+			r.Get("/import", importHandler.ListImports)
+			r.Get("/import/{id}", importHandler.GetImport)
+			r.Get("/events", eventHandler.Stream)
+			r.Get("/sync", syncHandler.PullChanges)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeNotesWrite))
+			r.Post("/notes", noteHandler.CreateNote)
+			r.Put("/notes/order", noteFlagHandler.ReorderNotes)
+			r.Put("/notes/{id}", noteHandler.UpdateNote)
+			r.Delete("/notes/{id}", noteHandler.DeleteNote)
+			r.Post("/notes/{id}/shares", shareHandler.ShareNote)
+			r.Delete("/notes/{id}/shares/{userID}", shareHandler.RevokeShare)
+			r.Post("/notes/{id}/links", linkHandler.CreateShareLink)
+			r.Delete("/notes/{id}/links/{linkID}", linkHandler.RevokeShareLink)
+			r.Post("/notes/{id}/attachments", attachmentHandler.UploadAttachment)
+			r.Delete("/notes/{id}/attachments/{attachmentID}", attachmentHandler.DeleteAttachment)
+			r.Post("/workspaces", workspaceHandler.CreateWorkspace)
+			r.Put("/workspaces/{id}/members/{userID}", workspaceHandler.SetMemberRole)
+			r.Delete("/workspaces/{id}/members/{userID}", workspaceHandler.RemoveMember)
+			r.Post("/workspaces/{id}/invitations", workspaceHandler.Invite)
+			r.Post("/invitations/{id}/accept", workspaceHandler.AcceptInvitation)
+			r.Post("/invitations/{id}/decline", workspaceHandler.DeclineInvitation)
+			r.Post("/notes/{id}/move", notebookHandler.MoveNote)
+			r.Put("/notes/{id}/reminder", reminderHandler.SetReminder)
+			r.Delete("/notes/{id}/reminder", reminderHandler.DeleteReminder)
+			r.Post("/notes/{id}/reminder/snooze", reminderHandler.SnoozeReminder)
+			r.Post("/notes/{id}/checklist", checklistHandler.AddChecklistItem)
+			r.Put("/notes/{id}/checklist/order", checklistHandler.ReorderChecklist)
+			r.Put("/notes/{id}/checklist/{itemID}", checklistHandler.UpdateChecklistItem)
+			r.Post("/notes/{id}/checklist/{itemID}/toggle", checklistHandler.ToggleChecklistItem)
+			r.Delete("/notes/{id}/checklist/{itemID}", checklistHandler.DeleteChecklistItem)
+			r.Post("/notes/{id}/pin", noteFlagHandler.PinNote)
+			r.Post("/notes/{id}/unpin", noteFlagHandler.UnpinNote)
+			r.Post("/notes/{id}/archive", noteFlagHandler.ArchiveNote)
+			r.Post("/notes/{id}/unarchive", noteFlagHandler.UnarchiveNote)
+			r.Post("/notes/{id}/favorite", noteFlagHandler.FavoriteNote)
+			r.Post("/notes/{id}/unfavorite", noteFlagHandler.UnfavoriteNote)
+			r.Post("/import", importHandler.CreateImport)
+			r.Post("/sync", syncHandler.PushChanges)
+			r.Post("/notebooks", notebookHandler.CreateNotebook)
+			r.Put("/notebooks/{id}", notebookHandler.RenameNotebook)
+			r.Post("/notebooks/{id}/move", notebookHandler.MoveNotebook)
+			r.Delete("/notebooks/{id}", notebookHandler.TrashNotebook)
+			r.Post("/trash/notes/{id}/restore", notebookHandler.RestoreNote)
+			r.Post("/trash/notebooks/{id}/restore", notebookHandler.RestoreNotebook)
+			r.Delete("/trash/notes/{id}", notebookHandler.PurgeNote)
+			r.Delete("/trash/notebooks/{id}", notebookHandler.PurgeNotebook)
+		})
 	})
 
 	log.Printf("Starting server on %s", cfg.ServerAddress)
@@ -50,3 +284,151 @@ func main() {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
+
+// reloadSigningKeyOnSIGHUP перечитывает ключ подписи по SIGHUP. Предыдущий ключ
+// остается в JWKS, пока не истекут подписанные им токены.
+func reloadSigningKeyOnSIGHUP(keys *auth.KeySet, file string) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	for range sighup {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Failed to reload signing key: %v", err)
+			continue
+		}
+		key, err := auth.ParsePrivateKeyPEM(data)
+		if err != nil {
+			log.Printf("Failed to reload signing key: %v", err)
+			continue
+		}
+		if err := keys.Rotate(key, auth.TokenTTL); err != nil {
+			log.Printf("Failed to rotate signing key: %v", err)
+			continue
+		}
+		log.Printf("Signing key rotated, kid=%s", key.ID)
+	}
+}
+
+// pruneRateLimitBuckets периодически удаляет давно не использовавшиеся корзины
+func pruneRateLimitBuckets(store *ratelimit.PostgresStore) {
+	for range time.Tick(10 * time.Minute) {
+		if err := store.Prune(context.Background(), time.Now().Add(-time.Hour)); err != nil {
+			log.Printf("Failed to prune rate limit buckets: %v", err)
+		}
+	}
+}
+
+// purgeTrash периодически окончательно удаляет элементы корзины старше retention
+func purgeTrash(repo repository.NotebookRepository, retention time.Duration) {
+	for range time.Tick(time.Hour) {
+		purged, err := repo.PurgeTrash(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("Purged %d items from trash", purged)
+		}
+	}
+}
+
+// pruneNoteEvents периодически удаляет события изменений старше retention
+func pruneNoteEvents(repo repository.NoteEventRepository, retention time.Duration) {
+	for range time.Tick(time.Hour) {
+		pruned, err := repo.PruneNoteEvents(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Failed to prune note events: %v", err)
+			continue
+		}
+		if pruned > 0 {
+			log.Printf("Pruned %d note events", pruned)
+		}
+	}
+}
+
+// pruneWebhookDeliveries периодически удаляет завершенные доставки вебхуков старше retention
+func pruneWebhookDeliveries(repo repository.WebhookRepository, retention time.Duration) {
+	for range time.Tick(time.Hour) {
+		pruned, err := repo.PruneWebhookDeliveries(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Failed to prune webhook deliveries: %v", err)
+			continue
+		}
+		if pruned > 0 {
+			log.Printf("Pruned %d webhook deliveries", pruned)
+		}
+	}
+}
+
+// pruneOutbox периодически удаляет опубликованные доменные события старше retention
+func pruneOutbox(repo repository.OutboxRepository, retention time.Duration) {
+	for range time.Tick(time.Hour) {
+		pruned, err := repo.PruneOutbox(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Failed to prune outbox: %v", err)
+			continue
+		}
+		if pruned > 0 {
+			log.Printf("Pruned %d outbox events", pruned)
+		}
+	}
+}
+
+// newEventSink создает приемник доменных событий, выбранный в EVENT_SINK
+func newEventSink(cfg *config.Config) (eventbus.Sink, error) {
+	switch cfg.EventSink {
+	case "nats":
+		return eventbus.NewNATSSink(cfg.NATSURL, cfg.NATSSubjectPrefix)
+	case "kafka":
+		return eventbus.NewKafkaSink(cfg.KafkaBrokers, cfg.KafkaTopic), nil
+	case "log":
+		return eventbus.NewLogSink(), nil
+	default:
+		return nil, fmt.Errorf("unknown event sink %q", cfg.EventSink)
+	}
+}
+
+// newBlobStore создает хранилище вложений, выбранное в BLOB_STORE
+func newBlobStore(cfg *config.Config) (storage.BlobStore, error) {
+	if cfg.BlobStore == "s3" {
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+		})
+	}
+	return storage.NewLocalStore(cfg.BlobDir)
+}
+
+// removeOrphanedBlobs периодически удаляет из хранилища объекты удаленных
+// вложений, в том числе окончательно удаленных из корзины заметок
+func removeOrphanedBlobs(repo repository.AttachmentRepository, store storage.BlobStore) {
+	const batch = 100
+	for range time.Tick(time.Minute) {
+		for {
+			keys, err := repo.ListOrphanedBlobs(context.Background(), batch)
+			if err != nil {
+				log.Printf("Failed to list orphaned blobs: %v", err)
+				break
+			}
+			removed := 0
+			for _, key := range keys {
+				if err := store.Delete(context.Background(), key); err != nil {
+					log.Printf("Failed to delete blob %s: %v", key, err)
+					continue
+				}
+				if err := repo.ForgetOrphanedBlob(context.Background(), key); err != nil {
+					log.Printf("Failed to forget blob %s: %v", key, err)
+					continue
+				}
+				removed++
+			}
+			// Повторяем, только пока очередь полна и продвигается
+			if len(keys) < batch || removed == 0 {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"notes-service/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+	apiKeyPrefix = "nsk_"
+)
+
+// Права доступа. Сессии (JWT) получают все права, ключи API — только
+// явно выбранные при создании и никогда ScopeAccount.
+const (
+	ScopeNotesRead  = "notes:read"
+	ScopeNotesWrite = "notes:write"
+	// ScopeAccount — управление аккаунтом: пароль, 2FA, ключи API
+	ScopeAccount = "account"
+)
+
+var (
+	sessionScopes = []string{ScopeNotesRead, ScopeNotesWrite, ScopeAccount}
+	apiKeyScopes  = map[string]bool{ScopeNotesRead: true, ScopeNotesWrite: true}
+)
+
+// WithAPIKeys включает аутентификацию по персональным ключам API
+func WithAPIKeys(apiKeys repository.APIKeyRepository) Option {
+	return func(s *AuthServiceImpl) {
+		s.apiKeys = apiKeys
+	}
+}
+
+// HasScope сообщает, есть ли у запроса право scope
+func HasScope(r *http.Request, scope string) bool {
+	for _, s := range ScopesFromContext(r.Context()) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireScope пропускает только запросы с правом scope
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasScope(r, scope) {
+				http.Error(w, "Insufficient scope", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *AuthServiceImpl) authenticateAPIKey(w http.ResponseWriter, r *http.Request, apiKey string, next http.Handler) {
+	if s.apiKeys == nil {
+		http.Error(w, "Invalid API key", http.StatusUnauthorized)
+		return
+	}
+
+	key, err := s.apiKeys.GetAPIKeyByHash(r.Context(), HashToken(apiKey))
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Invalid API key", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to check API key", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		http.Error(w, "API key expired", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := s.userRepo.GetUserByID(r.Context(), key.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Invalid API key", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load user", http.StatusInternalServerError)
+		return
+	}
+	if disabled(w, user) {
+		return
+	}
+
+	if err := s.apiKeys.TouchAPIKey(r.Context(), key.ID, now); err != nil {
+		log.Printf("Failed to record API key usage: %v", err)
+	}
+
+	ctx := withPrincipal(r.Context(), user, key.Scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKey создает ключ API (POST /me/api-keys). Ключ возвращается
+// только в этом ответе.
+func (s *AuthServiceImpl) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || s.apiKeys == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Scopes) == 0 {
+		http.Error(w, "At least one scope is required", http.StatusBadRequest)
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !apiKeyScopes[scope] {
+			http.Error(w, "Unknown scope: "+scope, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		http.Error(w, "Expiration must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		http.Error(w, "Failed to generate key", http.StatusInternalServerError)
+		return
+	}
+	plaintext := apiKeyPrefix + secret
+
+	key := &repository.APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    plaintext[:len(apiKeyPrefix)+8],
+		KeyHash:   HashToken(plaintext),
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.apiKeys.CreateAPIKey(r.Context(), key); err != nil {
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		*repository.APIKey
+		Key string `json:"key"`
+	}{key, plaintext})
+}
+
+// ListAPIKeys возвращает ключи API пользователя без самих ключей (GET /me/api-keys)
+func (s *AuthServiceImpl) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || s.apiKeys == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := s.apiKeys.ListAPIKeys(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch API keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// RevokeAPIKey отзывает ключ API (DELETE /me/api-keys/{id})
+func (s *AuthServiceImpl) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok || s.apiKeys == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	err = s.apiKeys.RevokeAPIKey(r.Context(), userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
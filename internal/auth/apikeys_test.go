@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) CreateAPIKey(ctx context.Context, key *repository.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) ListAPIKeys(ctx context.Context, userID int64) ([]*repository.APIKey, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*repository.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*repository.APIKey, error) {
+	args := m.Called(ctx, keyHash)
+	return args.Get(0).(*repository.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) RevokeAPIKey(ctx context.Context, userID, id int64) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error {
+	args := m.Called(ctx, id, usedAt)
+	return args.Error(0)
+}
+
+func serveWithScope(s *AuthServiceImpl, scope string, header, value string) *httptest.ResponseRecorder {
+	handler := s.Authenticate(RequireScope(scope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req, _ := http.NewRequest("GET", "/notes", nil)
+	req.Header.Set(header, value)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	keyRepo := new(MockAPIKeyRepository)
+	authService := NewAuthService(new(MockUserRepository), "secret", WithAPIKeys(keyRepo))
+
+	var stored *repository.APIKey
+	keyRepo.On("CreateAPIKey", mock.Anything, mock.AnythingOfType("*repository.APIKey")).
+		Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*repository.APIKey)
+			stored.ID = 3
+		}).
+		Return(nil)
+
+	req, _ := http.NewRequest("POST", "/me/api-keys",
+		bytes.NewBufferString(`{"name":"ci","scopes":["notes:read"]}`))
+	req = req.WithContext(NewContext(req.Context(), 1, repository.RoleUser))
+	rr := httptest.NewRecorder()
+	authService.CreateAPIKey(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+
+	key := response["key"].(string)
+	assert.True(t, strings.HasPrefix(key, "nsk_"))
+	assert.True(t, strings.HasPrefix(key, response["prefix"].(string)))
+	assert.Equal(t, HashToken(key), stored.KeyHash)
+	assert.Equal(t, int64(1), stored.UserID)
+	assert.NotContains(t, rr.Body.String(), stored.KeyHash)
+}
+
+func TestCreateAPIKeyRejectsAccountScope(t *testing.T) {
+	keyRepo := new(MockAPIKeyRepository)
+	authService := NewAuthService(new(MockUserRepository), "secret", WithAPIKeys(keyRepo))
+
+	req, _ := http.NewRequest("POST", "/me/api-keys",
+		bytes.NewBufferString(`{"name":"ci","scopes":["account"]}`))
+	req = req.WithContext(NewContext(req.Context(), 1, repository.RoleUser))
+	rr := httptest.NewRecorder()
+	authService.CreateAPIKey(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	keyRepo.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything)
+}
+
+func TestAuthenticateWithAPIKey(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	keyRepo := new(MockAPIKeyRepository)
+	authService := NewAuthService(mockRepo, "secret", WithAPIKeys(keyRepo))
+
+	const key = "nsk_test-key"
+	keyRepo.On("GetAPIKeyByHash", mock.Anything, HashToken(key)).
+		Return(&repository.APIKey{ID: 3, UserID: 1, Scopes: []string{ScopeNotesRead}}, nil)
+	keyRepo.On("TouchAPIKey", mock.Anything, int64(3), mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("GetUserByID", mock.Anything, int64(1)).Return(&repository.User{ID: 1}, nil)
+
+	assert.Equal(t, http.StatusOK, serveWithScope(authService, ScopeNotesRead, "X-API-Key", key).Code)
+	assert.Equal(t, http.StatusOK, serveWithScope(authService, ScopeNotesRead, "Authorization", "Bearer "+key).Code)
+
+	// Ключ только для чтения не дает права на запись и управление аккаунтом
+	assert.Equal(t, http.StatusForbidden, serveWithScope(authService, ScopeNotesWrite, "X-API-Key", key).Code)
+	assert.Equal(t, http.StatusForbidden, serveWithScope(authService, ScopeAccount, "X-API-Key", key).Code)
+}
+
+func TestAuthenticateRejectsExpiredAPIKey(t *testing.T) {
+	keyRepo := new(MockAPIKeyRepository)
+	authService := NewAuthService(new(MockUserRepository), "secret", WithAPIKeys(keyRepo))
+
+	expired := time.Now().Add(-time.Minute)
+	keyRepo.On("GetAPIKeyByHash", mock.Anything, mock.Anything).
+		Return(&repository.APIKey{ID: 3, UserID: 1, Scopes: []string{ScopeNotesRead}, ExpiresAt: &expired}, nil)
+
+	rr := serveWithScope(authService, ScopeNotesRead, "X-API-Key", "nsk_expired")
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	keyRepo.AssertNotCalled(t, "TouchAPIKey", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthenticateRejectsUnknownAPIKey(t *testing.T) {
+	keyRepo := new(MockAPIKeyRepository)
+	authService := NewAuthService(new(MockUserRepository), "secret", WithAPIKeys(keyRepo))
+
+	keyRepo.On("GetAPIKeyByHash", mock.Anything, mock.Anything).
+		Return((*repository.APIKey)(nil), repository.ErrNotFound)
+
+	rr := serveWithScope(authService, ScopeNotesRead, "X-API-Key", "nsk_revoked")
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
@@ -3,9 +3,15 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
+	"notes-service/internal/mail"
+	"notes-service/internal/ratelimit"
 	"notes-service/internal/repository"
 
 	"github.com/dgrijalva/jwt-go"
@@ -18,18 +24,45 @@ type AuthService interface {
 type AuthServiceImpl struct {
 	userRepo  repository.UserRepository
 	jwtSecret []byte
+	keys      *KeySet
+	limiter   *ratelimit.Limiter
+	lockout   *LockoutPolicy
+	mailer    mail.Sender
+	resetURL  string
+	verifyURL string
+
+	totpIssuer string
+	oidc       *OIDCProvider
+	apiKeys    repository.APIKeyRepository
+}
+
+// Option настраивает AuthServiceImpl
+type Option func(*AuthServiceImpl)
+
+// WithKeySet включает асимметричную подпись токенов (RS256/EdDSA).
+// Токены HS256 без kid продолжают приниматься, пока не истечет их срок.
+func WithKeySet(keys *KeySet) Option {
+	return func(s *AuthServiceImpl) {
+		s.keys = keys
+	}
 }
 
-func NewAuthService(userRepo repository.UserRepository, jwtSecret string) *AuthServiceImpl {
-	return &AuthServiceImpl{
-		userRepo:  userRepo,
-		jwtSecret: []byte(jwtSecret),
+func NewAuthService(userRepo repository.UserRepository, jwtSecret string, opts ...Option) *AuthServiceImpl {
+	s := &AuthServiceImpl{
+		userRepo:   userRepo,
+		jwtSecret:  []byte(jwtSecret),
+		totpIssuer: "notes-service",
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 type RegisterRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Email    string `json:"email"`
 }
 
 type LoginRequest struct {
@@ -41,20 +74,74 @@ type contextKey string
 
 const (
 	userIDKey contextKey = "user_id"
+	roleKey   contextKey = "role"
+	scopesKey contextKey = "scopes"
 )
 
+func withPrincipal(ctx context.Context, user *repository.User, scopes []string) context.Context {
+	ctx = context.WithValue(ctx, userIDKey, user.ID)
+	ctx = context.WithValue(ctx, roleKey, user.Role)
+	return context.WithValue(ctx, scopesKey, scopes)
+}
+
+// NewContext возвращает контекст, в котором пользователь userID
+// аутентифицирован как сессия с ролью role
+func NewContext(ctx context.Context, userID int64, role string) context.Context {
+	return withPrincipal(ctx, &repository.User{ID: userID, Role: role}, sessionScopes)
+}
+
+// UserIDFromContext возвращает ID пользователя, установленный Authenticate
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(userIDKey).(int64)
+	return userID, ok
+}
+
+// ScopesFromContext возвращает права, с которыми выполняется запрос
+func ScopesFromContext(ctx context.Context) []string {
+	scopes, _ := ctx.Value(scopesKey).([]string)
+	return scopes
+}
+
+// TokenTTL — срок действия выдаваемых токенов
+const TokenTTL = 24 * time.Hour
+
 func (s *AuthServiceImpl) Register(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, "register-ip:"+ratelimit.ClientIP(r)) {
+		return
+	}
+
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	user, err := s.userRepo.CreateUser(r.Context(), req.Username, req.Password)
+	if !s.allow(w, r, "register-"+usernameKey(req.Username)) {
+		return
+	}
+	if err := validatePassword(req.Password); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.userRepo.CreateUser(r.Context(), req.Username, req.Password, req.Email)
+	if errors.Is(err, repository.ErrEmailTaken) {
+		http.Error(w, "Email already taken", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, repository.ErrDuplicate) {
+		http.Error(w, "Username already taken", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Failed to create user", http.StatusInternalServerError)
 		return
 	}
+	if user.Email != "" && s.mailer != nil && s.verifyURL != "" {
+		if err := s.sendVerificationLink(r.Context(), user); err != nil {
+			log.Printf("Failed to send email verification link to user %d: %v", user.ID, err)
+		}
+	}
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -64,23 +151,61 @@ func (s *AuthServiceImpl) Register(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *AuthServiceImpl) Login(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, "login-ip:"+ratelimit.ClientIP(r)) {
+		return
+	}
+
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	user, err := s.userRepo.ValidateUser(r.Context(), req.Username, req.Password)
+	if !s.allow(w, r, usernameKey(req.Username)) {
+		return
+	}
+	locked, err := s.locked(r.Context(), req.Username)
 	if err != nil {
+		http.Error(w, "Failed to check account status", http.StatusInternalServerError)
+		return
+	}
+
+	// Пароль проверяется и у заблокированного аккаунта, чтобы время ответа не
+	// выдавало блокировку; верный пароль при блокировке вход не открывает
+	user, err := s.userRepo.ValidateUser(r.Context(), req.Username, req.Password)
+	if locked && (err == nil || errors.Is(err, repository.ErrInvalidCredentials)) {
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
-	if user == nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+	if errors.Is(err, repository.ErrInvalidCredentials) {
+		s.recordLoginFailure(r.Context(), req.Username)
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to validate credentials", http.StatusInternalServerError)
+		return
+	}
+
+	if disabled(w, user) {
+		return
+	}
+
+	if user.TOTPEnabled {
+		s.requireSecondFactor(w, user)
+		return
+	}
+
+	s.resetLoginFailures(r.Context(), user.ID)
+	s.respondWithToken(w, user)
+}
+
+func (s *AuthServiceImpl) respondWithToken(w http.ResponseWriter, user *repository.User) {
+	if disabled(w, user) {
 		return
 	}
 
-	token, err := s.generateToken(user.ID, user.Username)
+	token, err := s.generateToken(user)
 	if err != nil {
 		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
@@ -92,24 +217,30 @@ func (s *AuthServiceImpl) Login(w http.ResponseWriter, r *http.Request) {
 
 func (s *AuthServiceImpl) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenString := r.Header.Get("Authorization")
+		tokenString := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		apiKey := r.Header.Get(apiKeyHeader)
+		if apiKey == "" && strings.HasPrefix(tokenString, apiKeyPrefix) {
+			apiKey = tokenString
+		}
+		if apiKey != "" {
+			s.authenticateAPIKey(w, r, apiKey, next)
+			return
+		}
+
 		if tokenString == "" {
 			http.Error(w, "Missing authorization header", http.StatusUnauthorized)
 			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return s.jwtSecret, nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := s.parseToken(tokenString)
+		if err != nil {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+		// Промежуточный токен второго фактора не дает доступа к API
+		if claims["typ"] == mfaTokenType {
+			http.Error(w, "Two-factor authentication required", http.StatusUnauthorized)
 			return
 		}
 
@@ -119,17 +250,82 @@ func (s *AuthServiceImpl) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), userIDKey, int64(userID))
+		// Версия токенов растет при смене пароля, что отзывает ранее выданные токены
+		user, err := s.userRepo.GetUserByID(r.Context(), int64(userID))
+		if errors.Is(err, repository.ErrNotFound) {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to load user", http.StatusInternalServerError)
+			return
+		}
+		version, _ := claims["ver"].(float64)
+		if int(version) != user.TokenVersion {
+			http.Error(w, "Token revoked", http.StatusUnauthorized)
+			return
+		}
+		if disabled(w, user) {
+			return
+		}
+
+		ctx := withPrincipal(r.Context(), user, sessionScopes)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func (s *AuthServiceImpl) generateToken(userID int64, username string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id":  userID,
-		"username": username,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
+func (s *AuthServiceImpl) parseToken(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, s.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	return claims, nil
+}
+
+func (s *AuthServiceImpl) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if s.keys == nil || kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return s.jwtSecret, nil
+	}
+
+	key, err := s.keys.Lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+func (s *AuthServiceImpl) generateToken(user *repository.User) (string, error) {
+	return s.signToken(jwt.MapClaims{
+		"user_id":  user.ID,
+		"username": user.Username,
+		"ver":      user.TokenVersion,
+		"role":     user.Role,
+		"exp":      time.Now().Add(TokenTTL).Unix(),
 	})
+}
+
+func (s *AuthServiceImpl) signToken(claims jwt.MapClaims) (string, error) {
+	if s.keys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	}
 
-	return token.SignedString(s.jwtSecret)
+	key := s.keys.Current()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signingKey())
 }
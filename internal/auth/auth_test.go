@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"notes-service/internal/repository"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -17,8 +18,8 @@ type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) CreateUser(ctx context.Context, username, password string) (*repository.User, error) {
-	args := m.Called(ctx, username, password)
+func (m *MockUserRepository) CreateUser(ctx context.Context, username, password, email string) (*repository.User, error) {
+	args := m.Called(ctx, username, password, email)
 	return args.Get(0).(*repository.User), args.Error(1)
 }
 
@@ -27,16 +28,114 @@ func (m *MockUserRepository) GetUserByUsername(ctx context.Context, username str
 	return args.Get(0).(*repository.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetUserByID(ctx context.Context, id int64) (*repository.User, error) {
+	args := m.Called(ctx, id)
+	if fn, ok := args.Get(0).(func(context.Context, int64) *repository.User); ok {
+		return fn(ctx, id), args.Error(1)
+	}
+	return args.Get(0).(*repository.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetUserByEmail(ctx context.Context, email string) (*repository.User, error) {
+	args := m.Called(ctx, email)
+	return args.Get(0).(*repository.User), args.Error(1)
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, userID int64, password string) (int, error) {
+	args := m.Called(ctx, userID, password)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) CreatePasswordResetToken(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ResetPassword(ctx context.Context, tokenHash, password string) (int64, error) {
+	args := m.Called(ctx, tokenHash, password)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRepository) CreateEmailVerificationToken(ctx context.Context, userID int64, email, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, email, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) VerifyEmail(ctx context.Context, tokenHash string) (int64, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, userID int64, email string) error {
+	args := m.Called(ctx, userID, email)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) EnableTOTP(ctx context.Context, userID int64, secret string, recoveryCodeHashes []string) error {
+	args := m.Called(ctx, userID, secret, recoveryCodeHashes)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) DisableTOTP(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UseTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) ConsumeRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetUserByIdentity(ctx context.Context, provider, subject string) (*repository.User, error) {
+	args := m.Called(ctx, provider, subject)
+	return args.Get(0).(*repository.User), args.Error(1)
+}
+
+func (m *MockUserRepository) CreateIdentity(ctx context.Context, userID int64, provider, subject, email string) error {
+	args := m.Called(ctx, userID, provider, subject, email)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) ValidateUser(ctx context.Context, username, password string) (*repository.User, error) {
 	args := m.Called(ctx, username, password)
 	return args.Get(0).(*repository.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetLockedUntil(ctx context.Context, username string) (time.Time, error) {
+	args := m.Called(ctx, username)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockUserRepository) RecordLoginFailure(ctx context.Context, username string) (int, error) {
+	args := m.Called(ctx, username)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) LockUser(ctx context.Context, username string, until time.Time) error {
+	args := m.Called(ctx, username, until)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ResetLoginFailures(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
 func TestRegister(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	authService := NewAuthService(mockRepo, "secret")
 
-	mockRepo.On("CreateUser", mock.Anything, "testuser", "password").Return(&repository.User{
+	mockRepo.On("CreateUser", mock.Anything, "testuser", "password", "").Return(&repository.User{
 		ID:       1,
 		Username: "testuser",
 	}, nil)
@@ -78,3 +177,58 @@ func TestLogin(t *testing.T) {
 
 	assert.NotEmpty(t, response["token"])
 }
+
+func TestRegisterDuplicateUsername(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret")
+
+	mockRepo.On("CreateUser", mock.Anything, "testuser", "password", "").
+		Return((*repository.User)(nil), repository.ErrDuplicate)
+
+	reqBody := bytes.NewBufferString(`{"username":"testuser","password":"password"}`)
+	req, _ := http.NewRequest("POST", "/register", reqBody)
+	rr := httptest.NewRecorder()
+
+	authService.Register(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestRegisterWeakPassword(t *testing.T) {
+	for _, password := range []string{"", "short"} {
+		mockRepo := new(MockUserRepository)
+		authService := NewAuthService(mockRepo, "secret")
+
+		reqBody := bytes.NewBufferString(`{"username":"testuser","password":"` + password + `"}`)
+		req, _ := http.NewRequest("POST", "/register", reqBody)
+		rr := httptest.NewRecorder()
+
+		authService.Register(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, password)
+		mockRepo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret")
+
+	mockRepo.On("ValidateUser", mock.Anything, "nobody", "password").
+		Return((*repository.User)(nil), repository.ErrInvalidCredentials)
+	mockRepo.On("ValidateUser", mock.Anything, "testuser", "wrong").
+		Return((*repository.User)(nil), repository.ErrInvalidCredentials)
+
+	for _, body := range []string{
+		`{"username":"nobody","password":"password"}`,
+		`{"username":"testuser","password":"wrong"}`,
+	} {
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+
+		authService.Login(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, "Invalid credentials\n", rr.Body.String())
+	}
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"notes-service/internal/mail"
+	"notes-service/internal/ratelimit"
+	"notes-service/internal/repository"
+)
+
+const verifyTokenTTL = 24 * time.Hour
+
+// WithEmailVerification включает подтверждение адреса email. Ссылка в письме
+// строится как verifyURL?token=<токен>; письмо отправляется при регистрации и
+// по запросу POST /me/email/verification.
+func WithEmailVerification(mailer mail.Sender, verifyURL string) Option {
+	return func(s *AuthServiceImpl) {
+		s.mailer = mailer
+		s.verifyURL = verifyURL
+	}
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// SendEmailVerification повторно отправляет ссылку для подтверждения адреса
+// текущего пользователя (POST /me/email/verification)
+func (s *AuthServiceImpl) SendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	if s.mailer == nil || s.verifyURL == "" {
+		http.Error(w, "Email verification is not configured", http.StatusNotImplemented)
+		return
+	}
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if !s.allow(w, r, "verify-email:"+strconv.FormatInt(user.ID, 10)) {
+		return
+	}
+	if user.Email == "" {
+		http.Error(w, "No email address to verify", http.StatusBadRequest)
+		return
+	}
+	if user.EmailVerified {
+		http.Error(w, "Email is already verified", http.StatusConflict)
+		return
+	}
+
+	if err := s.sendVerificationLink(r.Context(), user); err != nil {
+		log.Printf("Failed to send email verification link to user %d: %v", user.ID, err)
+		http.Error(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *AuthServiceImpl) sendVerificationLink(ctx context.Context, user *repository.User) error {
+	token, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+
+	err = s.userRepo.CreateEmailVerificationToken(ctx, user.ID, user.Email, HashToken(token), time.Now().Add(verifyTokenTTL))
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hello, %s!\n\nTo confirm your email address follow the link below. It is valid for %s and can be used once.\n\n%s?token=%s\n\nIf you did not create an account, ignore this message.",
+			user.Username, verifyTokenTTL, s.verifyURL, token),
+	})
+}
+
+// VerifyEmail подтверждает адрес по одноразовому токену из письма (POST /email/verify)
+func (s *AuthServiceImpl) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, "verify-email-ip:"+ratelimit.ClientIP(r)) {
+		return
+	}
+
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err := s.userRepo.VerifyEmail(r.Context(), HashToken(req.Token))
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
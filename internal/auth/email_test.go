@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/mail"
+	"notes-service/internal/repository"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterSendsVerificationLink(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockMailer := new(MockMailSender)
+	authService := NewAuthService(mockRepo, "secret", WithEmailVerification(mockMailer, "https://notes.example.com/verify"))
+
+	mockRepo.On("CreateUser", mock.Anything, "testuser", "password", "user@example.com").
+		Return(&repository.User{ID: 1, Username: "testuser", Email: "user@example.com"}, nil)
+
+	var tokenHash string
+	mockRepo.On("CreateEmailVerificationToken", mock.Anything, int64(1), "user@example.com", mock.AnythingOfType("string"), mock.Anything).
+		Run(func(args mock.Arguments) { tokenHash = args.String(3) }).
+		Return(nil)
+
+	var sent mail.Message
+	mockMailer.On("Send", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { sent = args.Get(1).(mail.Message) }).
+		Return(nil)
+
+	reqBody := bytes.NewBufferString(`{"username":"testuser","password":"password","email":"user@example.com"}`)
+	req, _ := http.NewRequest("POST", "/register", reqBody)
+	rr := httptest.NewRecorder()
+	authService.Register(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "user@example.com", sent.To)
+
+	idx := strings.Index(sent.Body, "https://notes.example.com/verify?token=")
+	require.NotEqual(t, -1, idx)
+	token := strings.Fields(sent.Body[idx+len("https://notes.example.com/verify?token="):])[0]
+	assert.Equal(t, HashToken(token), tokenHash)
+
+	mockRepo.On("VerifyEmail", mock.Anything, tokenHash).Return(int64(1), nil)
+
+	req, _ = http.NewRequest("POST", "/email/verify", bytes.NewBufferString(`{"token":"`+token+`"}`))
+	rr = httptest.NewRecorder()
+	authService.VerifyEmail(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestVerifyEmailInvalidToken(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret")
+
+	mockRepo.On("VerifyEmail", mock.Anything, HashToken("used-token")).
+		Return(int64(0), repository.ErrNotFound)
+
+	req, _ := http.NewRequest("POST", "/email/verify", bytes.NewBufferString(`{"token":"used-token"}`))
+	rr := httptest.NewRecorder()
+	authService.VerifyEmail(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestSendEmailVerificationAlreadyVerified(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockMailer := new(MockMailSender)
+	authService := NewAuthService(mockRepo, "secret", WithEmailVerification(mockMailer, "https://notes.example.com/verify"))
+
+	mockRepo.On("GetUserByID", mock.Anything, int64(1)).
+		Return(&repository.User{ID: 1, Username: "testuser", Email: "user@example.com", EmailVerified: true}, nil)
+
+	req, _ := http.NewRequest("POST", "/me/email/verification", nil)
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, int64(1)))
+	rr := httptest.NewRecorder()
+	authService.SendEmailVerification(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	mockMailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,397 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	ErrUnknownKey         = errors.New("unknown signing key")
+	ErrUnsupportedKeyType = errors.New("unsupported key type")
+)
+
+// SigningMethodEdDSA реализует подпись Ed25519 (RFC 8037), которой нет в jwt-go v3
+type SigningMethodEdDSA struct{}
+
+var signingMethodEdDSA = &SigningMethodEdDSA{}
+
+func init() {
+	jwt.RegisterSigningMethod(signingMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return signingMethodEdDSA
+	})
+}
+
+func (m *SigningMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+func (m *SigningMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+func (m *SigningMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
+
+// Key описывает асимметричный ключ. Для ключей, загруженных только для
+// проверки подписи, Private равен nil.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// ParsePrivateKeyPEM разбирает закрытый ключ RSA (PKCS#1 или PKCS#8) или Ed25519 (PKCS#8)
+func ParsePrivateKeyPEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKeyType
+	}
+	key, err := newKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.Private = signer
+	return key, nil
+}
+
+// ParsePublicKeyPEM разбирает открытый ключ RSA или Ed25519. Закрытые ключи
+// тоже принимаются, от них используется только открытая часть.
+func ParsePublicKeyPEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(parsed)
+	case "RSA PUBLIC KEY":
+		parsed, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(parsed)
+	}
+
+	key, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	key.Private = nil
+	return key, nil
+}
+
+func newKey(public crypto.PublicKey) (*Key, error) {
+	key := &Key{Public: public}
+	switch public.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = signingMethodEdDSA
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+
+	jwk, err := key.JWK()
+	if err != nil {
+		return nil, err
+	}
+	key.ID = jwk.Thumbprint()
+	return key, nil
+}
+
+func (k *Key) signingKey() interface{} {
+	if privateKey, ok := k.Private.(ed25519.PrivateKey); ok {
+		return privateKey
+	}
+	if privateKey, ok := k.Private.(*ed25519.PrivateKey); ok {
+		return *privateKey
+	}
+	return k.Private
+}
+
+// JWK представляет открытый ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWK возвращает открытую часть ключа в формате JWK
+func (k *Key) JWK() (JWK, error) {
+	switch public := k.Public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: k.ID,
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			N:   jwt.EncodeSegment(public.N.Bytes()),
+			E:   jwt.EncodeSegment(big.NewInt(int64(public.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: k.ID,
+			Use: "sig",
+			Alg: k.Method.Alg(),
+			Crv: "Ed25519",
+			X:   jwt.EncodeSegment(public),
+		}, nil
+	}
+	return JWK{}, ErrUnsupportedKeyType
+}
+
+// Key восстанавливает открытый ключ из JWK (используется для ключей
+// внешнего провайдера OIDC)
+func (j JWK) Key() (*Key, error) {
+	var public crypto.PublicKey
+	switch j.Kty {
+	case "RSA":
+		n, err := jwt.DecodeSegment(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := jwt.DecodeSegment(j.E)
+		if err != nil {
+			return nil, err
+		}
+		public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, ErrUnsupportedKeyType
+		}
+		x, err := jwt.DecodeSegment(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		public = ed25519.PublicKey(x)
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+
+	key, err := newKey(public)
+	if err != nil {
+		return nil, err
+	}
+	if j.Kid != "" {
+		key.ID = j.Kid
+	}
+	return key, nil
+}
+
+// Thumbprint вычисляет отпечаток ключа по RFC 7638
+func (j JWK) Thumbprint() string {
+	var canonical string
+	switch j.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, j.E, j.N)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, j.Crv, j.X)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return jwt.EncodeSegment(sum[:])
+}
+
+// KeySet хранит текущий ключ подписи и ключи, которые еще принимаются при
+// проверке. После ротации старый ключ остается действительным до истечения
+// выданных им токенов.
+type KeySet struct {
+	mu       sync.RWMutex
+	current  *Key
+	keys     map[string]*Key
+	retireAt map[string]time.Time
+}
+
+// NewKeySet создает набор ключей с ключом подписи current и дополнительными
+// ключами проверки
+func NewKeySet(current *Key, verification ...*Key) (*KeySet, error) {
+	if current == nil || current.Private == nil {
+		return nil, errors.New("signing key must include a private key")
+	}
+
+	ks := &KeySet{
+		current:  current,
+		keys:     map[string]*Key{current.ID: current},
+		retireAt: map[string]time.Time{},
+	}
+	for _, key := range verification {
+		ks.keys[key.ID] = key
+	}
+	return ks, nil
+}
+
+// LoadKeySet загружает ключ подписи и ключи проверки из PEM-файлов
+func LoadKeySet(privateKeyFile string, verificationKeyFiles []string) (*KeySet, error) {
+	data, err := os.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	current, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", privateKeyFile, err)
+	}
+
+	var verification []*Key
+	for _, file := range verificationKeyFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParsePublicKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		verification = append(verification, key)
+	}
+
+	return NewKeySet(current, verification...)
+}
+
+// Rotate делает next ключом подписи. Предыдущий ключ продолжает приниматься
+// при проверке в течение overlap.
+func (ks *KeySet) Rotate(next *Key, overlap time.Duration) error {
+	if next == nil || next.Private == nil {
+		return errors.New("signing key must include a private key")
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.current.ID == next.ID {
+		return nil
+	}
+	ks.retireAt[ks.current.ID] = time.Now().Add(overlap)
+	ks.current = next
+	ks.keys[next.ID] = next
+	delete(ks.retireAt, next.ID)
+	return nil
+}
+
+// Current возвращает текущий ключ подписи
+func (ks *KeySet) Current() *Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.current
+}
+
+// Lookup возвращает ключ проверки по идентификатору
+func (ks *KeySet) Lookup(kid string) (*Key, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.pruneLocked(time.Now())
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// JWKS возвращает все действующие открытые ключи
+func (ks *KeySet) JWKS() ([]JWK, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.pruneLocked(time.Now())
+	jwks := make([]JWK, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		jwk, err := key.JWK()
+		if err != nil {
+			return nil, err
+		}
+		jwks = append(jwks, jwk)
+	}
+	sort.Slice(jwks, func(i, j int) bool { return jwks[i].Kid < jwks[j].Kid })
+	return jwks, nil
+}
+
+func (ks *KeySet) pruneLocked(now time.Time) {
+	for kid, at := range ks.retireAt {
+		if now.After(at) {
+			delete(ks.keys, kid)
+			delete(ks.retireAt, kid)
+		}
+	}
+}
+
+// JWKS публикует открытые ключи подписи токенов (/.well-known/jwks.json)
+func (s *AuthServiceImpl) JWKS(w http.ResponseWriter, r *http.Request) {
+	keys := []JWK{}
+	if s.keys != nil {
+		var err error
+		keys, err = s.keys.JWKS()
+		if err != nil {
+			http.Error(w, "Failed to encode keys", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/repository"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+func generateRSAKey(t *testing.T) *Key {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	key, err := ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	}))
+	require.NoError(t, err)
+	return key
+}
+
+func generateEd25519Key(t *testing.T) *Key {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	key, err := ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	return key
+}
+
+func authenticatedUserID(t *testing.T, s *AuthServiceImpl, token string) (int, int64) {
+	s.userRepo.(*MockUserRepository).
+		On("GetUserByID", mock.Anything, mock.AnythingOfType("int64")).
+		Return(func(ctx context.Context, id int64) *repository.User {
+			return &repository.User{ID: id, Username: "testuser"}
+		}, nil)
+
+	var userID int64
+	handler := s.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID = r.Context().Value(userIDKey).(int64)
+	}))
+
+	req, _ := http.NewRequest("GET", "/notes", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr.Code, userID
+}
+
+func TestLoadKeySet(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	privateFile := writePEM(t, "PRIVATE KEY", der)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	publicFile := writePEM(t, "PUBLIC KEY", publicDER)
+
+	keys, err := LoadKeySet(privateFile, []string{publicFile})
+	require.NoError(t, err)
+
+	assert.Equal(t, "EdDSA", keys.Current().Method.Alg())
+	jwks, err := keys.JWKS()
+	require.NoError(t, err)
+	assert.Len(t, jwks, 2)
+}
+
+func TestAsymmetricTokens(t *testing.T) {
+	for name, key := range map[string]*Key{"RS256": generateRSAKey(t), "EdDSA": generateEd25519Key(t)} {
+		t.Run(name, func(t *testing.T) {
+			keys, err := NewKeySet(key)
+			require.NoError(t, err)
+			authService := NewAuthService(new(MockUserRepository), "secret", WithKeySet(keys))
+
+			token, err := authService.generateToken(&repository.User{ID: 7, Username: "testuser"})
+			require.NoError(t, err)
+
+			parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, name, parsed.Header["alg"])
+			assert.Equal(t, key.ID, parsed.Header["kid"])
+
+			code, userID := authenticatedUserID(t, authService, token)
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, int64(7), userID)
+		})
+	}
+}
+
+func TestKeyRotationOverlap(t *testing.T) {
+	oldKey, newKey := generateEd25519Key(t), generateRSAKey(t)
+	keys, err := NewKeySet(oldKey)
+	require.NoError(t, err)
+	authService := NewAuthService(new(MockUserRepository), "secret", WithKeySet(keys))
+
+	oldToken, err := authService.generateToken(&repository.User{ID: 1, Username: "testuser"})
+	require.NoError(t, err)
+
+	require.NoError(t, keys.Rotate(newKey, time.Hour))
+	newToken, err := authService.generateToken(&repository.User{ID: 1, Username: "testuser"})
+	require.NoError(t, err)
+
+	code, _ := authenticatedUserID(t, authService, oldToken)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = authenticatedUserID(t, authService, newToken)
+	assert.Equal(t, http.StatusOK, code)
+
+	// После окончания периода перекрытия старый ключ больше не принимается
+	keys.retireAt[oldKey.ID] = time.Now().Add(-time.Second)
+	code, _ = authenticatedUserID(t, authService, oldToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestRejectsHS256ForgedWithPublicKey(t *testing.T) {
+	key := generateRSAKey(t)
+	keys, err := NewKeySet(key)
+	require.NoError(t, err)
+	authService := NewAuthService(new(MockUserRepository), "secret", WithKeySet(keys))
+
+	jwk, err := key.JWK()
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+	forged.Header["kid"] = key.ID
+	token, err := forged.SignedString([]byte(jwk.N))
+	require.NoError(t, err)
+
+	code, _ := authenticatedUserID(t, authService, token)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestJWKSHandler(t *testing.T) {
+	key := generateEd25519Key(t)
+	keys, err := NewKeySet(key)
+	require.NoError(t, err)
+	authService := NewAuthService(new(MockUserRepository), "secret", WithKeySet(keys))
+
+	req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	rr := httptest.NewRecorder()
+	authService.JWKS(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response struct {
+		Keys []JWK `json:"keys"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Len(t, response.Keys, 1)
+	assert.Equal(t, "OKP", response.Keys[0].Kty)
+	assert.Equal(t, "Ed25519", response.Keys[0].Crv)
+	assert.Equal(t, key.ID, response.Keys[0].Kid)
+}
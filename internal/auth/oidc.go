@@ -0,0 +1,499 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"notes-service/internal/repository"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	oidcStateCookie   = "oidc_state"
+	oidcStateTTL      = 10 * time.Minute
+	oidcStateType     = "oidc_state"
+	oidcJWKSRefresh   = 5 * time.Minute
+	maxUsernameLength = 64
+)
+
+// OIDCConfig описывает подключение к внешнему провайдеру OpenID Connect
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCProvider выполняет вход через провайдера OIDC по схеме authorization
+// code + PKCE. Метаданные и ключи провайдера загружаются при первом
+// обращении и кэшируются.
+type OIDCProvider struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]*Key
+	keysFetched time.Time
+}
+
+// NewOIDCProvider создает новый экземпляр OIDCProvider
+func NewOIDCProvider(cfg OIDCConfig) *OIDCProvider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	return &OIDCProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// WithOIDC включает вход через внешнего провайдера (/login/oidc)
+func WithOIDC(provider *OIDCProvider) Option {
+	return func(s *AuthServiceImpl) {
+		s.oidc = provider
+	}
+}
+
+// oidcClaims — поля ID-токена, которые использует сервис
+type oidcClaims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+}
+
+func (p *OIDCProvider) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, &discovery); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC discovery document: %w", err)
+	}
+	if discovery.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("OIDC issuer mismatch: expected %q, got %q", p.cfg.Issuer, discovery.Issuer)
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// lookupKey ищет ключ провайдера по kid, перечитывая JWKS, если ключ
+// неизвестен (провайдер мог провести ротацию)
+func (p *OIDCProvider) lookupKey(ctx context.Context, kid string) (*Key, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < oidcJWKSRefresh && p.keys != nil {
+		return nil, ErrUnknownKey
+	}
+
+	var jwks struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := p.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC keys: %w", err)
+	}
+
+	p.keys = make(map[string]*Key)
+	p.keysFetched = time.Now()
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.Key()
+		if err != nil {
+			continue // ключи неподдерживаемых типов пропускаются
+		}
+		p.keys[key.ID] = key
+	}
+
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (p *OIDCProvider) authCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.cfg.ClientID)
+	params.Set("redirect_uri", p.cfg.RedirectURL)
+	params.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", pkceChallenge(verifier))
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return discovery.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// exchange обменивает код авторизации на ID-токен и проверяет его
+func (p *OIDCProvider) exchange(ctx context.Context, code, verifier, nonce string) (*oidcClaims, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("client_id", p.cfg.ClientID)
+	form.Set("client_secret", p.cfg.ClientSecret)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return nil, err
+	}
+	if tokenResponse.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return p.verifyIDToken(ctx, tokenResponse.IDToken, nonce)
+}
+
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, idToken, nonce string) (*oidcClaims, error) {
+	token, err := jwt.Parse(idToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.lookupKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return key.Public, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid ID token claims")
+	}
+	if !claims.VerifyIssuer(p.cfg.Issuer, true) {
+		return nil, errors.New("invalid ID token issuer")
+	}
+	if !verifyAudience(claims["aud"], p.cfg.ClientID) {
+		return nil, errors.New("invalid ID token audience")
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("ID token has no expiration")
+	}
+	if claims["nonce"] != nonce {
+		return nil, errors.New("invalid ID token nonce")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+
+	result := &oidcClaims{Subject: subject}
+	result.Email, _ = claims["email"].(string)
+	result.EmailVerified, _ = claims["email_verified"].(bool)
+	result.PreferredUsername, _ = claims["preferred_username"].(string)
+	return result, nil
+}
+
+// verifyAudience проверяет aud, который по спецификации может быть строкой или массивом
+func verifyAudience(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return jwt.EncodeSegment(sum[:])
+}
+
+// StartOIDCLogin перенаправляет пользователя к провайдеру (GET /login/oidc).
+// state, nonce и code_verifier сохраняются в подписанной cookie, поэтому
+// callback может обработать любой экземпляр сервиса.
+func (s *AuthServiceImpl) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		http.Error(w, "OIDC login is not configured", http.StatusNotFound)
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		v, err := randomToken(32)
+		if err != nil {
+			http.Error(w, "Failed to start login", http.StatusInternalServerError)
+			return
+		}
+		values[i] = v
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authURL, err := s.oidc.authCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		http.Error(w, "Identity provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	cookie, err := s.signToken(jwt.MapClaims{
+		"typ":      oidcStateType,
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"exp":      time.Now().Add(oidcStateTTL).Unix(),
+	})
+	if err != nil {
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    cookie,
+		Path:     "/login/oidc",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil || strings.HasPrefix(s.oidc.cfg.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback завершает вход через провайдера (GET /login/oidc/callback)
+func (s *AuthServiceImpl) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if s.oidc == nil {
+		http.Error(w, "OIDC login is not configured", http.StatusNotFound)
+		return
+	}
+
+	if errCode := r.URL.Query().Get("error"); errCode != "" {
+		http.Error(w, "Identity provider returned error: "+errCode, http.StatusUnauthorized)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		http.Error(w, "Missing login state", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/login/oidc", MaxAge: -1})
+
+	stateClaims, err := s.parseToken(cookie.Value)
+	if err != nil || stateClaims["typ"] != oidcStateType {
+		http.Error(w, "Invalid login state", http.StatusBadRequest)
+		return
+	}
+	if state, _ := stateClaims["state"].(string); state == "" || state != r.URL.Query().Get("state") {
+		http.Error(w, "Invalid login state", http.StatusBadRequest)
+		return
+	}
+	nonce, _ := stateClaims["nonce"].(string)
+	verifier, _ := stateClaims["verifier"].(string)
+
+	claims, err := s.oidc.exchange(r.Context(), r.URL.Query().Get("code"), verifier, nonce)
+	if err != nil {
+		http.Error(w, "Failed to verify identity", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := s.userForIdentity(r.Context(), claims)
+	if err != nil {
+		http.Error(w, "Failed to link identity", http.StatusInternalServerError)
+		return
+	}
+
+	if user.TOTPEnabled {
+		s.requireSecondFactor(w, user)
+		return
+	}
+	s.respondWithToken(w, user)
+}
+
+// userForIdentity находит пользователя, связанного с внешней учетной записью.
+// При первом входе учетная запись связывается с пользователем с тем же email,
+// только если адрес подтвержден и провайдером, и самим пользователем;
+// иначе создается новый пользователь без пароля. Без проверки на стороне
+// сервиса достаточно было бы заранее зарегистрироваться с чужим адресом,
+// чтобы получить доступ к его учетной записи у провайдера.
+func (s *AuthServiceImpl) userForIdentity(ctx context.Context, claims *oidcClaims) (*repository.User, error) {
+	provider := s.oidc.cfg.Issuer
+
+	user, err := s.userRepo.GetUserByIdentity(ctx, provider, claims.Subject)
+	if err == nil || !errors.Is(err, repository.ErrNotFound) {
+		return user, err
+	}
+
+	if claims.Email != "" && claims.EmailVerified {
+		user, err = s.userRepo.GetUserByEmail(ctx, claims.Email)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		if user != nil && !user.EmailVerified {
+			user = nil
+		}
+	}
+
+	if user == nil {
+		user, err = s.createUserForIdentity(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.userRepo.CreateIdentity(ctx, user.ID, provider, claims.Subject, claims.Email)
+	if errors.Is(err, repository.ErrDuplicate) {
+		// Параллельный первый вход уже создал связь
+		return s.userRepo.GetUserByIdentity(ctx, provider, claims.Subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+var usernameDisallowed = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func (s *AuthServiceImpl) createUserForIdentity(ctx context.Context, claims *oidcClaims) (*repository.User, error) {
+	base := claims.PreferredUsername
+	if base == "" && claims.Email != "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = usernameDisallowed.ReplaceAllString(base, "")
+	if base == "" {
+		base = "user"
+	}
+	if len(base) > maxUsernameLength-6 {
+		base = base[:maxUsernameLength-6]
+	}
+
+	// Пароль случайный и нигде не сохраняется: войти можно только через
+	// провайдера, пока пользователь не сбросит пароль
+	password, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	email := ""
+	if claims.EmailVerified {
+		email = claims.Email
+	}
+
+	username := base
+	for attempt := 0; attempt < 5; attempt++ {
+		user, err := s.userRepo.CreateUser(ctx, username, password, email)
+		if err == nil && email != "" {
+			// Адрес подтвержден провайдером
+			if err := s.userRepo.MarkEmailVerified(ctx, user.ID, email); err != nil {
+				return nil, err
+			}
+			user.EmailVerified = true
+		}
+		if err == nil {
+			return user, nil
+		}
+		if errors.Is(err, repository.ErrEmailTaken) {
+			// Адрес занят другим пользователем, с которым учетная запись не
+			// связывается: пользователь создается без адреса
+			email = ""
+			continue
+		}
+		if !errors.Is(err, repository.ErrDuplicate) {
+			return nil, err
+		}
+
+		suffix, err := randomToken(3)
+		if err != nil {
+			return nil, err
+		}
+		username = base + "-" + strings.ToLower(usernameDisallowed.ReplaceAllString(suffix, ""))
+	}
+	return nil, errors.New("failed to pick a unique username")
+}
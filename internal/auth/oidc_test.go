@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"notes-service/internal/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// stubOIDCServer — минимальный провайдер OIDC: discovery, JWKS и token endpoint
+type stubOIDCServer struct {
+	*httptest.Server
+	key *Key
+
+	// Параметры последнего запроса авторизации
+	nonce     string
+	challenge string
+	subject   string
+	email     string
+}
+
+func newStubOIDCServer(t *testing.T) *stubOIDCServer {
+	stub := &stubOIDCServer{key: generateRSAKey(t), subject: "external-42", email: "sso@example.com"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 stub.URL,
+			"authorization_endpoint": stub.URL + "/authorize",
+			"token_endpoint":         stub.URL + "/token",
+			"jwks_uri":               stub.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		jwk, _ := stub.key.JWK()
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []JWK{jwk}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "valid-code" || r.Form.Get("client_secret") != "client-secret" ||
+			pkceChallenge(r.Form.Get("code_verifier")) != stub.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":                stub.URL,
+			"aud":                "notes-client",
+			"sub":                stub.subject,
+			"email":              stub.email,
+			"email_verified":     true,
+			"preferred_username": "sso.user",
+			"nonce":              stub.nonce,
+			"exp":                time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = stub.key.ID
+		idToken, _ := token.SignedString(stub.key.signingKey())
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "id_token": idToken})
+	})
+	stub.Server = httptest.NewServer(mux)
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+// startLogin выполняет GET /login/oidc и запоминает параметры, которые
+// провайдер получил бы в запросе авторизации
+func (stub *stubOIDCServer) startLogin(t *testing.T, s *AuthServiceImpl) (*http.Cookie, string) {
+	req, _ := http.NewRequest("GET", "/login/oidc", nil)
+	rr := httptest.NewRecorder()
+	s.StartOIDCLogin(rr, req)
+	require.Equal(t, http.StatusFound, rr.Code)
+
+	location, err := url.Parse(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, stub.URL+"/authorize", location.Scheme+"://"+location.Host+location.Path)
+
+	query := location.Query()
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, "notes-client", query.Get("client_id"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, "openid profile email", query.Get("scope"))
+	stub.nonce = query.Get("nonce")
+	stub.challenge = query.Get("code_challenge")
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	return cookies[0], query.Get("state")
+}
+
+func newOIDCAuthService(stub *stubOIDCServer, repo *MockUserRepository) *AuthServiceImpl {
+	return NewAuthService(repo, "secret", WithOIDC(NewOIDCProvider(OIDCConfig{
+		Issuer:       stub.URL,
+		ClientID:     "notes-client",
+		ClientSecret: "client-secret",
+		RedirectURL:  "http://localhost:8080/login/oidc/callback",
+	})))
+}
+
+func callback(s *AuthServiceImpl, cookie *http.Cookie, state, code string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/login/oidc/callback?code="+code+"&state="+state, nil)
+	req.AddCookie(cookie)
+	rr := httptest.NewRecorder()
+	s.OIDCCallback(rr, req)
+	return rr
+}
+
+func TestOIDCLoginLinkedIdentity(t *testing.T) {
+	stub := newStubOIDCServer(t)
+	mockRepo := new(MockUserRepository)
+	authService := newOIDCAuthService(stub, mockRepo)
+
+	mockRepo.On("GetUserByIdentity", mock.Anything, stub.URL, "external-42").
+		Return(&repository.User{ID: 5, Username: "testuser"}, nil)
+
+	cookie, state := stub.startLogin(t, authService)
+	rr := callback(authService, cookie, state, "valid-code")
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var response map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	code, userID := authenticatedUserID(t, authService, response["token"])
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(5), userID)
+}
+
+func TestOIDCLoginCreatesUserOnFirstLogin(t *testing.T) {
+	stub := newStubOIDCServer(t)
+	mockRepo := new(MockUserRepository)
+	authService := newOIDCAuthService(stub, mockRepo)
+
+	mockRepo.On("GetUserByIdentity", mock.Anything, stub.URL, "external-42").
+		Return((*repository.User)(nil), repository.ErrNotFound)
+	mockRepo.On("GetUserByEmail", mock.Anything, "sso@example.com").
+		Return((*repository.User)(nil), repository.ErrNotFound)
+	mockRepo.On("CreateUser", mock.Anything, "sso.user", mock.AnythingOfType("string"), "sso@example.com").
+		Return(&repository.User{ID: 9, Username: "sso.user"}, nil)
+	mockRepo.On("MarkEmailVerified", mock.Anything, int64(9), "sso@example.com").Return(nil)
+	mockRepo.On("CreateIdentity", mock.Anything, int64(9), stub.URL, "external-42", "sso@example.com").
+		Return(nil)
+
+	cookie, state := stub.startLogin(t, authService)
+	rr := callback(authService, cookie, state, "valid-code")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestOIDCLoginUsernameTakenKeepsEmail(t *testing.T) {
+	stub := newStubOIDCServer(t)
+	mockRepo := new(MockUserRepository)
+	authService := newOIDCAuthService(stub, mockRepo)
+
+	mockRepo.On("GetUserByIdentity", mock.Anything, stub.URL, "external-42").
+		Return((*repository.User)(nil), repository.ErrNotFound)
+	mockRepo.On("GetUserByEmail", mock.Anything, "sso@example.com").
+		Return((*repository.User)(nil), repository.ErrNotFound)
+	mockRepo.On("CreateUser", mock.Anything, "sso.user", mock.AnythingOfType("string"), "sso@example.com").
+		Return((*repository.User)(nil), repository.ErrDuplicate).Once()
+	mockRepo.On("CreateUser", mock.Anything, mock.MatchedBy(func(username string) bool {
+		return strings.HasPrefix(username, "sso.user-")
+	}), mock.AnythingOfType("string"), "sso@example.com").
+		Return(&repository.User{ID: 9, Username: "sso.user-abcd"}, nil).Once()
+	mockRepo.On("MarkEmailVerified", mock.Anything, int64(9), "sso@example.com").Return(nil)
+	mockRepo.On("CreateIdentity", mock.Anything, int64(9), stub.URL, "external-42", "sso@example.com").
+		Return(nil)
+
+	cookie, state := stub.startLogin(t, authService)
+	rr := callback(authService, cookie, state, "valid-code")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestOIDCLoginLinksVerifiedEmail(t *testing.T) {
+	stub := newStubOIDCServer(t)
+	mockRepo := new(MockUserRepository)
+	authService := newOIDCAuthService(stub, mockRepo)
+
+	mockRepo.On("GetUserByIdentity", mock.Anything, stub.URL, "external-42").
+		Return((*repository.User)(nil), repository.ErrNotFound)
+	mockRepo.On("GetUserByEmail", mock.Anything, "sso@example.com").
+		Return(&repository.User{ID: 5, Username: "testuser", Email: "sso@example.com", EmailVerified: true}, nil)
+	mockRepo.On("CreateIdentity", mock.Anything, int64(5), stub.URL, "external-42", "sso@example.com").
+		Return(nil)
+
+	cookie, state := stub.startLogin(t, authService)
+	rr := callback(authService, cookie, state, "valid-code")
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestOIDCLoginDoesNotLinkUnverifiedEmail(t *testing.T) {
+	stub := newStubOIDCServer(t)
+	mockRepo := new(MockUserRepository)
+	authService := newOIDCAuthService(stub, mockRepo)
+
+	// Кто-то заранее зарегистрировался с адресом владельца учетной записи у
+	// провайдера, но не подтвердил его
+	mockRepo.On("GetUserByIdentity", mock.Anything, stub.URL, "external-42").
+		Return((*repository.User)(nil), repository.ErrNotFound)
+	mockRepo.On("GetUserByEmail", mock.Anything, "sso@example.com").
+		Return(&repository.User{ID: 5, Username: "attacker", Email: "sso@example.com"}, nil)
+	mockRepo.On("CreateUser", mock.Anything, "sso.user", mock.AnythingOfType("string"), "sso@example.com").
+		Return((*repository.User)(nil), repository.ErrEmailTaken).Once()
+	mockRepo.On("CreateUser", mock.Anything, "sso.user", mock.AnythingOfType("string"), "").
+		Return(&repository.User{ID: 9, Username: "sso.user"}, nil).Once()
+	mockRepo.On("CreateIdentity", mock.Anything, int64(9), stub.URL, "external-42", "sso@example.com").
+		Return(nil)
+
+	cookie, state := stub.startLogin(t, authService)
+	rr := callback(authService, cookie, state, "valid-code")
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var response map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	_, userID := authenticatedUserID(t, authService, response["token"])
+	assert.Equal(t, int64(9), userID)
+	mockRepo.AssertNotCalled(t, "CreateIdentity", mock.Anything, int64(5), mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	stub := newStubOIDCServer(t)
+	mockRepo := new(MockUserRepository)
+	authService := newOIDCAuthService(stub, mockRepo)
+
+	cookie, _ := stub.startLogin(t, authService)
+	rr := callback(authService, cookie, "forged-state", "valid-code")
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockRepo.AssertNotCalled(t, "GetUserByIdentity", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOIDCCallbackRejectsWrongNonce(t *testing.T) {
+	stub := newStubOIDCServer(t)
+	mockRepo := new(MockUserRepository)
+	authService := newOIDCAuthService(stub, mockRepo)
+
+	cookie, state := stub.startLogin(t, authService)
+	stub.nonce = "replayed-nonce"
+	rr := callback(authService, cookie, state, "valid-code")
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestOIDCCallbackRejectsInvalidCode(t *testing.T) {
+	stub := newStubOIDCServer(t)
+	authService := newOIDCAuthService(stub, new(MockUserRepository))
+
+	cookie, state := stub.startLogin(t, authService)
+	rr := callback(authService, cookie, state, "stolen-code")
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
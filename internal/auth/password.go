@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"notes-service/internal/mail"
+	"notes-service/internal/ratelimit"
+	"notes-service/internal/repository"
+)
+
+const (
+	minPasswordLength = 8
+	resetTokenTTL     = time.Hour
+)
+
+// WithPasswordReset включает сброс пароля по email. Ссылка в письме строится
+// как resetURL?token=<токен>.
+func WithPasswordReset(mailer mail.Sender, resetURL string) Option {
+	return func(s *AuthServiceImpl) {
+		s.mailer = mailer
+		s.resetURL = resetURL
+	}
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func validatePassword(password string) error {
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters long", minPasswordLength)
+	}
+	return nil
+}
+
+// ChangePassword меняет пароль текущего пользователя (POST /me/password).
+// Все остальные сессии и ключи API отзываются, вызывающему возвращается
+// новый токен.
+func (s *AuthServiceImpl) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.userRepo.ValidateUser(r.Context(), user.Username, req.CurrentPassword); err != nil {
+		if errors.Is(err, repository.ErrInvalidCredentials) {
+			http.Error(w, "Invalid current password", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Failed to validate credentials", http.StatusInternalServerError)
+		return
+	}
+
+	var err error
+	user.TokenVersion, err = s.userRepo.UpdatePassword(r.Context(), user.ID, req.NewPassword)
+	if err != nil {
+		http.Error(w, "Failed to update password", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := s.generateToken(user)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"token": token})
+}
+
+// ForgotPassword отправляет ссылку для сброса пароля (POST /password/forgot).
+// Ответ не зависит от того, существует ли пользователь с таким адресом.
+func (s *AuthServiceImpl) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if s.mailer == nil {
+		http.Error(w, "Password reset is not configured", http.StatusNotImplemented)
+		return
+	}
+	if !s.allow(w, r, "forgot-ip:"+ratelimit.ClientIP(r)) {
+		return
+	}
+
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.userRepo.GetUserByEmail(r.Context(), req.Email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Failed to load user", http.StatusInternalServerError)
+		return
+	}
+	if user != nil {
+		if err := s.sendResetLink(r.Context(), user); err != nil {
+			log.Printf("Failed to send password reset link to user %d: %v", user.ID, err)
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *AuthServiceImpl) sendResetLink(ctx context.Context, user *repository.User) error {
+	token, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.CreatePasswordResetToken(ctx, user.ID, HashToken(token), time.Now().Add(resetTokenTTL)); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Hello, %s!\n\nTo reset your password follow the link below. It is valid for %s and can be used once.\n\n%s?token=%s\n\nIf you did not request a password reset, ignore this message.",
+			user.Username, resetTokenTTL, s.resetURL, token),
+	})
+}
+
+// ResetPassword устанавливает новый пароль по одноразовому токену (POST /password/reset)
+func (s *AuthServiceImpl) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, err := s.userRepo.ResetPassword(r.Context(), HashToken(req.Token), req.NewPassword)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	s.resetLoginFailures(r.Context(), userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken возвращает SHA-256 от токена в hex. Под этим хешем хранятся все
+// случайные токены сервиса: сброса пароля, ключей API, публичных ссылок.
+// Токены случайные и длинные, поэтому медленный хеш для них не нужен.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/mail"
+	"notes-service/internal/repository"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockMailSender struct {
+	mock.Mock
+}
+
+func (m *MockMailSender) Send(ctx context.Context, msg mail.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
+func TestChangePassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret")
+
+	user := &repository.User{ID: 1, Username: "testuser", TokenVersion: 3}
+	mockRepo.On("GetUserByID", mock.Anything, int64(1)).Return(user, nil)
+	mockRepo.On("ValidateUser", mock.Anything, "testuser", "old-password").Return(user, nil)
+	mockRepo.On("UpdatePassword", mock.Anything, int64(1), "new-password").Return(4, nil)
+
+	reqBody := bytes.NewBufferString(`{"current_password":"old-password","new_password":"new-password"}`)
+	req, _ := http.NewRequest("POST", "/me/password", reqBody)
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, int64(1)))
+	rr := httptest.NewRecorder()
+
+	authService.ChangePassword(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertExpectations(t)
+
+	var response map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	// Новый токен выдан с новой версией, старые токены отозваны
+	code, _ := authenticatedUserID(t, authService, response["token"])
+	assert.Equal(t, http.StatusOK, code)
+	oldToken, err := authService.generateToken(&repository.User{ID: 1, Username: "testuser", TokenVersion: 3})
+	require.NoError(t, err)
+	code, _ = authenticatedUserID(t, authService, oldToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestChangePasswordWrongCurrentPassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret")
+
+	mockRepo.On("GetUserByID", mock.Anything, int64(1)).Return(&repository.User{ID: 1, Username: "testuser"}, nil)
+	mockRepo.On("ValidateUser", mock.Anything, "testuser", "wrong").
+		Return((*repository.User)(nil), repository.ErrInvalidCredentials)
+
+	reqBody := bytes.NewBufferString(`{"current_password":"wrong","new_password":"new-password"}`)
+	req, _ := http.NewRequest("POST", "/me/password", reqBody)
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, int64(1)))
+	rr := httptest.NewRecorder()
+
+	authService.ChangePassword(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	mockRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockMailer := new(MockMailSender)
+	authService := NewAuthService(mockRepo, "secret", WithPasswordReset(mockMailer, "https://notes.example.com/reset"))
+
+	var tokenHash string
+	mockRepo.On("GetUserByEmail", mock.Anything, "user@example.com").
+		Return(&repository.User{ID: 1, Username: "testuser", Email: "user@example.com"}, nil)
+	mockRepo.On("CreatePasswordResetToken", mock.Anything, int64(1), mock.AnythingOfType("string"), mock.Anything).
+		Run(func(args mock.Arguments) { tokenHash = args.String(2) }).
+		Return(nil)
+
+	var sent mail.Message
+	mockMailer.On("Send", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { sent = args.Get(1).(mail.Message) }).
+		Return(nil)
+
+	req, _ := http.NewRequest("POST", "/password/forgot", bytes.NewBufferString(`{"email":"user@example.com"}`))
+	rr := httptest.NewRecorder()
+	authService.ForgotPassword(rr, req)
+
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "user@example.com", sent.To)
+
+	// В письме — сам токен, в базе — только его хеш
+	idx := strings.Index(sent.Body, "https://notes.example.com/reset?token=")
+	require.NotEqual(t, -1, idx)
+	token := strings.Fields(sent.Body[idx+len("https://notes.example.com/reset?token="):])[0]
+	assert.Equal(t, HashToken(token), tokenHash)
+	assert.NotContains(t, tokenHash, token)
+
+	mockRepo.On("ResetPassword", mock.Anything, tokenHash, "new-password").Return(int64(1), nil)
+
+	reqBody := bytes.NewBufferString(`{"token":"` + token + `","new_password":"new-password"}`)
+	req, _ = http.NewRequest("POST", "/password/reset", reqBody)
+	rr = httptest.NewRecorder()
+	authService.ResetPassword(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockMailer := new(MockMailSender)
+	authService := NewAuthService(mockRepo, "secret", WithPasswordReset(mockMailer, "https://notes.example.com/reset"))
+
+	mockRepo.On("GetUserByEmail", mock.Anything, "nobody@example.com").
+		Return((*repository.User)(nil), repository.ErrNotFound)
+
+	req, _ := http.NewRequest("POST", "/password/forgot", bytes.NewBufferString(`{"email":"nobody@example.com"}`))
+	rr := httptest.NewRecorder()
+	authService.ForgotPassword(rr, req)
+
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	mockMailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestResetPasswordInvalidToken(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret")
+
+	mockRepo.On("ResetPassword", mock.Anything, HashToken("used-token"), "new-password").
+		Return(int64(0), repository.ErrNotFound)
+
+	reqBody := bytes.NewBufferString(`{"token":"used-token","new_password":"new-password"}`)
+	req, _ := http.NewRequest("POST", "/password/reset", reqBody)
+	rr := httptest.NewRecorder()
+	authService.ResetPassword(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/ratelimit"
+	"notes-service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func loginRequest(username, password string) *http.Request {
+	body := bytes.NewBufferString(`{"username":"` + username + `","password":"` + password + `"}`)
+	req, _ := http.NewRequest("POST", "/login", body)
+	req.RemoteAddr = "10.0.0.1:12345"
+	return req
+}
+
+func TestLoginRateLimitedPerIP(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), 1, 2)
+	authService := NewAuthService(mockRepo, "secret", WithRateLimiter(limiter))
+
+	mockRepo.On("ValidateUser", mock.Anything, mock.Anything, mock.Anything).
+		Return((*repository.User)(nil), repository.ErrInvalidCredentials)
+
+	for _, username := range []string{"alice", "bob"} {
+		rr := httptest.NewRecorder()
+		authService.Login(rr, loginRequest(username, "wrong"))
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	}
+
+	rr := httptest.NewRecorder()
+	authService.Login(rr, loginRequest("carol", "wrong"))
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	mockRepo.AssertNumberOfCalls(t, "ValidateUser", 2)
+}
+
+func TestLoginLockedAccount(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret", WithLockout(LockoutPolicy{Threshold: 3, Duration: time.Minute}))
+
+	mockRepo.On("GetLockedUntil", mock.Anything, "testuser").Return(time.Now().Add(30*time.Second), nil)
+	mockRepo.On("ValidateUser", mock.Anything, "testuser", "password").
+		Return(&repository.User{ID: 1, Username: "testuser"}, nil)
+
+	rr := httptest.NewRecorder()
+	authService.Login(rr, loginRequest("testuser", "password"))
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Empty(t, rr.Header().Get("Retry-After"))
+	mockRepo.AssertNotCalled(t, "RecordLoginFailure", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "ResetLoginFailures", mock.Anything, mock.Anything)
+}
+
+func TestLoginLockedAccountLooksLikeUnknownUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), 60, 10)
+	authService := NewAuthService(mockRepo, "secret",
+		WithRateLimiter(limiter), WithLockout(LockoutPolicy{Threshold: 3, Duration: time.Minute}))
+
+	mockRepo.On("GetLockedUntil", mock.Anything, "alice").Return(time.Now().Add(time.Minute), nil)
+	mockRepo.On("GetLockedUntil", mock.Anything, "nobody").Return(time.Time{}, nil)
+	mockRepo.On("ValidateUser", mock.Anything, mock.Anything, "wrong").
+		Return((*repository.User)(nil), repository.ErrInvalidCredentials)
+	mockRepo.On("RecordLoginFailure", mock.Anything, "nobody").Return(0, nil)
+
+	locked := httptest.NewRecorder()
+	authService.Login(locked, loginRequest("alice", "wrong"))
+	unknown := httptest.NewRecorder()
+	authService.Login(unknown, loginRequest("nobody", "wrong"))
+
+	assert.Equal(t, http.StatusUnauthorized, locked.Code)
+	assert.Equal(t, unknown.Code, locked.Code)
+	assert.Equal(t, unknown.Header(), locked.Header())
+	assert.Equal(t, unknown.Body.String(), locked.Body.String())
+}
+
+func TestLoginFailureLocksAccount(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret", WithLockout(LockoutPolicy{Threshold: 3, Duration: time.Minute}))
+
+	mockRepo.On("GetLockedUntil", mock.Anything, "testuser").Return(time.Time{}, nil)
+	mockRepo.On("ValidateUser", mock.Anything, "testuser", "wrong").
+		Return((*repository.User)(nil), repository.ErrInvalidCredentials)
+	mockRepo.On("RecordLoginFailure", mock.Anything, "testuser").Return(3, nil)
+	mockRepo.On("LockUser", mock.Anything, "testuser", mock.AnythingOfType("time.Time")).Return(nil)
+
+	rr := httptest.NewRecorder()
+	authService.Login(rr, loginRequest("testuser", "wrong"))
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLoginSuccessResetsFailures(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret", WithLockout(LockoutPolicy{Threshold: 3, Duration: time.Minute}))
+
+	mockRepo.On("GetLockedUntil", mock.Anything, "testuser").Return(time.Now().Add(-time.Minute), nil)
+	mockRepo.On("ValidateUser", mock.Anything, "testuser", "password").
+		Return(&repository.User{ID: 1, Username: "testuser"}, nil)
+	mockRepo.On("ResetLoginFailures", mock.Anything, int64(1)).Return(nil)
+
+	rr := httptest.NewRecorder()
+	authService.Login(rr, loginRequest("testuser", "password"))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLockoutPolicyIsProgressive(t *testing.T) {
+	policy := LockoutPolicy{Threshold: 5, Duration: time.Minute, MaxDuration: 10 * time.Minute}
+
+	assert.Equal(t, time.Duration(0), policy.lockDuration(4))
+	assert.Equal(t, time.Minute, policy.lockDuration(5))
+	assert.Equal(t, time.Duration(0), policy.lockDuration(6))
+	assert.Equal(t, 2*time.Minute, policy.lockDuration(10))
+	assert.Equal(t, 4*time.Minute, policy.lockDuration(15))
+	assert.Equal(t, 10*time.Minute, policy.lockDuration(50))
+}
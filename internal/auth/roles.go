@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"notes-service/internal/repository"
+)
+
+// RoleFromContext возвращает роль пользователя, выполняющего запрос
+func RoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(roleKey).(string)
+	return role
+}
+
+// RequireRole пропускает только пользователей с ролью role. Роль берется из
+// базы при каждом запросе, поэтому понижение прав действует сразу.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if RoleFromContext(r.Context()) != role {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// disabled отвечает 403, если аккаунт отключен администратором
+func disabled(w http.ResponseWriter, user *repository.User) bool {
+	if user.DisabledAt == nil {
+		return false
+	}
+	http.Error(w, "Account disabled", http.StatusForbidden)
+	return true
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireRole(t *testing.T) {
+	handler := RequireRole(repository.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for role, expected := range map[string]int{
+		repository.RoleAdmin: http.StatusOK,
+		repository.RoleUser:  http.StatusForbidden,
+	} {
+		req, _ := http.NewRequest("GET", "/admin/users", nil)
+		req = req.WithContext(NewContext(req.Context(), 1, role))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, expected, rr.Code, role)
+	}
+}
+
+func TestAuthenticateUsesCurrentRole(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret")
+
+	// Токен выдан до назначения администратором; роль берется из базы
+	token, err := authService.generateToken(&repository.User{ID: 1, Username: "testuser", Role: repository.RoleUser})
+	require.NoError(t, err)
+	mockRepo.On("GetUserByID", mock.Anything, int64(1)).
+		Return(&repository.User{ID: 1, Role: repository.RoleAdmin}, nil)
+
+	var role string
+	handler := authService.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role = RoleFromContext(r.Context())
+	}))
+	req, _ := http.NewRequest("GET", "/admin/users", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, repository.RoleAdmin, role)
+}
+
+func TestAuthenticateRejectsDisabledAccount(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret")
+
+	token, err := authService.generateToken(&repository.User{ID: 1, Username: "testuser"})
+	require.NoError(t, err)
+	disabledAt := time.Now()
+	mockRepo.On("GetUserByID", mock.Anything, int64(1)).
+		Return(&repository.User{ID: 1, DisabledAt: &disabledAt}, nil)
+
+	handler := authService.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not be called for a disabled account")
+	}))
+	req, _ := http.NewRequest("GET", "/notes", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestLoginRejectsDisabledAccount(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret")
+
+	disabledAt := time.Now()
+	mockRepo.On("ValidateUser", mock.Anything, "testuser", "password").
+		Return(&repository.User{ID: 1, Username: "testuser", DisabledAt: &disabledAt}, nil)
+
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(`{"username":"testuser","password":"password"}`))
+	rr := httptest.NewRecorder()
+	authService.Login(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "token")
+}
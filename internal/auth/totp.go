@@ -0,0 +1,388 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"notes-service/internal/ratelimit"
+	"notes-service/internal/repository"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	totpDigits = 6
+	totpPeriod = 30 // секунд
+	// totpSkew — сколько соседних шагов принимается из-за расхождения часов
+	totpSkew = 1
+
+	mfaTokenType      = "mfa"
+	mfaTokenTTL       = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// WithTOTPIssuer задает имя сервиса, которое приложение-аутентификатор
+// показывает рядом с аккаунтом
+func WithTOTPIssuer(issuer string) Option {
+	return func(s *AuthServiceImpl) {
+		s.totpIssuer = issuer
+	}
+}
+
+// generateTOTPSecret создает 160-битный секрет, как рекомендует RFC 4226
+func generateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base32NoPadding.EncodeToString(secret), nil
+}
+
+// totpCode вычисляет код RFC 6238 (HMAC-SHA1) для временного шага step
+func totpCode(secret string, step int64) (string, error) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// validateTOTP проверяет код с учетом соседних шагов и возвращает шаг, которому
+// он соответствует
+func validateTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := totpCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// otpauthURI формирует URI для QR-кода по формату Google Authenticator
+func otpauthURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// generateRecoveryCodes создает одноразовые коды вида xxxxx-xxxxx
+func generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32NoPadding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type LoginTwoFactorRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// requireSecondFactor отвечает на вход по паролю промежуточным токеном,
+// который можно обменять на обычный только через /login/2fa
+func (s *AuthServiceImpl) requireSecondFactor(w http.ResponseWriter, user *repository.User) {
+	token, err := s.signToken(jwt.MapClaims{
+		"user_id": user.ID,
+		"typ":     mfaTokenType,
+		"ver":     user.TokenVersion,
+		"exp":     time.Now().Add(mfaTokenTTL).Unix(),
+	})
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"mfa_required": true,
+		"mfa_token":    token,
+	})
+}
+
+// LoginTwoFactor завершает вход кодом TOTP или кодом восстановления (POST /login/2fa)
+func (s *AuthServiceImpl) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if !s.allow(w, r, "login-ip:"+ratelimit.ClientIP(r)) {
+		return
+	}
+
+	var req LoginTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims, err := s.parseToken(req.MFAToken)
+	if err != nil || claims["typ"] != mfaTokenType {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+	userID, _ := claims["user_id"].(float64)
+	version, _ := claims["ver"].(float64)
+
+	user, err := s.userRepo.GetUserByID(r.Context(), int64(userID))
+	if err != nil || int(version) != user.TokenVersion || !user.TOTPEnabled {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	if !s.allow(w, r, usernameKey(user.Username)) {
+		return
+	}
+	locked, err := s.locked(r.Context(), user.Username)
+	if err != nil {
+		http.Error(w, "Failed to check account status", http.StatusInternalServerError)
+		return
+	}
+	if locked {
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	ok, err := s.verifySecondFactor(r, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		s.recordLoginFailure(r.Context(), user.Username)
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	s.resetLoginFailures(r.Context(), user.ID)
+	s.respondWithToken(w, user)
+}
+
+// verifySecondFactor проверяет код TOTP, а если он не передан — код восстановления
+func (s *AuthServiceImpl) verifySecondFactor(r *http.Request, user *repository.User, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, ok := validateTOTP(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return s.userRepo.UseTOTPStep(r.Context(), user.ID, step)
+	}
+
+	if recoveryCode != "" {
+		err := s.userRepo.ConsumeRecoveryCode(r.Context(), user.ID, HashToken(normalizeRecoveryCode(recoveryCode)))
+		if errors.Is(err, repository.ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	return false, nil
+}
+
+// SetupTwoFactor создает новый секрет TOTP и возвращает его вместе с
+// otpauth:// URI (POST /me/2fa/setup). 2FA включается только после
+// подтверждения кодом в EnableTwoFactor.
+func (s *AuthServiceImpl) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabled {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		http.Error(w, "Failed to generate secret", http.StatusInternalServerError)
+		return
+	}
+	if err := s.userRepo.SetTOTPSecret(r.Context(), user.ID, secret); err != nil {
+		http.Error(w, "Failed to save secret", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":      secret,
+		"otpauth_uri": otpauthURI(s.totpIssuer, user.Username, secret),
+	})
+}
+
+// EnableTwoFactor подтверждает подключение 2FA кодом из приложения и
+// возвращает коды восстановления, которые больше нигде не показываются
+// (POST /me/2fa/enable)
+func (s *AuthServiceImpl) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if user.TOTPEnabled {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	if user.TOTPSecret == "" {
+		http.Error(w, "Two-factor setup has not been started", http.StatusBadRequest)
+		return
+	}
+
+	step, valid := validateTOTP(user.TOTPSecret, req.Code, time.Now())
+	if !valid {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.userRepo.UseTOTPStep(r.Context(), user.ID, step); err != nil {
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		return
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = HashToken(code)
+	}
+
+	err = s.userRepo.EnableTOTP(r.Context(), user.ID, user.TOTPSecret, hashes)
+	if errors.Is(err, repository.ErrConflict) {
+		http.Error(w, "Two-factor setup has changed, start it again", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": codes})
+}
+
+// DisableTwoFactor отключает 2FA по паролю и коду TOTP или коду
+// восстановления (POST /me/2fa/disable)
+func (s *AuthServiceImpl) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req DisableTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !user.TOTPEnabled {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.userRepo.ValidateUser(r.Context(), user.Username, req.Password); err != nil {
+		if errors.Is(err, repository.ErrInvalidCredentials) {
+			http.Error(w, "Invalid password", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Failed to validate credentials", http.StatusInternalServerError)
+		return
+	}
+
+	valid, err := s.verifySecondFactor(r, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		http.Error(w, "Invalid code", http.StatusForbidden)
+		return
+	}
+
+	if err := s.userRepo.DisableTOTP(r.Context(), user.ID); err != nil {
+		http.Error(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// currentUser загружает пользователя, аутентифицированного Authenticate
+func (s *AuthServiceImpl) currentUser(w http.ResponseWriter, r *http.Request) (*repository.User, bool) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	user, err := s.userRepo.GetUserByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to load user", http.StatusInternalServerError)
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// Секрет "12345678901234567890" из тестовых векторов RFC 6238
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, expected := range vectors {
+		code, err := totpCode(rfcSecret, unix/totpPeriod)
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestValidateTOTPAllowsClockSkew(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	previous, err := totpCode(rfcSecret, now.Unix()/totpPeriod-1)
+	require.NoError(t, err)
+
+	step, ok := validateTOTP(rfcSecret, previous, now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/totpPeriod-1, step)
+
+	tooOld, err := totpCode(rfcSecret, now.Unix()/totpPeriod-2)
+	require.NoError(t, err)
+	_, ok = validateTOTP(rfcSecret, tooOld, now)
+	assert.False(t, ok)
+}
+
+func TestOTPAuthURI(t *testing.T) {
+	uri := otpauthURI("notes-service", "test user", rfcSecret)
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/notes-service:test%20user?"))
+	assert.Contains(t, uri, "secret="+rfcSecret)
+	assert.Contains(t, uri, "issuer=notes-service")
+}
+
+func TestLoginWithTwoFactor(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret")
+
+	user := &repository.User{ID: 1, Username: "testuser", TOTPSecret: rfcSecret, TOTPEnabled: true}
+	mockRepo.On("ValidateUser", mock.Anything, "testuser", "password").Return(user, nil)
+	mockRepo.On("GetUserByID", mock.Anything, int64(1)).Return(user, nil)
+
+	reqBody := bytes.NewBufferString(`{"username":"testuser","password":"password"}`)
+	req, _ := http.NewRequest("POST", "/login", reqBody)
+	rr := httptest.NewRecorder()
+	authService.Login(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var interim map[string]interface{}
+	json.Unmarshal(rr.Body.Bytes(), &interim)
+	assert.Equal(t, true, interim["mfa_required"])
+	assert.Nil(t, interim["token"])
+	mfaToken := interim["mfa_token"].(string)
+
+	// Промежуточный токен не дает доступа к API
+	code, _ := authenticatedUserID(t, authService, mfaToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	now := time.Now()
+	totp, err := totpCode(rfcSecret, now.Unix()/totpPeriod)
+	require.NoError(t, err)
+	mockRepo.On("UseTOTPStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(true, nil)
+
+	reqBody = bytes.NewBufferString(`{"mfa_token":"` + mfaToken + `","code":"` + totp + `"}`)
+	req, _ = http.NewRequest("POST", "/login/2fa", reqBody)
+	rr = httptest.NewRecorder()
+	authService.LoginTwoFactor(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var response map[string]string
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	code, userID := authenticatedUserID(t, authService, response["token"])
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(1), userID)
+}
+
+func TestLoginTwoFactorRejectsReplayedCode(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret")
+
+	user := &repository.User{ID: 1, Username: "testuser", TOTPSecret: rfcSecret, TOTPEnabled: true}
+	mockRepo.On("GetUserByID", mock.Anything, int64(1)).Return(user, nil)
+	mockRepo.On("UseTOTPStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(false, nil)
+
+	rr := httptest.NewRecorder()
+	authService.requireSecondFactor(rr, user)
+	var interim map[string]interface{}
+	json.Unmarshal(rr.Body.Bytes(), &interim)
+
+	totp, err := totpCode(rfcSecret, time.Now().Unix()/totpPeriod)
+	require.NoError(t, err)
+	reqBody := bytes.NewBufferString(`{"mfa_token":"` + interim["mfa_token"].(string) + `","code":"` + totp + `"}`)
+	req, _ := http.NewRequest("POST", "/login/2fa", reqBody)
+	rr = httptest.NewRecorder()
+	authService.LoginTwoFactor(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestEnableTwoFactorReturnsRecoveryCodes(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret")
+
+	mockRepo.On("GetUserByID", mock.Anything, int64(1)).
+		Return(&repository.User{ID: 1, Username: "testuser", TOTPSecret: rfcSecret}, nil)
+	mockRepo.On("UseTOTPStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(true, nil)
+
+	var storedHashes []string
+	mockRepo.On("EnableTOTP", mock.Anything, int64(1), rfcSecret, mock.Anything).
+		Run(func(args mock.Arguments) { storedHashes = args.Get(3).([]string) }).
+		Return(nil)
+
+	totp, err := totpCode(rfcSecret, time.Now().Unix()/totpPeriod)
+	require.NoError(t, err)
+	req, _ := http.NewRequest("POST", "/me/2fa/enable", bytes.NewBufferString(`{"code":"`+totp+`"}`))
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, int64(1)))
+	rr := httptest.NewRecorder()
+	authService.EnableTwoFactor(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var response map[string][]string
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	require.Len(t, response["recovery_codes"], recoveryCodeCount)
+	require.Len(t, storedHashes, recoveryCodeCount)
+	for i, code := range response["recovery_codes"] {
+		assert.Equal(t, HashToken(code), storedHashes[i])
+		assert.Equal(t, code, normalizeRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", ""))))
+	}
+}
+
+func TestEnableTwoFactorSecretReplaced(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	authService := NewAuthService(mockRepo, "secret")
+
+	mockRepo.On("GetUserByID", mock.Anything, int64(1)).
+		Return(&repository.User{ID: 1, Username: "testuser", TOTPSecret: rfcSecret}, nil)
+	mockRepo.On("UseTOTPStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(true, nil)
+	mockRepo.On("EnableTOTP", mock.Anything, int64(1), rfcSecret, mock.Anything).Return(repository.ErrConflict)
+
+	totp, err := totpCode(rfcSecret, time.Now().Unix()/totpPeriod)
+	require.NoError(t, err)
+	req, _ := http.NewRequest("POST", "/me/2fa/enable", bytes.NewBufferString(`{"code":"`+totp+`"}`))
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, int64(1)))
+	rr := httptest.NewRecorder()
+	authService.EnableTwoFactor(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "recovery_codes")
+}
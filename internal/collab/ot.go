@@ -0,0 +1,290 @@
+// Package collab объединяет одновременные правки текста заметки методом
+// операционных преобразований (OT) и ведет сессии совместного редактирования
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ErrLengthMismatch возвращается, если операция рассчитана на текст другой длины
+var ErrLengthMismatch = errors.New("operation does not match document length")
+
+// Component — шаг операции: пропустить Retain символов, вставить Insert или
+// удалить Delete символов. Задано ровно одно поле.
+type Component struct {
+	Retain int
+	Insert string
+	Delete int
+}
+
+// Operation — правка текста в формате ot.js: последовательность шагов,
+// которая проходит документ от начала до конца. В JSON положительное число
+// означает пропуск, отрицательное — удаление, строка — вставку. Длины
+// считаются в символах Unicode (code points).
+type Operation []Component
+
+// BaseLen возвращает длину текста, к которому применима операция
+func (op Operation) BaseLen() int {
+	n := 0
+	for _, c := range op {
+		n += c.Retain + c.Delete
+	}
+	return n
+}
+
+// TargetLen возвращает длину текста после применения операции
+func (op Operation) TargetLen() int {
+	n := 0
+	for _, c := range op {
+		n += c.Retain + utf8.RuneCountInString(c.Insert)
+	}
+	return n
+}
+
+// IsNoop сообщает, что операция не меняет текст
+func (op Operation) IsNoop() bool {
+	for _, c := range op {
+		if c.Insert != "" || c.Delete > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON кодирует операцию в формате ot.js
+func (op Operation) MarshalJSON() ([]byte, error) {
+	parts := make([]any, 0, len(op))
+	for _, c := range op {
+		switch {
+		case c.Insert != "":
+			parts = append(parts, c.Insert)
+		case c.Delete > 0:
+			parts = append(parts, -c.Delete)
+		default:
+			parts = append(parts, c.Retain)
+		}
+	}
+	return json.Marshal(parts)
+}
+
+// UnmarshalJSON разбирает операцию в формате ot.js
+func (op *Operation) UnmarshalJSON(data []byte) error {
+	var parts []json.RawMessage
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+	var b builder
+	for _, part := range parts {
+		var insert string
+		if err := json.Unmarshal(part, &insert); err == nil {
+			b.insert(insert)
+			continue
+		}
+		var n int
+		if err := json.Unmarshal(part, &n); err != nil || n == 0 {
+			return fmt.Errorf("invalid operation component %s", part)
+		}
+		if n > 0 {
+			b.retain(n)
+		} else {
+			b.delete(-n)
+		}
+	}
+	*op = b.op
+	return nil
+}
+
+// Apply применяет операцию к тексту
+func Apply(doc string, op Operation) (string, error) {
+	runes := []rune(doc)
+	if op.BaseLen() != len(runes) {
+		return "", ErrLengthMismatch
+	}
+	out := make([]rune, 0, op.TargetLen())
+	i := 0
+	for _, c := range op {
+		switch {
+		case c.Insert != "":
+			out = append(out, []rune(c.Insert)...)
+		case c.Delete > 0:
+			i += c.Delete
+		default:
+			out = append(out, runes[i:i+c.Retain]...)
+			i += c.Retain
+		}
+	}
+	return string(out), nil
+}
+
+// Transform преобразует одновременные операции a и b над одним текстом так,
+// что Apply(Apply(doc, a), b2) == Apply(Apply(doc, b), a2). Вставки a в одну
+// позицию со вставками b оказываются раньше.
+func Transform(a, b Operation) (a2, b2 Operation, err error) {
+	if a.BaseLen() != b.BaseLen() {
+		return nil, nil, ErrLengthMismatch
+	}
+
+	var ra, rb builder
+	i, j := 0, 0
+	var x, y Component
+	next := func(op Operation, k *int, c *Component) {
+		if *k < len(op) {
+			*c = op[*k]
+			*k++
+		} else {
+			*c = Component{}
+		}
+	}
+	next(a, &i, &x)
+	next(b, &j, &y)
+
+	for !x.empty() || !y.empty() {
+		if x.Insert != "" {
+			ra.insert(x.Insert)
+			rb.retain(utf8.RuneCountInString(x.Insert))
+			next(a, &i, &x)
+			continue
+		}
+		if y.Insert != "" {
+			ra.retain(utf8.RuneCountInString(y.Insert))
+			rb.insert(y.Insert)
+			next(b, &j, &y)
+			continue
+		}
+
+		n := min(x.Retain+x.Delete, y.Retain+y.Delete)
+		switch {
+		case x.Retain > 0 && y.Retain > 0:
+			ra.retain(n)
+			rb.retain(n)
+		case x.Delete > 0 && y.Retain > 0:
+			ra.delete(n)
+		case x.Retain > 0 && y.Delete > 0:
+			rb.delete(n)
+		}
+		// Одновременное удаление одних и тех же символов не попадает ни в одну операцию
+
+		if x.shorten(n) {
+			next(a, &i, &x)
+		}
+		if y.shorten(n) {
+			next(b, &j, &y)
+		}
+	}
+	return ra.op, rb.op, nil
+}
+
+// TransformIndex сдвигает позицию в тексте с учетом операции
+func TransformIndex(pos int, op Operation) int {
+	moved, rest := pos, pos
+	for _, c := range op {
+		if rest < 0 {
+			break
+		}
+		switch {
+		case c.Insert != "":
+			moved += utf8.RuneCountInString(c.Insert)
+		case c.Delete > 0:
+			moved -= min(rest, c.Delete)
+			rest -= c.Delete
+		default:
+			rest -= c.Retain
+		}
+	}
+	return moved
+}
+
+// Diff строит операцию, которая превращает from в to: общие начало и конец
+// сохраняются, середина заменяется целиком
+func Diff(from, to string) Operation {
+	a, b := []rune(from), []rune(to)
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var op builder
+	op.retain(prefix)
+	op.delete(len(a) - prefix - suffix)
+	op.insert(string(b[prefix : len(b)-suffix]))
+	op.retain(suffix)
+	return op.op
+}
+
+func (c Component) empty() bool {
+	return c.Retain == 0 && c.Insert == "" && c.Delete == 0
+}
+
+// shorten уменьшает пропуск или удаление на n и сообщает, исчерпан ли шаг
+func (c *Component) shorten(n int) bool {
+	if c.Retain > 0 {
+		c.Retain -= n
+	} else {
+		c.Delete -= n
+	}
+	return c.empty()
+}
+
+// builder собирает операцию в каноническом виде: соседние шаги одного типа
+// слиты, вставка стоит перед удалением в той же позиции
+type builder struct {
+	op Operation
+}
+
+func (b *builder) last() *Component {
+	if len(b.op) == 0 {
+		return nil
+	}
+	return &b.op[len(b.op)-1]
+}
+
+func (b *builder) retain(n int) {
+	if n <= 0 {
+		return
+	}
+	if last := b.last(); last != nil && last.Retain > 0 {
+		last.Retain += n
+		return
+	}
+	b.op = append(b.op, Component{Retain: n})
+}
+
+func (b *builder) delete(n int) {
+	if n <= 0 {
+		return
+	}
+	if last := b.last(); last != nil && last.Delete > 0 {
+		last.Delete += n
+		return
+	}
+	b.op = append(b.op, Component{Delete: n})
+}
+
+func (b *builder) insert(s string) {
+	if s == "" {
+		return
+	}
+	last := b.last()
+	if last != nil && last.Delete > 0 {
+		// Удаление и вставка в одной позиции перестановочны: вставка идет первой
+		if len(b.op) > 1 && b.op[len(b.op)-2].Insert != "" {
+			b.op[len(b.op)-2].Insert += s
+			return
+		}
+		b.op = append(b.op, *last)
+		b.op[len(b.op)-2] = Component{Insert: s}
+		return
+	}
+	if last != nil && last.Insert != "" {
+		last.Insert += s
+		return
+	}
+	b.op = append(b.op, Component{Insert: s})
+}
@@ -0,0 +1,127 @@
+package collab
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationJSON(t *testing.T) {
+	var op Operation
+	require.NoError(t, json.Unmarshal([]byte(`[2,"ab",-1,"c",3]`), &op))
+
+	// Вставки перед удалением в одной позиции сливаются
+	assert.Equal(t, Operation{{Retain: 2}, {Insert: "abc"}, {Delete: 1}, {Retain: 3}}, op)
+	assert.Equal(t, 6, op.BaseLen())
+	assert.Equal(t, 8, op.TargetLen())
+
+	data, err := json.Marshal(op)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[2,"abc",-1,3]`, string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`[0]`), &op))
+	assert.Error(t, json.Unmarshal([]byte(`[true]`), &op))
+}
+
+func TestApply(t *testing.T) {
+	doc, err := Apply("привет мир", Operation{{Retain: 7}, {Delete: 3}, {Insert: "всем"}})
+	require.NoError(t, err)
+	assert.Equal(t, "привет всем", doc)
+
+	_, err = Apply("short", Operation{{Retain: 10}})
+	assert.ErrorIs(t, err, ErrLengthMismatch)
+}
+
+func TestTransformSamePosition(t *testing.T) {
+	a := Operation{{Retain: 3}, {Insert: "A"}}
+	b := Operation{{Retain: 3}, {Insert: "B"}}
+
+	a2, b2, err := Transform(a, b)
+	require.NoError(t, err)
+
+	left, _ := Apply("abc", a)
+	left, _ = Apply(left, b2)
+	right, _ := Apply("abc", b)
+	right, _ = Apply(right, a2)
+	assert.Equal(t, "abcAB", left)
+	assert.Equal(t, left, right)
+}
+
+func TestTransformConverges(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		doc := randomText(rnd, rnd.Intn(20))
+		a, b := randomOperation(rnd, doc), randomOperation(rnd, doc)
+
+		a2, b2, err := Transform(a, b)
+		require.NoError(t, err)
+
+		left, err := Apply(doc, a)
+		require.NoError(t, err)
+		left, err = Apply(left, b2)
+		require.NoError(t, err)
+		right, err := Apply(doc, b)
+		require.NoError(t, err)
+		right, err = Apply(right, a2)
+		require.NoError(t, err)
+		assert.Equal(t, left, right, "doc %q, a %v, b %v", doc, a, b)
+	}
+}
+
+func TestTransformIndex(t *testing.T) {
+	// "hello world": вставка перед курсором сдвигает его, удаление под курсором стягивает
+	assert.Equal(t, 9, TransformIndex(6, Operation{{Insert: "big"}, {Retain: 11}}))
+	assert.Equal(t, 6, TransformIndex(6, Operation{{Retain: 8}, {Insert: "!"}, {Retain: 3}}))
+	assert.Equal(t, 2, TransformIndex(6, Operation{{Retain: 2}, {Delete: 6}, {Retain: 3}}))
+}
+
+func TestDiff(t *testing.T) {
+	for _, tc := range [][2]string{
+		{"", ""},
+		{"", "new"},
+		{"old", ""},
+		{"привет мир", "привет, мир"},
+		{"aaa", "aa"},
+		{"abc", "xbz"},
+	} {
+		op := Diff(tc[0], tc[1])
+		got, err := Apply(tc[0], op)
+		require.NoError(t, err)
+		assert.Equal(t, tc[1], got)
+	}
+	assert.True(t, Diff("same", "same").IsNoop())
+}
+
+func randomText(rnd *rand.Rand, n int) string {
+	letters := []rune("abcяю ")
+	text := make([]rune, n)
+	for i := range text {
+		text[i] = letters[rnd.Intn(len(letters))]
+	}
+	return string(text)
+}
+
+func randomOperation(rnd *rand.Rand, doc string) Operation {
+	var b builder
+	left := len([]rune(doc))
+	for left > 0 {
+		n := 1 + rnd.Intn(left)
+		switch rnd.Intn(3) {
+		case 0:
+			b.retain(n)
+			left -= n
+		case 1:
+			b.delete(n)
+			left -= n
+		default:
+			b.insert(randomText(rnd, 1+rnd.Intn(3)))
+		}
+	}
+	if rnd.Intn(2) == 0 {
+		b.insert(randomText(rnd, 1+rnd.Intn(3)))
+	}
+	return b.op
+}
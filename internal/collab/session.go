@@ -0,0 +1,445 @@
+package collab
+
+import (
+	"context"
+	"errors"
+	"log"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// maxHistory — сколько последних операций хранится для клиентов, отставших
+	// от сервера; более старые ревизии требуют переподключения
+	maxHistory = 1000
+	// sendBuffer — очередь сообщений клиента; клиент, который не успевает
+	// читать, отключается
+	sendBuffer = 256
+)
+
+// Типы сообщений протокола
+const (
+	MessageInit   = "init"   // сервер: текст, ревизия и участники при подключении
+	MessageOp     = "op"     // клиент и сервер: операция над текстом
+	MessageAck    = "ack"    // сервер: операция клиента применена
+	MessageCursor = "cursor" // клиент и сервер: положение курсора
+	MessageJoin   = "join"   // сервер: подключился участник
+	MessageLeave  = "leave"  // сервер: участник отключился
+	MessageError  = "error"  // сервер: сообщение клиента отклонено
+	MessageClosed = "closed" // сервер: сессия завершена, заметка недоступна
+)
+
+// Cursor — курсор или выделение участника, позиции в символах Unicode
+type Cursor struct {
+	Position     int `json:"position"`
+	SelectionEnd int `json:"selection_end"`
+}
+
+// Participant — участник сессии
+type Participant struct {
+	ClientID string  `json:"client_id"`
+	UserID   int64   `json:"user_id"`
+	CanEdit  bool    `json:"can_edit"`
+	Cursor   *Cursor `json:"cursor,omitempty"`
+}
+
+// Message — сообщение протокола совместного редактирования
+type Message struct {
+	Type     string `json:"type"`
+	Revision int    `json:"revision"`
+
+	ClientID  string    `json:"client_id,omitempty"`
+	UserID    int64     `json:"user_id,omitempty"`
+	Operation Operation `json:"operation,omitempty"`
+	Cursor    *Cursor   `json:"cursor,omitempty"`
+
+	Content      string        `json:"content,omitempty"`
+	Participants []Participant `json:"participants,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// Manager ведет сессии совместного редактирования заметок на этом экземпляре
+type Manager struct {
+	notes    repository.NoteRepository
+	interval time.Duration
+
+	mu       sync.Mutex
+	sessions map[int64]*Session
+}
+
+// NewManager создает новый экземпляр Manager. Текст сессии сохраняется в
+// заметку через notes каждые interval.
+func NewManager(notes repository.NoteRepository, interval time.Duration) *Manager {
+	return &Manager{notes: notes, interval: interval, sessions: make(map[int64]*Session)}
+}
+
+// Join подключает пользователя к сессии заметки, открывая ее при
+// необходимости. Первым сообщением клиент получает init.
+func (m *Manager) Join(ctx context.Context, userID, noteID int64, canWrite bool) (*Client, error) {
+	note, err := m.notes.GetNote(ctx, userID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	canEdit := canWrite && (note.Permission == models.PermissionOwner || note.Permission == models.PermissionEdit)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.sessions[noteID]
+	if s == nil {
+		s = newSession(m, note, userID)
+		m.sessions[noteID] = s
+		go s.run()
+	}
+	return s.join(userID, canEdit), nil
+}
+
+// release закрывает сессию без участников, если ее текст сохранен
+func (m *Manager) release(s *Session) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return true
+	}
+	if len(s.clients) > 0 || s.dirty {
+		return false
+	}
+	s.closed = true
+	delete(m.sessions, s.noteID)
+	return true
+}
+
+// Session — текст заметки, который редактируют подключенные клиенты
+type Session struct {
+	manager *Manager
+	noteID  int64
+
+	mu       sync.Mutex
+	doc      string
+	revision int
+	// history[i] переводит текст из ревизии base+i в base+i+1
+	history []Operation
+	base    int
+	clients map[string]*Client
+	lastID  int
+
+	// saved — текст заметки в базе на момент savedRevision
+	saved         string
+	savedRevision int
+	dirty         bool
+	// editor — пользователь, от имени которого сохраняется текст
+	editor int64
+	closed bool
+}
+
+func newSession(m *Manager, note *models.Note, userID int64) *Session {
+	return &Session{
+		manager: m,
+		noteID:  note.ID,
+		doc:     note.Content,
+		clients: make(map[string]*Client),
+		saved:   note.Content,
+		editor:  userID,
+	}
+}
+
+// Client — подключение участника к сессии
+type Client struct {
+	session *Session
+	id      string
+	userID  int64
+	canEdit bool
+	cursor  *Cursor
+	send    chan Message
+	left    bool
+}
+
+// Messages возвращает сообщения для отправки клиенту. Канал закрывается,
+// когда клиент отключен от сессии.
+func (c *Client) Messages() <-chan Message {
+	return c.send
+}
+
+// Handle обрабатывает сообщение, полученное от клиента
+func (c *Client) Handle(msg Message) {
+	switch msg.Type {
+	case MessageOp:
+		c.session.applyClientOp(c, msg.Revision, msg.Operation)
+	case MessageCursor:
+		c.session.moveCursor(c, msg.Cursor)
+	default:
+		c.session.reply(c, "unknown message type")
+	}
+}
+
+// Leave отключает клиента от сессии. Повторный вызов ничего не делает.
+func (c *Client) Leave() {
+	s := c.session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop(c)
+}
+
+func (s *Session) join(userID int64, canEdit bool) *Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	c := &Client{
+		session: s,
+		id:      strconv.Itoa(s.lastID),
+		userID:  userID,
+		canEdit: canEdit,
+		send:    make(chan Message, sendBuffer),
+	}
+
+	participants := make([]Participant, 0, len(s.clients))
+	for _, other := range s.clients {
+		participants = append(participants, other.participant())
+	}
+	c.send <- Message{
+		Type:         MessageInit,
+		Revision:     s.revision,
+		ClientID:     c.id,
+		Content:      s.doc,
+		Participants: participants,
+	}
+	s.broadcast(c, Message{Type: MessageJoin, ClientID: c.id, UserID: userID, Participants: []Participant{c.participant()}})
+	s.clients[c.id] = c
+	return c
+}
+
+func (c *Client) participant() Participant {
+	return Participant{ClientID: c.id, UserID: c.userID, CanEdit: c.canEdit, Cursor: c.cursor}
+}
+
+// applyClientOp преобразует операцию клиента, сделанную в ревизии revision,
+// относительно более поздних операций и применяет ее к тексту
+func (s *Session) applyClientOp(c *Client, revision int, op Operation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.left {
+		return
+	}
+	if !c.canEdit {
+		s.reply(c, "read-only access")
+		return
+	}
+	if revision < s.base || revision > s.revision {
+		s.reply(c, "unknown revision, reconnect to resync")
+		return
+	}
+
+	for _, concurrent := range s.history[revision-s.base:] {
+		var err error
+		if op, _, err = Transform(op, concurrent); err != nil {
+			s.reply(c, "invalid operation")
+			return
+		}
+	}
+	if err := s.apply(op); err != nil {
+		s.reply(c, "invalid operation")
+		return
+	}
+	s.editor = c.userID
+
+	s.send(c, Message{Type: MessageAck, Revision: s.revision})
+	s.broadcast(c, Message{Type: MessageOp, Revision: s.revision, ClientID: c.id, UserID: c.userID, Operation: op})
+}
+
+// apply применяет операцию в текущей ревизии и сдвигает курсоры участников
+func (s *Session) apply(op Operation) error {
+	doc, err := Apply(s.doc, op)
+	if err != nil {
+		return err
+	}
+	s.doc = doc
+	s.history = append(s.history, op)
+	s.revision++
+	s.dirty = true
+
+	// История до сохраненной ревизии нужна для слияния внешних правок
+	if trim := min(s.revision-maxHistory, s.savedRevision) - s.base; trim > 0 {
+		s.history = append([]Operation(nil), s.history[trim:]...)
+		s.base += trim
+	}
+
+	for _, other := range s.clients {
+		if other.cursor != nil {
+			other.cursor.Position = TransformIndex(other.cursor.Position, op)
+			other.cursor.SelectionEnd = TransformIndex(other.cursor.SelectionEnd, op)
+		}
+	}
+	return nil
+}
+
+func (s *Session) moveCursor(c *Client, cursor *Cursor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.left {
+		return
+	}
+	length := utf8.RuneCountInString(s.doc)
+	if cursor == nil || cursor.Position < 0 || cursor.Position > length ||
+		cursor.SelectionEnd < 0 || cursor.SelectionEnd > length {
+		s.reply(c, "invalid cursor")
+		return
+	}
+	c.cursor = cursor
+	s.broadcast(c, Message{Type: MessageCursor, Revision: s.revision, ClientID: c.id, UserID: c.userID, Cursor: cursor})
+}
+
+func (s *Session) reply(c *Client, message string) {
+	s.send(c, Message{Type: MessageError, Revision: s.revision, Error: message})
+}
+
+// send ставит сообщение в очередь клиента; не успевающий клиент отключается
+func (s *Session) send(c *Client, msg Message) {
+	if c.left {
+		return
+	}
+	select {
+	case c.send <- msg:
+	default:
+		s.drop(c)
+	}
+}
+
+// broadcast отправляет сообщение всем участникам, кроме except
+func (s *Session) broadcast(except *Client, msg Message) {
+	for _, c := range s.clients {
+		if c != except {
+			s.send(c, msg)
+		}
+	}
+}
+
+func (s *Session) drop(c *Client) {
+	if c.left {
+		return
+	}
+	c.left = true
+	close(c.send)
+	if _, ok := s.clients[c.id]; ok {
+		delete(s.clients, c.id)
+		s.broadcast(nil, Message{Type: MessageLeave, Revision: s.revision, ClientID: c.id, UserID: c.userID})
+	}
+}
+
+// run сохраняет текст сессии, пока у нее есть участники или несохраненные правки
+func (s *Session) run() {
+	ticker := time.NewTicker(s.manager.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.snapshot(context.Background())
+		if s.manager.release(s) {
+			return
+		}
+	}
+}
+
+// snapshot сливает с текстом сессии правки, сделанные в обход нее (PUT
+// /notes/{id} или сессия на другом экземпляре), и сохраняет текст в заметку
+func (s *Session) snapshot(ctx context.Context) {
+	s.mu.Lock()
+	editor := s.editor
+	s.mu.Unlock()
+
+	note, err := s.manager.notes.GetNote(ctx, editor, s.noteID)
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrForbidden) {
+		s.close()
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load note %d for collaborative session: %v", s.noteID, err)
+		return
+	}
+
+	s.mu.Lock()
+	if note.Content != s.saved {
+		s.mergeExternal(note.Content)
+	}
+	if !s.dirty {
+		s.mu.Unlock()
+		return
+	}
+	content, revision := s.doc, s.revision
+	s.mu.Unlock()
+
+	note.Content = content
+	note.UpdatedAt = time.Now()
+	if err := s.manager.notes.UpdateNote(ctx, editor, note); err != nil {
+		if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrForbidden) {
+			s.close()
+			return
+		}
+		// Заметка изменилась после чтения: правка вольется при следующем сохранении
+		if !errors.Is(err, repository.ErrConflict) {
+			log.Printf("Failed to save note %d from collaborative session: %v", s.noteID, err)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	s.saved = content
+	s.savedRevision = revision
+	s.dirty = s.revision != revision
+	s.mu.Unlock()
+}
+
+// mergeExternal применяет к сессии разницу между сохраненным текстом и
+// текстом заметки в базе как операцию, сделанную в сохраненной ревизии
+func (s *Session) mergeExternal(content string) {
+	op := Diff(s.saved, content)
+	for _, concurrent := range s.history[s.savedRevision-s.base:] {
+		// Внешняя правка считается более ранней: ее вставки идут первыми
+		transformed, _, err := Transform(op, concurrent)
+		if err != nil {
+			// Текст в базе менялся несколько раз без сохранения сессии:
+			// отличающийся фрагмент берется из базы
+			op = Diff(s.doc, content)
+			break
+		}
+		op = transformed
+	}
+	if err := s.apply(op); err != nil {
+		log.Printf("Failed to merge external change into note %d: %v", s.noteID, err)
+		return
+	}
+	s.saved = content
+	// Без собственных правок сессии сохранять нечего
+	s.dirty = s.doc != content
+	if !s.dirty {
+		s.savedRevision = s.revision
+	}
+	s.broadcast(nil, Message{Type: MessageOp, Revision: s.revision, Operation: op})
+}
+
+// close завершает сессию, когда заметка стала недоступна
+func (s *Session) close() {
+	m := s.manager
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.dirty = false
+	if m.sessions[s.noteID] == s {
+		delete(m.sessions, s.noteID)
+	}
+	for _, c := range s.clients {
+		select {
+		case c.send <- Message{Type: MessageClosed, Revision: s.revision}:
+		default:
+		}
+		c.left = true
+		close(c.send)
+	}
+	s.clients = map[string]*Client{}
+}
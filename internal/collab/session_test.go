@@ -0,0 +1,185 @@
+package collab
+
+import (
+	"context"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNotes хранит одну заметку в памяти
+type fakeNotes struct {
+	mu      sync.Mutex
+	note    models.Note
+	updates int
+}
+
+func (f *fakeNotes) CreateNote(ctx context.Context, note *models.Note) error { return nil }
+
+func (f *fakeNotes) ListNotes(ctx context.Context, userID int64, filter models.NoteFilter) ([]*models.Note, error) {
+	return nil, nil
+}
+
+func (f *fakeNotes) GetNote(ctx context.Context, userID, noteID int64) (*models.Note, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if noteID != f.note.ID || f.note.DeletedAt != nil {
+		return nil, repository.ErrNotFound
+	}
+	note := f.note
+	if userID == 3 {
+		note.Permission = models.PermissionRead
+	}
+	return &note, nil
+}
+
+func (f *fakeNotes) UpdateNote(ctx context.Context, userID int64, note *models.Note) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.note.Title = note.Title
+	f.note.Content = note.Content
+	f.updates++
+	return nil
+}
+
+func (f *fakeNotes) DeleteNote(ctx context.Context, userID, noteID int64) error { return nil }
+
+func (f *fakeNotes) Close() error { return nil }
+
+func (f *fakeNotes) content() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.note.Content
+}
+
+func newTestManager() (*Manager, *fakeNotes) {
+	notes := &fakeNotes{note: models.Note{ID: 7, Title: "Plan", Content: "hello", Permission: models.PermissionOwner}}
+	return NewManager(notes, time.Hour), notes
+}
+
+// receive возвращает следующее сообщение клиента
+func receive(t *testing.T, c *Client) Message {
+	t.Helper()
+	select {
+	case msg, ok := <-c.Messages():
+		require.True(t, ok, "client disconnected")
+		return msg
+	default:
+		t.Fatal("no message")
+		return Message{}
+	}
+}
+
+func TestSessionMergesConcurrentEdits(t *testing.T) {
+	m, _ := newTestManager()
+	alice, err := m.Join(context.Background(), 1, 7, true)
+	require.NoError(t, err)
+	bob, err := m.Join(context.Background(), 2, 7, true)
+	require.NoError(t, err)
+
+	init := receive(t, alice)
+	assert.Equal(t, MessageInit, init.Type)
+	assert.Equal(t, "hello", init.Content)
+	assert.Equal(t, 0, init.Revision)
+	assert.Equal(t, MessageJoin, receive(t, alice).Type)
+	assert.Len(t, receive(t, bob).Participants, 1)
+
+	// Оба правят ревизию 0
+	alice.Handle(Message{Type: MessageOp, Revision: 0, Operation: Operation{{Insert: "A "}, {Retain: 5}}})
+	bob.Handle(Message{Type: MessageOp, Revision: 0, Operation: Operation{{Retain: 5}, {Insert: "!"}}})
+
+	assert.Equal(t, Message{Type: MessageAck, Revision: 1}, receive(t, alice))
+	fromBob := receive(t, alice)
+	assert.Equal(t, MessageOp, fromBob.Type)
+	assert.Equal(t, Operation{{Retain: 7}, {Insert: "!"}}, fromBob.Operation)
+
+	fromAlice := receive(t, bob)
+	assert.Equal(t, Operation{{Insert: "A "}, {Retain: 5}}, fromAlice.Operation)
+	assert.Equal(t, Message{Type: MessageAck, Revision: 2}, receive(t, bob))
+
+	s := m.sessions[7]
+	assert.Equal(t, "A hello!", s.doc)
+}
+
+func TestSessionCursorsAndReadOnly(t *testing.T) {
+	m, _ := newTestManager()
+	alice, _ := m.Join(context.Background(), 1, 7, true)
+	viewer, _ := m.Join(context.Background(), 3, 7, true)
+	receive(t, alice)
+	receive(t, alice)
+	receive(t, viewer)
+
+	viewer.Handle(Message{Type: MessageCursor, Cursor: &Cursor{Position: 2, SelectionEnd: 4}})
+	cursor := receive(t, alice)
+	assert.Equal(t, MessageCursor, cursor.Type)
+	assert.Equal(t, int64(3), cursor.UserID)
+
+	viewer.Handle(Message{Type: MessageOp, Revision: 0, Operation: Operation{{Delete: 5}}})
+	assert.Equal(t, "read-only access", receive(t, viewer).Error)
+
+	// Курсор зрителя сдвигается правкой перед ним
+	alice.Handle(Message{Type: MessageOp, Revision: 0, Operation: Operation{{Insert: "ab"}, {Retain: 5}}})
+	assert.Equal(t, &Cursor{Position: 4, SelectionEnd: 6}, m.sessions[7].clients[viewer.id].cursor)
+
+	viewer.Leave()
+	receive(t, alice) // ack
+	assert.Equal(t, MessageLeave, receive(t, alice).Type)
+	assert.Equal(t, MessageOp, receive(t, viewer).Type)
+	_, ok := <-viewer.Messages()
+	assert.False(t, ok)
+}
+
+func TestSessionSnapshotMergesExternalChange(t *testing.T) {
+	m, notes := newTestManager()
+	alice, _ := m.Join(context.Background(), 1, 7, true)
+	receive(t, alice)
+
+	alice.Handle(Message{Type: MessageOp, Revision: 0, Operation: Operation{{Retain: 5}, {Insert: " world"}}})
+	receive(t, alice)
+
+	// Правка через PUT /notes/{id}, пока сессия не сохранилась
+	notes.mu.Lock()
+	notes.note.Content = "Well, hello"
+	notes.mu.Unlock()
+
+	s := m.sessions[7]
+	s.snapshot(context.Background())
+
+	external := receive(t, alice)
+	assert.Equal(t, MessageOp, external.Type)
+	assert.Equal(t, 2, external.Revision)
+	assert.Equal(t, "Well, hello world", s.doc)
+	assert.Equal(t, "Well, hello world", notes.content())
+
+	// Без изменений повторное сохранение не пишет в базу
+	s.snapshot(context.Background())
+	assert.Equal(t, 1, notes.updates)
+
+	alice.Leave()
+	assert.True(t, m.release(s))
+	assert.Empty(t, m.sessions)
+}
+
+func TestSessionClosedWhenNoteDeleted(t *testing.T) {
+	m, notes := newTestManager()
+	alice, _ := m.Join(context.Background(), 1, 7, true)
+	receive(t, alice)
+
+	now := time.Now()
+	notes.note.DeletedAt = &now
+	m.sessions[7].snapshot(context.Background())
+
+	assert.Equal(t, MessageClosed, receive(t, alice).Type)
+	_, ok := <-alice.Messages()
+	assert.False(t, ok)
+	assert.Empty(t, m.sessions)
+	alice.Leave()
+
+	_, err := m.Join(context.Background(), 1, 7, true)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
@@ -10,6 +12,85 @@ type Config struct {
 	DatabaseURL           string `envconfig:"DATABASE_URL" required:"true"`
 	YandexSpellcheckerURL string `envconfig:"YANDEX_SPELLCHECKER_URL" default:"https://speller.yandex.net/services/spellservice.json/checkText"`
 	JWTSecret             string `envconfig:"JWT_SECRET" required:"true"`
+	// JWTPrivateKeyFile — PEM-файл ключа RSA или Ed25519; если задан, токены
+	// подписываются RS256/EdDSA вместо HS256
+	JWTPrivateKeyFile string `envconfig:"JWT_PRIVATE_KEY_FILE"`
+	// JWTVerificationKeyFiles — ключи, которые принимаются при проверке и
+	// публикуются в JWKS (например, предыдущий ключ после ротации)
+	JWTVerificationKeyFiles []string `envconfig:"JWT_VERIFICATION_KEY_FILES"`
+
+	// Защита /login и /register от перебора паролей
+	LoginRatePerMinute int           `envconfig:"LOGIN_RATE_PER_MINUTE" default:"10"`
+	LoginRateBurst     int           `envconfig:"LOGIN_RATE_BURST" default:"5"`
+	RateLimitStore     string        `envconfig:"RATE_LIMIT_STORE" default:"memory"` // memory или postgres
+	LockoutThreshold   int           `envconfig:"LOCKOUT_THRESHOLD" default:"5"`
+	LockoutDuration    time.Duration `envconfig:"LOCKOUT_DURATION" default:"1m"`
+	LockoutMaxDuration time.Duration `envconfig:"LOCKOUT_MAX_DURATION" default:"1h"`
+
+	// Хеширование паролей: при смене алгоритма или стоимости хеши обновляются при входе
+	PasswordHashAlgorithm string `envconfig:"PASSWORD_HASH_ALGORITHM" default:"bcrypt"` // bcrypt или argon2id
+	BcryptCost            int    `envconfig:"BCRYPT_COST" default:"10"`
+
+	// Сброс пароля по email
+	MailSender       string `envconfig:"MAIL_SENDER" default:"log"` // log или file
+	MailDir          string `envconfig:"MAIL_DIR" default:"mail"`
+	PasswordResetURL string `envconfig:"PASSWORD_RESET_URL" default:"http://localhost:8080/password/reset"`
+	EmailVerifyURL   string `envconfig:"EMAIL_VERIFY_URL" default:"http://localhost:8080/email/verify"`
+
+	// PublicURL — внешний адрес сервиса для публичных ссылок на заметки
+	PublicURL string `envconfig:"PUBLIC_URL" default:"http://localhost:8080"`
+
+	// TrashRetention — сколько заметки и блокноты хранятся в корзине
+	TrashRetention time.Duration `envconfig:"TRASH_RETENTION" default:"720h"`
+
+	// NoteEventsRetention — сколько хранятся события для возобновления потока GET /events
+	NoteEventsRetention time.Duration `envconfig:"NOTE_EVENTS_RETENTION" default:"168h"`
+
+	// CollabSnapshotInterval — как часто текст совместного редактирования сохраняется в заметку
+	CollabSnapshotInterval time.Duration `envconfig:"COLLAB_SNAPSHOT_INTERVAL" default:"5s"`
+
+	// Вебхуки: WEBHOOK_ALLOW_PRIVATE разрешает доставку на внутренние адреса,
+	// завершенные доставки хранятся WEBHOOK_DELIVERY_RETENTION
+	WebhookAllowPrivate      bool          `envconfig:"WEBHOOK_ALLOW_PRIVATE" default:"false"`
+	WebhookDeliveryRetention time.Duration `envconfig:"WEBHOOK_DELIVERY_RETENTION" default:"720h"`
+
+	// Публикация доменных событий из outbox: log, nats или kafka. Опубликованные
+	// события хранятся OUTBOX_RETENTION.
+	EventSink         string        `envconfig:"EVENT_SINK" default:"log"`
+	NATSURL           string        `envconfig:"NATS_URL" default:"nats://localhost:4222"`
+	NATSSubjectPrefix string        `envconfig:"NATS_SUBJECT_PREFIX" default:"notes"`
+	KafkaBrokers      []string      `envconfig:"KAFKA_BROKERS" default:"localhost:9092"`
+	KafkaTopic        string        `envconfig:"KAFKA_TOPIC" default:"note-events"`
+	OutboxRetention   time.Duration `envconfig:"OUTBOX_RETENTION" default:"168h"`
+
+	// ImportMaxBytes — максимальный размер файла для POST /import
+	ImportMaxBytes int64 `envconfig:"IMPORT_MAX_BYTES" default:"52428800"`
+
+	// Хранилище вложений: local (каталог BLOB_DIR) или s3
+	BlobStore         string `envconfig:"BLOB_STORE" default:"local"`
+	BlobDir           string `envconfig:"BLOB_DIR" default:"attachments"`
+	S3Endpoint        string `envconfig:"S3_ENDPOINT"`
+	S3Region          string `envconfig:"S3_REGION" default:"us-east-1"`
+	S3Bucket          string `envconfig:"S3_BUCKET"`
+	S3AccessKeyID     string `envconfig:"S3_ACCESS_KEY_ID"`
+	S3SecretAccessKey string `envconfig:"S3_SECRET_ACCESS_KEY"`
+	// AttachmentMaxBytes ограничивает размер одного вложения, AttachmentQuotaBytes —
+	// суммарный размер вложений, загруженных пользователем
+	AttachmentMaxBytes   int64 `envconfig:"ATTACHMENT_MAX_BYTES" default:"26214400"`
+	AttachmentQuotaBytes int64 `envconfig:"ATTACHMENT_QUOTA_BYTES" default:"1073741824"`
+	// Подписанные ссылки на вложения в HTML заметок; по умолчанию подписываются JWT_SECRET
+	AttachmentURLSecret string        `envconfig:"ATTACHMENT_URL_SECRET"`
+	AttachmentURLTTL    time.Duration `envconfig:"ATTACHMENT_URL_TTL" default:"1h"`
+
+	// TOTPIssuer — имя сервиса в приложении-аутентификаторе
+	TOTPIssuer string `envconfig:"TOTP_ISSUER" default:"notes-service"`
+
+	// Вход через внешнего провайдера OpenID Connect; отключен, если OIDC_ISSUER пуст
+	OIDCIssuer       string   `envconfig:"OIDC_ISSUER"`
+	OIDCClientID     string   `envconfig:"OIDC_CLIENT_ID"`
+	OIDCClientSecret string   `envconfig:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectURL  string   `envconfig:"OIDC_REDIRECT_URL" default:"http://localhost:8080/login/oidc/callback"`
+	OIDCScopes       []string `envconfig:"OIDC_SCOPES" default:"openid,profile,email"`
 }
 
 // Load загружает конфигурацию из переменных окружения
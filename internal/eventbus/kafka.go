@@ -0,0 +1,61 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"notes-service/internal/models"
+	"strconv"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaSink публикует события в топик Kafka. Ключ сообщения — ID заметки,
+// поэтому события одной заметки попадают в одну партицию.
+type KafkaSink struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaSink создает новый экземпляр KafkaSink. Запись синхронная и ждет
+// подтверждения от всех реплик.
+func NewKafkaSink(brokers []string, topic string) *KafkaSink {
+	return &KafkaSink{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}}
+}
+
+// Publish записывает пачку событий одним запросом
+func (s *KafkaSink) Publish(ctx context.Context, events []*models.DomainEvent) error {
+	messages := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		msg, err := kafkaMessage(event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, msg)
+	}
+	return s.writer.WriteMessages(ctx, messages...)
+}
+
+// Close дожидается отправки и закрывает соединения
+func (s *KafkaSink) Close() error {
+	return s.writer.Close()
+}
+
+func kafkaMessage(event *models.DomainEvent) (kafka.Message, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	return kafka.Message{
+		Key:   []byte(strconv.FormatInt(event.AggregateID, 10)),
+		Value: data,
+		Headers: []kafka.Header{
+			{Key: "event-id", Value: []byte(strconv.FormatInt(event.ID, 10))},
+			{Key: "event-type", Value: []byte(event.Type)},
+		},
+		Time: event.OccurredAt,
+	}, nil
+}
@@ -0,0 +1,66 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"notes-service/internal/models"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSink публикует события в NATS JetStream. Тема сообщения —
+// "<prefix>.<тип события>", например notes.note.updated; потоку JetStream
+// нужны темы "<prefix>.>". Заголовок Nats-Msg-Id равен ID события, поэтому
+// JetStream сам отбрасывает повторы в пределах окна дедупликации.
+type NATSSink struct {
+	conn   *nats.Conn
+	js     nats.JetStreamContext
+	prefix string
+}
+
+// NewNATSSink подключается к серверу NATS по url
+func NewNATSSink(url, prefix string) (*NATSSink, error) {
+	conn, err := nats.Connect(url, nats.Name("notes-service"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &NATSSink{conn: conn, js: js, prefix: prefix}, nil
+}
+
+// Publish публикует события по одному и ждет подтверждения каждого
+func (s *NATSSink) Publish(ctx context.Context, events []*models.DomainEvent) error {
+	for _, event := range events {
+		msg, err := natsMessage(s.prefix, event)
+		if err != nil {
+			return err
+		}
+		if _, err := s.js.PublishMsg(msg, nats.Context(ctx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close закрывает соединение с NATS
+func (s *NATSSink) Close() error {
+	s.conn.Close()
+	return nil
+}
+
+func natsMessage(prefix string, event *models.DomainEvent) (*nats.Msg, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	msg := nats.NewMsg(prefix + "." + event.Type)
+	msg.Data = data
+	msg.Header.Set(nats.MsgIdHdr, strconv.FormatInt(event.ID, 10))
+	msg.Header.Set("Event-Type", event.Type)
+	return msg, nil
+}
@@ -0,0 +1,109 @@
+// Package eventbus публикует доменные события из исходящей очереди (outbox)
+// в брокер сообщений. Событие удаляется из очереди только после того, как
+// брокер подтвердил его прием, поэтому каждое событие доставляется хотя бы
+// один раз; повторы получатели отбрасывают по ID события.
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"log"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"time"
+)
+
+const (
+	// pollInterval — как часто Publisher проверяет очередь
+	pollInterval = time.Second
+	// batchSize — сколько событий публикуется за раз
+	batchSize = 100
+	// lease — на сколько пачка закрепляется за Publisher
+	lease = time.Minute
+	// retryBase и retryMax задают экспоненциальную задержку после ошибки брокера
+	retryBase = 5 * time.Second
+	retryMax  = 5 * time.Minute
+)
+
+// Sink — брокер сообщений, в который публикуются события. Publish
+// возвращает nil, только когда брокер принял все события пачки.
+type Sink interface {
+	Publish(ctx context.Context, events []*models.DomainEvent) error
+	Close() error
+}
+
+// Publisher переносит события из исходящей очереди в Sink
+type Publisher struct {
+	repo repository.OutboxRepository
+	sink Sink
+}
+
+// NewPublisher создает новый экземпляр Publisher
+func NewPublisher(repo repository.OutboxRepository, sink Sink) *Publisher {
+	return &Publisher{repo: repo, sink: sink}
+}
+
+// Run публикует события, пока не отменен ctx
+func (p *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for p.publishNext(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishNext публикует одну пачку событий и сообщает, стоит ли сразу
+// забирать следующую
+func (p *Publisher) publishNext(ctx context.Context) bool {
+	events, err := p.repo.ClaimOutboxEvents(ctx, batchSize, lease)
+	if err != nil {
+		log.Printf("Failed to claim outbox events: %v", err)
+		return false
+	}
+	if len(events) == 0 {
+		return false
+	}
+
+	ids := make([]int64, len(events))
+	attempts := 0
+	for i, event := range events {
+		ids[i] = event.ID
+		attempts = max(attempts, event.Attempts)
+	}
+
+	if err := p.sink.Publish(ctx, events); err != nil {
+		log.Printf("Failed to publish %d events: %v", len(events), err)
+		if errors.Is(err, context.Canceled) {
+			// Закрепление истечет само, и события опубликует следующий запуск
+			return false
+		}
+		if err := p.repo.RetryOutboxEvents(ctx, ids, err.Error(), time.Now().Add(Backoff(attempts))); err != nil {
+			log.Printf("Failed to reschedule outbox events: %v", err)
+		}
+		return false
+	}
+
+	// Если отметка не сохранится, события будут опубликованы повторно
+	if err := p.repo.MarkOutboxPublished(ctx, ids); err != nil {
+		log.Printf("Failed to mark outbox events published: %v", err)
+		return false
+	}
+	return len(events) == batchSize
+}
+
+// Backoff возвращает задержку перед повтором после attempts неудачных
+// попыток: 5s, 10s, 20s, ... но не больше 5m
+func Backoff(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts && delay < retryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMax)
+}
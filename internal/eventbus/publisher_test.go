@@ -0,0 +1,157 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"notes-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOutbox хранит исходящую очередь в памяти с той же семантикой
+// закрепления, что и таблица outbox
+type fakeOutbox struct {
+	rows    []*outboxRow
+	now     time.Time
+	markErr error
+}
+
+type outboxRow struct {
+	event       models.DomainEvent
+	published   bool
+	lockedUntil time.Time
+	nextAttempt time.Time
+	lastError   string
+}
+
+func newFakeOutbox(n int) *fakeOutbox {
+	o := &fakeOutbox{now: time.Now()}
+	for i := 1; i <= n; i++ {
+		o.rows = append(o.rows, &outboxRow{event: models.DomainEvent{
+			ID:          int64(i),
+			Type:        "note.updated",
+			AggregateID: 7,
+			Payload:     json.RawMessage(`{"id":7}`),
+		}})
+	}
+	return o
+}
+
+func (o *fakeOutbox) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]*models.DomainEvent, error) {
+	events := []*models.DomainEvent{}
+	for _, row := range o.rows {
+		if len(events) == limit {
+			break
+		}
+		if row.published || row.nextAttempt.After(o.now) || row.lockedUntil.After(o.now) {
+			continue
+		}
+		row.lockedUntil = o.now.Add(lease)
+		row.event.Attempts++
+		event := row.event
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+func (o *fakeOutbox) MarkOutboxPublished(ctx context.Context, ids []int64) error {
+	if o.markErr != nil {
+		return o.markErr
+	}
+	for _, id := range ids {
+		row := o.rows[id-1]
+		row.published, row.lockedUntil = true, time.Time{}
+	}
+	return nil
+}
+
+func (o *fakeOutbox) RetryOutboxEvents(ctx context.Context, ids []int64, lastError string, retryAt time.Time) error {
+	for _, id := range ids {
+		row := o.rows[id-1]
+		row.lockedUntil, row.nextAttempt, row.lastError = time.Time{}, retryAt, lastError
+	}
+	return nil
+}
+
+func (o *fakeOutbox) PruneOutbox(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestPublisherPublishesInBatches(t *testing.T) {
+	outbox := newFakeOutbox(batchSize + 5)
+	sink := NewMemorySink()
+	publisher := NewPublisher(outbox, sink)
+
+	// Полная пачка — сразу забирается следующая
+	assert.True(t, publisher.publishNext(context.Background()))
+	assert.False(t, publisher.publishNext(context.Background()))
+	assert.False(t, publisher.publishNext(context.Background()))
+
+	events := sink.Events()
+	require.Len(t, events, batchSize+5)
+	for i, event := range events {
+		assert.Equal(t, int64(i+1), event.ID)
+	}
+	for _, row := range outbox.rows {
+		assert.True(t, row.published)
+	}
+}
+
+func TestPublisherRetriesWhenSinkFails(t *testing.T) {
+	outbox := newFakeOutbox(2)
+	sink := NewMemorySink()
+	sink.Fail(errors.New("broker unavailable"))
+	publisher := NewPublisher(outbox, sink)
+
+	assert.False(t, publisher.publishNext(context.Background()))
+	assert.Empty(t, sink.Events())
+	for _, row := range outbox.rows {
+		assert.False(t, row.published)
+		assert.Equal(t, "broker unavailable", row.lastError)
+		assert.True(t, row.nextAttempt.After(outbox.now))
+	}
+
+	// До времени повтора события не забираются
+	sink.Fail(nil)
+	publisher.publishNext(context.Background())
+	assert.Empty(t, sink.Events())
+
+	outbox.now = outbox.now.Add(retryBase + time.Minute)
+	publisher.publishNext(context.Background())
+	assert.Len(t, sink.Events(), 2)
+}
+
+func TestPublisherRepublishesUnacknowledgedEvents(t *testing.T) {
+	outbox := newFakeOutbox(1)
+	outbox.markErr = errors.New("connection lost")
+	sink := NewMemorySink()
+	publisher := NewPublisher(outbox, sink)
+
+	publisher.publishNext(context.Background())
+	require.Len(t, sink.Events(), 1)
+
+	// Пока действует закрепление, повтора нет
+	publisher.publishNext(context.Background())
+	assert.Len(t, sink.Events(), 1)
+
+	// Отметка о публикации не сохранилась: после истечения закрепления
+	// событие публикуется еще раз с тем же ID
+	outbox.markErr = nil
+	outbox.now = outbox.now.Add(lease + time.Second)
+	publisher.publishNext(context.Background())
+
+	events := sink.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, events[0].ID, events[1].ID)
+	assert.True(t, outbox.rows[0].published)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 5*time.Second, Backoff(1))
+	assert.Equal(t, 20*time.Second, Backoff(3))
+	assert.Equal(t, 5*time.Minute, Backoff(30))
+}
@@ -0,0 +1,72 @@
+package eventbus
+
+import (
+	"context"
+	"log"
+	"notes-service/internal/models"
+	"sync"
+)
+
+// LogSink пишет события в журнал сервиса. Подходит для разработки и как
+// приемник по умолчанию, чтобы очередь не росла без брокера.
+type LogSink struct{}
+
+// NewLogSink создает новый экземпляр LogSink
+func NewLogSink() *LogSink {
+	return &LogSink{}
+}
+
+// Publish пишет в журнал по строке на событие
+func (s *LogSink) Publish(ctx context.Context, events []*models.DomainEvent) error {
+	for _, event := range events {
+		log.Printf("Event %d %s aggregate=%d", event.ID, event.Type, event.AggregateID)
+	}
+	return nil
+}
+
+// Close ничего не делает
+func (s *LogSink) Close() error {
+	return nil
+}
+
+// MemorySink хранит опубликованные события в памяти. Используется в тестах.
+type MemorySink struct {
+	mu     sync.Mutex
+	events []*models.DomainEvent
+	err    error
+}
+
+// NewMemorySink создает новый экземпляр MemorySink
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+// Fail заставляет следующие вызовы Publish возвращать err; nil отменяет сбой
+func (s *MemorySink) Fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Publish сохраняет события или возвращает ошибку, заданную Fail
+func (s *MemorySink) Publish(ctx context.Context, events []*models.DomainEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, events...)
+	return nil
+}
+
+// Events возвращает опубликованные события в порядке публикации
+func (s *MemorySink) Events() []*models.DomainEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*models.DomainEvent(nil), s.events...)
+}
+
+// Close ничего не делает
+func (s *MemorySink) Close() error {
+	return nil
+}
@@ -0,0 +1,46 @@
+package eventbus
+
+import (
+	"encoding/json"
+	"notes-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEvent() *models.DomainEvent {
+	return &models.DomainEvent{
+		ID:          42,
+		Type:        "note.created",
+		AggregateID: 7,
+		OccurredAt:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Payload:     json.RawMessage(`{"id":7,"title":"Hello"}`),
+		Attempts:    3,
+	}
+}
+
+func TestNATSMessage(t *testing.T) {
+	msg, err := natsMessage("notes", testEvent())
+	require.NoError(t, err)
+
+	assert.Equal(t, "notes.note.created", msg.Subject)
+	assert.Equal(t, "42", msg.Header.Get(nats.MsgIdHdr))
+	assert.Equal(t, "note.created", msg.Header.Get("Event-Type"))
+	assert.JSONEq(t, `{"id":42,"type":"note.created","aggregate_id":7,"occurred_at":"2024-05-01T12:00:00Z",
+		"data":{"id":7,"title":"Hello"}}`, string(msg.Data))
+}
+
+func TestKafkaMessage(t *testing.T) {
+	msg, err := kafkaMessage(testEvent())
+	require.NoError(t, err)
+
+	assert.Equal(t, "7", string(msg.Key))
+	require.Len(t, msg.Headers, 2)
+	assert.Equal(t, "event-id", msg.Headers[0].Key)
+	assert.Equal(t, "42", string(msg.Headers[0].Value))
+	assert.Equal(t, "note.created", string(msg.Headers[1].Value))
+	assert.Contains(t, string(msg.Value), `"data":{"id":7,"title":"Hello"}`)
+}
@@ -0,0 +1,112 @@
+// Package events доставляет подписчикам уведомления об изменениях заметок,
+// полученные через PostgreSQL LISTEN/NOTIFY
+package events
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel — канал NOTIFY, в который триггеры пишут ID пользователя
+const Channel = "note_events"
+
+// Hub рассылает уведомления подписчикам по ID пользователя. Уведомление
+// только будит подписчика: сами события он читает из note_events, поэтому
+// несколько уведомлений подряд схлопываются в одно.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[int64]map[chan struct{}]struct{}
+}
+
+// NewHub создает новый экземпляр Hub
+func NewHub() *Hub {
+	return &Hub{subs: make(map[int64]map[chan struct{}]struct{})}
+}
+
+// Subscribe подписывает на уведомления для userID. Вызывающий обязан вызвать
+// cancel, когда подписка больше не нужна.
+func (h *Hub) Subscribe(userID int64) (wake <-chan struct{}, cancel func()) {
+	ch := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[chan struct{}]struct{})
+	}
+	h.subs[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs[userID], ch)
+		if len(h.subs[userID]) == 0 {
+			delete(h.subs, userID)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// Notify будит подписчиков userID
+func (h *Hub) Notify(userID int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[userID] {
+		wake(ch)
+	}
+}
+
+// NotifyAll будит всех подписчиков, например после переподключения к базе,
+// когда уведомления могли быть потеряны
+func (h *Hub) NotifyAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, subs := range h.subs {
+		for ch := range subs {
+			wake(ch)
+		}
+	}
+}
+
+func wake(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Listen слушает канал Channel и передает уведомления в hub. Соединение
+// восстанавливается автоматически; функция не возвращает управление.
+func Listen(databaseURL string, hub *Hub) {
+	listener := pq.NewListener(databaseURL, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Note events listener: %v", err)
+		}
+	})
+	if err := listener.Listen(Channel); err != nil {
+		log.Printf("Failed to listen for note events: %v", err)
+	}
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case n := <-listener.Notify:
+			if n == nil {
+				// Соединение восстановлено: уведомления за время разрыва потеряны
+				hub.NotifyAll()
+				continue
+			}
+			userID, err := strconv.ParseInt(n.Extra, 10, 64)
+			if err != nil {
+				log.Printf("Invalid note event payload %q", n.Extra)
+				continue
+			}
+			hub.Notify(userID)
+		case <-ping.C:
+			go listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+	alice, cancelAlice := hub.Subscribe(1)
+	bob, cancelBob := hub.Subscribe(2)
+	defer cancelBob()
+
+	hub.Notify(1)
+	hub.Notify(1)
+	assert.Len(t, alice, 1, "notifications are coalesced")
+	assert.Len(t, bob, 0)
+	<-alice
+
+	hub.NotifyAll()
+	assert.Len(t, alice, 1)
+	assert.Len(t, bob, 1)
+
+	cancelAlice()
+	<-alice
+	hub.Notify(1)
+	assert.Len(t, alice, 0)
+	assert.NotContains(t, hub.subs, int64(1))
+}
@@ -0,0 +1,254 @@
+// Package export упаковывает заметки в ZIP-архив: файл Markdown с YAML front
+// matter и HTML-версия на каждую заметку, плюс манифест notes.json
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"html"
+	"io"
+	"notes-service/internal/markdown"
+	"notes-service/internal/models"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile — имя манифеста в архиве
+const ManifestFile = "notes.json"
+
+// FrontMatter — метаданные заметки в начале файла Markdown
+type FrontMatter struct {
+	Title     string          `yaml:"title"`
+	Format    string          `yaml:"format"`
+	Notebook  string          `yaml:"notebook,omitempty"`
+	Created   time.Time       `yaml:"created"`
+	Updated   time.Time       `yaml:"updated"`
+	Pinned    bool            `yaml:"pinned,omitempty"`
+	Archived  bool            `yaml:"archived,omitempty"`
+	Favorite  bool            `yaml:"favorite,omitempty"`
+	Checklist []ChecklistItem `yaml:"checklist,omitempty"`
+}
+
+// ChecklistItem — пункт чек-листа заметки в архиве
+type ChecklistItem struct {
+	Text    string `yaml:"text" json:"text"`
+	Checked bool   `yaml:"checked" json:"checked"`
+}
+
+// Manifest описывает содержимое архива
+type Manifest struct {
+	ExportedAt time.Time `json:"exported_at"`
+	Notes      []Entry   `json:"notes"`
+}
+
+// Entry — запись манифеста об одной заметке
+type Entry struct {
+	ID            int64           `json:"id"`
+	Title         string          `json:"title"`
+	ContentFormat string          `json:"content_format"`
+	Notebook      string          `json:"notebook,omitempty"`
+	File          string          `json:"file"`
+	HTMLFile      string          `json:"html_file"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	Pinned        bool            `json:"pinned,omitempty"`
+	Archived      bool            `json:"archived,omitempty"`
+	Favorite      bool            `json:"favorite,omitempty"`
+	Checklist     []ChecklistItem `json:"checklist,omitempty"`
+}
+
+// Writer пишет архив по мере поступления заметок; в памяти остаются только
+// записи манифеста
+type Writer struct {
+	zw        *zip.Writer
+	notebooks map[int64]string
+	used      map[string]bool
+	manifest  Manifest
+}
+
+// NewWriter создает Writer. Заметки раскладываются по папкам, повторяющим
+// дерево блокнотов notebooks.
+func NewWriter(w io.Writer, notebooks []*models.Notebook) *Writer {
+	return &Writer{
+		zw:        zip.NewWriter(w),
+		notebooks: notebookPaths(notebooks),
+		used:      map[string]bool{},
+		manifest:  Manifest{ExportedAt: time.Now().UTC(), Notes: []Entry{}},
+	}
+}
+
+// Add добавляет заметку в архив
+func (w *Writer) Add(note *models.Note) error {
+	var notebook string
+	if note.NotebookID != nil {
+		notebook = w.notebooks[*note.NotebookID]
+	}
+	base := w.uniqueName(path.Join("notes", notebook, fileName(note.Title, note.ID)))
+	checklist := make([]ChecklistItem, len(note.Checklist))
+	for i, item := range note.Checklist {
+		checklist[i] = ChecklistItem{Text: item.Text, Checked: item.Checked}
+	}
+
+	var doc bytes.Buffer
+	doc.WriteString("---\n")
+	if err := yaml.NewEncoder(&doc).Encode(FrontMatter{
+		Title:     note.Title,
+		Format:    note.ContentFormat,
+		Notebook:  notebook,
+		Created:   note.CreatedAt,
+		Updated:   note.UpdatedAt,
+		Pinned:    note.Pinned,
+		Archived:  note.Archived,
+		Favorite:  note.Favorite,
+		Checklist: checklist,
+	}); err != nil {
+		return err
+	}
+	doc.WriteString("---\n")
+	doc.WriteString(note.Content)
+	if err := w.writeFile(base+".md", note.UpdatedAt, doc.Bytes()); err != nil {
+		return err
+	}
+
+	body, err := markdown.ToHTML(note.ContentFormat, note.Content, nil)
+	if err != nil {
+		return err
+	}
+	page := "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" +
+		html.EscapeString(note.Title) + "</title>\n</head>\n<body>\n<h1>" + html.EscapeString(note.Title) + "</h1>\n" +
+		body + checklistHTML(checklist) + "</body>\n</html>\n"
+	htmlFile := "html" + strings.TrimPrefix(base, "notes") + ".html"
+	if err := w.writeFile(htmlFile, note.UpdatedAt, []byte(page)); err != nil {
+		return err
+	}
+
+	w.manifest.Notes = append(w.manifest.Notes, Entry{
+		ID:            note.ID,
+		Title:         note.Title,
+		ContentFormat: note.ContentFormat,
+		Notebook:      notebook,
+		File:          base + ".md",
+		HTMLFile:      htmlFile,
+		CreatedAt:     note.CreatedAt,
+		UpdatedAt:     note.UpdatedAt,
+		Pinned:        note.Pinned,
+		Archived:      note.Archived,
+		Favorite:      note.Favorite,
+		Checklist:     checklist,
+	})
+	return nil
+}
+
+// checklistHTML выводит чек-лист списком с неактивными флажками
+func checklistHTML(checklist []ChecklistItem) string {
+	if len(checklist) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("<ul class=\"checklist\">\n")
+	for _, item := range checklist {
+		b.WriteString("<li><input type=\"checkbox\" disabled")
+		if item.Checked {
+			b.WriteString(" checked")
+		}
+		b.WriteString("> " + html.EscapeString(item.Text) + "</li>\n")
+	}
+	b.WriteString("</ul>\n")
+	return b.String()
+}
+
+// Close дописывает манифест и завершает архив
+func (w *Writer) Close() error {
+	data, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := w.writeFile(ManifestFile, w.manifest.ExportedAt, data); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+func (w *Writer) writeFile(name string, modified time.Time, data []byte) error {
+	f, err := w.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// uniqueName добавляет суффикс, если такое имя в архиве уже есть
+func (w *Writer) uniqueName(name string) string {
+	candidate := name
+	for i := 2; w.used[candidate]; i++ {
+		candidate = name + "-" + strconv.Itoa(i)
+	}
+	w.used[candidate] = true
+	return candidate
+}
+
+// notebookPaths строит для каждого блокнота путь из имен его предков
+func notebookPaths(notebooks []*models.Notebook) map[int64]string {
+	byID := make(map[int64]*models.Notebook, len(notebooks))
+	for _, notebook := range notebooks {
+		byID[notebook.ID] = notebook
+	}
+
+	paths := make(map[int64]string, len(notebooks))
+	for _, notebook := range notebooks {
+		var parts []string
+		// Глубина ограничена числом блокнотов на случай поврежденного дерева
+		for n, depth := notebook, 0; n != nil && depth < len(notebooks); depth++ {
+			parts = append([]string{slug(n.Name)}, parts...)
+			if n.ParentID == nil {
+				break
+			}
+			n = byID[*n.ParentID]
+		}
+		paths[notebook.ID] = path.Join(parts...)
+	}
+	return paths
+}
+
+// fileName — имя файла заметки без расширения: заголовок, а для заметок без
+// заголовка — note-ID
+func fileName(title string, id int64) string {
+	name := slug(title)
+	if name == "untitled" {
+		return "note-" + strconv.FormatInt(id, 10)
+	}
+	return name
+}
+
+const maxNameLength = 100
+
+// slug оставляет в имени буквы, цифры, точку, дефис и подчеркивание
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.':
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	s := strings.Trim(b.String(), "-.")
+	if s == "" {
+		return "untitled"
+	}
+	if runes := []rune(s); len(runes) > maxNameLength {
+		s = strings.TrimRight(string(runes[:maxNameLength]), "-.")
+	}
+	return s
+}
@@ -0,0 +1,100 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"notes-service/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readArchive(t *testing.T, data []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func TestWriter(t *testing.T) {
+	rootID, childID := int64(1), int64(2)
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, []*models.Notebook{
+		{ID: rootID, Name: "Work"},
+		{ID: childID, ParentID: &rootID, Name: "Q1 / plans"},
+	})
+	require.NoError(t, w.Add(&models.Note{
+		ID: 7, NotebookID: &childID, Title: "Road map", Content: "# Goals", ContentFormat: models.FormatMarkdown,
+		CreatedAt: created, UpdatedAt: created,
+	}))
+	require.NoError(t, w.Add(&models.Note{ID: 8, Title: "Road map", Content: "plain", ContentFormat: models.FormatPlain,
+		CreatedAt: created, UpdatedAt: created}))
+	require.NoError(t, w.Add(&models.Note{ID: 9, Title: "../..", ContentFormat: models.FormatPlain,
+		CreatedAt: created, UpdatedAt: created, Pinned: true, Archived: true}))
+	require.NoError(t, w.Close())
+
+	files := readArchive(t, buf.Bytes())
+
+	doc := files["notes/Work/Q1-plans/Road-map.md"]
+	assert.True(t, strings.HasPrefix(doc, "---\ntitle: Road map\nformat: markdown\nnotebook: Work/Q1-plans\ncreated: 2026-01-02T03:04:05Z\n"))
+	assert.True(t, strings.HasSuffix(doc, "---\n# Goals"))
+	assert.Contains(t, files["html/Work/Q1-plans/Road-map.html"], "<h1>Goals</h1>")
+	assert.Contains(t, files, "notes/Road-map.md")
+	assert.Contains(t, files["notes/note-9.md"], "updated: 2026-01-02T03:04:05Z\npinned: true\narchived: true\n---\n")
+	assert.NotContains(t, doc, "pinned")
+
+	var manifest Manifest
+	require.NoError(t, json.Unmarshal([]byte(files[ManifestFile]), &manifest))
+	assert.Len(t, manifest.Notes, 3)
+	assert.Equal(t, "notes/Work/Q1-plans/Road-map.md", manifest.Notes[0].File)
+	assert.Equal(t, "Work/Q1-plans", manifest.Notes[0].Notebook)
+	assert.True(t, manifest.Notes[2].Archived)
+}
+
+func TestWriterChecklist(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, nil)
+	require.NoError(t, w.Add(&models.Note{ID: 1, Title: "Groceries", ContentFormat: models.FormatPlain,
+		Checklist: []*models.ChecklistItem{{Text: "Milk", Checked: true}, {Text: "<Bread>"}}}))
+	require.NoError(t, w.Add(&models.Note{ID: 2, Title: "Plain", ContentFormat: models.FormatPlain}))
+	require.NoError(t, w.Close())
+
+	files := readArchive(t, buf.Bytes())
+	assert.Contains(t, files["notes/Groceries.md"], "checklist:\n    - text: Milk\n      checked: true\n    - text: <Bread>\n      checked: false\n")
+	assert.NotContains(t, files["notes/Plain.md"], "checklist")
+	assert.Contains(t, files["html/Groceries.html"],
+		"<li><input type=\"checkbox\" disabled checked> Milk</li>\n<li><input type=\"checkbox\" disabled> &lt;Bread&gt;</li>")
+	assert.NotContains(t, files["html/Plain.html"], "checklist")
+
+	var manifest Manifest
+	require.NoError(t, json.Unmarshal([]byte(files[ManifestFile]), &manifest))
+	assert.Equal(t, []ChecklistItem{{Text: "Milk", Checked: true}, {Text: "<Bread>"}}, manifest.Notes[0].Checklist)
+	assert.Nil(t, manifest.Notes[1].Checklist)
+}
+
+func TestWriterDeduplicatesNames(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, nil)
+	require.NoError(t, w.Add(&models.Note{ID: 1, Title: "Same"}))
+	require.NoError(t, w.Add(&models.Note{ID: 2, Title: "Same"}))
+	require.NoError(t, w.Close())
+
+	files := readArchive(t, buf.Bytes())
+	assert.Contains(t, files, "notes/Same.md")
+	assert.Contains(t, files, "notes/Same-2.md")
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/repository"
+)
+
+// AdminHandler обрабатывает административные запросы
+type AdminHandler struct {
+	repo repository.AdminRepository
+}
+
+// NewAdminHandler создает новый экземпляр AdminHandler
+func NewAdminHandler(repo repository.AdminRepository) *AdminHandler {
+	return &AdminHandler{repo: repo}
+}
+
+// ListUsers возвращает пользователей с числом заметок (GET /admin/users?limit=&offset=)
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	users, err := h.repo.ListUsers(r.Context(), limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+}
+
+// GetUser возвращает пользователя с числом заметок (GET /admin/users/{id})
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	user, err := h.repo.GetUserSummary(r.Context(), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch user", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// DisableUser отключает аккаунт (POST /admin/users/{id}/disable). Выданные
+// токены и ключи API перестают приниматься сразу.
+func (h *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if currentUserID, _ := auth.UserIDFromContext(r.Context()); currentUserID == userID {
+		http.Error(w, "Cannot disable your own account", http.StatusBadRequest)
+		return
+	}
+
+	h.setDisabled(w, r, userID, true)
+}
+
+// EnableUser снова включает аккаунт (POST /admin/users/{id}/enable)
+func (h *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	h.setDisabled(w, r, userID, false)
+}
+
+func (h *AdminHandler) setDisabled(w http.ResponseWriter, r *http.Request, userID int64, disabled bool) {
+	err := h.repo.SetUserDisabled(r.Context(), userID, disabled)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutUser отзывает все токены и ключи API пользователя (POST /admin/users/{id}/logout)
+func (h *AdminHandler) LogoutUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := h.repo.RevokeUserTokens(r.Context(), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to revoke tokens", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/auth"
+	"notes-service/internal/repository"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAdminRepository struct {
+	mock.Mock
+}
+
+func (m *MockAdminRepository) ListUsers(ctx context.Context, limit, offset int) ([]*repository.UserSummary, error) {
+	args := m.Called(ctx, limit, offset)
+	return args.Get(0).([]*repository.UserSummary), args.Error(1)
+}
+
+func (m *MockAdminRepository) GetUserSummary(ctx context.Context, id int64) (*repository.UserSummary, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*repository.UserSummary), args.Error(1)
+}
+
+func (m *MockAdminRepository) SetUserDisabled(ctx context.Context, id int64, disabled bool) error {
+	args := m.Called(ctx, id, disabled)
+	return args.Error(0)
+}
+
+func (m *MockAdminRepository) RevokeUserTokens(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func adminRouter(h *AdminHandler) http.Handler {
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), 1, repository.RoleAdmin)))
+		})
+	})
+	r.Get("/admin/users", h.ListUsers)
+	r.Post("/admin/users/{id}/disable", h.DisableUser)
+	r.Post("/admin/users/{id}/logout", h.LogoutUser)
+	return r
+}
+
+func TestAdminListUsers(t *testing.T) {
+	mockRepo := new(MockAdminRepository)
+	router := adminRouter(NewAdminHandler(mockRepo))
+
+	mockRepo.On("ListUsers", mock.Anything, 10, 20).Return([]*repository.UserSummary{
+		{User: &repository.User{ID: 2, Username: "alice", Password: "hash", Role: repository.RoleUser}, NoteCount: 3},
+	}, nil)
+
+	req, _ := http.NewRequest("GET", "/admin/users?limit=10&offset=20", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "hash")
+
+	var response []map[string]interface{}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Len(t, response, 1)
+	assert.Equal(t, "alice", response[0]["username"])
+	assert.Equal(t, float64(3), response[0]["note_count"])
+}
+
+func TestAdminDisableUser(t *testing.T) {
+	mockRepo := new(MockAdminRepository)
+	router := adminRouter(NewAdminHandler(mockRepo))
+
+	mockRepo.On("SetUserDisabled", mock.Anything, int64(2), true).Return(nil)
+
+	req, _ := http.NewRequest("POST", "/admin/users/2/disable", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAdminCannotDisableSelf(t *testing.T) {
+	mockRepo := new(MockAdminRepository)
+	router := adminRouter(NewAdminHandler(mockRepo))
+
+	req, _ := http.NewRequest("POST", "/admin/users/1/disable", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockRepo.AssertNotCalled(t, "SetUserDisabled", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAdminLogoutUnknownUser(t *testing.T) {
+	mockRepo := new(MockAdminRepository)
+	router := adminRouter(NewAdminHandler(mockRepo))
+
+	mockRepo.On("RevokeUserTokens", mock.Anything, int64(42)).Return(repository.ErrNotFound)
+
+	req, _ := http.NewRequest("POST", "/admin/users/42/logout", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
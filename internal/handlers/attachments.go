@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/markdown"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"notes-service/internal/signedurl"
+	"notes-service/internal/storage"
+	"notes-service/internal/thumbnail"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxFilenameLength — длина колонки attachments.filename
+const maxFilenameLength = 255
+
+// inlineContentTypes — типы вложений, которые браузер показывает на странице;
+// остальные отдаются только для скачивания
+var inlineContentTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// AttachmentHandler обрабатывает загрузку и скачивание вложений заметок
+type AttachmentHandler struct {
+	repo            repository.AttachmentRepository
+	store           storage.BlobStore
+	maxBytes        int64
+	quota           int64
+	notifyThumbnail func()
+	signer          *signedurl.Signer
+}
+
+// AttachmentOption настраивает AttachmentHandler
+type AttachmentOption func(*AttachmentHandler)
+
+// WithThumbnailNotify задает функцию, которая будит обработчик миниатюр
+// после загрузки изображения
+func WithThumbnailNotify(notify func()) AttachmentOption {
+	return func(h *AttachmentHandler) { h.notifyThumbnail = notify }
+}
+
+// WithSignedURLs включает скачивание по подписанным ссылкам (GET /files/{id})
+func WithSignedURLs(signer *signedurl.Signer) AttachmentOption {
+	return func(h *AttachmentHandler) { h.signer = signer }
+}
+
+// NewAttachmentHandler создает новый экземпляр AttachmentHandler. maxBytes
+// ограничивает размер одного файла, quota — суммарный размер вложений,
+// загруженных пользователем.
+func NewAttachmentHandler(repo repository.AttachmentRepository, store storage.BlobStore, maxBytes, quota int64, opts ...AttachmentOption) *AttachmentHandler {
+	h := &AttachmentHandler{repo: repo, store: store, maxBytes: maxBytes, quota: quota, notifyThumbnail: func() {}}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// UploadAttachment прикрепляет к заметке файл из поля file формы
+// multipart/form-data. Тип содержимого определяется по самому файлу.
+func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	// Запас на заголовки multipart; файлы больше 1 МБ ParseMultipartForm
+	// сохраняет во временные файлы
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBytes+1<<20)
+	err := r.ParseMultipartForm(1 << 20)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "File is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	if header.Size > h.maxBytes {
+		http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	contentType, err := sniffContentType(file)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		return
+	}
+
+	attachment := models.Attachment{
+		NoteID:      noteID,
+		UserID:      userID,
+		Filename:    attachmentFilename(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+		StorageKey:  storage.NewKey("notes/" + strconv.FormatInt(noteID, 10)),
+	}
+	if thumbnail.Supported(contentType) {
+		attachment.ThumbnailStatus = models.ThumbnailPending
+	}
+	if err := h.repo.CheckAttachmentQuota(r.Context(), userID, noteID, attachment.Size, h.quota); err != nil {
+		uploadError(w, err)
+		return
+	}
+
+	if err := h.store.Put(r.Context(), attachment.StorageKey, file, attachment.Size, contentType); err != nil {
+		log.Printf("Failed to store attachment for note %d: %v", noteID, err)
+		http.Error(w, "Failed to store attachment", http.StatusInternalServerError)
+		return
+	}
+	if err := h.repo.CreateAttachment(r.Context(), &attachment, h.quota); err != nil {
+		if delErr := h.store.Delete(r.Context(), attachment.StorageKey); delErr != nil {
+			log.Printf("Failed to delete blob %s: %v", attachment.StorageKey, delErr)
+		}
+		uploadError(w, err)
+		return
+	}
+	if attachment.ThumbnailStatus != "" {
+		h.notifyThumbnail()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// ListAttachments возвращает вложения заметки
+func (h *AttachmentHandler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	attachments, err := h.repo.ListAttachments(r.Context(), userID, noteID)
+	if err != nil {
+		noteError(w, err, "Failed to fetch attachments")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// DownloadAttachment отдает содержимое вложения потоком из хранилища.
+// Поддерживаются запросы Range, в том числе для возобновления загрузки.
+func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	attachmentID, ok := pathID(w, r, "attachmentID")
+	if !ok {
+		return
+	}
+
+	attachment, err := h.repo.GetAttachment(r.Context(), userID, noteID, attachmentID)
+	if err != nil {
+		attachmentError(w, err, "Failed to fetch attachment")
+		return
+	}
+
+	h.serve(w, r, attachment)
+}
+
+// DownloadThumbnail отдает миниатюру изображения из вложения
+// (GET /notes/{id}/attachments/{attachmentID}/thumbnails/{size})
+func (h *AttachmentHandler) DownloadThumbnail(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	attachmentID, ok := pathID(w, r, "attachmentID")
+	if !ok {
+		return
+	}
+
+	// Проверка доступа к заметке, затем поиск миниатюры
+	if _, err := h.repo.GetAttachment(r.Context(), userID, noteID, attachmentID); err != nil {
+		attachmentError(w, err, "Failed to fetch attachment")
+		return
+	}
+	blob, err := h.repo.GetAttachmentBlob(r.Context(), attachmentID, chi.URLParam(r, "size"))
+	if err != nil {
+		attachmentError(w, err, "Failed to fetch attachment")
+		return
+	}
+
+	h.serve(w, r, blob)
+}
+
+// ServeSignedFile отдает вложение или его миниатюру по подписанной ссылке
+// без аутентификации (GET /files/{id}?size=&expires=&signature=). Такие
+// ссылки подставляются в HTML заметок вместо attachment:ID.
+func (h *AttachmentHandler) ServeSignedFile(w http.ResponseWriter, r *http.Request) {
+	if h.signer == nil {
+		http.NotFound(w, r)
+		return
+	}
+	attachmentID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	size := query.Get("size")
+	err := h.signer.Verify(attachmentID, size, query.Get("expires"), query.Get("signature"))
+	if errors.Is(err, signedurl.ErrExpired) {
+		http.Error(w, "Link expired", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Invalid signature", http.StatusForbidden)
+		return
+	}
+
+	blob, err := h.repo.GetAttachmentBlob(r.Context(), attachmentID, size)
+	if err != nil {
+		attachmentError(w, err, "Failed to fetch attachment")
+		return
+	}
+
+	h.serve(w, r, blob)
+}
+
+// serve отдает объект вложения потоком из хранилища
+func (h *AttachmentHandler) serve(w http.ResponseWriter, r *http.Request, attachment *models.Attachment) {
+	disposition := "attachment"
+	if inlineContentTypes[attachment.ContentType] {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private")
+	// Содержимое по ключу не меняется, поэтому ключ годится как ETag для If-Range
+	w.Header().Set("ETag", strconv.Quote(attachment.StorageKey))
+
+	content := storage.NewReader(r.Context(), h.store, attachment.StorageKey, attachment.Size)
+	defer content.Close()
+	http.ServeContent(w, r, "", attachment.CreatedAt, content)
+}
+
+// DeleteAttachment удаляет вложение заметки
+func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	attachmentID, ok := pathID(w, r, "attachmentID")
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteAttachment(r.Context(), userID, noteID, attachmentID); err != nil {
+		attachmentError(w, err, "Failed to delete attachment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sniffContentType определяет тип файла по первым 512 байтам и возвращает
+// файл в начало
+func sniffContentType(file io.ReadSeeker) (string, error) {
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// attachmentFilename приводит имя загруженного файла к виду, пригодному
+// для хранения и заголовка Content-Disposition
+func attachmentFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '/' || r == '\\' {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(name, ""))
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." {
+		return "attachment"
+	}
+	if utf8.RuneCountInString(name) > maxFilenameLength {
+		name = string([]rune(name)[:maxFilenameLength])
+	}
+	return name
+}
+
+// AttachmentLinks заменяет ссылки attachment:ID в HTML заметок подписанными
+// ссылками на вложения этой заметки
+type AttachmentLinks struct {
+	repo   repository.AttachmentRepository
+	signer *signedurl.Signer
+}
+
+// NewAttachmentLinks создает новый экземпляр AttachmentLinks
+func NewAttachmentLinks(repo repository.AttachmentRepository, signer *signedurl.Signer) *AttachmentLinks {
+	return &AttachmentLinks{repo: repo, signer: signer}
+}
+
+// Resolver возвращает markdown.AttachmentResolver для заметки. Разрешаются
+// только вложения самой заметки; если миниатюры нужного размера нет,
+// подставляется исходный файл. Для l == nil возвращается nil.
+func (l *AttachmentLinks) Resolver(ctx context.Context, note *models.Note) (markdown.AttachmentResolver, error) {
+	if l == nil || note.ContentFormat != models.FormatMarkdown ||
+		!strings.Contains(note.Content, markdown.AttachmentScheme) {
+		return nil, nil
+	}
+
+	attachments, err := l.repo.NoteAttachments(ctx, note.ID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*models.Attachment, len(attachments))
+	for _, a := range attachments {
+		byID[a.ID] = a
+	}
+
+	return func(id int64, size string) (string, bool) {
+		attachment, ok := byID[id]
+		if !ok {
+			return "", false
+		}
+		for _, t := range attachment.Thumbnails {
+			if t.Size == size {
+				return l.signer.AttachmentURL(id, size), true
+			}
+		}
+		return l.signer.AttachmentURL(id, ""), true
+	}, nil
+}
+
+func uploadError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repository.ErrQuotaExceeded) {
+		http.Error(w, "Attachment quota exceeded", http.StatusRequestEntityTooLarge)
+		return
+	}
+	noteError(w, err, "Failed to upload attachment")
+}
+
+func attachmentError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+	default:
+		noteError(w, err, message)
+	}
+}
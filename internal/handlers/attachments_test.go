@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/markdown"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"notes-service/internal/signedurl"
+	"notes-service/internal/storage"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAttachmentRepository struct {
+	mock.Mock
+}
+
+func (m *MockAttachmentRepository) CheckAttachmentQuota(ctx context.Context, userID, noteID, size, quota int64) error {
+	return m.Called(ctx, userID, noteID, size, quota).Error(0)
+}
+
+func (m *MockAttachmentRepository) CreateAttachment(ctx context.Context, attachment *models.Attachment, quota int64) error {
+	args := m.Called(ctx, attachment, quota)
+	attachment.ID = 3
+	return args.Error(0)
+}
+
+func (m *MockAttachmentRepository) ListAttachments(ctx context.Context, userID, noteID int64) ([]*models.Attachment, error) {
+	args := m.Called(ctx, userID, noteID)
+	return args.Get(0).([]*models.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentRepository) NoteAttachments(ctx context.Context, noteID int64) ([]*models.Attachment, error) {
+	args := m.Called(ctx, noteID)
+	return args.Get(0).([]*models.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentRepository) GetAttachmentBlob(ctx context.Context, attachmentID int64, size string) (*models.Attachment, error) {
+	args := m.Called(ctx, attachmentID, size)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentRepository) GetAttachment(ctx context.Context, userID, noteID, attachmentID int64) (*models.Attachment, error) {
+	args := m.Called(ctx, userID, noteID, attachmentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentRepository) DeleteAttachment(ctx context.Context, userID, noteID, attachmentID int64) error {
+	return m.Called(ctx, userID, noteID, attachmentID).Error(0)
+}
+
+func (m *MockAttachmentRepository) ListOrphanedBlobs(ctx context.Context, limit int) ([]string, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockAttachmentRepository) ForgetOrphanedBlob(ctx context.Context, key string) error {
+	return m.Called(ctx, key).Error(0)
+}
+
+func attachmentRouter(handler *AttachmentHandler) http.Handler {
+	r := chi.NewRouter()
+	r.Post("/notes/{id}/attachments", handler.UploadAttachment)
+	r.Get("/notes/{id}/attachments/{attachmentID}", handler.DownloadAttachment)
+	r.Get("/files/{id}", handler.ServeSignedFile)
+	return new(MockAuthService).Authenticate(r)
+}
+
+func uploadRequest(t *testing.T, filename string, content []byte) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	f, err := mw.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	f.Write(content)
+	require.NoError(t, mw.Close())
+
+	req, _ := http.NewRequest("POST", "/notes/7/attachments", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestUploadAttachment(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	mockRepo := new(MockAttachmentRepository)
+	notified := 0
+	handler := NewAttachmentHandler(mockRepo, store, 1<<20, 10<<20, WithThumbnailNotify(func() { notified++ }))
+
+	// Тип берется из содержимого, а не из расширения или заголовка клиента
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 100)...)
+	var stored *models.Attachment
+	mockRepo.On("CheckAttachmentQuota", mock.Anything, int64(1), int64(7), int64(len(png)), int64(10<<20)).Return(nil)
+	mockRepo.On("CreateAttachment", mock.Anything, mock.AnythingOfType("*models.Attachment"), int64(10<<20)).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*models.Attachment) }).
+		Return(nil)
+
+	rr := httptest.NewRecorder()
+	attachmentRouter(handler).ServeHTTP(rr, uploadRequest(t, "photo.txt", png))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var attachment models.Attachment
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&attachment))
+	assert.Equal(t, int64(3), attachment.ID)
+	assert.Equal(t, "photo.txt", attachment.Filename)
+	assert.Equal(t, "image/png", attachment.ContentType)
+	assert.Equal(t, models.ThumbnailPending, attachment.ThumbnailStatus)
+	assert.Equal(t, 1, notified)
+	assert.NotContains(t, rr.Body.String(), "storage_key")
+
+	body, err := store.Get(context.Background(), stored.StorageKey, 0, -1)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, png, data)
+}
+
+func TestUploadAttachmentQuotaExceeded(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	mockRepo := new(MockAttachmentRepository)
+	handler := NewAttachmentHandler(mockRepo, store, 1<<20, 10)
+
+	mockRepo.On("CheckAttachmentQuota", mock.Anything, int64(1), int64(7), int64(11), int64(10)).
+		Return(repository.ErrQuotaExceeded)
+
+	rr := httptest.NewRecorder()
+	attachmentRouter(handler).ServeHTTP(rr, uploadRequest(t, "a.txt", []byte("hello world")))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	mockRepo.AssertNotCalled(t, "CreateAttachment", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUploadAttachmentTooLarge(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	handler := NewAttachmentHandler(new(MockAttachmentRepository), store, 10, 10<<20)
+
+	rr := httptest.NewRecorder()
+	attachmentRouter(handler).ServeHTTP(rr, uploadRequest(t, "a.txt", []byte("hello world")))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+}
+
+func TestDownloadAttachmentRange(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(context.Background(), "notes/7/abc", strings.NewReader("hello world"), 11, "text/plain"))
+	mockRepo := new(MockAttachmentRepository)
+	handler := NewAttachmentHandler(mockRepo, store, 1<<20, 10<<20)
+
+	mockRepo.On("GetAttachment", mock.Anything, int64(1), int64(7), int64(3)).Return(&models.Attachment{
+		ID: 3, NoteID: 7, Filename: "отчет.html", ContentType: "text/html; charset=utf-8", Size: 11,
+		StorageKey: "notes/7/abc", CreatedAt: time.Now(),
+	}, nil)
+
+	req, _ := http.NewRequest("GET", "/notes/7/attachments/3", nil)
+	req.Header.Set("Range", "bytes=6-")
+	rr := httptest.NewRecorder()
+	attachmentRouter(handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusPartialContent, rr.Code)
+	assert.Equal(t, "world", rr.Body.String())
+	assert.Equal(t, "bytes 6-10/11", rr.Header().Get("Content-Range"))
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(rr.Header().Get("Content-Disposition"), "attachment;"))
+	assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+}
+
+func TestDownloadAttachmentNotFound(t *testing.T) {
+	mockRepo := new(MockAttachmentRepository)
+	handler := NewAttachmentHandler(mockRepo, nil, 1<<20, 10<<20)
+
+	mockRepo.On("GetAttachment", mock.Anything, int64(1), int64(7), int64(3)).Return(nil, repository.ErrNotFound)
+
+	req, _ := http.NewRequest("GET", "/notes/7/attachments/3", nil)
+	rr := httptest.NewRecorder()
+	attachmentRouter(handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestServeSignedFile(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(context.Background(), "thumbnails/3/abc", strings.NewReader("png"), 3, "image/png"))
+	mockRepo := new(MockAttachmentRepository)
+	signer := signedurl.NewSigner([]byte("secret"), "http://localhost:8080", time.Hour)
+	handler := NewAttachmentHandler(mockRepo, store, 1<<20, 10<<20, WithSignedURLs(signer))
+
+	mockRepo.On("GetAttachmentBlob", mock.Anything, int64(3), "small").Return(&models.Attachment{
+		ID: 3, Filename: "photo.png", ContentType: "image/png", Size: 3, StorageKey: "thumbnails/3/abc",
+	}, nil)
+
+	link := strings.TrimPrefix(signer.AttachmentURL(3, "small"), "http://localhost:8080")
+	req, _ := http.NewRequest("GET", link, nil)
+	rr := httptest.NewRecorder()
+	attachmentRouter(handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "png", rr.Body.String())
+	assert.True(t, strings.HasPrefix(rr.Header().Get("Content-Disposition"), "inline;"))
+
+	// Подпись выдана для миниатюры, а не для исходного файла
+	req, _ = http.NewRequest("GET", strings.Replace(link, "size=small", "size=", 1), nil)
+	rr = httptest.NewRecorder()
+	attachmentRouter(handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestAttachmentLinksResolver(t *testing.T) {
+	mockRepo := new(MockAttachmentRepository)
+	signer := signedurl.NewSigner([]byte("secret"), "http://localhost:8080", time.Hour)
+	links := NewAttachmentLinks(mockRepo, signer)
+
+	mockRepo.On("NoteAttachments", mock.Anything, int64(7)).Return([]*models.Attachment{
+		{ID: 3, NoteID: 7, ThumbnailStatus: models.ThumbnailReady, Thumbnails: []*models.Thumbnail{{Size: "small"}}},
+		{ID: 4, NoteID: 7},
+	}, nil)
+
+	note := &models.Note{ID: 7, ContentFormat: models.FormatMarkdown,
+		Content: "![a](attachment:3?size=small) ![b](attachment:4?size=small) ![c](attachment:5)"}
+	resolve, err := links.Resolver(context.Background(), note)
+	require.NoError(t, err)
+
+	html, err := markdown.ToHTML(note.ContentFormat, note.Content, resolve)
+	require.NoError(t, err)
+	assert.Contains(t, html, `src="http://localhost:8080/files/3?expires=`)
+	assert.Contains(t, html, "size=small")
+	// Миниатюры нет — подставляется исходный файл
+	assert.Contains(t, html, `src="http://localhost:8080/files/4?expires=`)
+	// Чужое вложение не разрешается и удаляется при очистке
+	assert.NotContains(t, html, "/files/5")
+	assert.NotContains(t, html, "attachment:")
+
+	// Без ссылок на вложения база не запрашивается
+	resolve, err = links.Resolver(context.Background(), &models.Note{ID: 8, ContentFormat: models.FormatMarkdown, Content: "text"})
+	assert.NoError(t, err)
+	assert.Nil(t, resolve)
+	mockRepo.AssertNumberOfCalls(t, "NoteAttachments", 1)
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxChecklistTextLength = 1000
+
+// ChecklistHandler управляет чек-листами заметок
+type ChecklistHandler struct {
+	repo repository.ChecklistRepository
+}
+
+// NewChecklistHandler создает новый экземпляр ChecklistHandler
+func NewChecklistHandler(repo repository.ChecklistRepository) *ChecklistHandler {
+	return &ChecklistHandler{repo: repo}
+}
+
+// ChecklistItemRequest — текст и отметка пункта чек-листа. Position задает
+// место нового пункта; без него пункт добавляется в конец.
+type ChecklistItemRequest struct {
+	Text     string `json:"text"`
+	Checked  bool   `json:"checked"`
+	Position *int   `json:"position,omitempty"`
+}
+
+func (req *ChecklistItemRequest) validate() string {
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" {
+		return "Text is required"
+	}
+	if utf8.RuneCountInString(req.Text) > maxChecklistTextLength {
+		return "Text is too long"
+	}
+	if req.Position != nil && *req.Position < 0 {
+		return "Position must not be negative"
+	}
+	return ""
+}
+
+// ChecklistOrderRequest — новый порядок всех пунктов чек-листа
+type ChecklistOrderRequest struct {
+	ItemIDs []int64 `json:"item_ids"`
+}
+
+// ListChecklist возвращает чек-лист заметки (GET /notes/{id}/checklist)
+func (h *ChecklistHandler) ListChecklist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	items, err := h.repo.ListChecklist(r.Context(), userID, noteID)
+	if err != nil {
+		checklistError(w, err, "Failed to fetch checklist")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// AddChecklistItem добавляет пункт в чек-лист заметки (POST /notes/{id}/checklist)
+func (h *ChecklistHandler) AddChecklistItem(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req ChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if message := req.validate(); message != "" {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+
+	item := &models.ChecklistItem{NoteID: noteID, Text: req.Text, Checked: req.Checked, Position: -1}
+	if req.Position != nil {
+		item.Position = *req.Position
+	}
+	if err := h.repo.AddChecklistItem(r.Context(), userID, item); err != nil {
+		checklistError(w, err, "Failed to add checklist item")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(item)
+}
+
+// UpdateChecklistItem меняет текст и отметку пункта
+// (PUT /notes/{id}/checklist/{itemID}). Переместить пункт можно через
+// PUT /notes/{id}/checklist/order.
+func (h *ChecklistHandler) UpdateChecklistItem(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := pathID(w, r, "itemID")
+	if !ok {
+		return
+	}
+
+	var req ChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if message := req.validate(); message != "" {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+
+	item := &models.ChecklistItem{ID: itemID, NoteID: noteID, Text: req.Text, Checked: req.Checked}
+	if err := h.repo.UpdateChecklistItem(r.Context(), userID, item); err != nil {
+		checklistError(w, err, "Failed to update checklist item")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// ToggleChecklistItem переключает отметку пункта
+// (POST /notes/{id}/checklist/{itemID}/toggle)
+func (h *ChecklistHandler) ToggleChecklistItem(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := pathID(w, r, "itemID")
+	if !ok {
+		return
+	}
+
+	item, err := h.repo.ToggleChecklistItem(r.Context(), userID, noteID, itemID)
+	if err != nil {
+		checklistError(w, err, "Failed to toggle checklist item")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// DeleteChecklistItem удаляет пункт чек-листа
+// (DELETE /notes/{id}/checklist/{itemID})
+func (h *ChecklistHandler) DeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := pathID(w, r, "itemID")
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteChecklistItem(r.Context(), userID, noteID, itemID); err != nil {
+		checklistError(w, err, "Failed to delete checklist item")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReorderChecklist меняет порядок пунктов (PUT /notes/{id}/checklist/order).
+// item_ids перечисляет все пункты чек-листа в новом порядке.
+func (h *ChecklistHandler) ReorderChecklist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req ChecklistOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items, err := h.repo.ReorderChecklist(r.Context(), userID, noteID, req.ItemIDs)
+	if err != nil {
+		checklistError(w, err, "Failed to reorder checklist")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+func checklistError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrChecklistItemNotFound):
+		http.Error(w, "Checklist item not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrChecklistOrder):
+		http.Error(w, "item_ids must list every checklist item exactly once", http.StatusBadRequest)
+	default:
+		noteError(w, err, message)
+	}
+}
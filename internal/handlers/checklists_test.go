@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockChecklistRepository struct {
+	mock.Mock
+}
+
+func (m *MockChecklistRepository) ListChecklist(ctx context.Context, userID, noteID int64) ([]*models.ChecklistItem, error) {
+	args := m.Called(ctx, userID, noteID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ChecklistItem), args.Error(1)
+}
+
+func (m *MockChecklistRepository) AddChecklistItem(ctx context.Context, userID int64, item *models.ChecklistItem) error {
+	args := m.Called(ctx, userID, item)
+	item.ID = 11
+	return args.Error(0)
+}
+
+func (m *MockChecklistRepository) UpdateChecklistItem(ctx context.Context, userID int64, item *models.ChecklistItem) error {
+	return m.Called(ctx, userID, item).Error(0)
+}
+
+func (m *MockChecklistRepository) ToggleChecklistItem(ctx context.Context, userID, noteID, itemID int64) (*models.ChecklistItem, error) {
+	args := m.Called(ctx, userID, noteID, itemID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChecklistItem), args.Error(1)
+}
+
+func (m *MockChecklistRepository) DeleteChecklistItem(ctx context.Context, userID, noteID, itemID int64) error {
+	return m.Called(ctx, userID, noteID, itemID).Error(0)
+}
+
+func (m *MockChecklistRepository) ReorderChecklist(ctx context.Context, userID, noteID int64, itemIDs []int64) ([]*models.ChecklistItem, error) {
+	args := m.Called(ctx, userID, noteID, itemIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.ChecklistItem), args.Error(1)
+}
+
+func checklistRouter(h *ChecklistHandler) http.Handler {
+	r := chi.NewRouter()
+	r.Use(new(MockAuthService).Authenticate)
+	r.Get("/notes/{id}/checklist", h.ListChecklist)
+	r.Post("/notes/{id}/checklist", h.AddChecklistItem)
+	r.Put("/notes/{id}/checklist/order", h.ReorderChecklist)
+	r.Put("/notes/{id}/checklist/{itemID}", h.UpdateChecklistItem)
+	r.Post("/notes/{id}/checklist/{itemID}/toggle", h.ToggleChecklistItem)
+	r.Delete("/notes/{id}/checklist/{itemID}", h.DeleteChecklistItem)
+	return r
+}
+
+func TestAddChecklistItem(t *testing.T) {
+	mockRepo := new(MockChecklistRepository)
+	router := checklistRouter(NewChecklistHandler(mockRepo))
+
+	mockRepo.On("AddChecklistItem", mock.Anything, int64(1), mock.MatchedBy(func(item *models.ChecklistItem) bool {
+		return item.NoteID == 7 && item.Text == "Buy milk" && item.Position == -1
+	})).Return(nil)
+
+	req, _ := http.NewRequest("POST", "/notes/7/checklist", bytes.NewBufferString(`{"text":"  Buy milk "}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var item models.ChecklistItem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&item))
+	assert.Equal(t, int64(11), item.ID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAddChecklistItemRequiresText(t *testing.T) {
+	mockRepo := new(MockChecklistRepository)
+	router := checklistRouter(NewChecklistHandler(mockRepo))
+
+	req, _ := http.NewRequest("POST", "/notes/7/checklist", bytes.NewBufferString(`{"text":"   "}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockRepo.AssertNotCalled(t, "AddChecklistItem", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestToggleChecklistItemNotFound(t *testing.T) {
+	mockRepo := new(MockChecklistRepository)
+	router := checklistRouter(NewChecklistHandler(mockRepo))
+
+	mockRepo.On("ToggleChecklistItem", mock.Anything, int64(1), int64(7), int64(3)).
+		Return(nil, repository.ErrChecklistItemNotFound)
+
+	req, _ := http.NewRequest("POST", "/notes/7/checklist/3/toggle", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Checklist item not found")
+}
+
+func TestReorderChecklist(t *testing.T) {
+	mockRepo := new(MockChecklistRepository)
+	router := checklistRouter(NewChecklistHandler(mockRepo))
+
+	mockRepo.On("ReorderChecklist", mock.Anything, int64(1), int64(7), []int64{5, 4}).
+		Return([]*models.ChecklistItem{{ID: 5, Position: 0}, {ID: 4, Position: 1}}, nil)
+	mockRepo.On("ReorderChecklist", mock.Anything, int64(1), int64(7), []int64{5}).
+		Return(nil, repository.ErrChecklistOrder)
+
+	req, _ := http.NewRequest("PUT", "/notes/7/checklist/order", bytes.NewBufferString(`{"item_ids":[5,4]}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var items []*models.ChecklistItem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&items))
+	assert.Equal(t, int64(5), items[0].ID)
+
+	req, _ = http.NewRequest("PUT", "/notes/7/checklist/order", bytes.NewBufferString(`{"item_ids":[5]}`))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestDeleteChecklistItemReadOnly(t *testing.T) {
+	mockRepo := new(MockChecklistRepository)
+	router := checklistRouter(NewChecklistHandler(mockRepo))
+
+	mockRepo.On("DeleteChecklistItem", mock.Anything, int64(1), int64(7), int64(3)).Return(repository.ErrForbidden)
+
+	req, _ := http.NewRequest("DELETE", "/notes/7/checklist/3", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/collab"
+
+	"golang.org/x/net/websocket"
+)
+
+// collabMaxMessageBytes ограничивает размер одного сообщения клиента
+const collabMaxMessageBytes = 1 << 20
+
+// CollabHandler подключает клиентов к совместному редактированию заметок
+type CollabHandler struct {
+	manager *collab.Manager
+}
+
+// NewCollabHandler создает новый экземпляр CollabHandler
+func NewCollabHandler(manager *collab.Manager) *CollabHandler {
+	return &CollabHandler{manager: manager}
+}
+
+// Connect открывает WebSocket-сессию редактирования заметки
+// (GET /notes/{id}/collab). Править текст могут пользователи с доступом
+// owner или edit и правом notes:write, остальные видят правки и курсоры.
+func (h *CollabHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if r.Header.Get("Upgrade") == "" {
+		http.Error(w, "WebSocket upgrade required", http.StatusUpgradeRequired)
+		return
+	}
+
+	client, err := h.manager.Join(r.Context(), userID, noteID, auth.HasScope(r, auth.ScopeNotesWrite))
+	if err != nil {
+		noteError(w, err, "Failed to open collaborative session")
+		return
+	}
+	defer client.Leave()
+
+	// Запрос аутентифицирован заголовком, а не cookie, поэтому Origin не проверяется
+	server := websocket.Server{
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = collabMaxMessageBytes
+			go func() {
+				defer ws.Close()
+				for msg := range client.Messages() {
+					if err := websocket.JSON.Send(ws, msg); err != nil {
+						return
+					}
+				}
+			}()
+
+			for {
+				var msg collab.Message
+				if err := websocket.JSON.Receive(ws, &msg); err != nil {
+					return
+				}
+				client.Handle(msg)
+			}
+		},
+	}
+	server.ServeHTTP(w, r)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/collab"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func newCollabServer(t *testing.T, mockRepo *MockRepository) *httptest.Server {
+	handler := NewCollabHandler(collab.NewManager(mockRepo, time.Hour))
+	r := chi.NewRouter()
+	r.Use(new(MockAuthService).Authenticate)
+	r.Get("/notes/{id}/collab", handler.Connect)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func dialCollab(t *testing.T, server *httptest.Server, noteID string) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/notes/" + noteID + "/collab"
+	ws, err := websocket.Dial(url, "", server.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() { ws.Close() })
+	ws.SetDeadline(time.Now().Add(5 * time.Second))
+	return ws
+}
+
+func TestCollabConnect(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetNote", mock.Anything, int64(1), int64(7)).
+		Return(&models.Note{ID: 7, Content: "hello", Permission: models.PermissionOwner}, nil)
+	server := newCollabServer(t, mockRepo)
+
+	first := dialCollab(t, server, "7")
+	second := dialCollab(t, server, "7")
+
+	var msg collab.Message
+	require.NoError(t, websocket.JSON.Receive(first, &msg))
+	assert.Equal(t, collab.MessageInit, msg.Type)
+	assert.Equal(t, "hello", msg.Content)
+	require.NoError(t, websocket.JSON.Receive(first, &msg))
+	assert.Equal(t, collab.MessageJoin, msg.Type)
+	require.NoError(t, websocket.JSON.Receive(second, &msg))
+	assert.Len(t, msg.Participants, 1)
+
+	require.NoError(t, websocket.Message.Send(first, `{"type":"op","revision":0,"operation":[5," world"]}`))
+
+	var ack, op collab.Message
+	require.NoError(t, websocket.JSON.Receive(first, &ack))
+	assert.Equal(t, collab.Message{Type: collab.MessageAck, Revision: 1}, ack)
+	require.NoError(t, websocket.JSON.Receive(second, &op))
+	assert.Equal(t, collab.MessageOp, op.Type)
+	assert.Equal(t, collab.Operation{{Retain: 5}, {Insert: " world"}}, op.Operation)
+}
+
+func TestCollabConnectNoteNotFound(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetNote", mock.Anything, int64(1), int64(8)).Return((*models.Note)(nil), repository.ErrNotFound)
+	server := newCollabServer(t, mockRepo)
+
+	req, _ := http.NewRequest("GET", server.URL+"/notes/8/collab", nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/notes/8/collab")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUpgradeRequired, resp.StatusCode)
+}
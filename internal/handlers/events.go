@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/events"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// eventBatchSize — сколько событий читается из базы за один запрос
+	eventBatchSize = 100
+	// eventHeartbeat — интервал комментариев, которые не дают прокси закрыть
+	// простаивающее соединение
+	eventHeartbeat = 30 * time.Second
+	// eventRetry — через сколько миллисекунд EventSource переподключается
+	eventRetry = 3000
+	// eventPendingPoll — через сколько повторить чтение, если события ждут
+	// завершения более старой транзакции: ее фиксация может не разбудить поток
+	eventPendingPoll = time.Second
+)
+
+// EventHandler отдает поток изменений заметок пользователя
+type EventHandler struct {
+	repo repository.NoteEventRepository
+	hub  *events.Hub
+}
+
+// NewEventHandler создает новый экземпляр EventHandler
+func NewEventHandler(repo repository.NoteEventRepository, hub *events.Hub) *EventHandler {
+	return &EventHandler{repo: repo, hub: hub}
+}
+
+// Stream отдает события created, updated и deleted для заметок пользователя
+// в формате Server-Sent Events (GET /events). Поток продолжается с события
+// после Last-Event-ID (заголовок или параметр last_event_id), без него — с
+// текущего момента. Если часть пропущенных событий уже удалена или
+// Last-Event-ID в прежнем формате, первым приходит событие reset: клиенту
+// нужно заново загрузить список заметок.
+func (h *EventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// Подписка до чтения курсора, чтобы не пропустить событие между ними
+	wake, cancel := h.hub.Subscribe(userID)
+	defer cancel()
+
+	cursor, reset, ok := h.cursor(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", eventRetry)
+	if reset {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		var poll <-chan time.Time
+		for {
+			batch, pending, err := h.repo.ListNoteEvents(r.Context(), userID, cursor, eventBatchSize)
+			if err != nil {
+				if r.Context().Err() == nil {
+					log.Printf("Failed to fetch note events for user %d: %v", userID, err)
+				}
+				return
+			}
+			for _, event := range batch {
+				writeEvent(w, event)
+				cursor = event.Cursor()
+			}
+			if pending {
+				poll = time.After(eventPendingPoll)
+				break
+			}
+			if len(batch) < eventBatchSize {
+				break
+			}
+		}
+		flusher.Flush()
+
+	wait:
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-wake:
+				break wait
+			case <-poll:
+				break wait
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// cursor определяет, после какой позиции начинать поток
+func (h *EventHandler) cursor(w http.ResponseWriter, r *http.Request) (cursor models.NoteEventCursor, reset, ok bool) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+
+	if value != "" {
+		if cursor, err := parseEventCursor(value); err == nil {
+			oldest, err := h.repo.OldestNoteEventXID(r.Context())
+			if err != nil {
+				http.Error(w, "Failed to fetch events", http.StatusInternalServerError)
+				return cursor, false, false
+			}
+			return cursor, oldest > cursor.XID, true
+		}
+		// Числовой ID из прежнего формата потока не задает позицию: поток
+		// начинается с текущего момента после события reset
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return cursor, false, false
+		}
+		reset = true
+	}
+
+	cursor, err := h.repo.NoteEventWatermark(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to fetch events", http.StatusInternalServerError)
+		return cursor, false, false
+	}
+	return cursor, reset, true
+}
+
+// parseEventCursor разбирает позицию "<xid>-<id>" из поля id потока
+func parseEventCursor(value string) (models.NoteEventCursor, error) {
+	xid, id, found := strings.Cut(value, "-")
+	if !found {
+		return models.NoteEventCursor{}, errors.New("invalid cursor")
+	}
+	var cursor models.NoteEventCursor
+	var err error
+	if cursor.XID, err = strconv.ParseUint(xid, 10, 64); err != nil {
+		return cursor, err
+	}
+	if cursor.ID, err = strconv.ParseInt(id, 10, 64); err != nil || cursor.ID < 0 {
+		return cursor, errors.New("invalid cursor")
+	}
+	return cursor, nil
+}
+
+func writeEvent(w http.ResponseWriter, event *models.NoteEvent) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.Cursor(), event.Type, data)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/events"
+	"notes-service/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockNoteEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockNoteEventRepository) NoteEventWatermark(ctx context.Context) (models.NoteEventCursor, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(models.NoteEventCursor), args.Error(1)
+}
+
+func (m *MockNoteEventRepository) OldestNoteEventXID(ctx context.Context) (uint64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (m *MockNoteEventRepository) ListNoteEvents(ctx context.Context, userID int64, after models.NoteEventCursor, limit int) ([]*models.NoteEvent, bool, error) {
+	args := m.Called(ctx, userID, after, limit)
+	return args.Get(0).([]*models.NoteEvent), args.Bool(1), args.Error(2)
+}
+
+func (m *MockNoteEventRepository) PruneNoteEvents(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestStreamEventsResumesAfterLastEventID(t *testing.T) {
+	mockRepo := new(MockNoteEventRepository)
+	hub := events.NewHub()
+	handler := NewEventHandler(mockRepo, hub)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockRepo.On("OldestNoteEventXID", mock.Anything).Return(uint64(90), nil)
+	mockRepo.On("ListNoteEvents", mock.Anything, int64(1), models.NoteEventCursor{XID: 100, ID: 5}, eventBatchSize).Return([]*models.NoteEvent{
+		{ID: 6, NoteID: 10, Type: models.NoteUpdated, XID: 101},
+	}, false, nil).Once()
+	// Новое событие приходит после уведомления, затем клиент отключается
+	mockRepo.On("ListNoteEvents", mock.Anything, int64(1), models.NoteEventCursor{XID: 101, ID: 6}, eventBatchSize).Return([]*models.NoteEvent{
+		{ID: 9, NoteID: 11, Type: models.NoteDeleted, XID: 104},
+	}, false, nil).Once().Run(func(mock.Arguments) { cancel() })
+	mockRepo.On("ListNoteEvents", mock.Anything, int64(1), models.NoteEventCursor{XID: 104, ID: 9}, eventBatchSize).Return([]*models.NoteEvent{}, false, nil)
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/events", nil)
+	req.Header.Set("Last-Event-ID", "100-5")
+	rr := httptest.NewRecorder()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		hub.Notify(1)
+	}()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.Stream)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+	body := rr.Body.String()
+	assert.NotContains(t, body, "event: reset")
+	assert.Contains(t, body, "id: 101-6\nevent: updated\ndata: {\"id\":6,\"note_id\":10,\"type\":\"updated\"")
+	assert.Contains(t, body, "id: 104-9\nevent: deleted\n")
+}
+
+func TestStreamEventsOverlappingTransactions(t *testing.T) {
+	mockRepo := new(MockNoteEventRepository)
+	handler := NewEventHandler(mockRepo, events.NewHub())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Транзакция A (xid 100) получила событие 10 и еще не завершена,
+	// транзакция B (xid 101) получила событие 11 и уже зафиксирована.
+	// Пока A не завершена, событие B не отдается: иначе курсор ушел бы за
+	// событие 10, и после фиксации A оно бы потерялось.
+	start := models.NoteEventCursor{XID: 100}
+	mockRepo.On("NoteEventWatermark", mock.Anything).Return(start, nil)
+	mockRepo.On("ListNoteEvents", mock.Anything, int64(1), start, eventBatchSize).
+		Return([]*models.NoteEvent{}, true, nil).Once()
+	// A зафиксирована без уведомления пользователя: поток перечитывает
+	// события сам и отдает оба по порядку транзакций
+	mockRepo.On("ListNoteEvents", mock.Anything, int64(1), start, eventBatchSize).Return([]*models.NoteEvent{
+		{ID: 10, NoteID: 7, Type: models.NoteUpdated, XID: 100},
+		{ID: 11, NoteID: 8, Type: models.NoteCreated, XID: 101},
+	}, false, nil).Once().Run(func(mock.Arguments) { cancel() })
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/events", nil)
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.Stream)).ServeHTTP(rr, req)
+
+	body := rr.Body.String()
+	first := strings.Index(body, "id: 100-10\n")
+	second := strings.Index(body, "id: 101-11\n")
+	assert.NotEqual(t, -1, first)
+	assert.Greater(t, second, first)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestStreamEventsResetWhenHistoryPruned(t *testing.T) {
+	mockRepo := new(MockNoteEventRepository)
+	handler := NewEventHandler(mockRepo, events.NewHub())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mockRepo.On("OldestNoteEventXID", mock.Anything).Return(uint64(200), nil)
+	mockRepo.On("ListNoteEvents", mock.Anything, int64(1), models.NoteEventCursor{XID: 100, ID: 5}, eventBatchSize).
+		Return([]*models.NoteEvent{}, false, nil).Run(func(mock.Arguments) { cancel() })
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/events?last_event_id=100-5", nil)
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.Stream)).ServeHTTP(rr, req)
+
+	assert.Contains(t, rr.Body.String(), "event: reset\n")
+}
+
+func TestStreamEventsResetOnLegacyLastEventID(t *testing.T) {
+	mockRepo := new(MockNoteEventRepository)
+	handler := NewEventHandler(mockRepo, events.NewHub())
+	ctx, cancel := context.WithCancel(context.Background())
+
+	start := models.NoteEventCursor{XID: 300}
+	mockRepo.On("NoteEventWatermark", mock.Anything).Return(start, nil)
+	mockRepo.On("ListNoteEvents", mock.Anything, int64(1), start, eventBatchSize).
+		Return([]*models.NoteEvent{}, false, nil).Run(func(mock.Arguments) { cancel() })
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/events", nil)
+	req.Header.Set("Last-Event-ID", "42")
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.Stream)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "event: reset\n")
+}
+
+func TestStreamEventsInvalidLastEventID(t *testing.T) {
+	handler := NewEventHandler(new(MockNoteEventRepository), events.NewHub())
+
+	req, _ := http.NewRequest("GET", "/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.Stream)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/export"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"time"
+)
+
+// ExportHandler выгружает заметки пользователя архивом
+type ExportHandler struct {
+	repo      repository.ExportRepository
+	notebooks repository.NotebookRepository
+}
+
+// NewExportHandler создает новый экземпляр ExportHandler
+func NewExportHandler(repo repository.ExportRepository, notebooks repository.NotebookRepository) *ExportHandler {
+	return &ExportHandler{repo: repo, notebooks: notebooks}
+}
+
+// Export отдает ZIP-архив с заметками (GET /export). С параметром
+// ?workspace= выгружаются заметки пространства. Архив пишется в ответ по мере
+// чтения заметок из базы.
+func (h *ExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var filter models.NoteFilter
+	if filter.WorkspaceID, ok = queryID(w, r, "workspace"); !ok {
+		return
+	}
+
+	// Блокноты бывают только у личных заметок
+	var notebooks []*models.Notebook
+	if filter.WorkspaceID == nil {
+		var err error
+		notebooks, err = h.notebooks.ListNotebooks(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "Failed to fetch notebooks", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	archive := export.NewWriter(w, notebooks)
+	started := false
+	err := h.repo.StreamNotes(r.Context(), userID, filter, func(note *models.Note) error {
+		if !started {
+			h.startDownload(w)
+			started = true
+		}
+		return archive.Add(note)
+	})
+	if err != nil && !started {
+		workspaceError(w, err, "Failed to export notes")
+		return
+	}
+	if err != nil {
+		// Заголовки уже отправлены: обрываем архив, клиент получит поврежденный файл
+		log.Printf("Export for user %d failed: %v", userID, err)
+		return
+	}
+
+	if !started {
+		h.startDownload(w)
+	}
+	if err := archive.Close(); err != nil {
+		log.Printf("Export for user %d failed: %v", userID, err)
+	}
+}
+
+func (h *ExportHandler) startDownload(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition",
+		`attachment; filename="notes-`+time.Now().UTC().Format("2006-01-02")+`.zip"`)
+	w.Header().Set("Cache-Control", "no-store")
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockExportRepository) StreamNotes(ctx context.Context, userID int64, filter models.NoteFilter, fn func(*models.Note) error) error {
+	args := m.Called(ctx, userID, filter)
+	for _, note := range args.Get(0).([]*models.Note) {
+		if err := fn(note); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func TestExport(t *testing.T) {
+	mockRepo := new(MockExportRepository)
+	mockNotebooks := new(MockNotebookRepository)
+	handler := NewExportHandler(mockRepo, mockNotebooks)
+
+	mockNotebooks.On("ListNotebooks", mock.Anything, int64(1)).Return([]*models.Notebook{}, nil)
+	mockRepo.On("StreamNotes", mock.Anything, int64(1), models.NoteFilter{}).Return([]*models.Note{
+		{ID: 1, Title: "First", Content: "one", ContentFormat: models.FormatPlain},
+		{ID: 2, Title: "Second", Content: "two", ContentFormat: models.FormatMarkdown},
+	}, nil)
+
+	req, _ := http.NewRequest("GET", "/export", nil)
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.Export)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "attachment")
+
+	zr, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.ElementsMatch(t, []string{"notes/First.md", "html/First.html", "notes/Second.md", "html/Second.html", "notes.json"}, names)
+}
+
+func TestExportUnknownWorkspace(t *testing.T) {
+	mockRepo := new(MockExportRepository)
+	handler := NewExportHandler(mockRepo, new(MockNotebookRepository))
+	workspaceID := int64(3)
+
+	mockRepo.On("StreamNotes", mock.Anything, int64(1), models.NoteFilter{WorkspaceID: &workspaceID}).
+		Return([]*models.Note{}, repository.ErrNotFound)
+
+	req, _ := http.NewRequest("GET", "/export?workspace=3", nil)
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.Export)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
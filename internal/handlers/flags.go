@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"strconv"
+)
+
+// maxReorderNotes ограничивает число заметок в одном запросе ReorderNotes
+const maxReorderNotes = 1000
+
+// NoteFlagHandler закрепляет, архивирует и добавляет в избранное заметки и
+// задает их ручной порядок
+type NoteFlagHandler struct {
+	notes repository.NoteRepository
+	flags repository.NoteFlagRepository
+}
+
+// NewNoteFlagHandler создает новый экземпляр NoteFlagHandler
+func NewNoteFlagHandler(notes repository.NoteRepository, flags repository.NoteFlagRepository) *NoteFlagHandler {
+	return &NoteFlagHandler{notes: notes, flags: flags}
+}
+
+// PinNote закрепляет заметку вверху списка (POST /notes/{id}/pin)
+func (h *NoteFlagHandler) PinNote(w http.ResponseWriter, r *http.Request) {
+	h.setFlag(w, r, models.FlagPinned, true)
+}
+
+// UnpinNote открепляет заметку (POST /notes/{id}/unpin)
+func (h *NoteFlagHandler) UnpinNote(w http.ResponseWriter, r *http.Request) {
+	h.setFlag(w, r, models.FlagPinned, false)
+}
+
+// ArchiveNote перемещает заметку в архив (POST /notes/{id}/archive)
+func (h *NoteFlagHandler) ArchiveNote(w http.ResponseWriter, r *http.Request) {
+	h.setFlag(w, r, models.FlagArchived, true)
+}
+
+// UnarchiveNote возвращает заметку из архива (POST /notes/{id}/unarchive)
+func (h *NoteFlagHandler) UnarchiveNote(w http.ResponseWriter, r *http.Request) {
+	h.setFlag(w, r, models.FlagArchived, false)
+}
+
+// FavoriteNote добавляет заметку в избранное (POST /notes/{id}/favorite)
+func (h *NoteFlagHandler) FavoriteNote(w http.ResponseWriter, r *http.Request) {
+	h.setFlag(w, r, models.FlagFavorite, true)
+}
+
+// UnfavoriteNote убирает заметку из избранного (POST /notes/{id}/unfavorite)
+func (h *NoteFlagHandler) UnfavoriteNote(w http.ResponseWriter, r *http.Request) {
+	h.setFlag(w, r, models.FlagFavorite, false)
+}
+
+// setFlag меняет отметку заметки с ID из пути и отвечает заметкой
+func (h *NoteFlagHandler) setFlag(w http.ResponseWriter, r *http.Request, flag string, value bool) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.flags.SetNoteFlag(r.Context(), userID, noteID, flag, value); err != nil {
+		noteError(w, err, "Failed to update note")
+		return
+	}
+
+	note, err := h.notes.GetNote(r.Context(), userID, noteID)
+	if err != nil {
+		noteError(w, err, "Failed to fetch note")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
+
+// NoteOrderRequest — заметки в порядке, заданном пользователем
+type NoteOrderRequest struct {
+	NoteIDs []int64 `json:"note_ids"`
+}
+
+// ReorderNotes задает ручной порядок заметок (PUT /notes/order). Заметки
+// получают позиции по порядку note_ids; позиции остальных заметок не меняются.
+func (h *NoteFlagHandler) ReorderNotes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req NoteOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.NoteIDs) == 0 || len(req.NoteIDs) > maxReorderNotes {
+		http.Error(w, "note_ids must list from 1 to "+strconv.Itoa(maxReorderNotes)+" notes", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.flags.ReorderNotes(r.Context(), userID, req.NoteIDs); err != nil {
+		noteError(w, err, "Failed to reorder notes")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockNoteFlagRepository struct {
+	mock.Mock
+}
+
+func (m *MockNoteFlagRepository) SetNoteFlag(ctx context.Context, userID, noteID int64, flag string, value bool) error {
+	return m.Called(ctx, userID, noteID, flag, value).Error(0)
+}
+
+func (m *MockNoteFlagRepository) ReorderNotes(ctx context.Context, userID int64, noteIDs []int64) error {
+	return m.Called(ctx, userID, noteIDs).Error(0)
+}
+
+func noteFlagRouter(h *NoteFlagHandler) http.Handler {
+	r := chi.NewRouter()
+	r.Use(new(MockAuthService).Authenticate)
+	r.Put("/notes/order", h.ReorderNotes)
+	r.Post("/notes/{id}/pin", h.PinNote)
+	r.Post("/notes/{id}/archive", h.ArchiveNote)
+	r.Post("/notes/{id}/unfavorite", h.UnfavoriteNote)
+	return r
+}
+
+func TestPinNote(t *testing.T) {
+	mockNotes := new(MockRepository)
+	mockFlags := new(MockNoteFlagRepository)
+	router := noteFlagRouter(NewNoteFlagHandler(mockNotes, mockFlags))
+
+	mockFlags.On("SetNoteFlag", mock.Anything, int64(1), int64(7), models.FlagPinned, true).Return(nil)
+	mockNotes.On("GetNote", mock.Anything, int64(1), int64(7)).Return(&models.Note{ID: 7, Pinned: true}, nil)
+
+	req, _ := http.NewRequest("POST", "/notes/7/pin", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var note models.Note
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&note))
+	assert.True(t, note.Pinned)
+	mockFlags.AssertExpectations(t)
+}
+
+func TestArchiveNoteNotFound(t *testing.T) {
+	mockNotes := new(MockRepository)
+	mockFlags := new(MockNoteFlagRepository)
+	router := noteFlagRouter(NewNoteFlagHandler(mockNotes, mockFlags))
+
+	mockFlags.On("SetNoteFlag", mock.Anything, int64(1), int64(7), models.FlagArchived, true).Return(repository.ErrNotFound)
+
+	req, _ := http.NewRequest("POST", "/notes/7/archive", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockNotes.AssertNotCalled(t, "GetNote", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUnfavoriteNote(t *testing.T) {
+	mockNotes := new(MockRepository)
+	mockFlags := new(MockNoteFlagRepository)
+	router := noteFlagRouter(NewNoteFlagHandler(mockNotes, mockFlags))
+
+	mockFlags.On("SetNoteFlag", mock.Anything, int64(1), int64(7), models.FlagFavorite, false).Return(nil)
+	mockNotes.On("GetNote", mock.Anything, int64(1), int64(7)).Return(&models.Note{ID: 7}, nil)
+
+	req, _ := http.NewRequest("POST", "/notes/7/unfavorite", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockFlags.AssertExpectations(t)
+}
+
+func TestReorderNotes(t *testing.T) {
+	mockFlags := new(MockNoteFlagRepository)
+	router := noteFlagRouter(NewNoteFlagHandler(new(MockRepository), mockFlags))
+
+	mockFlags.On("ReorderNotes", mock.Anything, int64(1), []int64{9, 7, 8}).Return(nil)
+	mockFlags.On("ReorderNotes", mock.Anything, int64(1), []int64{9, 42}).Return(repository.ErrNotFound)
+
+	req, _ := http.NewRequest("PUT", "/notes/order", bytes.NewBufferString(`{"note_ids":[9,7,8]}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	req, _ = http.NewRequest("PUT", "/notes/order", bytes.NewBufferString(`{"note_ids":[9,42]}`))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	req, _ = http.NewRequest("PUT", "/notes/order", bytes.NewBufferString(`{"note_ids":[]}`))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/importer"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"strconv"
+)
+
+// ImportHandler принимает файлы импорта и показывает ход заданий
+type ImportHandler struct {
+	repo     repository.ImportRepository
+	notify   func()
+	maxBytes int64
+}
+
+// NewImportHandler создает новый экземпляр ImportHandler. notify будит
+// обработчик заданий после постановки нового задания в очередь.
+func NewImportHandler(repo repository.ImportRepository, notify func(), maxBytes int64) *ImportHandler {
+	return &ImportHandler{repo: repo, notify: notify, maxBytes: maxBytes}
+}
+
+// CreateImport ставит в очередь импорт файла из поля file формы
+// multipart/form-data (POST /import). Поле skip_spellcheck=true отключает
+// проверку орфографии.
+func (h *ImportHandler) CreateImport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBytes)
+	file, _, err := r.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "File is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	payload, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		return
+	}
+
+	format, err := importer.DetectFormat(payload)
+	if err != nil {
+		http.Error(w, "Unsupported file format, expected ZIP, JSON or ENEX", http.StatusBadRequest)
+		return
+	}
+	skipSpellcheck, _ := strconv.ParseBool(r.FormValue("skip_spellcheck"))
+
+	job := models.ImportJob{UserID: userID, Format: format, SkipSpellcheck: skipSpellcheck}
+	if err := h.repo.CreateImportJob(r.Context(), &job, payload); err != nil {
+		http.Error(w, "Failed to create import job", http.StatusInternalServerError)
+		return
+	}
+	h.notify()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/import/"+strconv.FormatInt(job.ID, 10))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// ListImports возвращает задания импорта пользователя (GET /import)
+func (h *ImportHandler) ListImports(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	jobs, err := h.repo.ListImportJobs(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch import jobs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// GetImport возвращает прогресс и отчет об ошибках задания (GET /import/{id})
+func (h *ImportHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	jobID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	job, err := h.repo.GetImportJob(r.Context(), userID, jobID)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Import job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch import job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
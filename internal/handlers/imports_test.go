@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockImportRepository struct {
+	mock.Mock
+}
+
+func (m *MockImportRepository) CreateImportJob(ctx context.Context, job *models.ImportJob, payload []byte) error {
+	args := m.Called(ctx, job, payload)
+	job.ID = 9
+	job.Status = models.ImportPending
+	return args.Error(0)
+}
+
+func (m *MockImportRepository) GetImportJob(ctx context.Context, userID, jobID int64) (*models.ImportJob, error) {
+	args := m.Called(ctx, userID, jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ImportJob), args.Error(1)
+}
+
+func (m *MockImportRepository) ListImportJobs(ctx context.Context, userID int64) ([]*models.ImportJob, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.ImportJob), args.Error(1)
+}
+
+func (m *MockImportRepository) ClaimImportJob(ctx context.Context, staleAfter time.Duration) (*models.ImportJob, []byte, error) {
+	args := m.Called(ctx, staleAfter)
+	return nil, nil, args.Error(0)
+}
+
+func (m *MockImportRepository) SaveImportProgress(ctx context.Context, job *models.ImportJob) error {
+	return m.Called(ctx, job).Error(0)
+}
+
+func (m *MockImportRepository) NoteExists(ctx context.Context, userID int64, title, content string) (bool, error) {
+	args := m.Called(ctx, userID, title, content)
+	return args.Bool(0), args.Error(1)
+}
+
+func importRequest(t *testing.T, content string, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	f, err := mw.CreateFormFile("file", "notes.json")
+	require.NoError(t, err)
+	f.Write([]byte(content))
+	require.NoError(t, mw.Close())
+
+	req, _ := http.NewRequest("POST", "/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestCreateImport(t *testing.T) {
+	mockRepo := new(MockImportRepository)
+	notified := 0
+	handler := NewImportHandler(mockRepo, func() { notified++ }, 1<<20)
+
+	payload := `[{"title": "Imported", "content": "text"}]`
+	mockRepo.On("CreateImportJob", mock.Anything, mock.MatchedBy(func(job *models.ImportJob) bool {
+		return job.UserID == 1 && job.Format == models.ImportFormatJSON && job.SkipSpellcheck
+	}), []byte(payload)).Return(nil)
+
+	req := importRequest(t, payload, map[string]string{"skip_spellcheck": "true"})
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.CreateImport)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "/import/9", rr.Header().Get("Location"))
+	assert.Equal(t, 1, notified)
+
+	var job models.ImportJob
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&job))
+	assert.Equal(t, models.ImportPending, job.Status)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateImportRejectsUnknownFormat(t *testing.T) {
+	mockRepo := new(MockImportRepository)
+	handler := NewImportHandler(mockRepo, func() {}, 1<<20)
+
+	req := importRequest(t, "just some text", nil)
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.CreateImport)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockRepo.AssertNotCalled(t, "CreateImportJob", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreateImportTooLarge(t *testing.T) {
+	mockRepo := new(MockImportRepository)
+	handler := NewImportHandler(mockRepo, func() {}, 64)
+
+	req := importRequest(t, "["+string(bytes.Repeat([]byte(" "), 1024))+"]", nil)
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.CreateImport)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+}
+
+func TestGetImportNotFound(t *testing.T) {
+	mockRepo := new(MockImportRepository)
+	handler := NewImportHandler(mockRepo, func() {}, 1<<20)
+
+	mockRepo.On("GetImportJob", mock.Anything, int64(1), int64(4)).Return(nil, repository.ErrNotFound)
+
+	r := chi.NewRouter()
+	r.Get("/import/{id}", handler.GetImport)
+	req, _ := http.NewRequest("GET", "/import/4", nil)
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(r).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/markdown"
+	"notes-service/internal/models"
+	"notes-service/internal/password"
+	"notes-service/internal/ratelimit"
+	"notes-service/internal/repository"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const sharePasswordHeader = "X-Share-Password"
+
+// ShareLinkHandler обрабатывает публичные ссылки на заметки
+type ShareLinkHandler struct {
+	repo    repository.ShareLinkRepository
+	hasher  *password.Hasher
+	baseURL string
+	// attachments подставляет в страницу заметки подписанные ссылки на вложения
+	attachments *AttachmentLinks
+	// limiter ограничивает проверки пароля ссылки по ссылке и по IP клиента
+	limiter *ratelimit.Limiter
+}
+
+// ShareLinkOption настраивает ShareLinkHandler
+type ShareLinkOption func(*ShareLinkHandler)
+
+// WithShareLinkAttachments включает показ вложений на публичной странице заметки
+func WithShareLinkAttachments(links *AttachmentLinks) ShareLinkOption {
+	return func(h *ShareLinkHandler) { h.attachments = links }
+}
+
+// WithShareLinkRateLimiter ограничивает частоту попыток ввода пароля ссылки:
+// отдельно для каждой ссылки и для каждого IP-адреса клиента
+func WithShareLinkRateLimiter(limiter *ratelimit.Limiter) ShareLinkOption {
+	return func(h *ShareLinkHandler) { h.limiter = limiter }
+}
+
+// NewShareLinkHandler создает новый экземпляр ShareLinkHandler. baseURL —
+// внешний адрес сервиса, от которого строятся ссылки.
+func NewShareLinkHandler(repo repository.ShareLinkRepository, hasher *password.Hasher, baseURL string, opts ...ShareLinkOption) *ShareLinkHandler {
+	h := &ShareLinkHandler{
+		repo:    repo,
+		hasher:  hasher,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+type CreateShareLinkRequest struct {
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type shareLinkResponse struct {
+	*models.ShareLink
+	HasPassword bool   `json:"has_password"`
+	Token       string `json:"token,omitempty"`
+	URL         string `json:"url,omitempty"`
+}
+
+// CreateShareLink создает публичную ссылку на заметку (POST /notes/{id}/links).
+// Токен ссылки возвращается только в этом ответе.
+func (h *ShareLinkHandler) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req CreateShareLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		http.Error(w, "Expiration must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	token, err := linkToken()
+	if err != nil {
+		http.Error(w, "Failed to generate link", http.StatusInternalServerError)
+		return
+	}
+
+	link := &models.ShareLink{NoteID: noteID, TokenHash: auth.HashToken(token), ExpiresAt: req.ExpiresAt}
+	if req.Password != "" {
+		link.PasswordHash, err = h.hasher.Hash(req.Password)
+		if err != nil {
+			http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := h.repo.CreateShareLink(r.Context(), userID, link); err != nil {
+		noteError(w, err, "Failed to create link")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shareLinkResponse{
+		ShareLink:   link,
+		HasPassword: link.HasPassword(),
+		Token:       token,
+		URL:         h.baseURL + "/s/" + token,
+	})
+}
+
+// ListShareLinks возвращает действующие ссылки на заметку без токенов (GET /notes/{id}/links)
+func (h *ShareLinkHandler) ListShareLinks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	links, err := h.repo.ListShareLinks(r.Context(), userID, noteID)
+	if err != nil {
+		noteError(w, err, "Failed to fetch links")
+		return
+	}
+
+	response := make([]shareLinkResponse, 0, len(links))
+	for _, link := range links {
+		response = append(response, shareLinkResponse{ShareLink: link, HasPassword: link.HasPassword()})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RevokeShareLink отзывает ссылку (DELETE /notes/{id}/links/{linkID})
+func (h *ShareLinkHandler) RevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	linkID, ok := pathID(w, r, "linkID")
+	if !ok {
+		return
+	}
+
+	err := h.repo.RevokeShareLink(r.Context(), userID, noteID, linkID)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to revoke link", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// publicNote — представление заметки для просмотра по ссылке, без сведений о владельце
+type publicNote struct {
+	Title         string    `json:"title"`
+	Content       string    `json:"content"`
+	ContentFormat string    `json:"content_format"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ViewSharedNote показывает заметку по публичной ссылке без аутентификации
+// (GET и POST /s/{token}). Браузеру, запросившему text/html, отдается
+// страница, остальным клиентам — JSON. Пароль ссылки передается в заголовке
+// X-Share-Password или полем password формы.
+func (h *ShareLinkHandler) ViewSharedNote(w http.ResponseWriter, r *http.Request) {
+	asHTML := strings.Contains(r.Header.Get("Accept"), "text/html")
+
+	// Токен ссылки в адресе не должен уходить в Referer и поисковые индексы
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	w.Header().Set("Cache-Control", "no-store")
+
+	tokenHash := auth.HashToken(chi.URLParam(r, "token"))
+	link, note, err := h.repo.GetSharedNote(r.Context(), tokenHash)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch note", http.StatusInternalServerError)
+		return
+	}
+
+	if link.HasPassword() {
+		given := r.Header.Get(sharePasswordHeader)
+		if given == "" && r.Method == http.MethodPost {
+			given = r.PostFormValue("password")
+		}
+		// Проверка пароля медленная: без лимита ссылка открыта для перебора
+		// и для нагрузки на сервер
+		if given != "" && !h.limiter.AllowRequest(w, r, "share-link:"+tokenHash, "share-ip:"+ratelimit.ClientIP(r)) {
+			return
+		}
+		if ok, _ := h.hasher.Verify(link.PasswordHash, given); given == "" || !ok {
+			message := "Password required"
+			if given != "" {
+				message = "Invalid password"
+			}
+			if asHTML {
+				h.renderPage(w, http.StatusUnauthorized, sharePage{PasswordRequired: true, Error: message})
+				return
+			}
+			http.Error(w, message, http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if asHTML {
+		resolve, err := h.attachments.Resolver(r.Context(), note)
+		if err != nil {
+			http.Error(w, "Failed to fetch attachments", http.StatusInternalServerError)
+			return
+		}
+		rendered, err := markdown.ToHTML(note.ContentFormat, note.Content, resolve)
+		if err != nil {
+			http.Error(w, "Failed to render note", http.StatusInternalServerError)
+			return
+		}
+		// Результат markdown.ToHTML уже очищен, повторное экранирование не нужно
+		h.renderPage(w, http.StatusOK, sharePage{Note: note, Content: template.HTML(rendered)})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(publicNote{
+		Title:         note.Title,
+		Content:       note.Content,
+		ContentFormat: note.ContentFormat,
+		CreatedAt:     note.CreatedAt,
+		UpdatedAt:     note.UpdatedAt,
+	})
+}
+
+type sharePage struct {
+	Note             *models.Note
+	Content          template.HTML
+	PasswordRequired bool
+	Error            string
+}
+
+var sharePageTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{if .Note}}{{.Note.Title}}{{else}}Protected note{{end}}</title>
+<style>
+body { font-family: sans-serif; max-width: 720px; margin: 2em auto; padding: 0 1em; color: #222; }
+.content { line-height: 1.5; }
+.content pre { background: #f6f6f6; padding: 0.75em; overflow-x: auto; }
+.content table { border-collapse: collapse; }
+.content th, .content td { border: 1px solid #ccc; padding: 0.25em 0.5em; }
+.meta { color: #777; font-size: 0.9em; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+{{if .Note}}
+<h1>{{.Note.Title}}</h1>
+<p class="meta">Updated {{.Note.UpdatedAt.Format "2006-01-02 15:04"}}</p>
+<div class="content">{{.Content}}</div>
+{{else}}
+<h1>This note is password protected</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post">
+<input type="password" name="password" autofocus>
+<button type="submit">Open</button>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+func (h *ShareLinkHandler) renderPage(w http.ResponseWriter, status int, page sharePage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src 'self' https:; style-src 'unsafe-inline'; form-action 'self'")
+	w.WriteHeader(status)
+	sharePageTemplate.Execute(w, page)
+}
+
+// linkToken генерирует токен ссылки: 32 случайных байта в base64url
+func linkToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"notes-service/internal/auth"
+	"notes-service/internal/models"
+	"notes-service/internal/password"
+	"notes-service/internal/ratelimit"
+	"notes-service/internal/repository"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockShareLinkRepository struct {
+	mock.Mock
+}
+
+func (m *MockShareLinkRepository) CreateShareLink(ctx context.Context, ownerID int64, link *models.ShareLink) error {
+	args := m.Called(ctx, ownerID, link)
+	return args.Error(0)
+}
+
+func (m *MockShareLinkRepository) ListShareLinks(ctx context.Context, ownerID, noteID int64) ([]*models.ShareLink, error) {
+	args := m.Called(ctx, ownerID, noteID)
+	return args.Get(0).([]*models.ShareLink), args.Error(1)
+}
+
+func (m *MockShareLinkRepository) RevokeShareLink(ctx context.Context, ownerID, noteID, linkID int64) error {
+	args := m.Called(ctx, ownerID, noteID, linkID)
+	return args.Error(0)
+}
+
+func (m *MockShareLinkRepository) GetSharedNote(ctx context.Context, tokenHash string) (*models.ShareLink, *models.Note, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(*models.ShareLink), args.Get(1).(*models.Note), args.Error(2)
+}
+
+func linkRouter(h *ShareLinkHandler) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/s/{token}", h.ViewSharedNote)
+	r.Post("/s/{token}", h.ViewSharedNote)
+	r.Group(func(r chi.Router) {
+		r.Use(new(MockAuthService).Authenticate)
+		r.Post("/notes/{id}/links", h.CreateShareLink)
+	})
+	return r
+}
+
+func TestCreateShareLink(t *testing.T) {
+	mockRepo := new(MockShareLinkRepository)
+	router := linkRouter(NewShareLinkHandler(mockRepo, password.DefaultHasher(), "https://notes.example.com/"))
+
+	var stored *models.ShareLink
+	mockRepo.On("CreateShareLink", mock.Anything, int64(1), mock.AnythingOfType("*models.ShareLink")).
+		Run(func(args mock.Arguments) { stored = args.Get(2).(*models.ShareLink) }).
+		Return(nil)
+
+	req, _ := http.NewRequest("POST", "/notes/7/links", bytes.NewBufferString(`{"password":"secret"}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+
+	token := response["token"].(string)
+	assert.Equal(t, "https://notes.example.com/s/"+token, response["url"])
+	assert.Equal(t, true, response["has_password"])
+	assert.Equal(t, int64(7), stored.NoteID)
+	assert.Equal(t, auth.HashToken(token), stored.TokenHash)
+	assert.NotEqual(t, "secret", stored.PasswordHash)
+}
+
+func TestViewSharedNote(t *testing.T) {
+	mockRepo := new(MockShareLinkRepository)
+	router := linkRouter(NewShareLinkHandler(mockRepo, password.DefaultHasher(), ""))
+
+	mockRepo.On("GetSharedNote", mock.Anything, auth.HashToken("token")).
+		Return(&models.ShareLink{ID: 1, NoteID: 7}, &models.Note{ID: 7, Title: "Plan", Content: "<script>alert(1)</script>"}, nil)
+
+	req, _ := http.NewRequest("GET", "/s/token", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "no-referrer", rr.Header().Get("Referrer-Policy"))
+	var response map[string]interface{}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, "Plan", response["title"])
+	assert.NotContains(t, response, "user_id")
+
+	req, _ = http.NewRequest("GET", "/s/token", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, rr.Body.String(), "&lt;script&gt;")
+	assert.NotContains(t, rr.Body.String(), "<script>")
+}
+
+func TestViewSharedNoteWithPassword(t *testing.T) {
+	hasher := password.DefaultHasher()
+	passwordHash, err := hasher.Hash("secret")
+	require.NoError(t, err)
+
+	mockRepo := new(MockShareLinkRepository)
+	router := linkRouter(NewShareLinkHandler(mockRepo, hasher, ""))
+	mockRepo.On("GetSharedNote", mock.Anything, auth.HashToken("token")).
+		Return(&models.ShareLink{ID: 1, NoteID: 7, PasswordHash: passwordHash}, &models.Note{ID: 7, Title: "Plan"}, nil)
+
+	req, _ := http.NewRequest("GET", "/s/token", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	req, _ = http.NewRequest("GET", "/s/token", nil)
+	req.Header.Set("X-Share-Password", "wrong")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	req, _ = http.NewRequest("GET", "/s/token", nil)
+	req.Header.Set("X-Share-Password", "secret")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// Браузер отправляет пароль формой
+	form := url.Values{"password": {"secret"}}
+	req, _ = http.NewRequest("POST", "/s/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "text/html")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "<h1>Plan</h1>")
+}
+
+func TestViewSharedNotePasswordRateLimited(t *testing.T) {
+	hasher := password.DefaultHasher()
+	passwordHash, err := hasher.Hash("secret")
+	require.NoError(t, err)
+
+	mockRepo := new(MockShareLinkRepository)
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), 1, 2)
+	router := linkRouter(NewShareLinkHandler(mockRepo, hasher, "", WithShareLinkRateLimiter(limiter)))
+	mockRepo.On("GetSharedNote", mock.Anything, auth.HashToken("token")).
+		Return(&models.ShareLink{ID: 1, NoteID: 7, PasswordHash: passwordHash}, &models.Note{ID: 7, Title: "Plan"}, nil)
+
+	attempt := func(ip, given string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/s/token", nil)
+		req.RemoteAddr = ip + ":12345"
+		req.Header.Set("X-Share-Password", given)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, attempt("10.0.0.1", "wrong").Code)
+	assert.Equal(t, http.StatusUnauthorized, attempt("10.0.0.2", "wrong").Code)
+
+	// Лимит ссылки исчерпан: смена IP-адреса не помогает, и даже верный
+	// пароль не проверяется
+	rr := attempt("10.0.0.3", "secret")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+}
+
+func TestViewSharedNoteRevoked(t *testing.T) {
+	mockRepo := new(MockShareLinkRepository)
+	router := linkRouter(NewShareLinkHandler(mockRepo, password.DefaultHasher(), ""))
+	mockRepo.On("GetSharedNote", mock.Anything, mock.Anything).
+		Return((*models.ShareLink)(nil), (*models.Note)(nil), repository.ErrNotFound)
+
+	req, _ := http.NewRequest("GET", "/s/revoked", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"strings"
+)
+
+// NotebookHandler обрабатывает запросы к блокнотам и корзине
+type NotebookHandler struct {
+	repo  repository.NotebookRepository
+	notes repository.NoteRepository
+}
+
+// NewNotebookHandler создает новый экземпляр NotebookHandler
+func NewNotebookHandler(repo repository.NotebookRepository, notes repository.NoteRepository) *NotebookHandler {
+	return &NotebookHandler{repo: repo, notes: notes}
+}
+
+type CreateNotebookRequest struct {
+	Name     string `json:"name"`
+	ParentID *int64 `json:"parent_id"`
+}
+
+type RenameNotebookRequest struct {
+	Name string `json:"name"`
+}
+
+type MoveNotebookRequest struct {
+	ParentID *int64 `json:"parent_id"`
+}
+
+type MoveNoteRequest struct {
+	NotebookID *int64 `json:"notebook_id"`
+}
+
+// NotebookContents — блокнот с вложенными блокнотами и заметками
+type NotebookContents struct {
+	Notebook  *models.Notebook   `json:"notebook"`
+	Notebooks []*models.Notebook `json:"notebooks"`
+	Notes     []*models.Note     `json:"notes"`
+}
+
+// CreateNotebook создает блокнот (POST /notebooks)
+func (h *NotebookHandler) CreateNotebook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateNotebookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	notebook := models.Notebook{Name: req.Name, ParentID: req.ParentID}
+	if err := h.repo.CreateNotebook(r.Context(), userID, &notebook); err != nil {
+		notebookError(w, err, "Failed to create notebook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(notebook)
+}
+
+// ListNotebooks возвращает все блокноты пользователя (GET /notebooks)
+func (h *NotebookHandler) ListNotebooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	notebooks, err := h.repo.ListNotebooks(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch notebooks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notebooks)
+}
+
+// GetNotebook возвращает блокнот с дочерними блокнотами и неархивными заметками
+// (GET /notebooks/{id})
+func (h *NotebookHandler) GetNotebook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	notebookID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	notebook, err := h.repo.GetNotebook(r.Context(), userID, notebookID)
+	if err != nil {
+		notebookError(w, err, "Failed to fetch notebook")
+		return
+	}
+	notebooks, err := h.repo.ListNotebooks(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch notebooks", http.StatusInternalServerError)
+		return
+	}
+	notes, err := h.notes.ListNotes(r.Context(), userID, models.NoteFilter{NotebookID: &notebookID, Archived: new(bool)})
+	if err != nil {
+		http.Error(w, "Failed to fetch notes", http.StatusInternalServerError)
+		return
+	}
+
+	contents := NotebookContents{Notebook: notebook, Notebooks: []*models.Notebook{}, Notes: notes}
+	for _, child := range notebooks {
+		if child.ParentID != nil && *child.ParentID == notebookID {
+			contents.Notebooks = append(contents.Notebooks, child)
+		}
+	}
+	if contents.Notes == nil {
+		contents.Notes = []*models.Note{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contents)
+}
+
+// RenameNotebook меняет название блокнота (PUT /notebooks/{id})
+func (h *NotebookHandler) RenameNotebook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	notebookID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req RenameNotebookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.RenameNotebook(r.Context(), userID, notebookID, req.Name); err != nil {
+		notebookError(w, err, "Failed to rename notebook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MoveNotebook переносит блокнот в другой блокнот или в корень
+// (POST /notebooks/{id}/move)
+func (h *NotebookHandler) MoveNotebook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	notebookID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req MoveNotebookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.MoveNotebook(r.Context(), userID, notebookID, req.ParentID); err != nil {
+		notebookError(w, err, "Failed to move notebook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TrashNotebook перемещает блокнот с содержимым в корзину (DELETE /notebooks/{id})
+func (h *NotebookHandler) TrashNotebook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	notebookID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.repo.TrashNotebook(r.Context(), userID, notebookID); err != nil {
+		notebookError(w, err, "Failed to delete notebook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MoveNote переносит заметку в блокнот или убирает ее из блокнота
+// (POST /notes/{id}/move)
+func (h *NotebookHandler) MoveNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req MoveNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := h.repo.MoveNote(r.Context(), userID, noteID, req.NotebookID)
+	if errors.Is(err, repository.ErrNotebookNotFound) {
+		notebookError(w, err, "Failed to move note")
+		return
+	}
+	if err != nil {
+		noteError(w, err, "Failed to move note")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListTrash возвращает содержимое корзины (GET /trash)
+func (h *NotebookHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	trash, err := h.repo.ListTrash(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch trash", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trash)
+}
+
+// RestoreNote возвращает заметку из корзины (POST /trash/notes/{id}/restore)
+func (h *NotebookHandler) RestoreNote(w http.ResponseWriter, r *http.Request) {
+	h.trashAction(w, r, h.repo.RestoreNote, noteError, "Failed to restore note")
+}
+
+// RestoreNotebook возвращает блокнот из корзины (POST /trash/notebooks/{id}/restore)
+func (h *NotebookHandler) RestoreNotebook(w http.ResponseWriter, r *http.Request) {
+	h.trashAction(w, r, h.repo.RestoreNotebook, notebookError, "Failed to restore notebook")
+}
+
+// PurgeNote окончательно удаляет заметку из корзины (DELETE /trash/notes/{id})
+func (h *NotebookHandler) PurgeNote(w http.ResponseWriter, r *http.Request) {
+	h.trashAction(w, r, h.repo.PurgeNote, noteError, "Failed to delete note")
+}
+
+// PurgeNotebook окончательно удаляет блокнот из корзины (DELETE /trash/notebooks/{id})
+func (h *NotebookHandler) PurgeNotebook(w http.ResponseWriter, r *http.Request) {
+	h.trashAction(w, r, h.repo.PurgeNotebook, notebookError, "Failed to delete notebook")
+}
+
+// trashAction выполняет операцию над элементом корзины с ID из пути
+func (h *NotebookHandler) trashAction(w http.ResponseWriter, r *http.Request,
+	action func(ctx context.Context, userID, id int64) error,
+	onError func(w http.ResponseWriter, err error, message string), message string) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := action(r.Context(), userID, id); err != nil {
+		onError(w, err, message)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// notebookError переводит ошибки блокнотов в HTTP-ответ
+func notebookError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "Notebook not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrCycle):
+		http.Error(w, "Notebook cannot be moved into itself", http.StatusConflict)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockNotebookRepository struct {
+	mock.Mock
+}
+
+func (m *MockNotebookRepository) CreateNotebook(ctx context.Context, userID int64, notebook *models.Notebook) error {
+	args := m.Called(ctx, userID, notebook)
+	return args.Error(0)
+}
+
+func (m *MockNotebookRepository) ListNotebooks(ctx context.Context, userID int64) ([]*models.Notebook, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.Notebook), args.Error(1)
+}
+
+func (m *MockNotebookRepository) GetNotebook(ctx context.Context, userID, notebookID int64) (*models.Notebook, error) {
+	args := m.Called(ctx, userID, notebookID)
+	return args.Get(0).(*models.Notebook), args.Error(1)
+}
+
+func (m *MockNotebookRepository) RenameNotebook(ctx context.Context, userID, notebookID int64, name string) error {
+	args := m.Called(ctx, userID, notebookID, name)
+	return args.Error(0)
+}
+
+func (m *MockNotebookRepository) MoveNotebook(ctx context.Context, userID, notebookID int64, parentID *int64) error {
+	args := m.Called(ctx, userID, notebookID, parentID)
+	return args.Error(0)
+}
+
+func (m *MockNotebookRepository) MoveNote(ctx context.Context, userID, noteID int64, notebookID *int64) error {
+	args := m.Called(ctx, userID, noteID, notebookID)
+	return args.Error(0)
+}
+
+func (m *MockNotebookRepository) TrashNotebook(ctx context.Context, userID, notebookID int64) error {
+	args := m.Called(ctx, userID, notebookID)
+	return args.Error(0)
+}
+
+func (m *MockNotebookRepository) ListTrash(ctx context.Context, userID int64) (*models.Trash, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.Trash), args.Error(1)
+}
+
+func (m *MockNotebookRepository) RestoreNote(ctx context.Context, userID, noteID int64) error {
+	args := m.Called(ctx, userID, noteID)
+	return args.Error(0)
+}
+
+func (m *MockNotebookRepository) RestoreNotebook(ctx context.Context, userID, notebookID int64) error {
+	args := m.Called(ctx, userID, notebookID)
+	return args.Error(0)
+}
+
+func (m *MockNotebookRepository) PurgeNote(ctx context.Context, userID, noteID int64) error {
+	args := m.Called(ctx, userID, noteID)
+	return args.Error(0)
+}
+
+func (m *MockNotebookRepository) PurgeNotebook(ctx context.Context, userID, notebookID int64) error {
+	args := m.Called(ctx, userID, notebookID)
+	return args.Error(0)
+}
+
+func (m *MockNotebookRepository) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func notebookRouter(h *NotebookHandler) http.Handler {
+	r := chi.NewRouter()
+	r.Use(new(MockAuthService).Authenticate)
+	r.Get("/notebooks/{id}", h.GetNotebook)
+	r.Post("/notebooks/{id}/move", h.MoveNotebook)
+	r.Post("/notes/{id}/move", h.MoveNote)
+	r.Post("/trash/notebooks/{id}/restore", h.RestoreNotebook)
+	return r
+}
+
+func TestGetNotebookContents(t *testing.T) {
+	mockRepo := new(MockNotebookRepository)
+	mockNotes := new(MockRepository)
+	router := notebookRouter(NewNotebookHandler(mockRepo, mockNotes))
+	notebookID, rootID := int64(2), int64(1)
+
+	mockRepo.On("GetNotebook", mock.Anything, int64(1), int64(2)).
+		Return(&models.Notebook{ID: 2, ParentID: &rootID, Name: "Work"}, nil)
+	mockRepo.On("ListNotebooks", mock.Anything, int64(1)).
+		Return([]*models.Notebook{
+			{ID: 1, Name: "Root"},
+			{ID: 2, ParentID: &rootID, Name: "Work"},
+			{ID: 3, ParentID: &notebookID, Name: "Projects"},
+		}, nil)
+	mockNotes.On("ListNotes", mock.Anything, int64(1), models.NoteFilter{NotebookID: &notebookID, Archived: new(bool)}).
+		Return([]*models.Note{{ID: 7, Title: "Plan", NotebookID: &notebookID}}, nil)
+
+	req, _ := http.NewRequest("GET", "/notebooks/2", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var contents NotebookContents
+	json.Unmarshal(rr.Body.Bytes(), &contents)
+	assert.Equal(t, "Work", contents.Notebook.Name)
+	assert.Len(t, contents.Notebooks, 1)
+	assert.Equal(t, int64(3), contents.Notebooks[0].ID)
+	assert.Len(t, contents.Notes, 1)
+}
+
+func TestMoveNotebookIntoDescendant(t *testing.T) {
+	mockRepo := new(MockNotebookRepository)
+	router := notebookRouter(NewNotebookHandler(mockRepo, new(MockRepository)))
+	parentID := int64(3)
+
+	mockRepo.On("MoveNotebook", mock.Anything, int64(1), int64(2), &parentID).Return(repository.ErrCycle)
+
+	req, _ := http.NewRequest("POST", "/notebooks/2/move", bytes.NewBufferString(`{"parent_id":3}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestMoveNoteToMissingNotebook(t *testing.T) {
+	mockRepo := new(MockNotebookRepository)
+	router := notebookRouter(NewNotebookHandler(mockRepo, new(MockRepository)))
+	notebookID := int64(9)
+
+	mockRepo.On("MoveNote", mock.Anything, int64(1), int64(7), &notebookID).Return(repository.ErrNotebookNotFound)
+
+	req, _ := http.NewRequest("POST", "/notes/7/move", bytes.NewBufferString(`{"notebook_id":9}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Notebook not found")
+}
+
+func TestRestoreNotebook(t *testing.T) {
+	mockRepo := new(MockNotebookRepository)
+	router := notebookRouter(NewNotebookHandler(mockRepo, new(MockRepository)))
+
+	mockRepo.On("RestoreNotebook", mock.Anything, int64(1), int64(2)).Return(nil)
+
+	req, _ := http.NewRequest("POST", "/trash/notebooks/2/restore", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockRepo.AssertExpectations(t)
+}
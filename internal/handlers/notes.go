@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"notes-service/internal/auth"
+	"notes-service/internal/markdown"
 	"notes-service/internal/models"
 	"notes-service/internal/repository"
 	"notes-service/internal/spellcheck"
@@ -15,15 +17,29 @@ type NoteHandler struct {
 	repo         repository.NoteRepository
 	spellchecker spellcheck.Spellchecker // Change this to an interface
 	authService  auth.AuthService        // Change this to an interface
+	attachments  *AttachmentLinks
+}
+
+// NoteOption настраивает NoteHandler
+type NoteOption func(*NoteHandler)
+
+// WithNoteAttachments включает подстановку подписанных ссылок на вложения
+// в HTML заметок
+func WithNoteAttachments(links *AttachmentLinks) NoteOption {
+	return func(h *NoteHandler) { h.attachments = links }
 }
 
 // NewNoteHandler создает новый экземпляр NoteHandler
-func NewNoteHandler(repo repository.NoteRepository, spellchecker spellcheck.Spellchecker, authService auth.AuthService) *NoteHandler {
-	return &NoteHandler{
+func NewNoteHandler(repo repository.NoteRepository, spellchecker spellcheck.Spellchecker, authService auth.AuthService, opts ...NoteOption) *NoteHandler {
+	h := &NoteHandler{
 		repo:         repo,
 		spellchecker: spellchecker,
 		authService:  authService,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // CreateNote обрабатывает создание новой заметки
@@ -34,8 +50,16 @@ func (h *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверка орфографии
-	correctedContent, err := h.spellchecker.CheckSpelling(note.Content)
+	if note.ContentFormat == "" {
+		note.ContentFormat = models.FormatPlain
+	}
+	if !models.ValidContentFormat(note.ContentFormat) {
+		http.Error(w, "Invalid content format", http.StatusBadRequest)
+		return
+	}
+
+	// Проверка орфографии; в Markdown код и адреса не проверяются
+	correctedContent, err := spellcheck.Check(h.spellchecker, note.ContentFormat, note.Content)
 	if err != nil {
 		http.Error(w, "Failed to check spelling", http.StatusInternalServerError)
 		return
@@ -43,18 +67,31 @@ func (h *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 	note.Content = correctedContent
 
 	// Получение ID пользователя из контекста (установленного middleware аутентификации)
-	userID, ok := r.Context().Value("user_id").(int64)
+	userID, ok := auth.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorize", http.StatusUnauthorized)
 		return
 	}
 	note.UserID = userID
+	if note.WorkspaceID != nil && note.NotebookID != nil {
+		http.Error(w, "Workspace notes cannot be filed into notebooks", http.StatusBadRequest)
+		return
+	}
 
 	note.CreatedAt = time.Now()
 	note.UpdatedAt = time.Now()
 
-	if err := h.repo.CreateNote(r.Context(), &note); err != nil {
-		http.Error(w, "Failed to create note", http.StatusInternalServerError)
+	err = h.repo.CreateNote(r.Context(), &note)
+	if errors.Is(err, repository.ErrDuplicate) {
+		http.Error(w, "Note with this client_id already exists", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, repository.ErrNotebookNotFound) {
+		notebookError(w, err, "Failed to create note")
+		return
+	}
+	if err != nil {
+		workspaceError(w, err, "Failed to create note")
 		return
 	}
 
@@ -63,20 +100,177 @@ func (h *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(note)
 }
 
-// ListNotes обрабатывает запрос на получение списка заметок пользователя
+// ListNotes обрабатывает запрос на получение списка заметок пользователя.
+// С параметром ?workspace= возвращаются заметки пространства, с ?notebook= —
+// заметки блокнота, с ?has_open_tasks=true или false — заметки с
+// неотмеченными пунктами чек-листа или без них. Архивные заметки
+// возвращаются только с ?archived=true, ?favorite=true оставляет только
+// избранные заметки, ?favorite=false — только не избранные.
 func (h *NoteHandler) ListNotes(w http.ResponseWriter, r *http.Request) {
-	userID, ok := r.Context().Value("user_id").(int64)
+	userID, ok := auth.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	notes, err := h.repo.ListNotes(r.Context(), userID)
+	var filter models.NoteFilter
+	if filter.WorkspaceID, ok = queryID(w, r, "workspace"); !ok {
+		return
+	}
+	if filter.NotebookID, ok = queryID(w, r, "notebook"); !ok {
+		return
+	}
+	if filter.HasOpenTasks, ok = queryBool(w, r, "has_open_tasks"); !ok {
+		return
+	}
+	if filter.Archived, ok = queryBool(w, r, "archived"); !ok {
+		return
+	}
+	if filter.Archived == nil {
+		filter.Archived = new(bool)
+	}
+	if filter.Favorite, ok = queryBool(w, r, "favorite"); !ok {
+		return
+	}
+
+	notes, err := h.repo.ListNotes(r.Context(), userID, filter)
 	if err != nil {
-		http.Error(w, "Failed to fetch notes", http.StatusInternalServerError)
+		workspaceError(w, err, "Failed to fetch notes")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(notes)
 }
+
+// GetNote возвращает заметку, доступную пользователю (GET /notes/{id}).
+// С ?format=html или Accept: text/html отдается текст заметки в виде
+// очищенного HTML.
+func (h *NoteHandler) GetNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	asHTML, ok := wantsHTML(w, r)
+	if !ok {
+		return
+	}
+
+	note, err := h.repo.GetNote(r.Context(), userID, noteID)
+	if err != nil {
+		noteError(w, err, "Failed to fetch note")
+		return
+	}
+
+	if asHTML {
+		resolve, err := h.attachments.Resolver(r.Context(), note)
+		if err != nil {
+			http.Error(w, "Failed to fetch attachments", http.StatusInternalServerError)
+			return
+		}
+		rendered, err := markdown.ToHTML(note.ContentFormat, note.Content, resolve)
+		if err != nil {
+			http.Error(w, "Failed to render note", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src 'self' https:; style-src 'unsafe-inline'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Write([]byte(rendered))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
+
+// UpdateNote изменяет заголовок и текст заметки (PUT /notes/{id}). Если
+// content_format не передан, формат заметки сохраняется. С полем version
+// заметка изменяется, только если с тех пор ее никто не менял.
+func (h *NoteHandler) UpdateNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var note models.Note
+	if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if note.ContentFormat == "" {
+		current, err := h.repo.GetNote(r.Context(), userID, noteID)
+		if err != nil {
+			noteError(w, err, "Failed to update note")
+			return
+		}
+		note.ContentFormat = current.ContentFormat
+	}
+	if !models.ValidContentFormat(note.ContentFormat) {
+		http.Error(w, "Invalid content format", http.StatusBadRequest)
+		return
+	}
+
+	correctedContent, err := spellcheck.Check(h.spellchecker, note.ContentFormat, note.Content)
+	if err != nil {
+		http.Error(w, "Failed to check spelling", http.StatusInternalServerError)
+		return
+	}
+	note.Content = correctedContent
+	note.ID = noteID
+	note.UpdatedAt = time.Now()
+
+	if err := h.repo.UpdateNote(r.Context(), userID, &note); err != nil {
+		noteError(w, err, "Failed to update note")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
+
+// DeleteNote перемещает заметку в корзину (DELETE /notes/{id})
+func (h *NoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteNote(r.Context(), userID, noteID); err != nil {
+		noteError(w, err, "Failed to delete note")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// noteError переводит ошибки доступа к заметке в HTTP-ответ
+func noteError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "Note not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrForbidden):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	case errors.Is(err, repository.ErrConflict):
+		http.Error(w, "Note was modified, reload it and retry", http.StatusConflict)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
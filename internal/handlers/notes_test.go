@@ -6,9 +6,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"notes-service/internal/auth"
 	"notes-service/internal/models"
+	"notes-service/internal/repository"
 	"testing"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -22,11 +25,26 @@ func (m *MockRepository) CreateNote(ctx context.Context, note *models.Note) erro
 	return args.Error(0)
 }
 
-func (m *MockRepository) ListNotes(ctx context.Context, userID int64) ([]*models.Note, error) {
-	args := m.Called(ctx, userID)
+func (m *MockRepository) ListNotes(ctx context.Context, userID int64, filter models.NoteFilter) ([]*models.Note, error) {
+	args := m.Called(ctx, userID, filter)
 	return args.Get(0).([]*models.Note), args.Error(1)
 }
 
+func (m *MockRepository) GetNote(ctx context.Context, userID, noteID int64) (*models.Note, error) {
+	args := m.Called(ctx, userID, noteID)
+	return args.Get(0).(*models.Note), args.Error(1)
+}
+
+func (m *MockRepository) UpdateNote(ctx context.Context, userID int64, note *models.Note) error {
+	args := m.Called(ctx, userID, note)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteNote(ctx context.Context, userID, noteID int64) error {
+	args := m.Called(ctx, userID, noteID)
+	return args.Error(0)
+}
+
 func (m *MockRepository) Close() error {
 	return nil
 }
@@ -46,7 +64,7 @@ type MockAuthService struct {
 
 func (m *MockAuthService) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.WithValue(r.Context(), "user_id", int64(1))
+		ctx := auth.NewContext(r.Context(), 1, repository.RoleUser)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -91,7 +109,7 @@ func TestListNotes(t *testing.T) {
 		{ID: 2, Title: "Note 2", Content: "Content 2"},
 	}
 
-	mockRepo.On("ListNotes", mock.Anything, int64(1)).Return(mockNotes, nil)
+	mockRepo.On("ListNotes", mock.Anything, int64(1), models.NoteFilter{Archived: new(bool)}).Return(mockNotes, nil)
 
 	req, _ := http.NewRequest("GET", "/notes", nil)
 	rr := httptest.NewRecorder()
@@ -110,3 +128,171 @@ func TestListNotes(t *testing.T) {
 	assert.Equal(t, "Note 1", response[0].Title)
 	assert.Equal(t, "Note 2", response[1].Title)
 }
+
+func TestListNotesWithOpenTasks(t *testing.T) {
+	mockRepo := new(MockRepository)
+	handler := NewNoteHandler(mockRepo, new(MockSpellchecker), new(MockAuthService))
+
+	mockRepo.On("ListNotes", mock.Anything, int64(1), mock.MatchedBy(func(filter models.NoteFilter) bool {
+		return filter.HasOpenTasks != nil && *filter.HasOpenTasks
+	})).Return([]*models.Note{}, nil)
+
+	req, _ := http.NewRequest("GET", "/notes?has_open_tasks=true", nil)
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.ListNotes)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertExpectations(t)
+
+	req, _ = http.NewRequest("GET", "/notes?has_open_tasks=maybe", nil)
+	rr = httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.ListNotes)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestListArchivedFavoriteNotes(t *testing.T) {
+	mockRepo := new(MockRepository)
+	handler := NewNoteHandler(mockRepo, new(MockSpellchecker), new(MockAuthService))
+
+	mockRepo.On("ListNotes", mock.Anything, int64(1), mock.MatchedBy(func(filter models.NoteFilter) bool {
+		return filter.Archived != nil && *filter.Archived && filter.Favorite != nil && *filter.Favorite
+	})).Return([]*models.Note{}, nil)
+
+	req, _ := http.NewRequest("GET", "/notes?archived=true&favorite=true", nil)
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.ListNotes)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestListNotFavoriteNotes(t *testing.T) {
+	mockRepo := new(MockRepository)
+	handler := NewNoteHandler(mockRepo, new(MockSpellchecker), new(MockAuthService))
+
+	mockRepo.On("ListNotes", mock.Anything, int64(1), mock.MatchedBy(func(filter models.NoteFilter) bool {
+		return filter.Favorite != nil && !*filter.Favorite
+	})).Return([]*models.Note{}, nil)
+
+	req, _ := http.NewRequest("GET", "/notes?favorite=false", nil)
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.ListNotes)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockRepo.AssertExpectations(t)
+
+	req, _ = http.NewRequest("GET", "/notes?favorite=maybe", nil)
+	rr = httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.ListNotes)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func noteRouter(h *NoteHandler) http.Handler {
+	r := chi.NewRouter()
+	r.Use(new(MockAuthService).Authenticate)
+	r.Get("/notes/{id}", h.GetNote)
+	r.Put("/notes/{id}", h.UpdateNote)
+	r.Delete("/notes/{id}", h.DeleteNote)
+	return r
+}
+
+func TestGetNoteNotFound(t *testing.T) {
+	mockRepo := new(MockRepository)
+	router := noteRouter(NewNoteHandler(mockRepo, new(MockSpellchecker), new(MockAuthService)))
+
+	mockRepo.On("GetNote", mock.Anything, int64(1), int64(7)).Return((*models.Note)(nil), repository.ErrNotFound)
+
+	req, _ := http.NewRequest("GET", "/notes/7", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestUpdateNoteReadOnlyShare(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockSpellchecker := new(MockSpellchecker)
+	router := noteRouter(NewNoteHandler(mockRepo, mockSpellchecker, new(MockAuthService)))
+
+	mockSpellchecker.On("CheckSpelling", "Updated").Return("Updated", nil)
+	mockRepo.On("GetNote", mock.Anything, int64(1), int64(7)).
+		Return(&models.Note{ID: 7, ContentFormat: models.FormatPlain, Permission: models.PermissionRead}, nil)
+	mockRepo.On("UpdateNote", mock.Anything, int64(1), mock.MatchedBy(func(note *models.Note) bool {
+		return note.ID == 7 && note.Content == "Updated"
+	})).Return(repository.ErrForbidden)
+
+	req, _ := http.NewRequest("PUT", "/notes/7", bytes.NewBufferString(`{"title":"Note","content":"Updated"}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestDeleteNote(t *testing.T) {
+	mockRepo := new(MockRepository)
+	router := noteRouter(NewNoteHandler(mockRepo, new(MockSpellchecker), new(MockAuthService)))
+
+	mockRepo.On("DeleteNote", mock.Anything, int64(1), int64(7)).Return(nil)
+
+	req, _ := http.NewRequest("DELETE", "/notes/7", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetNoteAsHTML(t *testing.T) {
+	mockRepo := new(MockRepository)
+	router := noteRouter(NewNoteHandler(mockRepo, new(MockSpellchecker), new(MockAuthService)))
+
+	mockRepo.On("GetNote", mock.Anything, int64(1), int64(7)).Return(&models.Note{
+		ID: 7, Title: "Plan", Content: "# Plan\n\n- [x] done\n\n<script>alert(1)</script>", ContentFormat: models.FormatMarkdown,
+	}, nil)
+
+	req, _ := http.NewRequest("GET", "/notes/7?format=html", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), "<h1")
+	assert.Contains(t, rr.Body.String(), `type="checkbox"`)
+	assert.NotContains(t, rr.Body.String(), "<script")
+
+	req, _ = http.NewRequest("GET", "/notes/7", nil)
+	req.Header.Set("Accept", "text/html")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+
+	req, _ = http.NewRequest("GET", "/notes/7?format=pdf", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestCreateMarkdownNoteSkipsCodeInSpellcheck(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockSpellchecker := new(MockSpellchecker)
+	handler := NewNoteHandler(mockRepo, mockSpellchecker, new(MockAuthService))
+
+	mockSpellchecker.On("CheckSpelling", "Exampel `\ue000` here").Return("Example `\ue000` here", nil)
+	mockRepo.On("CreateNote", mock.Anything, mock.MatchedBy(func(note *models.Note) bool {
+		return note.ContentFormat == models.FormatMarkdown
+	})).Return(nil)
+
+	body, _ := json.Marshal(map[string]string{
+		"title": "Code", "content": "Exampel `fmt.Prntln` here", "content_format": "markdown",
+	})
+	req, _ := http.NewRequest("POST", "/notes", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.CreateNote)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var response models.Note
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, "Example `fmt.Prntln` here", response.Content)
+}
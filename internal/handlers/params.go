@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// pathID разбирает числовой параметр маршрута
+func pathID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, name), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid "+name, http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// queryID разбирает необязательный числовой параметр запроса
+func queryID(w http.ResponseWriter, r *http.Request, name string) (*int64, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid "+name, http.StatusBadRequest)
+		return nil, false
+	}
+	return &id, true
+}
+
+// queryBool разбирает необязательный логический параметр запроса
+func queryBool(w http.ResponseWriter, r *http.Request, name string) (*bool, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, true
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		http.Error(w, "Invalid "+name, http.StatusBadRequest)
+		return nil, false
+	}
+	return &b, true
+}
+
+// pagination разбирает параметры limit и offset
+func pagination(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	limit, offset := defaultPageSize, 0
+	query := r.URL.Query()
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		limit = n
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		offset = n
+	}
+
+	return limit, offset, true
+}
+
+// wantsHTML сообщает, что клиент запросил HTML: параметром ?format=html или,
+// если параметра нет, заголовком Accept. Неизвестный формат — ошибка 400.
+func wantsHTML(w http.ResponseWriter, r *http.Request) (asHTML, ok bool) {
+	switch r.URL.Query().Get("format") {
+	case "html":
+		return true, true
+	case "json":
+		return false, true
+	case "":
+		return strings.Contains(r.Header.Get("Accept"), "text/html"), true
+	default:
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+		return false, false
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/models"
+	"notes-service/internal/reminder"
+	"notes-service/internal/repository"
+	"time"
+)
+
+// maxSnooze ограничивает, насколько можно отложить напоминание
+const maxSnooze = 365 * 24 * time.Hour
+
+// ReminderHandler управляет сроками и напоминаниями заметок
+type ReminderHandler struct {
+	notes     repository.NoteRepository
+	reminders repository.ReminderRepository
+}
+
+// NewReminderHandler создает новый экземпляр ReminderHandler
+func NewReminderHandler(notes repository.NoteRepository, reminders repository.ReminderRepository) *ReminderHandler {
+	return &ReminderHandler{notes: notes, reminders: reminders}
+}
+
+// SetReminder задает срок и напоминание заметки (PUT /notes/{id}/reminder).
+// Повторяющемуся напоминанию нужен remind_at — время первого повторения.
+func (h *ReminderHandler) SetReminder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req models.Reminder
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Recurrence != "" {
+		if req.RemindAt == nil {
+			http.Error(w, "Recurring reminder requires remind_at", http.StatusBadRequest)
+			return
+		}
+		if err := reminder.ValidateRecurrence(req.Recurrence); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Timezone != "" {
+		if _, err := reminder.LoadLocation(req.Timezone); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.reminders.SetReminder(r.Context(), userID, noteID, req); err != nil {
+		noteError(w, err, "Failed to set reminder")
+		return
+	}
+	h.writeNote(w, r, userID, noteID)
+}
+
+// DeleteReminder убирает срок и напоминание заметки (DELETE /notes/{id}/reminder)
+func (h *ReminderHandler) DeleteReminder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.reminders.SetReminder(r.Context(), userID, noteID, models.Reminder{}); err != nil {
+		noteError(w, err, "Failed to delete reminder")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SnoozeRequest — на сколько минут или до какого времени отложить напоминание
+type SnoozeRequest struct {
+	Minutes int        `json:"minutes"`
+	Until   *time.Time `json:"until"`
+}
+
+// SnoozeReminder откладывает напоминание (POST /notes/{id}/reminder/snooze).
+// Если напоминание уже сработало, оно придет еще раз; расписание
+// повторяющегося напоминания не меняется.
+func (h *ReminderHandler) SnoozeReminder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req SnoozeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	until := now.Add(time.Duration(req.Minutes) * time.Minute)
+	if req.Until != nil {
+		until = *req.Until
+	}
+	if (req.Until == nil) == (req.Minutes == 0) || !until.After(now) || until.Sub(now) > maxSnooze {
+		http.Error(w, "Specify either minutes or until in the future, at most a year ahead", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.reminders.SnoozeReminder(r.Context(), userID, noteID, until); err != nil {
+		noteError(w, err, "Failed to snooze reminder")
+		return
+	}
+	h.writeNote(w, r, userID, noteID)
+}
+
+// writeNote отвечает заметкой после изменения напоминания
+func (h *ReminderHandler) writeNote(w http.ResponseWriter, r *http.Request, userID, noteID int64) {
+	note, err := h.notes.GetNote(r.Context(), userID, noteID)
+	if err != nil {
+		noteError(w, err, "Failed to fetch note")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockReminderRepository struct {
+	mock.Mock
+}
+
+func (m *MockReminderRepository) SetReminder(ctx context.Context, userID, noteID int64, reminder models.Reminder) error {
+	return m.Called(ctx, userID, noteID, reminder).Error(0)
+}
+
+func (m *MockReminderRepository) SnoozeReminder(ctx context.Context, userID, noteID int64, until time.Time) error {
+	return m.Called(ctx, userID, noteID, until).Error(0)
+}
+
+func (m *MockReminderRepository) DueReminders(ctx context.Context, now time.Time, limit int) ([]*models.Note, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]*models.Note), args.Error(1)
+}
+
+func (m *MockReminderRepository) FireReminder(ctx context.Context, note *models.Note, remindAt, dueAt *time.Time) error {
+	return m.Called(ctx, note, remindAt, dueAt).Error(0)
+}
+
+func reminderRouter(handler *ReminderHandler) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(new(MockAuthService).Authenticate)
+	r.Put("/notes/{id}/reminder", handler.SetReminder)
+	r.Post("/notes/{id}/reminder/snooze", handler.SnoozeReminder)
+	return r
+}
+
+func TestSetReminder(t *testing.T) {
+	mockNotes := new(MockRepository)
+	mockReminders := new(MockReminderRepository)
+	handler := NewReminderHandler(mockNotes, mockReminders)
+
+	remindAt := time.Date(2030, 5, 6, 9, 0, 0, 0, time.UTC)
+	mockReminders.On("SetReminder", mock.Anything, int64(1), int64(7), mock.MatchedBy(func(r models.Reminder) bool {
+		return r.RemindAt != nil && r.RemindAt.Equal(remindAt) && r.DueAt == nil && r.Recurrence == "FREQ=WEEKLY;BYDAY=MO" &&
+			r.Timezone == "Europe/Moscow"
+	})).Return(nil)
+	mockNotes.On("GetNote", mock.Anything, int64(1), int64(7)).
+		Return(&models.Note{ID: 7, RemindAt: &remindAt, Recurrence: "FREQ=WEEKLY;BYDAY=MO", Timezone: "Europe/Moscow"}, nil)
+
+	req, _ := http.NewRequest("PUT", "/notes/7/reminder",
+		bytes.NewBufferString(`{"remind_at": "2030-05-06T09:00:00Z", "recurrence": "FREQ=WEEKLY;BYDAY=MO", "timezone": "Europe/Moscow"}`))
+	rr := httptest.NewRecorder()
+	reminderRouter(handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var note models.Note
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&note))
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO", note.Recurrence)
+	assert.Equal(t, "Europe/Moscow", note.Timezone)
+	mockReminders.AssertExpectations(t)
+}
+
+func TestSetReminderValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"recurrence without remind_at", `{"recurrence": "FREQ=DAILY"}`},
+		{"invalid rule", `{"remind_at": "2030-05-06T09:00:00Z", "recurrence": "FREQ=SOMETIMES"}`},
+		{"count", `{"remind_at": "2030-05-06T09:00:00Z", "recurrence": "FREQ=DAILY;COUNT=5"}`},
+		{"sub-hourly", `{"remind_at": "2030-05-06T09:00:00Z", "recurrence": "FREQ=HOURLY;BYMINUTE=0,15,30,45"}`},
+		{"invalid timezone", `{"remind_at": "2030-05-06T09:00:00Z", "recurrence": "FREQ=DAILY", "timezone": "Mars/Olympus"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockReminders := new(MockReminderRepository)
+			handler := NewReminderHandler(new(MockRepository), mockReminders)
+
+			req, _ := http.NewRequest("PUT", "/notes/7/reminder", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			reminderRouter(handler).ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			mockReminders.AssertNotCalled(t, "SetReminder", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestSetReminderReadOnly(t *testing.T) {
+	mockReminders := new(MockReminderRepository)
+	handler := NewReminderHandler(new(MockRepository), mockReminders)
+
+	mockReminders.On("SetReminder", mock.Anything, int64(1), int64(7), mock.Anything).Return(repository.ErrForbidden)
+
+	req, _ := http.NewRequest("PUT", "/notes/7/reminder", bytes.NewBufferString(`{"due_at": "2030-05-06T09:00:00Z"}`))
+	rr := httptest.NewRecorder()
+	reminderRouter(handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestSnoozeReminder(t *testing.T) {
+	mockNotes := new(MockRepository)
+	mockReminders := new(MockReminderRepository)
+	handler := NewReminderHandler(mockNotes, mockReminders)
+
+	start := time.Now()
+	mockReminders.On("SnoozeReminder", mock.Anything, int64(1), int64(7), mock.MatchedBy(func(until time.Time) bool {
+		return until.Sub(start) >= 10*time.Minute && until.Sub(start) < 11*time.Minute
+	})).Return(nil)
+	mockNotes.On("GetNote", mock.Anything, int64(1), int64(7)).Return(&models.Note{ID: 7}, nil)
+
+	req, _ := http.NewRequest("POST", "/notes/7/reminder/snooze", bytes.NewBufferString(`{"minutes": 10}`))
+	rr := httptest.NewRecorder()
+	reminderRouter(handler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockReminders.AssertExpectations(t)
+}
+
+func TestSnoozeReminderValidation(t *testing.T) {
+	for _, body := range []string{
+		`{}`,
+		`{"minutes": -5}`,
+		`{"until": "2000-01-01T00:00:00Z"}`,
+		`{"minutes": 10, "until": "2030-01-01T00:00:00Z"}`,
+	} {
+		mockReminders := new(MockReminderRepository)
+		handler := NewReminderHandler(new(MockRepository), mockReminders)
+
+		req, _ := http.NewRequest("POST", "/notes/7/reminder/snooze", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		reminderRouter(handler).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"strings"
+)
+
+// ShareHandler обрабатывает запросы на совместный доступ к заметкам
+type ShareHandler struct {
+	repo repository.ShareRepository
+}
+
+// NewShareHandler создает новый экземпляр ShareHandler
+func NewShareHandler(repo repository.ShareRepository) *ShareHandler {
+	return &ShareHandler{repo: repo}
+}
+
+type ShareNoteRequest struct {
+	Username   string `json:"username"`
+	Permission string `json:"permission"`
+}
+
+// ShareNote открывает доступ к заметке другому пользователю (POST /notes/{id}/shares)
+func (h *ShareHandler) ShareNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req ShareNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		http.Error(w, "Username is required", http.StatusBadRequest)
+		return
+	}
+	if req.Permission == "" {
+		req.Permission = models.PermissionRead
+	}
+	if req.Permission != models.PermissionRead && req.Permission != models.PermissionEdit {
+		http.Error(w, "Permission must be read or edit", http.StatusBadRequest)
+		return
+	}
+
+	share, err := h.repo.ShareNote(r.Context(), userID, noteID, req.Username, req.Permission)
+	switch {
+	case errors.Is(err, repository.ErrUnknownUser):
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	case errors.Is(err, repository.ErrShareWithOwner):
+		http.Error(w, "Cannot share a note with its owner", http.StatusBadRequest)
+		return
+	case err != nil:
+		noteError(w, err, "Failed to share note")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(share)
+}
+
+// ListShares возвращает список пользователей с доступом к заметке (GET /notes/{id}/shares)
+func (h *ShareHandler) ListShares(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	shares, err := h.repo.ListShares(r.Context(), userID, noteID)
+	if err != nil {
+		noteError(w, err, "Failed to fetch shares")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shares)
+}
+
+// RevokeShare закрывает доступ (DELETE /notes/{id}/shares/{userID})
+func (h *ShareHandler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	noteID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	targetUserID, ok := pathID(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	err := h.repo.RevokeShare(r.Context(), userID, noteID, targetUserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Share not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to revoke share", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSharedWithMe возвращает чужие заметки, доступные пользователю (GET /notes/shared-with-me)
+func (h *ShareHandler) ListSharedWithMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	notes, err := h.repo.ListSharedWithMe(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch notes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notes)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockShareRepository struct {
+	mock.Mock
+}
+
+func (m *MockShareRepository) ShareNote(ctx context.Context, ownerID, noteID int64, username, permission string) (*models.NoteShare, error) {
+	args := m.Called(ctx, ownerID, noteID, username, permission)
+	return args.Get(0).(*models.NoteShare), args.Error(1)
+}
+
+func (m *MockShareRepository) ListShares(ctx context.Context, ownerID, noteID int64) ([]*models.NoteShare, error) {
+	args := m.Called(ctx, ownerID, noteID)
+	return args.Get(0).([]*models.NoteShare), args.Error(1)
+}
+
+func (m *MockShareRepository) RevokeShare(ctx context.Context, userID, noteID, targetUserID int64) error {
+	args := m.Called(ctx, userID, noteID, targetUserID)
+	return args.Error(0)
+}
+
+func (m *MockShareRepository) ListSharedWithMe(ctx context.Context, userID int64) ([]*models.Note, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.Note), args.Error(1)
+}
+
+func shareRouter(h *ShareHandler) http.Handler {
+	r := chi.NewRouter()
+	r.Use(new(MockAuthService).Authenticate)
+	r.Post("/notes/{id}/shares", h.ShareNote)
+	r.Delete("/notes/{id}/shares/{userID}", h.RevokeShare)
+	return r
+}
+
+func TestShareNote(t *testing.T) {
+	mockRepo := new(MockShareRepository)
+	router := shareRouter(NewShareHandler(mockRepo))
+
+	mockRepo.On("ShareNote", mock.Anything, int64(1), int64(7), "bob", models.PermissionEdit).
+		Return(&models.NoteShare{NoteID: 7, UserID: 2, Username: "bob", Permission: models.PermissionEdit}, nil)
+
+	req, _ := http.NewRequest("POST", "/notes/7/shares", bytes.NewBufferString(`{"username":"bob","permission":"edit"}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestShareNoteErrors(t *testing.T) {
+	cases := map[string]struct {
+		body     string
+		err      error
+		expected int
+	}{
+		"invalid permission": {`{"username":"bob","permission":"admin"}`, nil, http.StatusBadRequest},
+		"unknown user":       {`{"username":"bob"}`, repository.ErrUnknownUser, http.StatusNotFound},
+		"not owner":          {`{"username":"bob"}`, repository.ErrForbidden, http.StatusForbidden},
+		"share with owner":   {`{"username":"bob"}`, repository.ErrShareWithOwner, http.StatusBadRequest},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(MockShareRepository)
+			router := shareRouter(NewShareHandler(mockRepo))
+			mockRepo.On("ShareNote", mock.Anything, int64(1), int64(7), "bob", models.PermissionRead).
+				Return((*models.NoteShare)(nil), tc.err)
+
+			req, _ := http.NewRequest("POST", "/notes/7/shares", bytes.NewBufferString(tc.body))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expected, rr.Code)
+		})
+	}
+}
+
+func TestRevokeShare(t *testing.T) {
+	mockRepo := new(MockShareRepository)
+	router := shareRouter(NewShareHandler(mockRepo))
+
+	mockRepo.On("RevokeShare", mock.Anything, int64(1), int64(7), int64(2)).Return(nil)
+
+	req, _ := http.NewRequest("DELETE", "/notes/7/shares/2", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+}
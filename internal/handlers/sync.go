@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"notes-service/internal/spellcheck"
+	"strconv"
+	"time"
+)
+
+// maxSyncChanges ограничивает число изменений в одном POST /sync
+const maxSyncChanges = 500
+
+// SyncHandler отдает изменения заметок офлайн-клиентам и применяет их правки
+type SyncHandler struct {
+	notes        repository.NoteRepository
+	sync         repository.SyncRepository
+	spellchecker spellcheck.Spellchecker
+}
+
+// NewSyncHandler создает новый экземпляр SyncHandler
+func NewSyncHandler(notes repository.NoteRepository, sync repository.SyncRepository, spellchecker spellcheck.Spellchecker) *SyncHandler {
+	return &SyncHandler{notes: notes, sync: sync, spellchecker: spellchecker}
+}
+
+// PullChanges возвращает заметки, созданные, измененные и удаленные после
+// токена ?since= (GET /sync). Без since возвращаются все доступные заметки.
+func (h *SyncHandler) PullChanges(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var since uint64
+	if value := r.URL.Query().Get("since"); value != "" {
+		var err error
+		if since, err = strconv.ParseUint(value, 10, 64); err != nil {
+			http.Error(w, "Invalid change token", http.StatusBadRequest)
+			return
+		}
+	}
+
+	changes, err := h.sync.SyncChanges(r.Context(), userID, since)
+	if err != nil {
+		http.Error(w, "Failed to fetch changes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
+
+// PushChangesRequest — изменения, накопленные клиентом офлайн
+type PushChangesRequest struct {
+	Changes []models.SyncChange `json:"changes"`
+}
+
+// PushChangesResponse — результаты в порядке изменений запроса
+type PushChangesResponse struct {
+	Results []models.SyncResult `json:"results"`
+}
+
+// PushChanges применяет изменения клиента по очереди (POST /sync). Изменение,
+// сделанное не в текущей версии заметки, не применяется и возвращается как
+// conflict вместе с версией на сервере.
+func (h *SyncHandler) PushChanges(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req PushChangesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Changes) > maxSyncChanges {
+		http.Error(w, "Too many changes, send at most "+strconv.Itoa(maxSyncChanges), http.StatusBadRequest)
+		return
+	}
+
+	resp := PushChangesResponse{Results: make([]models.SyncResult, 0, len(req.Changes))}
+	for _, change := range req.Changes {
+		resp.Results = append(resp.Results, h.apply(r.Context(), userID, change))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *SyncHandler) apply(ctx context.Context, userID int64, change models.SyncChange) models.SyncResult {
+	result := models.SyncResult{ID: change.ID, ClientID: change.ClientID, Status: models.SyncApplied}
+	fail := func(message string) models.SyncResult {
+		result.Status = models.SyncError
+		result.Error = message
+		return result
+	}
+	conflict := func() models.SyncResult {
+		result.Status = models.SyncConflict
+		result.Note, _ = h.notes.GetNote(ctx, userID, change.ID)
+		return result
+	}
+
+	switch change.Op {
+	case models.SyncCreate:
+		if change.ClientID == "" {
+			return fail("client_id is required")
+		}
+		if change.WorkspaceID != nil && change.NotebookID != nil {
+			return fail("Workspace notes cannot be filed into notebooks")
+		}
+		note := &models.Note{
+			UserID:        userID,
+			WorkspaceID:   change.WorkspaceID,
+			NotebookID:    change.NotebookID,
+			Title:         change.Title,
+			ContentFormat: change.ContentFormat,
+			ClientID:      change.ClientID,
+		}
+		if note.ContentFormat == "" {
+			note.ContentFormat = models.FormatPlain
+		}
+		if message := h.prepare(note, change.Content); message != "" {
+			return fail(message)
+		}
+		note.CreatedAt = time.Now()
+		note.UpdatedAt = note.CreatedAt
+
+		err := h.notes.CreateNote(ctx, note)
+		if errors.Is(err, repository.ErrDuplicate) {
+			// Повторная отправка: заметка уже создана
+			if note, err = h.sync.GetNoteByClientID(ctx, userID, change.ClientID); errors.Is(err, repository.ErrNotFound) {
+				// и с тех пор удалена
+				result.Status = models.SyncConflict
+				return result
+			}
+		}
+		if err != nil {
+			return fail(syncErrorMessage(err, "Workspace not found"))
+		}
+		result.ID = note.ID
+		result.Note = note
+
+	case models.SyncUpdate:
+		if change.ID == 0 || change.Version == 0 {
+			return fail("id and version are required")
+		}
+		note := &models.Note{ID: change.ID, Title: change.Title, ContentFormat: change.ContentFormat, Version: change.Version}
+		if note.ContentFormat == "" {
+			current, err := h.notes.GetNote(ctx, userID, change.ID)
+			if errors.Is(err, repository.ErrNotFound) {
+				return conflict()
+			}
+			if err != nil {
+				return fail(syncErrorMessage(err, "Note not found"))
+			}
+			note.ContentFormat = current.ContentFormat
+		}
+		if message := h.prepare(note, change.Content); message != "" {
+			return fail(message)
+		}
+		note.UpdatedAt = time.Now()
+
+		err := h.notes.UpdateNote(ctx, userID, note)
+		if errors.Is(err, repository.ErrConflict) || errors.Is(err, repository.ErrNotFound) {
+			return conflict()
+		}
+		if err != nil {
+			return fail(syncErrorMessage(err, "Note not found"))
+		}
+		result.Note = note
+
+	case models.SyncDelete:
+		if change.ID == 0 || change.Version == 0 {
+			return fail("id and version are required")
+		}
+		err := h.sync.TrashNote(ctx, userID, change.ID, change.Version)
+		if errors.Is(err, repository.ErrConflict) {
+			return conflict()
+		}
+		// Уже удаленная заметка считается удаленной успешно
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return fail(syncErrorMessage(err, "Note not found"))
+		}
+
+	default:
+		return fail("Unknown op, expected create, update or delete")
+	}
+	return result
+}
+
+// prepare проверяет формат и орфографию текста заметки; возвращает описание
+// ошибки или пустую строку
+func (h *SyncHandler) prepare(note *models.Note, content string) string {
+	if !models.ValidContentFormat(note.ContentFormat) {
+		return "Invalid content format"
+	}
+	corrected, err := spellcheck.Check(h.spellchecker, note.ContentFormat, content)
+	if err != nil {
+		return "Failed to check spelling"
+	}
+	note.Content = corrected
+	return ""
+}
+
+// syncErrorMessage описывает ошибку применения изменения; notFound —
+// описание ErrNotFound для этой операции
+func syncErrorMessage(err error, notFound string) string {
+	switch {
+	case errors.Is(err, repository.ErrNotebookNotFound):
+		return "Notebook not found"
+	case errors.Is(err, repository.ErrNotFound):
+		return notFound
+	case errors.Is(err, repository.ErrForbidden):
+		return "Forbidden"
+	default:
+		return "Failed to apply change"
+	}
+}
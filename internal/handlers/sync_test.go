@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSyncRepository struct {
+	mock.Mock
+}
+
+func (m *MockSyncRepository) SyncChanges(ctx context.Context, userID int64, since uint64) (*models.SyncChanges, error) {
+	args := m.Called(ctx, userID, since)
+	return args.Get(0).(*models.SyncChanges), args.Error(1)
+}
+
+func (m *MockSyncRepository) GetNoteByClientID(ctx context.Context, userID int64, clientID string) (*models.Note, error) {
+	args := m.Called(ctx, userID, clientID)
+	return args.Get(0).(*models.Note), args.Error(1)
+}
+
+func (m *MockSyncRepository) TrashNote(ctx context.Context, userID, noteID int64, version int) error {
+	args := m.Called(ctx, userID, noteID, version)
+	return args.Error(0)
+}
+
+func TestPullChanges(t *testing.T) {
+	mockSync := new(MockSyncRepository)
+	handler := NewSyncHandler(new(MockRepository), mockSync, new(MockSpellchecker))
+
+	mockSync.On("SyncChanges", mock.Anything, int64(1), uint64(1200)).Return(&models.SyncChanges{
+		Token:   "1250",
+		Notes:   []*models.Note{{ID: 3, Title: "Updated", Version: 4}},
+		Deleted: []int64{5},
+	}, nil)
+
+	req, _ := http.NewRequest("GET", "/sync?since=1200", nil)
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.PullChanges)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var changes models.SyncChanges
+	json.NewDecoder(rr.Body).Decode(&changes)
+	assert.Equal(t, "1250", changes.Token)
+	assert.False(t, changes.Reset)
+	assert.Equal(t, 4, changes.Notes[0].Version)
+	assert.Equal(t, []int64{5}, changes.Deleted)
+
+	req, _ = http.NewRequest("GET", "/sync?since=abc", nil)
+	rr = httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.PullChanges)).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestPushChanges(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockSync := new(MockSyncRepository)
+	mockSpellchecker := new(MockSpellchecker)
+	handler := NewSyncHandler(mockRepo, mockSync, mockSpellchecker)
+
+	for _, text := range []string{"text", "mine", "ok"} {
+		mockSpellchecker.On("CheckSpelling", text).Return(text, nil)
+	}
+
+	// Создание, уже примененное при прошлой отправке
+	mockRepo.On("CreateNote", mock.Anything, mock.MatchedBy(func(n *models.Note) bool { return n.ClientID == "local-1" })).
+		Return(repository.ErrDuplicate)
+	mockSync.On("GetNoteByClientID", mock.Anything, int64(1), "local-1").
+		Return(&models.Note{ID: 10, ClientID: "local-1", Version: 1}, nil)
+
+	// Правка устаревшей версии
+	mockRepo.On("UpdateNote", mock.Anything, int64(1), mock.MatchedBy(func(n *models.Note) bool { return n.ID == 3 && n.Version == 2 })).
+		Return(repository.ErrConflict)
+	mockRepo.On("GetNote", mock.Anything, int64(1), int64(3)).
+		Return(&models.Note{ID: 3, Content: "server", Version: 5}, nil)
+
+	// Правка текущей версии
+	mockRepo.On("UpdateNote", mock.Anything, int64(1), mock.MatchedBy(func(n *models.Note) bool { return n.ID == 4 })).
+		Run(func(args mock.Arguments) { args.Get(2).(*models.Note).Version = 8 }).
+		Return(nil)
+
+	// Удаление уже удаленной заметки
+	mockSync.On("TrashNote", mock.Anything, int64(1), int64(6), 1).Return(repository.ErrNotFound)
+
+	body := bytes.NewBufferString(`{"changes":[
+		{"op":"create","client_id":"local-1","title":"New","content":"text"},
+		{"op":"update","id":3,"version":2,"title":"Old","content":"mine","content_format":"plain"},
+		{"op":"update","id":4,"version":7,"title":"Fresh","content":"ok","content_format":"markdown"},
+		{"op":"delete","id":6,"version":1},
+		{"op":"update","id":9,"title":"No version"},
+		{"op":"rename"}
+	]}`)
+	req, _ := http.NewRequest("POST", "/sync", body)
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.PushChanges)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp PushChangesResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if assert.Len(t, resp.Results, 6) {
+		assert.Equal(t, models.SyncApplied, resp.Results[0].Status)
+		assert.Equal(t, int64(10), resp.Results[0].ID)
+
+		assert.Equal(t, models.SyncConflict, resp.Results[1].Status)
+		assert.Equal(t, 5, resp.Results[1].Note.Version)
+		assert.Equal(t, "server", resp.Results[1].Note.Content)
+
+		assert.Equal(t, models.SyncApplied, resp.Results[2].Status)
+		assert.Equal(t, 8, resp.Results[2].Note.Version)
+
+		assert.Equal(t, models.SyncApplied, resp.Results[3].Status)
+
+		assert.Equal(t, models.SyncError, resp.Results[4].Status)
+		assert.Equal(t, "id and version are required", resp.Results[4].Error)
+		assert.Equal(t, models.SyncError, resp.Results[5].Status)
+	}
+}
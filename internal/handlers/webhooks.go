@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"notes-service/internal/auth"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"slices"
+)
+
+// minWebhookSecretLength — минимальная длина секрета, заданного пользователем
+const minWebhookSecretLength = 16
+
+// WebhookHandler управляет вебхуками пользователя и их доставками
+type WebhookHandler struct {
+	repo   repository.WebhookRepository
+	notify func()
+}
+
+// NewWebhookHandler создает новый экземпляр WebhookHandler. notify будит
+// обработчик доставок после повторной постановки доставки в очередь.
+func NewWebhookHandler(repo repository.WebhookRepository, notify func()) *WebhookHandler {
+	return &WebhookHandler{repo: repo, notify: notify}
+}
+
+// WebhookRequest — параметры вебхука. Без events вебхук получает все события.
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
+
+// validate проверяет адрес и события и дополняет значения по умолчанию;
+// возвращает описание ошибки или пустую строку
+func (req *WebhookRequest) validate() string {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "URL must be an absolute http or https URL"
+	}
+	if len(req.Events) == 0 {
+		req.Events = slices.Clone(models.WebhookEvents)
+	}
+	for _, event := range req.Events {
+		if !models.ValidWebhookEvent(event) {
+			return "Unknown event " + event
+		}
+	}
+	slices.Sort(req.Events)
+	req.Events = slices.Compact(req.Events)
+	return ""
+}
+
+// CreateWebhook создает вебхук (POST /webhooks). Если секрет не задан, он
+// генерируется; секрет возвращается только в этом ответе.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if message := req.validate(); message != "" {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+	if req.Secret != "" && len(req.Secret) < minWebhookSecretLength {
+		http.Error(w, "Secret must be at least 16 characters", http.StatusBadRequest)
+		return
+	}
+	if req.Secret == "" {
+		secret, err := linkToken()
+		if err != nil {
+			http.Error(w, "Failed to generate secret", http.StatusInternalServerError)
+			return
+		}
+		req.Secret = secret
+	}
+
+	webhook := &models.Webhook{UserID: userID, URL: req.URL, Secret: req.Secret, Events: req.Events, Active: true}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	if err := h.repo.CreateWebhook(r.Context(), webhook); err != nil {
+		http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// ListWebhooks возвращает вебхуки пользователя без секретов (GET /webhooks)
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	webhooks, err := h.repo.ListWebhooks(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// GetWebhook возвращает вебхук без секрета (GET /webhooks/{id})
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	webhookID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	webhook, err := h.repo.GetWebhook(r.Context(), userID, webhookID)
+	if err != nil {
+		webhookError(w, err, "Failed to fetch webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// UpdateWebhook меняет адрес, события и активность вебхука (PUT /webhooks/{id}).
+// Секрет не меняется; без active вебхук остается активным.
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	webhookID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if message := req.validate(); message != "" {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+	if req.Secret != "" {
+		http.Error(w, "Secret cannot be changed, create a new webhook", http.StatusBadRequest)
+		return
+	}
+
+	webhook := &models.Webhook{ID: webhookID, UserID: userID, URL: req.URL, Events: req.Events, Active: true}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	if err := h.repo.UpdateWebhook(r.Context(), webhook); err != nil {
+		webhookError(w, err, "Failed to update webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// DeleteWebhook удаляет вебхук вместе с журналом доставок (DELETE /webhooks/{id})
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	webhookID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteWebhook(r.Context(), userID, webhookID); err != nil {
+		webhookError(w, err, "Failed to delete webhook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries возвращает доставки вебхука, новые первыми
+// (GET /webhooks/{id}/deliveries). ?status= отбирает доставки в одном состоянии.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	webhookID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	limit, offset, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.DeliveryPending, models.DeliveryDelivering, models.DeliverySucceeded, models.DeliveryFailed:
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.repo.GetWebhook(r.Context(), userID, webhookID); err != nil {
+		webhookError(w, err, "Failed to fetch deliveries")
+		return
+	}
+	deliveries, err := h.repo.ListWebhookDeliveries(r.Context(), userID, webhookID, status, limit, offset)
+	if err != nil {
+		http.Error(w, "Failed to fetch deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// GetDelivery возвращает доставку с журналом попыток
+// (GET /webhooks/{id}/deliveries/{deliveryID})
+func (h *WebhookHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	webhookID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := pathID(w, r, "deliveryID")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.repo.GetWebhookDelivery(r.Context(), userID, webhookID, deliveryID)
+	if err != nil {
+		deliveryError(w, err, "Failed to fetch delivery")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// ReplayDelivery отправляет доставку заново, в том числе успешную или
+// окончательно неудачную (POST /webhooks/{id}/deliveries/{deliveryID}/replay)
+func (h *WebhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	webhookID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := pathID(w, r, "deliveryID")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.repo.ReplayWebhookDelivery(r.Context(), userID, webhookID, deliveryID)
+	if err != nil {
+		deliveryError(w, err, "Failed to replay delivery")
+		return
+	}
+	h.notify()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+func webhookError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, message, http.StatusInternalServerError)
+}
+
+func deliveryError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrConflict):
+		http.Error(w, "Delivery is in progress", http.StatusConflict)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
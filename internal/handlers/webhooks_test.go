@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	args := m.Called(ctx, webhook)
+	webhook.ID = 5
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) ListWebhooks(ctx context.Context, userID int64) ([]*models.Webhook, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) GetWebhook(ctx context.Context, userID, webhookID int64) (*models.Webhook, error) {
+	args := m.Called(ctx, userID, webhookID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) UpdateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	return m.Called(ctx, webhook).Error(0)
+}
+
+func (m *MockWebhookRepository) DeleteWebhook(ctx context.Context, userID, webhookID int64) error {
+	return m.Called(ctx, userID, webhookID).Error(0)
+}
+
+func (m *MockWebhookRepository) ListWebhookDeliveries(ctx context.Context, userID, webhookID int64, status string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	args := m.Called(ctx, userID, webhookID, status, limit, offset)
+	return args.Get(0).([]*models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) GetWebhookDelivery(ctx context.Context, userID, webhookID, deliveryID int64) (*models.WebhookDelivery, error) {
+	args := m.Called(ctx, userID, webhookID, deliveryID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) ReplayWebhookDelivery(ctx context.Context, userID, webhookID, deliveryID int64) (*models.WebhookDelivery, error) {
+	args := m.Called(ctx, userID, webhookID, deliveryID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) ClaimWebhookDelivery(ctx context.Context, staleAfter time.Duration) (*models.WebhookDelivery, *models.Webhook, error) {
+	args := m.Called(ctx, staleAfter)
+	return nil, nil, args.Error(0)
+}
+
+func (m *MockWebhookRepository) RecordWebhookAttempt(ctx context.Context, deliveryID int64, attempt *models.WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	return m.Called(ctx, deliveryID, attempt, status, nextAttemptAt).Error(0)
+}
+
+func (m *MockWebhookRepository) PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestCreateWebhookGeneratesSecret(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	handler := NewWebhookHandler(mockRepo, func() {})
+
+	mockRepo.On("CreateWebhook", mock.Anything, mock.MatchedBy(func(w *models.Webhook) bool {
+		return w.UserID == 1 && w.URL == "https://ci.example.com/hook" && w.Active && len(w.Secret) >= 32 &&
+			assert.ObjectsAreEqual([]string{models.WebhookNoteCreated, models.WebhookNoteDeleted, models.WebhookNoteReminder, models.WebhookNoteUpdated}, w.Events)
+	})).Return(nil)
+
+	req, _ := http.NewRequest("POST", "/webhooks", bytes.NewBufferString(`{"url": "https://ci.example.com/hook"}`))
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.CreateWebhook)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var webhook models.Webhook
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&webhook))
+	assert.Equal(t, int64(5), webhook.ID)
+	assert.NotEmpty(t, webhook.Secret)
+	// Сортировка событий по умолчанию не меняет общий список
+	assert.Equal(t, models.WebhookEvents, []string{models.WebhookNoteCreated, models.WebhookNoteUpdated, models.WebhookNoteDeleted, models.WebhookNoteReminder})
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateWebhookValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"relative URL", `{"url": "/hook"}`},
+		{"unsupported scheme", `{"url": "ftp://example.com/hook"}`},
+		{"unknown event", `{"url": "https://example.com", "events": ["note.viewed"]}`},
+		{"short secret", `{"url": "https://example.com", "secret": "short"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockWebhookRepository)
+			handler := NewWebhookHandler(mockRepo, func() {})
+
+			req, _ := http.NewRequest("POST", "/webhooks", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			new(MockAuthService).Authenticate(http.HandlerFunc(handler.CreateWebhook)).ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			mockRepo.AssertNotCalled(t, "CreateWebhook", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	handler := NewWebhookHandler(mockRepo, func() {})
+
+	mockRepo.On("GetWebhook", mock.Anything, int64(1), int64(5)).Return(&models.Webhook{ID: 5}, nil)
+	mockRepo.On("ListWebhookDeliveries", mock.Anything, int64(1), int64(5), models.DeliveryFailed, defaultPageSize, 0).
+		Return([]*models.WebhookDelivery{{ID: 10, WebhookID: 5, Status: models.DeliveryFailed, Attempts: 8}}, nil)
+
+	r := chi.NewRouter()
+	r.Use(new(MockAuthService).Authenticate)
+	r.Get("/webhooks/{id}/deliveries", handler.ListDeliveries)
+
+	req, _ := http.NewRequest("GET", "/webhooks/5/deliveries?status=failed", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var deliveries []models.WebhookDelivery
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&deliveries))
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, 8, deliveries[0].Attempts)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestListWebhookDeliveriesUnknownWebhook(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	handler := NewWebhookHandler(mockRepo, func() {})
+
+	mockRepo.On("GetWebhook", mock.Anything, int64(1), int64(5)).Return(nil, repository.ErrNotFound)
+
+	r := chi.NewRouter()
+	r.Use(new(MockAuthService).Authenticate)
+	r.Get("/webhooks/{id}/deliveries", handler.ListDeliveries)
+
+	req, _ := http.NewRequest("GET", "/webhooks/5/deliveries", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockRepo.AssertNotCalled(t, "ListWebhookDeliveries", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReplayDelivery(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	notified := 0
+	handler := NewWebhookHandler(mockRepo, func() { notified++ })
+
+	mockRepo.On("ReplayWebhookDelivery", mock.Anything, int64(1), int64(5), int64(10)).
+		Return(&models.WebhookDelivery{ID: 10, WebhookID: 5, Status: models.DeliveryPending}, nil)
+
+	r := chi.NewRouter()
+	r.Use(new(MockAuthService).Authenticate)
+	r.Post("/webhooks/{id}/deliveries/{deliveryID}/replay", handler.ReplayDelivery)
+
+	req, _ := http.NewRequest("POST", "/webhooks/5/deliveries/10/replay", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, 1, notified)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestReplayDeliveryInProgress(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	notified := 0
+	handler := NewWebhookHandler(mockRepo, func() { notified++ })
+
+	mockRepo.On("ReplayWebhookDelivery", mock.Anything, int64(1), int64(5), int64(10)).
+		Return(nil, repository.ErrConflict)
+
+	r := chi.NewRouter()
+	r.Use(new(MockAuthService).Authenticate)
+	r.Post("/webhooks/{id}/deliveries/{deliveryID}/replay", handler.ReplayDelivery)
+
+	req, _ := http.NewRequest("POST", "/webhooks/5/deliveries/10/replay", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, 0, notified)
+}
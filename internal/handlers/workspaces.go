@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"notes-service/internal/auth"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"strings"
+)
+
+// WorkspaceHandler обрабатывает запросы к рабочим пространствам
+type WorkspaceHandler struct {
+	repo repository.WorkspaceRepository
+}
+
+// NewWorkspaceHandler создает новый экземпляр WorkspaceHandler
+func NewWorkspaceHandler(repo repository.WorkspaceRepository) *WorkspaceHandler {
+	return &WorkspaceHandler{repo: repo}
+}
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+type MemberRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type InviteRequest struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// CreateWorkspace создает пространство (POST /workspaces)
+func (h *WorkspaceHandler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	workspace, err := h.repo.CreateWorkspace(r.Context(), userID, req.Name)
+	if err != nil {
+		http.Error(w, "Failed to create workspace", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(workspace)
+}
+
+// ListWorkspaces возвращает пространства пользователя (GET /workspaces)
+func (h *WorkspaceHandler) ListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workspaces, err := h.repo.ListWorkspaces(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch workspaces", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(workspaces)
+}
+
+// GetWorkspace возвращает пространство (GET /workspaces/{id})
+func (h *WorkspaceHandler) GetWorkspace(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	workspaceID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	workspace, err := h.repo.GetWorkspace(r.Context(), userID, workspaceID)
+	if err != nil {
+		workspaceError(w, err, "Failed to fetch workspace")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(workspace)
+}
+
+// ListMembers возвращает участников пространства (GET /workspaces/{id}/members)
+func (h *WorkspaceHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	workspaceID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	members, err := h.repo.ListMembers(r.Context(), userID, workspaceID)
+	if err != nil {
+		workspaceError(w, err, "Failed to fetch members")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+// SetMemberRole меняет роль участника (PUT /workspaces/{id}/members/{userID})
+func (h *WorkspaceHandler) SetMemberRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	workspaceID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	memberID, ok := pathID(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	var req MemberRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !models.ValidWorkspaceRole(req.Role) {
+		http.Error(w, "Role must be owner, editor or viewer", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.SetMemberRole(r.Context(), userID, workspaceID, memberID, req.Role); err != nil {
+		workspaceError(w, err, "Failed to update member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveMember исключает участника или выходит из пространства
+// (DELETE /workspaces/{id}/members/{userID})
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	workspaceID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	memberID, ok := pathID(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	if err := h.repo.RemoveMember(r.Context(), userID, workspaceID, memberID); err != nil {
+		workspaceError(w, err, "Failed to remove member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Invite приглашает пользователя в пространство (POST /workspaces/{id}/invitations)
+func (h *WorkspaceHandler) Invite(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	workspaceID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req InviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		http.Error(w, "Username is required", http.StatusBadRequest)
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.WorkspaceEditor
+	}
+	if !models.ValidWorkspaceRole(req.Role) {
+		http.Error(w, "Role must be owner, editor or viewer", http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := h.repo.CreateInvitation(r.Context(), userID, workspaceID, req.Username, req.Role)
+	switch {
+	case errors.Is(err, repository.ErrUnknownUser):
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	case errors.Is(err, repository.ErrDuplicate):
+		http.Error(w, "User is already a member or invited", http.StatusConflict)
+		return
+	case err != nil:
+		workspaceError(w, err, "Failed to create invitation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invitation)
+}
+
+// ListInvitations возвращает ожидающие приглашения пользователя (GET /invitations)
+func (h *WorkspaceHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	invitations, err := h.repo.ListInvitations(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch invitations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// AcceptInvitation принимает приглашение (POST /invitations/{id}/accept)
+func (h *WorkspaceHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, r, true)
+}
+
+// DeclineInvitation отклоняет приглашение (POST /invitations/{id}/decline)
+func (h *WorkspaceHandler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, r, false)
+}
+
+func (h *WorkspaceHandler) respond(w http.ResponseWriter, r *http.Request, accept bool) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	invitationID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := h.repo.RespondToInvitation(r.Context(), userID, invitationID, accept)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to respond to invitation", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// workspaceError переводит ошибки доступа к пространству в HTTP-ответ
+func workspaceError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "Workspace not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrForbidden):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	case errors.Is(err, repository.ErrLastOwner):
+		http.Error(w, "Workspace must keep at least one owner", http.StatusConflict)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
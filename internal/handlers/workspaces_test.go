@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWorkspaceRepository struct {
+	mock.Mock
+}
+
+func (m *MockWorkspaceRepository) CreateWorkspace(ctx context.Context, userID int64, name string) (*models.Workspace, error) {
+	args := m.Called(ctx, userID, name)
+	return args.Get(0).(*models.Workspace), args.Error(1)
+}
+
+func (m *MockWorkspaceRepository) ListWorkspaces(ctx context.Context, userID int64) ([]*models.Workspace, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.Workspace), args.Error(1)
+}
+
+func (m *MockWorkspaceRepository) GetWorkspace(ctx context.Context, userID, workspaceID int64) (*models.Workspace, error) {
+	args := m.Called(ctx, userID, workspaceID)
+	return args.Get(0).(*models.Workspace), args.Error(1)
+}
+
+func (m *MockWorkspaceRepository) ListMembers(ctx context.Context, userID, workspaceID int64) ([]*models.WorkspaceMember, error) {
+	args := m.Called(ctx, userID, workspaceID)
+	return args.Get(0).([]*models.WorkspaceMember), args.Error(1)
+}
+
+func (m *MockWorkspaceRepository) SetMemberRole(ctx context.Context, userID, workspaceID, memberID int64, role string) error {
+	args := m.Called(ctx, userID, workspaceID, memberID, role)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceRepository) RemoveMember(ctx context.Context, userID, workspaceID, memberID int64) error {
+	args := m.Called(ctx, userID, workspaceID, memberID)
+	return args.Error(0)
+}
+
+func (m *MockWorkspaceRepository) CreateInvitation(ctx context.Context, userID, workspaceID int64, username, role string) (*models.WorkspaceInvitation, error) {
+	args := m.Called(ctx, userID, workspaceID, username, role)
+	return args.Get(0).(*models.WorkspaceInvitation), args.Error(1)
+}
+
+func (m *MockWorkspaceRepository) ListInvitations(ctx context.Context, userID int64) ([]*models.WorkspaceInvitation, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.WorkspaceInvitation), args.Error(1)
+}
+
+func (m *MockWorkspaceRepository) RespondToInvitation(ctx context.Context, userID, invitationID int64, accept bool) error {
+	args := m.Called(ctx, userID, invitationID, accept)
+	return args.Error(0)
+}
+
+func workspaceRouter(h *WorkspaceHandler) http.Handler {
+	r := chi.NewRouter()
+	r.Use(new(MockAuthService).Authenticate)
+	r.Put("/workspaces/{id}/members/{userID}", h.SetMemberRole)
+	r.Post("/workspaces/{id}/invitations", h.Invite)
+	r.Post("/invitations/{id}/accept", h.AcceptInvitation)
+	return r
+}
+
+func TestInviteToWorkspace(t *testing.T) {
+	mockRepo := new(MockWorkspaceRepository)
+	router := workspaceRouter(NewWorkspaceHandler(mockRepo))
+
+	mockRepo.On("CreateInvitation", mock.Anything, int64(1), int64(3), "bob", models.WorkspaceEditor).
+		Return(&models.WorkspaceInvitation{ID: 5, WorkspaceID: 3, UserID: 2, Role: models.WorkspaceEditor}, nil)
+	mockRepo.On("CreateInvitation", mock.Anything, int64(1), int64(3), "carol", models.WorkspaceViewer).
+		Return((*models.WorkspaceInvitation)(nil), repository.ErrDuplicate)
+
+	req, _ := http.NewRequest("POST", "/workspaces/3/invitations", bytes.NewBufferString(`{"username":"bob"}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	req, _ = http.NewRequest("POST", "/workspaces/3/invitations", bytes.NewBufferString(`{"username":"carol","role":"viewer"}`))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestSetMemberRole(t *testing.T) {
+	mockRepo := new(MockWorkspaceRepository)
+	router := workspaceRouter(NewWorkspaceHandler(mockRepo))
+
+	mockRepo.On("SetMemberRole", mock.Anything, int64(1), int64(3), int64(1), models.WorkspaceViewer).
+		Return(repository.ErrLastOwner)
+
+	req, _ := http.NewRequest("PUT", "/workspaces/3/members/1", bytes.NewBufferString(`{"role":"viewer"}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	req, _ = http.NewRequest("PUT", "/workspaces/3/members/2", bytes.NewBufferString(`{"role":"admin"}`))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	mockRepo := new(MockWorkspaceRepository)
+	router := workspaceRouter(NewWorkspaceHandler(mockRepo))
+
+	mockRepo.On("RespondToInvitation", mock.Anything, int64(1), int64(5), true).Return(nil)
+
+	req, _ := http.NewRequest("POST", "/invitations/5/accept", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestListNotesInWorkspace(t *testing.T) {
+	mockRepo := new(MockRepository)
+	handler := NewNoteHandler(mockRepo, new(MockSpellchecker), new(MockAuthService))
+	workspaceID := int64(3)
+
+	mockRepo.On("ListNotes", mock.Anything, int64(1), models.NoteFilter{WorkspaceID: &workspaceID, Archived: new(bool)}).
+		Return([]*models.Note(nil), repository.ErrNotFound)
+
+	req, _ := http.NewRequest("GET", "/notes?workspace=3", nil)
+	rr := httptest.NewRecorder()
+	new(MockAuthService).Authenticate(http.HandlerFunc(handler.ListNotes)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
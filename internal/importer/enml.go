@@ -0,0 +1,74 @@
+package importer
+
+import (
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// blockElements — элементы ENML, после которых начинается новая строка
+var blockElements = map[string]bool{
+	"div": true, "p": true, "br": true, "li": true, "tr": true, "pre": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "hr": true, "en-note": true,
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// enmlToText переводит разметку ENML в простой текст: блоки — в строки,
+// пункты списков — в строки с "- ", флажки en-todo — в "[ ]" и "[x]"
+func enmlToText(enml string) (string, error) {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(enml))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return "", z.Err()
+			}
+			text := blankLines.ReplaceAllString(b.String(), "\n\n")
+			return strings.TrimSpace(text), nil
+		case html.TextToken:
+			b.Write(z.Text())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			switch {
+			case tag == "li":
+				newline(&b)
+				b.WriteString("- ")
+			case tag == "en-todo":
+				checked := false
+				for hasAttr {
+					var key, val []byte
+					key, val, hasAttr = z.TagAttr()
+					if string(key) == "checked" && string(val) == "true" {
+						checked = true
+					}
+				}
+				if checked {
+					b.WriteString("[x] ")
+				} else {
+					b.WriteString("[ ] ")
+				}
+			case tag == "br":
+				b.WriteString("\n")
+			case blockElements[tag]:
+				newline(&b)
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if tag := string(name); blockElements[tag] && tag != "br" {
+				newline(&b)
+			}
+		}
+	}
+}
+
+// newline начинает новую строку, если текущая не пуста
+func newline(b *strings.Builder) {
+	if s := b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		b.WriteString("\n")
+	}
+}
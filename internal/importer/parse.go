@@ -0,0 +1,299 @@
+// Package importer разбирает файлы импорта и загружает из них заметки в
+// фоновых заданиях
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"notes-service/internal/export"
+	"notes-service/internal/models"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrUnknownFormat возвращается для файлов, которые не являются ZIP, JSON или ENEX
+	ErrUnknownFormat = errors.New("unsupported import file format")
+	// ErrArchiveTooLarge возвращается для архивов, в которых больше
+	// MaxArchiveEntries файлов или заметки которых в распакованном виде
+	// занимают больше MaxArchiveSize
+	ErrArchiveTooLarge = fmt.Errorf("archive has more than %d files or unpacks to more than %d bytes", MaxArchiveEntries, MaxArchiveSize)
+)
+
+const (
+	// MaxItemSize ограничивает размер одной заметки в распакованном виде
+	MaxItemSize = 10 << 20
+	// MaxArchiveEntries ограничивает число файлов в ZIP-архиве
+	MaxArchiveEntries = 10000
+	// MaxArchiveSize ограничивает суммарный размер заметок ZIP-архива в
+	// распакованном виде
+	MaxArchiveSize = 200 << 20
+)
+
+// Item — заметка, прочитанная из файла импорта
+type Item struct {
+	Name          string // путь в архиве или номер заметки, для отчета об ошибках
+	Title         string
+	Content       string
+	ContentFormat string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Err           error // ошибка разбора этой заметки
+}
+
+// DetectFormat определяет формат файла импорта по содержимому
+func DetectFormat(data []byte) (string, error) {
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return models.ImportFormatZIP, nil
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		return models.ImportFormatJSON, nil
+	case bytes.HasPrefix(trimmed, []byte("<")) && bytes.Contains(data, []byte("<en-export")):
+		return models.ImportFormatENEX, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Count возвращает число заметок в файле импорта, не распаковывая их
+// содержимое там, где это возможно
+func Count(format string, data []byte) (int, error) {
+	if format == models.ImportFormatZIP {
+		files, err := zipNoteFiles(data)
+		return len(files), err
+	}
+	count := 0
+	err := Parse(format, data, func(Item) { count++ })
+	return count, err
+}
+
+// Parse разбирает файл импорта и передает заметки в yield по одной, не
+// удерживая в памяти уже переданные. Ошибка возвращается, если файл не
+// читается целиком; ошибки отдельных заметок записываются в Item.Err.
+func Parse(format string, data []byte, yield func(Item)) error {
+	switch format {
+	case models.ImportFormatZIP:
+		return parseZIP(data, yield)
+	case models.ImportFormatJSON:
+		return parseJSON(data, yield)
+	case models.ImportFormatENEX:
+		return parseENEX(data, yield)
+	}
+	return ErrUnknownFormat
+}
+
+// zipNoteFiles возвращает файлы заметок архива: .md, .markdown и .txt.
+// HTML-версии и манифест из GET /export пропускаются. Архивы больше
+// MaxArchiveEntries файлов или MaxArchiveSize в распакованном виде
+// отклоняются целиком с ErrArchiveTooLarge. Размеры берутся из каталога
+// архива; archive/zip не дает прочитать из файла больше заявленного.
+func zipNoteFiles(data []byte) ([]*zip.File, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	if len(zr.File) > MaxArchiveEntries {
+		return nil, ErrArchiveTooLarge
+	}
+
+	var files []*zip.File
+	var total uint64
+	for _, f := range zr.File {
+		ext := strings.ToLower(path.Ext(f.Name))
+		if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") ||
+			(ext != ".md" && ext != ".markdown" && ext != ".txt") {
+			continue
+		}
+		// Файлы больше MaxItemSize не распаковываются и не входят в сумму
+		if f.UncompressedSize64 <= MaxItemSize {
+			total += f.UncompressedSize64
+		}
+		if total > MaxArchiveSize {
+			return nil, ErrArchiveTooLarge
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func parseZIP(data []byte, yield func(Item)) error {
+	files, err := zipNoteFiles(data)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		item := Item{Name: f.Name, ContentFormat: models.FormatMarkdown}
+		if strings.ToLower(path.Ext(f.Name)) == ".txt" {
+			item.ContentFormat = models.FormatPlain
+		}
+		item.Title = strings.TrimSuffix(path.Base(f.Name), path.Ext(f.Name))
+
+		content, err := readZIPFile(f)
+		if err != nil {
+			item.Err = err
+		} else {
+			item.Err = parseMarkdownFile(&item, content)
+		}
+		yield(item)
+	}
+	return nil
+}
+
+func readZIPFile(f *zip.File) (string, error) {
+	if f.UncompressedSize64 > MaxItemSize {
+		return "", fmt.Errorf("file is larger than %d bytes", MaxItemSize)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, MaxItemSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(content) > MaxItemSize {
+		return "", fmt.Errorf("file is larger than %d bytes", MaxItemSize)
+	}
+	if !utf8.Valid(content) {
+		return "", errors.New("file is not valid UTF-8")
+	}
+	return string(content), nil
+}
+
+// parseMarkdownFile отделяет YAML front matter от текста заметки
+func parseMarkdownFile(item *Item, content string) error {
+	content = strings.ReplaceAll(strings.TrimPrefix(content, "\ufeff"), "\r\n", "\n")
+	item.Content = content
+
+	rest, ok := strings.CutPrefix(content, "---\n")
+	if !ok {
+		return nil
+	}
+	var header string
+	switch {
+	case strings.HasPrefix(rest, "---\n"):
+		item.Content = rest[len("---\n"):]
+	case strings.Contains(rest, "\n---\n"):
+		header, item.Content, _ = strings.Cut(rest, "\n---\n")
+	case strings.HasSuffix(rest, "\n---"):
+		header, item.Content = strings.TrimSuffix(rest, "\n---"), ""
+	default:
+		// Нет закрывающего разделителя: это не front matter
+		return nil
+	}
+
+	var fm export.FrontMatter
+	if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+		return fmt.Errorf("invalid front matter: %w", err)
+	}
+	if fm.Title != "" {
+		item.Title = fm.Title
+	}
+	if fm.Format != "" {
+		if !models.ValidContentFormat(fm.Format) {
+			return fmt.Errorf("invalid format %q", fm.Format)
+		}
+		item.ContentFormat = fm.Format
+	}
+	item.CreatedAt = fm.Created
+	item.UpdatedAt = fm.Updated
+	return nil
+}
+
+// parseJSON читает массив заметок в том виде, в каком их отдает GET /notes
+func parseJSON(data []byte, yield func(Item)) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return errors.New("expected a JSON array of notes")
+	}
+
+	for i := 1; dec.More(); i++ {
+		var note models.Note
+		err := dec.Decode(&note)
+		var typeErr *json.UnmarshalTypeError
+		if err != nil && !errors.As(err, &typeErr) {
+			// Синтаксическая ошибка: дальше файл не читается
+			return err
+		}
+
+		item := Item{
+			Name:          fmt.Sprintf("#%d", i),
+			Title:         note.Title,
+			Content:       note.Content,
+			ContentFormat: note.ContentFormat,
+			CreatedAt:     note.CreatedAt,
+			UpdatedAt:     note.UpdatedAt,
+			Err:           err,
+		}
+		if note.Title != "" {
+			item.Name += " " + note.Title
+		}
+		if item.ContentFormat == "" {
+			item.ContentFormat = models.FormatPlain
+		}
+		if item.Err == nil && !models.ValidContentFormat(item.ContentFormat) {
+			item.Err = fmt.Errorf("invalid content_format %q", item.ContentFormat)
+		}
+		yield(item)
+	}
+	return nil
+}
+
+// enexNote — заметка в файле экспорта Evernote
+type enexNote struct {
+	Title   string `xml:"title"`
+	Content string `xml:"content"`
+	Created string `xml:"created"`
+	Updated string `xml:"updated"`
+}
+
+const enexTimeLayout = "20060102T150405Z"
+
+// parseENEX читает файл экспорта Evernote. Текст заметок в ENML переводится
+// в простой текст.
+func parseENEX(data []byte, yield func(Item)) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+
+	for i := 1; ; {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+
+		var note enexNote
+		if err := dec.DecodeElement(&note, &start); err != nil {
+			return err
+		}
+
+		item := Item{
+			Name:          fmt.Sprintf("#%d %s", i, note.Title),
+			Title:         note.Title,
+			ContentFormat: models.FormatPlain,
+		}
+		item.Content, item.Err = enmlToText(note.Content)
+		item.CreatedAt, _ = time.Parse(enexTimeLayout, note.Created)
+		item.UpdatedAt, _ = time.Parse(enexTimeLayout, note.Updated)
+		yield(item)
+		i++
+	}
+}
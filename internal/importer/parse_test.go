@@ -0,0 +1,174 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"notes-service/internal/export"
+	"notes-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseAll собирает все заметки файла импорта
+func parseAll(format string, data []byte) ([]Item, error) {
+	var items []Item
+	err := Parse(format, data, func(item Item) { items = append(items, item) })
+	return items, err
+}
+
+func TestDetectFormat(t *testing.T) {
+	for data, want := range map[string]string{
+		"PK\x03\x04rest":                               models.ImportFormatZIP,
+		"\ufeff  [{\"title\": \"a\"}]":                 models.ImportFormatJSON,
+		`<?xml version="1.0"?><en-export></en-export>`: models.ImportFormatENEX,
+	} {
+		format, err := DetectFormat([]byte(data))
+		require.NoError(t, err)
+		assert.Equal(t, want, format)
+	}
+
+	_, err := DetectFormat([]byte("<html></html>"))
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestParseExportArchive(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var buf bytes.Buffer
+	w := export.NewWriter(&buf, nil)
+	require.NoError(t, w.Add(&models.Note{ID: 1, Title: "Road map", Content: "# Goals\n---\nQ1",
+		ContentFormat: models.FormatMarkdown, CreatedAt: created, UpdatedAt: created}))
+	require.NoError(t, w.Add(&models.Note{ID: 2, Title: "Empty", ContentFormat: models.FormatPlain,
+		CreatedAt: created, UpdatedAt: created}))
+	require.NoError(t, w.Close())
+
+	items, err := parseAll(models.ImportFormatZIP, buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	assert.NoError(t, items[0].Err)
+	assert.Equal(t, "Road map", items[0].Title)
+	assert.Equal(t, "# Goals\n---\nQ1", items[0].Content)
+	assert.Equal(t, models.FormatMarkdown, items[0].ContentFormat)
+	assert.True(t, created.Equal(items[0].CreatedAt))
+
+	assert.NoError(t, items[1].Err)
+	assert.Equal(t, "Empty", items[1].Title)
+	assert.Equal(t, "", items[1].Content)
+	assert.Equal(t, models.FormatPlain, items[1].ContentFormat)
+}
+
+func TestParseZIPWithoutFrontMatter(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"Ideas/shopping.txt":   "milk",
+		"readme.md":            "---\ntitle: [broken\n---\ntext",
+		"__MACOSX/._readme.md": "junk",
+		"image.png":            "png",
+	} {
+		f, err := zw.Create(name)
+		require.NoError(t, err)
+		f.Write([]byte(content))
+	}
+	require.NoError(t, zw.Close())
+
+	items, err := parseAll(models.ImportFormatZIP, buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	byName := map[string]Item{}
+	for _, item := range items {
+		byName[item.Name] = item
+	}
+	shopping := byName["Ideas/shopping.txt"]
+	assert.NoError(t, shopping.Err)
+	assert.Equal(t, "shopping", shopping.Title)
+	assert.Equal(t, "milk", shopping.Content)
+	assert.Equal(t, models.FormatPlain, shopping.ContentFormat)
+
+	assert.ErrorContains(t, byName["readme.md"].Err, "invalid front matter")
+}
+
+func TestParseZIPTooManyEntries(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i <= MaxArchiveEntries; i++ {
+		_, err := zw.Create(fmt.Sprintf("note-%d.txt", i))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	_, err := Count(models.ImportFormatZIP, buf.Bytes())
+	assert.ErrorIs(t, err, ErrArchiveTooLarge)
+	err = Parse(models.ImportFormatZIP, buf.Bytes(), func(Item) { t.Fatal("no item expected") })
+	assert.ErrorIs(t, err, ErrArchiveTooLarge)
+}
+
+func TestParseZIPTooLargeUncompressed(t *testing.T) {
+	// Нули сжимаются примерно в тысячу раз: архив весит сотни килобайт,
+	// а распаковывается больше чем в MaxArchiveSize
+	chunk := bytes.Repeat([]byte{0}, MaxItemSize)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i <= MaxArchiveSize/MaxItemSize; i++ {
+		f, err := zw.Create(fmt.Sprintf("bomb-%d.md", i))
+		require.NoError(t, err)
+		_, err = f.Write(chunk)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.Less(t, buf.Len(), 1<<20)
+
+	_, err := Count(models.ImportFormatZIP, buf.Bytes())
+	assert.ErrorIs(t, err, ErrArchiveTooLarge)
+	err = Parse(models.ImportFormatZIP, buf.Bytes(), func(Item) { t.Fatal("no item expected") })
+	assert.ErrorIs(t, err, ErrArchiveTooLarge)
+}
+
+func TestParseJSON(t *testing.T) {
+	data := `[
+		{"title": "First", "content": "one", "content_format": "markdown", "created_at": "2026-01-02T03:04:05Z"},
+		{"title": 42, "content": "bad"},
+		{"title": "Third", "content": "three", "content_format": "rtf"}
+	]`
+
+	items, err := parseAll(models.ImportFormatJSON, []byte(data))
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+
+	assert.NoError(t, items[0].Err)
+	assert.Equal(t, "#1 First", items[0].Name)
+	assert.Equal(t, models.FormatMarkdown, items[0].ContentFormat)
+	assert.Error(t, items[1].Err)
+	assert.ErrorContains(t, items[2].Err, "invalid content_format")
+
+	_, err = parseAll(models.ImportFormatJSON, []byte(`[{"title": `))
+	assert.Error(t, err)
+}
+
+func TestParseENEX(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-export SYSTEM "http://xml.evernote.com/pub/evernote-export3.dtd">
+<en-export>
+  <note>
+    <title>Groceries</title>
+    <content><![CDATA[<?xml version="1.0" encoding="UTF-8"?><en-note><div>Buy:</div><ul><li>milk</li><li>bread</li></ul><div><en-todo checked="true"/>done</div></en-note>]]></content>
+    <created>20260102T030405Z</created>
+    <updated>20260103T030405Z</updated>
+  </note>
+</en-export>`
+
+	items, err := parseAll(models.ImportFormatENEX, []byte(data))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	assert.NoError(t, items[0].Err)
+	assert.Equal(t, "Groceries", items[0].Title)
+	assert.Equal(t, "Buy:\n- milk\n- bread\n[x] done", items[0].Content)
+	assert.Equal(t, models.FormatPlain, items[0].ContentFormat)
+	assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), items[0].CreatedAt)
+}
@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"notes-service/internal/spellcheck"
+	"time"
+)
+
+const (
+	// pollInterval — как часто Worker проверяет очередь без уведомлений
+	pollInterval = 10 * time.Second
+	// staleAfter — через сколько задание без обновлений считается брошенным
+	staleAfter = 5 * time.Minute
+	// progressInterval — как часто прогресс задания сохраняется в базу
+	progressInterval = time.Second
+	// maxReportedErrors ограничивает отчет об ошибках одного задания
+	maxReportedErrors = 1000
+)
+
+// Worker выполняет задания импорта из очереди в базе
+type Worker struct {
+	jobs         repository.ImportRepository
+	notes        repository.NoteRepository
+	spellchecker spellcheck.Spellchecker
+	notify       chan struct{}
+}
+
+// NewWorker создает новый экземпляр Worker
+func NewWorker(jobs repository.ImportRepository, notes repository.NoteRepository, spellchecker spellcheck.Spellchecker) *Worker {
+	return &Worker{
+		jobs:         jobs,
+		notes:        notes,
+		spellchecker: spellchecker,
+		notify:       make(chan struct{}, 1),
+	}
+}
+
+// Notify сообщает Worker о новом задании, чтобы не ждать следующего опроса
+func (w *Worker) Notify() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run выполняет задания, пока не отменен ctx
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for w.runNext(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.notify:
+		}
+	}
+}
+
+// runNext выполняет одно задание из очереди и сообщает, было ли оно
+func (w *Worker) runNext(ctx context.Context) bool {
+	job, payload, err := w.jobs.ClaimImportJob(ctx, staleAfter)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to claim import job: %v", err)
+		return false
+	}
+
+	w.Process(ctx, job, payload)
+	return true
+}
+
+// Process загружает заметки из файла задания, сохраняя прогресс по ходу
+func (w *Worker) Process(ctx context.Context, job *models.ImportJob, payload []byte) {
+	total, err := Count(job.Format, payload)
+	if err != nil {
+		w.fail(ctx, job, err)
+		return
+	}
+
+	job.Total = total
+	saved := time.Now()
+	w.save(ctx, job)
+
+	err = Parse(job.Format, payload, func(item Item) {
+		if err := w.importItem(ctx, job, item); err != nil {
+			job.Failed++
+			if len(job.Errors) < maxReportedErrors {
+				job.Errors = append(job.Errors, models.ImportItemError{Item: item.Name, Error: err.Error()})
+			}
+		}
+		job.Processed++
+
+		if time.Since(saved) >= progressInterval {
+			w.save(ctx, job)
+			saved = time.Now()
+		}
+	})
+	if err != nil {
+		w.fail(ctx, job, err)
+		return
+	}
+
+	job.Status = models.ImportCompleted
+	w.save(ctx, job)
+}
+
+// importItem создает заметку из item. Дубликаты существующих заметок
+// пропускаются и учитываются в job.Skipped.
+func (w *Worker) importItem(ctx context.Context, job *models.ImportJob, item Item) error {
+	if item.Err != nil {
+		return item.Err
+	}
+	if item.Title == "" && item.Content == "" {
+		return errors.New("note is empty")
+	}
+
+	duplicate, err := w.jobs.NoteExists(ctx, job.UserID, item.Title, item.Content)
+	if err != nil {
+		return err
+	}
+
+	content := item.Content
+	if !duplicate && !job.SkipSpellcheck {
+		if content, err = spellcheck.Check(w.spellchecker, item.ContentFormat, item.Content); err != nil {
+			return err
+		}
+		// Ранее импортированная заметка хранится уже исправленной
+		if content != item.Content {
+			if duplicate, err = w.jobs.NoteExists(ctx, job.UserID, item.Title, content); err != nil {
+				return err
+			}
+		}
+	}
+	if duplicate {
+		job.Skipped++
+		return nil
+	}
+
+	now := time.Now()
+	note := &models.Note{
+		UserID:        job.UserID,
+		Title:         item.Title,
+		Content:       content,
+		ContentFormat: item.ContentFormat,
+		CreatedAt:     item.CreatedAt,
+		UpdatedAt:     item.UpdatedAt,
+	}
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = now
+	}
+	if note.UpdatedAt.IsZero() {
+		note.UpdatedAt = note.CreatedAt
+	}
+	if err := w.notes.CreateNote(ctx, note); err != nil {
+		return err
+	}
+	job.Imported++
+	return nil
+}
+
+// fail завершает задание с ошибкой чтения файла. Заметки, импортированные
+// до ошибки, остаются.
+func (w *Worker) fail(ctx context.Context, job *models.ImportJob, err error) {
+	job.Status = models.ImportFailed
+	job.Error = err.Error()
+	w.save(ctx, job)
+}
+
+func (w *Worker) save(ctx context.Context, job *models.ImportJob) {
+	if err := w.jobs.SaveImportProgress(ctx, job); err != nil {
+		log.Printf("Failed to save import job %d: %v", job.ID, err)
+	}
+}
@@ -0,0 +1,166 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"notes-service/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore хранит заметки и задания импорта в памяти
+type fakeStore struct {
+	notes []*models.Note
+	saved []models.ImportJob
+}
+
+func (s *fakeStore) CreateImportJob(ctx context.Context, job *models.ImportJob, payload []byte) error {
+	return nil
+}
+
+func (s *fakeStore) GetImportJob(ctx context.Context, userID, jobID int64) (*models.ImportJob, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) ListImportJobs(ctx context.Context, userID int64) ([]*models.ImportJob, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) ClaimImportJob(ctx context.Context, staleAfter time.Duration) (*models.ImportJob, []byte, error) {
+	return nil, nil, nil
+}
+
+func (s *fakeStore) SaveImportProgress(ctx context.Context, job *models.ImportJob) error {
+	s.saved = append(s.saved, *job)
+	return nil
+}
+
+func (s *fakeStore) NoteExists(ctx context.Context, userID int64, title, content string) (bool, error) {
+	for _, note := range s.notes {
+		if note.UserID == userID && note.Title == title && note.Content == content {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *fakeStore) CreateNote(ctx context.Context, note *models.Note) error {
+	s.notes = append(s.notes, note)
+	return nil
+}
+
+func (s *fakeStore) ListNotes(ctx context.Context, userID int64, filter models.NoteFilter) ([]*models.Note, error) {
+	return s.notes, nil
+}
+
+func (s *fakeStore) GetNote(ctx context.Context, userID, noteID int64) (*models.Note, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) UpdateNote(ctx context.Context, userID int64, note *models.Note) error {
+	return nil
+}
+
+func (s *fakeStore) DeleteNote(ctx context.Context, userID, noteID int64) error {
+	return nil
+}
+
+func (s *fakeStore) Close() error {
+	return nil
+}
+
+// upperChecker «исправляет» текст, переводя его в верхний регистр
+type upperChecker struct {
+	calls int
+	err   error
+}
+
+func (c *upperChecker) CheckSpelling(text string) (string, error) {
+	c.calls++
+	return strings.ToUpper(text), c.err
+}
+
+func TestProcess(t *testing.T) {
+	store := &fakeStore{notes: []*models.Note{{UserID: 1, Title: "Old", Content: "KNOWN"}}}
+	checker := &upperChecker{}
+	w := NewWorker(store, store, checker)
+
+	job := &models.ImportJob{ID: 5, UserID: 1, Format: models.ImportFormatJSON}
+	payload := `[
+		{"title": "New", "content": "fresh"},
+		{"title": "Old", "content": "known"},
+		{"title": "", "content": ""},
+		{"title": 1}
+	]`
+	w.Process(context.Background(), job, []byte(payload))
+
+	assert.Equal(t, models.ImportCompleted, job.Status)
+	assert.Equal(t, 4, job.Total)
+	assert.Equal(t, 4, job.Processed)
+	assert.Equal(t, 1, job.Imported)
+	assert.Equal(t, 1, job.Skipped)
+	assert.Equal(t, 2, job.Failed)
+	require.Len(t, job.Errors, 2)
+	assert.Equal(t, "#3", job.Errors[0].Item)
+	assert.Equal(t, "note is empty", job.Errors[0].Error)
+
+	require.Len(t, store.notes, 2)
+	assert.Equal(t, "FRESH", store.notes[1].Content)
+	assert.False(t, store.notes[1].CreatedAt.IsZero())
+	assert.Equal(t, models.ImportCompleted, store.saved[len(store.saved)-1].Status)
+}
+
+func TestProcessSkipSpellcheck(t *testing.T) {
+	store := &fakeStore{}
+	checker := &upperChecker{err: errors.New("speller is down")}
+	w := NewWorker(store, store, checker)
+
+	job := &models.ImportJob{ID: 6, UserID: 1, Format: models.ImportFormatJSON, SkipSpellcheck: true}
+	w.Process(context.Background(), job, []byte(`[{"title": "New", "content": "fresh"}]`))
+
+	assert.Equal(t, 0, checker.calls)
+	assert.Equal(t, 1, job.Imported)
+	require.Len(t, store.notes, 1)
+	assert.Equal(t, "fresh", store.notes[0].Content)
+}
+
+func TestProcessUnreadableFile(t *testing.T) {
+	store := &fakeStore{}
+	w := NewWorker(store, store, &upperChecker{})
+
+	job := &models.ImportJob{ID: 7, UserID: 1, Format: models.ImportFormatZIP}
+	w.Process(context.Background(), job, []byte("PK\x03\x04 truncated"))
+
+	assert.Equal(t, models.ImportFailed, job.Status)
+	assert.NotEmpty(t, job.Error)
+	assert.Empty(t, store.notes)
+}
+
+func TestProcessZIPBomb(t *testing.T) {
+	store := &fakeStore{}
+	w := NewWorker(store, store, &upperChecker{})
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i <= MaxArchiveEntries; i++ {
+		f, err := zw.Create(fmt.Sprintf("note-%d.txt", i))
+		require.NoError(t, err)
+		f.Write([]byte("text"))
+	}
+	require.NoError(t, zw.Close())
+
+	job := &models.ImportJob{ID: 8, UserID: 1, Format: models.ImportFormatZIP}
+	w.Process(context.Background(), job, buf.Bytes())
+
+	assert.Equal(t, models.ImportFailed, job.Status)
+	assert.Equal(t, ErrArchiveTooLarge.Error(), job.Error)
+	assert.Equal(t, 0, job.Processed)
+	assert.Empty(t, store.notes)
+}
@@ -0,0 +1,71 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message — письмо для отправки пользователю
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender отправляет письма. Для продакшена подключается реализация поверх
+// SMTP или почтового API, для локальной разработки — LogSender или FileSender.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender выводит письма в лог приложения
+type LogSender struct{}
+
+// NewLogSender создает новый экземпляр LogSender
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+// Send реализует Sender
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileSender сохраняет каждое письмо в отдельный .eml файл в каталоге dir
+type FileSender struct {
+	dir string
+}
+
+// NewFileSender создает новый экземпляр FileSender
+func NewFileSender(dir string) *FileSender {
+	return &FileSender{dir: dir}
+}
+
+// Send реализует Sender
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405.000000000"), sanitize(msg.To))
+	content := fmt.Sprintf("Date: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		now.Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+
+	return os.WriteFile(filepath.Join(s.dir, name), []byte(content), 0o644)
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,220 @@
+// Package markdown преобразует текст заметок в безопасный HTML
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"notes-service/internal/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// md разбирает CommonMark с расширениями GFM: таблицы, списки задач,
+// зачеркивание и автоссылки. Сырой HTML из текста не выводится.
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithASTTransformers(util.Prioritized(attachmentLinks{}, 100))),
+)
+
+// AttachmentScheme — схема ссылок на вложения заметки в Markdown:
+// ![схема](attachment:42) или ![схема](attachment:42?size=small) для миниатюры
+const AttachmentScheme = "attachment:"
+
+// AttachmentResolver возвращает URL вложения id или его миниатюры size
+// (пустая строка — исходный файл). false означает, что вложения нет.
+type AttachmentResolver func(id int64, size string) (string, bool)
+
+var resolverKey = parser.NewContextKey()
+
+// attachmentLinks заменяет адреса attachment: в ссылках и изображениях на
+// URL из AttachmentResolver. Неразрешенные адреса остаются как есть и
+// удаляются при очистке HTML.
+type attachmentLinks struct{}
+
+func (attachmentLinks) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	resolve, _ := pc.Get(resolverKey).(AttachmentResolver)
+	if resolve == nil {
+		return
+	}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Link:
+			node.Destination = resolveAttachment(node.Destination, resolve)
+		case *ast.Image:
+			node.Destination = resolveAttachment(node.Destination, resolve)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+func resolveAttachment(dest []byte, resolve AttachmentResolver) []byte {
+	id, size, ok := ParseAttachmentRef(string(dest))
+	if !ok {
+		return dest
+	}
+	if url, ok := resolve(id, size); ok {
+		return []byte(url)
+	}
+	return dest
+}
+
+// ParseAttachmentRef разбирает адрес вида attachment:42 или attachment:42?size=small
+func ParseAttachmentRef(dest string) (id int64, size string, ok bool) {
+	ref, found := strings.CutPrefix(dest, AttachmentScheme)
+	if !found {
+		return 0, "", false
+	}
+	ref, query, _ := strings.Cut(ref, "?")
+	id, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, "", false
+	}
+	if query != "" {
+		size, found = strings.CutPrefix(query, "size=")
+		if !found {
+			return 0, "", false
+		}
+	}
+	return id, size, true
+}
+
+// policy пропускает только разметку, которую порождает md
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	return p
+}()
+
+// Render преобразует Markdown в HTML и очищает результат
+func Render(source string) (string, error) {
+	return RenderWithAttachments(source, nil)
+}
+
+// RenderWithAttachments преобразует Markdown в HTML, заменяя ссылки на
+// вложения (attachment:ID) адресами из resolve
+func RenderWithAttachments(source string, resolve AttachmentResolver) (string, error) {
+	pc := parser.NewContext()
+	if resolve != nil {
+		pc.Set(resolverKey, resolve)
+	}
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(source), &buf, parser.WithContext(pc)); err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
+
+// RenderText преобразует простой текст в HTML: абзацы разделяются пустой
+// строкой, переносы строк сохраняются
+func RenderText(source string) string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	var b strings.Builder
+	for _, paragraph := range strings.Split(source, "\n\n") {
+		paragraph = strings.Trim(paragraph, "\n")
+		if paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+// ToHTML преобразует текст заметки в HTML с учетом ее формата. resolve
+// может быть nil, тогда ссылки на вложения не разрешаются.
+func ToHTML(format, source string, resolve AttachmentResolver) (string, error) {
+	if format == models.FormatMarkdown {
+		return RenderWithAttachments(source, resolve)
+	}
+	return RenderText(source), nil
+}
+
+// urlPattern находит адреса в тексте, в том числе в ссылках [текст](адрес)
+var urlPattern = regexp.MustCompile(`(?:https?://|ftp://|mailto:|www\.|attachment:)[^\s<>()\[\]]+`)
+
+// ProtectedRanges возвращает упорядоченные непересекающиеся диапазоны байт
+// source, которые не являются текстом на естественном языке: блоки и
+// фрагменты кода, HTML и адреса. Проверка орфографии их пропускает.
+func ProtectedRanges(source string) [][2]int {
+	src := []byte(source)
+	doc := md.Parser().Parse(text.NewReader(src))
+
+	var ranges [][2]int
+	addSegments := func(lines *text.Segments) {
+		for i := 0; i < lines.Len(); i++ {
+			segment := lines.At(i)
+			ranges = append(ranges, [2]int{segment.Start, segment.Stop})
+		}
+	}
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.FencedCodeBlock:
+			if node.Info != nil {
+				ranges = append(ranges, [2]int{node.Info.Segment.Start, node.Info.Segment.Stop})
+			}
+			addSegments(node.Lines())
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeBlock, *ast.HTMLBlock:
+			addSegments(node.Lines())
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML:
+			addSegments(node.Segments)
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeSpan:
+			for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+				if t, ok := c.(*ast.Text); ok {
+					ranges = append(ranges, [2]int{t.Segment.Start, t.Segment.Stop})
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, match := range urlPattern.FindAllStringIndex(source, -1) {
+		ranges = append(ranges, [2]int{match[0], match[1]})
+	}
+
+	return merge(ranges)
+}
+
+// merge сортирует диапазоны и объединяет пересекающиеся и смежные
+func merge(ranges [][2]int) [][2]int {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+
+	merged := [][2]int{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r[0] <= last[1] {
+			if r[1] > last[1] {
+				last[1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
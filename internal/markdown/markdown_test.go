@@ -0,0 +1,61 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderGFM(t *testing.T) {
+	html, err := Render("| a | b |\n|---|---|\n| 1 | 2 |\n\n- [ ] todo\n- [x] done\n")
+
+	assert.NoError(t, err)
+	assert.Contains(t, html, "<table>")
+	assert.Contains(t, html, `<input checked="" disabled="" type="checkbox"`)
+}
+
+func TestRenderSanitizes(t *testing.T) {
+	html, err := Render("<script>alert(1)</script>\n\n[x](javascript:alert(1)) <img src=x onerror=alert(1)>")
+
+	assert.NoError(t, err)
+	assert.NotContains(t, html, "<script")
+	assert.NotContains(t, html, "javascript:")
+	assert.NotContains(t, html, "onerror")
+}
+
+func TestRenderText(t *testing.T) {
+	assert.Equal(t, "<p>a &lt;b&gt;<br>\nc</p>\n<p>d</p>\n", RenderText("a <b>\nc\n\nd"))
+}
+
+func TestProtectedRanges(t *testing.T) {
+	source := "Text `code` and https://example.com/path.\n\n```go\nfunc main() {}\n```\n"
+	var protected []string
+	for _, r := range ProtectedRanges(source) {
+		protected = append(protected, source[r[0]:r[1]])
+	}
+
+	assert.Equal(t, []string{"code", "https://example.com/path.", "go", "func main() {}\n"}, protected)
+}
+
+func TestParseAttachmentRef(t *testing.T) {
+	id, size, ok := ParseAttachmentRef("attachment:42?size=small")
+	assert.True(t, ok)
+	assert.Equal(t, int64(42), id)
+	assert.Equal(t, "small", size)
+
+	_, _, ok = ParseAttachmentRef("attachment:x")
+	assert.False(t, ok)
+	_, _, ok = ParseAttachmentRef("https://example.com/42")
+	assert.False(t, ok)
+}
+
+func TestRenderWithAttachments(t *testing.T) {
+	resolve := func(id int64, size string) (string, bool) {
+		return "https://notes.example.com/files/1?size=" + size, id == 1
+	}
+	html, err := RenderWithAttachments("![plan](attachment:1?size=large) [other](attachment:2)", resolve)
+
+	assert.NoError(t, err)
+	assert.Contains(t, html, `<img src="https://notes.example.com/files/1?size=large" alt="plan"`)
+	assert.NotContains(t, html, "attachment:2")
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Состояния обработки миниатюр вложения
+const (
+	ThumbnailPending = "pending"
+	ThumbnailRunning = "running"
+	ThumbnailReady   = "ready"
+	ThumbnailFailed  = "failed"
+)
+
+// Attachment — файл, прикрепленный к заметке
+type Attachment struct {
+	ID          int64     `json:"id"`
+	NoteID      int64     `json:"note_id"`
+	UserID      int64     `json:"user_id"` // кто загрузил; размер учитывается в его квоте
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	// ThumbnailStatus пуст для файлов, у которых миниатюр не бывает
+	ThumbnailStatus string       `json:"thumbnail_status,omitempty"`
+	Thumbnails      []*Thumbnail `json:"thumbnails,omitempty"`
+}
+
+// Thumbnail — уменьшенная копия изображения из вложения
+type Thumbnail struct {
+	Size        string `json:"size"` // вариант размера, например small
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ContentType string `json:"content_type"`
+	ByteSize    int64  `json:"byte_size"`
+	StorageKey  string `json:"-"`
+}
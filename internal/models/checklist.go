@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ChecklistItem — пункт чек-листа заметки. Position — номер пункта в
+// чек-листе, начиная с нуля.
+type ChecklistItem struct {
+	ID        int64     `json:"id"`
+	NoteID    int64     `json:"note_id"`
+	Text      string    `json:"text"`
+	Checked   bool      `json:"checked"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
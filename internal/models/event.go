@@ -0,0 +1,42 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// Типы событий в потоке изменений заметок
+const (
+	NoteCreated = "created"
+	NoteUpdated = "updated"
+	NoteDeleted = "deleted"
+	// NoteReminder — сработало напоминание заметки
+	NoteReminder = "reminder"
+)
+
+// NoteEvent — изменение заметки, видимой пользователю
+type NoteEvent struct {
+	ID        int64     `json:"id"`
+	NoteID    int64     `json:"note_id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	XID       uint64    `json:"-"` // ID транзакции, записавшей событие
+}
+
+// Cursor возвращает позицию потока сразу после события
+func (e *NoteEvent) Cursor() NoteEventCursor {
+	return NoteEventCursor{XID: e.XID, ID: e.ID}
+}
+
+// NoteEventCursor — позиция в потоке событий. События упорядочены по ID
+// транзакции, а внутри нее — по ID события: ID событий выдаются при вставке,
+// а не при фиксации, и событие с меньшим ID может стать видимым позже.
+type NoteEventCursor struct {
+	XID uint64
+	ID  int64
+}
+
+// String возвращает позицию в виде "<xid>-<id>", как в поле id потока SSE
+func (c NoteEventCursor) String() string {
+	return strconv.FormatUint(c.XID, 10) + "-" + strconv.FormatInt(c.ID, 10)
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Состояния задания импорта
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// Форматы импортируемых файлов
+const (
+	ImportFormatZIP  = "zip"  // ZIP с файлами Markdown, например из GET /export
+	ImportFormatJSON = "json" // массив заметок в формате API
+	ImportFormatENEX = "enex" // экспорт Evernote
+)
+
+// ImportJob — фоновое задание импорта заметок и его прогресс
+type ImportJob struct {
+	ID             int64             `json:"id"`
+	UserID         int64             `json:"-"`
+	Status         string            `json:"status"`
+	Format         string            `json:"format"`
+	SkipSpellcheck bool              `json:"skip_spellcheck"`
+	Total          int               `json:"total"`
+	Processed      int               `json:"processed"`
+	Imported       int               `json:"imported"`
+	Skipped        int               `json:"skipped"` // дубликаты уже существующих заметок
+	Failed         int               `json:"failed"`
+	Errors         []ImportItemError `json:"errors"`
+	Error          string            `json:"error,omitempty"` // причина, по которой задание не выполнено
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	FinishedAt     *time.Time        `json:"finished_at,omitempty"`
+}
+
+// ImportItemError описывает элемент импорта, который не удалось загрузить
+type ImportItemError struct {
+	Item  string `json:"item"`
+	Error string `json:"error"`
+}
@@ -2,12 +2,114 @@ package models
 
 import "time"
 
+// Отметки заметки, которые переключает SetNoteFlag
+const (
+	FlagPinned   = "pinned"
+	FlagArchived = "archived"
+	FlagFavorite = "favorite"
+)
+
+// Уровни доступа к заметке
+const (
+	PermissionOwner = "owner"
+	PermissionEdit  = "edit"
+	PermissionRead  = "read"
+)
+
+// Форматы текста заметки
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+)
+
+// ValidContentFormat проверяет, что формат текста заметки известен
+func ValidContentFormat(format string) bool {
+	return format == FormatPlain || format == FormatMarkdown
+}
+
 // Note представляет структуру заметки
 type Note struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"user_id"`
+	WorkspaceID *int64 `json:"workspace_id,omitempty"`
+	NotebookID  *int64 `json:"notebook_id,omitempty"`
+	Title       string `json:"title"`
+	Content     string `json:"content"`
+	// ContentFormat — формат Content: plain или markdown
+	ContentFormat string     `json:"content_format"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"` // Задано у заметок в корзине
+	// Version растет при каждом изменении заметки. Если Version передан в
+	// UpdateNote, заметка изменяется, только пока ее версия совпадает.
+	Version int `json:"version"`
+	// ClientID — ID, который офлайн-клиент присвоил созданной им заметке
+	ClientID string `json:"client_id,omitempty"`
+
+	// DueAt — срок задачи; RemindAt — время очередного напоминания. Recurrence —
+	// правило повторения RRULE: после срабатывания RemindAt и DueAt переносятся
+	// на следующее повторение; правило вычисляется в часовом поясе Timezone.
+	// SnoozedUntil — напоминание отложено до этого времени.
+	DueAt        *time.Time `json:"due_at,omitempty"`
+	RemindAt     *time.Time `json:"remind_at,omitempty"`
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+	Recurrence   string     `json:"recurrence,omitempty"`
+	Timezone     string     `json:"timezone,omitempty"`
+
+	// Pinned — заметка закреплена вверху списка; Archived — заметка в архиве и
+	// по умолчанию не показывается в списке; Favorite — заметка в избранном.
+	// Position — место заметки, заданное ручной сортировкой. Отметки у каждого
+	// пользователя свои.
+	Pinned   bool `json:"pinned"`
+	Archived bool `json:"archived"`
+	Favorite bool `json:"favorite"`
+	Position *int `json:"position,omitempty"`
+
+	// Checklist — пункты чек-листа; заполняется в списке заметок и при экспорте
+	Checklist []*ChecklistItem `json:"checklist,omitempty"`
+
+	// Permission — доступ текущего пользователя к заметке
+	Permission string `json:"permission,omitempty"`
+}
+
+// NoteFilter задает выборку заметок в ListNotes
+type NoteFilter struct {
+	// WorkspaceID — заметки пространства; nil означает личные заметки
+	WorkspaceID *int64
+	// NotebookID — только заметки из этого блокнота
+	NotebookID *int64
+	// HasOpenTasks — true: только заметки с неотмеченными пунктами чек-листа,
+	// false: только заметки без них
+	HasOpenTasks *bool
+	// Archived — true: только архивные заметки, false: только неархивные,
+	// nil: все
+	Archived *bool
+	// Favorite — true: только избранные заметки, false: только не избранные,
+	// nil: все
+	Favorite *bool
+}
+
+// NoteShare описывает доступ другого пользователя к заметке
+type NoteShare struct {
+	NoteID     int64     `json:"note_id"`
+	UserID     int64     `json:"user_id"`
+	Username   string    `json:"username"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ShareLink — публичная ссылка на заметку только для чтения. Токен ссылки
+// показывается один раз при создании, в базе хранится его хеш.
+type ShareLink struct {
+	ID           int64      `json:"id"`
+	NoteID       int64      `json:"note_id"`
+	TokenHash    string     `json:"-"`
+	PasswordHash string     `json:"-"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// HasPassword сообщает, защищена ли ссылка паролем
+func (l *ShareLink) HasPassword() bool {
+	return l.PasswordHash != ""
 }
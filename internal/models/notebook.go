@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Notebook — блокнот (папка) для заметок. Блокноты образуют дерево через ParentID.
+type Notebook struct {
+	ID        int64      `json:"id"`
+	ParentID  *int64     `json:"parent_id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Trash — содержимое корзины пользователя
+type Trash struct {
+	Notebooks []*Notebook `json:"notebooks"`
+	Notes     []*Note     `json:"notes"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DomainEvent — событие из исходящей очереди для брокера сообщений. ID
+// уникален и одинаков при повторной публикации, по нему получатели
+// отбрасывают дубликаты.
+type DomainEvent struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"` // note.created, note.updated, note.deleted или note.reminder
+	AggregateID int64           `json:"aggregate_id"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	Payload     json.RawMessage `json:"data"`
+	Attempts    int             `json:"-"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Reminder — срок и напоминание заметки
+type Reminder struct {
+	DueAt      *time.Time `json:"due_at"`
+	RemindAt   *time.Time `json:"remind_at"`
+	Recurrence string     `json:"recurrence"` // Правило RRULE, например FREQ=WEEKLY;BYDAY=MO
+	Timezone   string     `json:"timezone"`   // Часовой пояс правила, например Europe/Moscow; по умолчанию UTC
+}
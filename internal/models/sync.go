@@ -0,0 +1,55 @@
+package models
+
+// Операции синхронизации
+const (
+	SyncCreate = "create"
+	SyncUpdate = "update"
+	SyncDelete = "delete"
+)
+
+// Результаты применения изменения клиента
+const (
+	SyncApplied  = "applied"
+	SyncConflict = "conflict"
+	SyncError    = "error"
+)
+
+// SyncChanges — изменения заметок пользователя после токена синхронизации
+type SyncChanges struct {
+	// Token передается в следующий запрос как since
+	Token string `json:"token"`
+	// Reset означает, что Notes содержит все доступные заметки и локальные
+	// копии остальных заметок нужно удалить
+	Reset bool `json:"reset"`
+	// Notes — созданные и измененные заметки
+	Notes []*Note `json:"notes"`
+	// Deleted — ID заметок, удаленных или ставших недоступными
+	Deleted []int64 `json:"deleted"`
+}
+
+// SyncChange — изменение, сделанное клиентом офлайн
+type SyncChange struct {
+	Op string `json:"op"`
+	// ID — заметка для update и delete
+	ID int64 `json:"id,omitempty"`
+	// ClientID — ID новой заметки на клиенте, обязателен для create
+	ClientID string `json:"client_id,omitempty"`
+	// Version — версия заметки, которую клиент изменил или удалил
+	Version       int    `json:"version,omitempty"`
+	WorkspaceID   *int64 `json:"workspace_id,omitempty"`
+	NotebookID    *int64 `json:"notebook_id,omitempty"`
+	Title         string `json:"title"`
+	Content       string `json:"content"`
+	ContentFormat string `json:"content_format,omitempty"`
+}
+
+// SyncResult — результат применения одного изменения клиента
+type SyncResult struct {
+	ID       int64  `json:"id,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	Status   string `json:"status"`
+	// Note — заметка после применения изменения или, при конфликте, текущая
+	// версия на сервере. При конфликте без Note заметка удалена или недоступна.
+	Note  *Note  `json:"note,omitempty"`
+	Error string `json:"error,omitempty"`
+}
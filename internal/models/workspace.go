@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Роли участников пространства
+const (
+	WorkspaceOwner  = "owner"
+	WorkspaceEditor = "editor"
+	WorkspaceViewer = "viewer"
+)
+
+// ValidWorkspaceRole сообщает, существует ли роль участника пространства
+func ValidWorkspaceRole(role string) bool {
+	return role == WorkspaceOwner || role == WorkspaceEditor || role == WorkspaceViewer
+}
+
+// Workspace — рабочее пространство команды с общими заметками
+type Workspace struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Role — роль текущего пользователя в пространстве
+	Role string `json:"role,omitempty"`
+}
+
+// WorkspaceMember — участник пространства
+type WorkspaceMember struct {
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WorkspaceInvitation — приглашение пользователя в пространство
+type WorkspaceInvitation struct {
+	ID            int64     `json:"id"`
+	WorkspaceID   int64     `json:"workspace_id"`
+	WorkspaceName string    `json:"workspace_name,omitempty"`
+	UserID        int64     `json:"user_id"`
+	Username      string    `json:"username,omitempty"`
+	Role          string    `json:"role"`
+	CreatedAt     time.Time `json:"created_at"`
+}
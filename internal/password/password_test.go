@@ -0,0 +1,62 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	for _, algorithm := range []string{Bcrypt, Argon2id} {
+		t.Run(algorithm, func(t *testing.T) {
+			hasher, err := NewHasher(algorithm, bcrypt.MinCost)
+			require.NoError(t, err)
+
+			hash, err := hasher.Hash("password")
+			require.NoError(t, err)
+
+			ok, err := hasher.Verify(hash, "password")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = hasher.Verify(hash, "wrong")
+			assert.NoError(t, err)
+			assert.False(t, ok)
+
+			assert.False(t, hasher.NeedsRehash(hash))
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	oldHasher, err := NewHasher(Bcrypt, bcrypt.MinCost)
+	require.NoError(t, err)
+	oldHash, err := oldHasher.Hash("password")
+	require.NoError(t, err)
+
+	strongerBcrypt, err := NewHasher(Bcrypt, bcrypt.MinCost+1)
+	require.NoError(t, err)
+	assert.True(t, strongerBcrypt.NeedsRehash(oldHash))
+
+	argon, err := NewHasher(Argon2id, bcrypt.MinCost)
+	require.NoError(t, err)
+	assert.True(t, argon.NeedsRehash(oldHash))
+
+	// Хеши прежнего алгоритма по-прежнему проверяются
+	ok, err := argon.Verify(oldHash, "password")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	argonHash, err := argon.Hash("password")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(argonHash, "$argon2id$v=19$m=19456,t=2,p=1$"))
+	assert.True(t, oldHasher.NeedsRehash(argonHash))
+}
+
+func TestNewHasherRejectsUnknownAlgorithm(t *testing.T) {
+	_, err := NewHasher("md5", 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AllowRequest проверяет лимит для каждого из ключей и отвечает 429 с
+// заголовком Retry-After, если хотя бы один из них исчерпан. Ограничитель nil
+// пропускает все запросы.
+func (l *Limiter) AllowRequest(w http.ResponseWriter, r *http.Request, keys ...string) bool {
+	if l == nil {
+		return true
+	}
+
+	for _, key := range keys {
+		allowed, retryAfter, err := l.Allow(r.Context(), key)
+		if err != nil {
+			// Недоступность хранилища лимитов не должна блокировать запросы
+			log.Printf("Rate limiter failed: %v", err)
+			continue
+		}
+		if !allowed {
+			TooManyRequests(w, retryAfter)
+			return false
+		}
+	}
+	return true
+}
+
+// TooManyRequests отвечает 429 и сообщает, через сколько секунд можно
+// повторить запрос
+func TooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, "Too many requests", http.StatusTooManyRequests)
+}
+
+// ClientIP возвращает IP-адрес клиента для ключей ограничений
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryStore хранит корзины в памяти процесса
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+	swept   time.Time
+}
+
+// NewMemoryStore создает новый экземпляр MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Take реализует Store
+func (m *MemoryStore) Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now, rate, burst)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), updated: now}
+		m.buckets[key] = b
+	}
+
+	tokens, allowed, retryAfter := take(b.tokens, b.updated, now, rate, burst)
+	b.tokens, b.updated = tokens, now
+	return allowed, retryAfter, nil
+}
+
+// sweep раз в минуту удаляет корзины, которые уже успели заполниться:
+// они неотличимы от новых
+func (m *MemoryStore) sweep(now time.Time, rate float64, burst int) {
+	if now.Sub(m.swept) < time.Minute || rate <= 0 {
+		return
+	}
+	m.swept = now
+
+	full := time.Duration(float64(burst) / rate * float64(time.Second))
+	for key, b := range m.buckets {
+		if now.Sub(b.updated) > full {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	limiter := NewLimiter(store, 6, 2) // один токен каждые 10 секунд
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := limiter.Allow(ctx, "ip:1.2.3.4")
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, retryAfter, err := limiter.Allow(ctx, "ip:1.2.3.4")
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 10*time.Second, retryAfter)
+
+	// Другие ключи не затрагиваются
+	allowed, _, _ = limiter.Allow(ctx, "ip:5.6.7.8")
+	assert.True(t, allowed)
+
+	now = now.Add(10 * time.Second)
+	allowed, _, _ = limiter.Allow(ctx, "ip:1.2.3.4")
+	assert.True(t, allowed)
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	limiter := NewLimiter(store, 60, 5)
+	limiter.Allow(context.Background(), "a")
+
+	now = now.Add(2 * time.Minute)
+	limiter.Allow(context.Background(), "b")
+
+	assert.NotContains(t, store.buckets, "a")
+	assert.Contains(t, store.buckets, "b")
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// PostgresStore хранит корзины в таблице rate_limit_buckets, чтобы лимиты
+// были общими для всех экземпляров сервиса
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore создает новый экземпляр PostgresStore
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Take реализует Store
+func (p *PostgresStore) Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO NOTHING`,
+		key, float64(burst), now)
+	if err != nil {
+		return false, 0, err
+	}
+
+	var tokens float64
+	var updated time.Time
+	err = tx.QueryRowContext(ctx,
+		"SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE",
+		key).Scan(&tokens, &updated)
+	if err != nil {
+		return false, 0, err
+	}
+
+	tokens, allowed, retryAfter := take(tokens, updated, now, rate, burst)
+	_, err = tx.ExecContext(ctx,
+		"UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3 WHERE key = $1",
+		key, tokens, now)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return allowed, retryAfter, tx.Commit()
+}
+
+// Prune удаляет корзины, не использовавшиеся с момента before
+func (p *PostgresStore) Prune(ctx context.Context, before time.Time) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM rate_limit_buckets WHERE updated_at < $1", before)
+	return err
+}
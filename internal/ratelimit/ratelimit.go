@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Store хранит состояние корзин токенов. Реализация в памяти подходит для
+// одного экземпляра сервиса, PostgresStore — для нескольких.
+type Store interface {
+	// Take забирает один токен из корзины key. Если корзина пуста, возвращает
+	// false и время, через которое появится следующий токен.
+	Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error)
+}
+
+// Limiter ограничивает частоту запросов по алгоритму token bucket
+type Limiter struct {
+	store Store
+	rate  float64 // токенов в секунду
+	burst int
+}
+
+// NewLimiter создает ограничитель, пропускающий perMinute запросов в минуту
+// с допустимым всплеском burst
+func NewLimiter(store Store, perMinute int, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		store: store,
+		rate:  float64(perMinute) / 60,
+		burst: burst,
+	}
+}
+
+// Allow проверяет, можно ли выполнить запрос с ключом key
+func (l *Limiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	return l.store.Take(ctx, key, l.rate, l.burst)
+}
+
+// take пополняет корзину за прошедшее время и пытается забрать из нее токен
+func take(tokens float64, last, now time.Time, rate float64, burst int) (float64, bool, time.Duration) {
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(float64(burst), tokens+elapsed*rate)
+	}
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+	if rate <= 0 {
+		return tokens, false, time.Duration(math.MaxInt64)
+	}
+	wait := time.Duration((1 - tokens) / rate * float64(time.Second))
+	return tokens, false, wait
+}
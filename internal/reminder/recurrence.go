@@ -0,0 +1,91 @@
+// Package reminder отправляет напоминания заметок и переносит повторяющиеся
+// напоминания на следующее время по правилу RRULE
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	// Часовые пояса напоминаний не должны зависеть от tzdata на сервере
+	_ "time/tzdata"
+
+	"github.com/teambition/rrule-go"
+)
+
+var (
+	// ErrInvalidRecurrence возвращается для правила повторения, которое не
+	// поддерживается
+	ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+	// ErrInvalidTimezone возвращается для неизвестного часового пояса
+	ErrInvalidTimezone = errors.New("invalid timezone")
+)
+
+// parseRule разбирает правило RRULE (RFC 5545) без DTSTART. COUNT не
+// поддерживается: правило считается от текущего напоминания, а не от первого,
+// поэтому конец повторений задается UNTIL. Повторы чаще раза в час запрещены:
+// и частотой MINUTELY или SECONDLY, и несколькими BYMINUTE или BYSECOND,
+// которые дают несколько повторений в каждом часе.
+func parseRule(rule string) (*rrule.ROption, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	if strings.ContainsAny(rule, "\r\n") {
+		return nil, ErrInvalidRecurrence
+	}
+	opt, err := rrule.StrToROption(rule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	if opt.Count != 0 || !opt.Dtstart.IsZero() {
+		return nil, fmt.Errorf("%w: COUNT and DTSTART are not supported, use UNTIL", ErrInvalidRecurrence)
+	}
+	if opt.Freq > rrule.HOURLY || len(opt.Byminute) > 1 || len(opt.Bysecond) > 1 {
+		return nil, fmt.Errorf("%w: reminders cannot repeat more often than hourly", ErrInvalidRecurrence)
+	}
+	return opt, nil
+}
+
+// ValidateRecurrence проверяет правило повторения напоминания
+func ValidateRecurrence(rule string) error {
+	_, err := parseRule(rule)
+	return err
+}
+
+// LoadLocation возвращает часовой пояс напоминания по имени из базы IANA,
+// например Europe/Moscow. Пустое имя означает UTC.
+func LoadLocation(name string) (*time.Location, error) {
+	// time.LoadLocation принимает и "Local" — часовой пояс сервера
+	if name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTimezone, name)
+	}
+	return loc, nil
+}
+
+// Next возвращает первое повторение по правилу rule, отсчитанному от
+// напоминания occurrence, позже after. Правило вычисляется в часовом поясе
+// timezone: FREQ=DAILY;BYHOUR=9 — это 9 часов по местному времени и после
+// перехода на летнее время. Пропущенные, пока сервис не работал, повторения
+// не возвращаются. false — повторений больше нет.
+func Next(rule, timezone string, occurrence, after time.Time) (time.Time, bool) {
+	opt, err := parseRule(rule)
+	if err != nil {
+		return time.Time{}, false
+	}
+	loc, err := LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, false
+	}
+	opt.Dtstart = occurrence.In(loc)
+	r, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if after.Before(occurrence) {
+		after = occurrence
+	}
+	next := r.After(after.In(loc), false)
+	return next, !next.IsZero()
+}
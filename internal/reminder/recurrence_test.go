@@ -0,0 +1,78 @@
+package reminder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRecurrence(t *testing.T) {
+	assert.NoError(t, ValidateRecurrence("FREQ=WEEKLY;BYDAY=MO,TH"))
+	assert.NoError(t, ValidateRecurrence("RRULE:FREQ=DAILY;INTERVAL=2;UNTIL=20300101T000000Z"))
+
+	for _, rule := range []string{
+		"",
+		"WEEKLY",
+		"FREQ=FORTNIGHTLY",
+		"FREQ=DAILY;COUNT=3",
+		"FREQ=MINUTELY",
+		"FREQ=HOURLY;BYMINUTE=0,30",
+		"FREQ=HOURLY;BYSECOND=0,30",
+		"FREQ=DAILY;BYHOUR=9;BYMINUTE=0,1",
+		"DTSTART:20240101T000000Z\nRRULE:FREQ=DAILY",
+	} {
+		assert.ErrorIs(t, ValidateRecurrence(rule), ErrInvalidRecurrence, rule)
+	}
+}
+
+func TestNext(t *testing.T) {
+	monday := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+
+	next, ok := Next("FREQ=WEEKLY;BYDAY=MO,TH", "", monday, monday)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 5, 9, 9, 0, 0, 0, time.UTC), next)
+
+	// Повторения, пропущенные, пока сервис не работал, не отправляются
+	next, ok = Next("FREQ=DAILY", "", monday, monday.Add(50*time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 5, 9, 9, 0, 0, 0, time.UTC), next)
+
+	_, ok = Next("FREQ=DAILY;UNTIL=20240506T235959Z", "", monday, monday)
+	assert.False(t, ok)
+
+	_, ok = Next("FREQ=DAILY", "Mars/Olympus", monday, monday)
+	assert.False(t, ok)
+}
+
+func TestNextInTimezone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+
+	// 30 марта 2024 года — последний день зимнего времени в Берлине:
+	// напоминание на 9 утра остается на 9 утра по местному времени
+	saturday := time.Date(2024, 3, 30, 9, 0, 0, 0, berlin)
+	next, ok := Next("FREQ=DAILY", "Europe/Berlin", saturday.UTC(), saturday)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 3, 31, 7, 0, 0, 0, time.UTC), next.UTC())
+
+	// BYDAY считается по местной дате, а не по дате в UTC
+	monday := time.Date(2024, 5, 6, 0, 30, 0, 0, berlin)
+	next, ok = Next("FREQ=WEEKLY;BYDAY=MO,TH", "Europe/Berlin", monday.UTC(), monday)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 5, 9, 0, 30, 0, 0, berlin), next.In(berlin))
+}
+
+func TestLoadLocation(t *testing.T) {
+	loc, err := LoadLocation("")
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	_, err = LoadLocation("Europe/Moscow")
+	assert.NoError(t, err)
+
+	for _, name := range []string{"Local", "Mars/Olympus", "+03:00"} {
+		_, err := LoadLocation(name)
+		assert.ErrorIs(t, err, ErrInvalidTimezone, name)
+	}
+}
@@ -0,0 +1,132 @@
+package reminder
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// leaseName — имя аренды, которую держит работающий Scheduler
+	leaseName = "reminders"
+	// leaseTTL — срок аренды; Scheduler продлевает ее каждые tickInterval,
+	// поэтому после остановки экземпляра задачу подхватит другой через leaseTTL
+	leaseTTL = time.Minute
+	// tickInterval — как часто Scheduler проверяет наступившие напоминания
+	tickInterval = 15 * time.Second
+	// batchSize — сколько напоминаний обрабатывается за запрос
+	batchSize = 100
+)
+
+// Scheduler отправляет наступившие напоминания. Напоминания отправляет только
+// экземпляр сервиса, который держит аренду в базе.
+type Scheduler struct {
+	reminders repository.ReminderRepository
+	leases    repository.LeaseRepository
+	holder    string
+	now       func() time.Time
+}
+
+// NewScheduler создает новый экземпляр Scheduler
+func NewScheduler(reminders repository.ReminderRepository, leases repository.LeaseRepository) *Scheduler {
+	return &Scheduler{reminders: reminders, leases: leases, holder: holderID(), now: time.Now}
+}
+
+// Run отправляет напоминания, пока не отменен ctx
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick продлевает аренду и, если она принадлежит Scheduler, отправляет все
+// наступившие напоминания
+func (s *Scheduler) tick(ctx context.Context) {
+	held, err := s.leases.AcquireLease(ctx, leaseName, s.holder, leaseTTL)
+	if err != nil {
+		log.Printf("Failed to acquire reminders lease: %v", err)
+		return
+	}
+	if !held {
+		return
+	}
+
+	for {
+		notes, err := s.reminders.DueReminders(ctx, s.now(), batchSize)
+		if err != nil {
+			log.Printf("Failed to fetch due reminders: %v", err)
+			return
+		}
+		fired := 0
+		for _, note := range notes {
+			if s.fire(ctx, note) {
+				fired++
+			}
+		}
+		// Повторяем, только пока очередь полна и продвигается
+		if len(notes) < batchSize || fired == 0 {
+			return
+		}
+	}
+}
+
+// fire отправляет напоминание заметки и переносит его на следующее повторение
+func (s *Scheduler) fire(ctx context.Context, note *models.Note) bool {
+	remindAt, dueAt := s.reschedule(note)
+	err := s.reminders.FireReminder(ctx, note, remindAt, dueAt)
+	if errors.Is(err, repository.ErrConflict) {
+		// Напоминание изменили после выборки: оно будет выбрано снова
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to fire reminder for note %d: %v", note.ID, err)
+		return false
+	}
+	return true
+}
+
+// reschedule возвращает напоминание и срок заметки после срабатывания.
+// Повтор уже сработавшего напоминания (отложенного раньше времени очередного)
+// расписание не меняет. Иначе повторяющееся напоминание переносится на
+// следующее повторение вместе со сроком, а разовое снимается.
+func (s *Scheduler) reschedule(note *models.Note) (remindAt, dueAt *time.Time) {
+	if note.RemindAt == nil || (note.SnoozedUntil != nil && note.SnoozedUntil.Before(*note.RemindAt)) {
+		return note.RemindAt, note.DueAt
+	}
+	if note.Recurrence == "" {
+		return nil, note.DueAt
+	}
+
+	next, ok := Next(note.Recurrence, note.Timezone, *note.RemindAt, s.now())
+	if !ok {
+		return nil, note.DueAt
+	}
+	if note.DueAt != nil {
+		due := note.DueAt.Add(next.Sub(*note.RemindAt))
+		dueAt = &due
+	}
+	return &next, dueAt
+}
+
+// holderID отличает экземпляры сервиса друг от друга при аренде
+func holderID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return host + ":" + strconv.Itoa(os.Getpid()) + ":" + hex.EncodeToString(b)
+}
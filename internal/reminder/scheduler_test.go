@@ -0,0 +1,125 @@
+package reminder
+
+import (
+	"context"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore отдает заданные напоминания и запоминает срабатывания
+type fakeStore struct {
+	due   []*models.Note
+	fired []firedReminder
+	held  bool
+	err   error
+}
+
+type firedReminder struct {
+	noteID   int64
+	remindAt *time.Time
+	dueAt    *time.Time
+}
+
+func (s *fakeStore) SetReminder(ctx context.Context, userID, noteID int64, reminder models.Reminder) error {
+	return nil
+}
+
+func (s *fakeStore) SnoozeReminder(ctx context.Context, userID, noteID int64, until time.Time) error {
+	return nil
+}
+
+func (s *fakeStore) DueReminders(ctx context.Context, now time.Time, limit int) ([]*models.Note, error) {
+	due := s.due
+	s.due = nil
+	return due, nil
+}
+
+func (s *fakeStore) FireReminder(ctx context.Context, note *models.Note, remindAt, dueAt *time.Time) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.fired = append(s.fired, firedReminder{noteID: note.ID, remindAt: remindAt, dueAt: dueAt})
+	return nil
+}
+
+func (s *fakeStore) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	return s.held, nil
+}
+
+func at(t time.Time) *time.Time {
+	return &t
+}
+
+func newTestScheduler(store *fakeStore, now time.Time) *Scheduler {
+	s := NewScheduler(store, store)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestSchedulerWithoutLeaseDoesNothing(t *testing.T) {
+	now := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	store := &fakeStore{due: []*models.Note{{ID: 1, RemindAt: at(now)}}}
+
+	newTestScheduler(store, now).tick(context.Background())
+
+	assert.Empty(t, store.fired)
+	assert.Len(t, store.due, 1)
+}
+
+func TestSchedulerFiresOneOffReminder(t *testing.T) {
+	now := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	due := now.Add(time.Hour)
+	store := &fakeStore{held: true, due: []*models.Note{{ID: 1, RemindAt: at(now), DueAt: &due}}}
+
+	newTestScheduler(store, now).tick(context.Background())
+
+	require.Len(t, store.fired, 1)
+	assert.Nil(t, store.fired[0].remindAt)
+	assert.Equal(t, due, *store.fired[0].dueAt)
+}
+
+func TestSchedulerMovesRecurringReminder(t *testing.T) {
+	monday := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	store := &fakeStore{held: true, due: []*models.Note{{
+		ID:         1,
+		RemindAt:   at(monday),
+		DueAt:      at(monday.Add(3 * time.Hour)),
+		Recurrence: "FREQ=WEEKLY",
+	}}}
+
+	newTestScheduler(store, monday.Add(time.Minute)).tick(context.Background())
+
+	require.Len(t, store.fired, 1)
+	assert.Equal(t, monday.AddDate(0, 0, 7), *store.fired[0].remindAt)
+	assert.Equal(t, monday.AddDate(0, 0, 7).Add(3*time.Hour), *store.fired[0].dueAt)
+}
+
+func TestSchedulerRepeatsSnoozedReminderWithoutMovingSchedule(t *testing.T) {
+	now := time.Date(2024, 5, 6, 9, 10, 0, 0, time.UTC)
+	nextWeek := time.Date(2024, 5, 13, 9, 0, 0, 0, time.UTC)
+	store := &fakeStore{held: true, due: []*models.Note{{
+		ID:           1,
+		RemindAt:     &nextWeek,
+		SnoozedUntil: at(now),
+		Recurrence:   "FREQ=WEEKLY",
+	}}}
+
+	newTestScheduler(store, now).tick(context.Background())
+
+	require.Len(t, store.fired, 1)
+	assert.Equal(t, nextWeek, *store.fired[0].remindAt)
+}
+
+func TestSchedulerSkipsChangedReminder(t *testing.T) {
+	now := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	store := &fakeStore{held: true, err: repository.ErrConflict, due: []*models.Note{{ID: 1, RemindAt: at(now)}}}
+
+	newTestScheduler(store, now).tick(context.Background())
+
+	assert.Empty(t, store.fired)
+}
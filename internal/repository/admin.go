@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UserSummary — пользователь с числом заметок для административного API
+type UserSummary struct {
+	*User
+	CreatedAt time.Time `json:"created_at"`
+	NoteCount int       `json:"note_count"`
+}
+
+var userSummaryQuery = `
+	SELECT ` + prefixColumns("u", userColumns) + `, u.created_at, COUNT(n.id)
+	FROM users u
+	LEFT JOIN notes n ON n.user_id = u.id AND n.deleted_at IS NULL`
+
+func scanUserSummary(row interface{ Scan(...interface{}) error }) (*UserSummary, error) {
+	var summary UserSummary
+	var createdAt sql.NullTime
+	user, err := scanUser(row, &createdAt, &summary.NoteCount)
+	if err != nil {
+		return nil, err
+	}
+	summary.User = user
+	summary.CreatedAt = createdAt.Time
+	return &summary, nil
+}
+
+// ListUsers возвращает страницу пользователей, упорядоченных по ID
+func (r *SQLUserRepository) ListUsers(ctx context.Context, limit, offset int) ([]*UserSummary, error) {
+	rows, err := r.db.QueryContext(ctx, userSummaryQuery+`
+		GROUP BY u.id
+		ORDER BY u.id
+		LIMIT $1 OFFSET $2`,
+		limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*UserSummary{}
+	for rows.Next() {
+		summary, err := scanUserSummary(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, summary)
+	}
+
+	return users, rows.Err()
+}
+
+// GetUserSummary возвращает пользователя с числом заметок
+func (r *SQLUserRepository) GetUserSummary(ctx context.Context, id int64) (*UserSummary, error) {
+	return scanUserSummary(r.db.QueryRowContext(ctx, userSummaryQuery+`
+		WHERE u.id = $1
+		GROUP BY u.id`,
+		id))
+}
+
+// SetUserDisabled отключает или снова включает аккаунт. Время отключения
+// сохраняется при повторном вызове.
+func (r *SQLUserRepository) SetUserDisabled(ctx context.Context, id int64, disabled bool) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE users SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, now()) END WHERE id = $1",
+		id, disabled)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RevokeUserTokens отзывает все выданные пользователю токены и его ключи API:
+// принудительный выход должен закрыть доступ и тому, кто завладел аккаунтом и
+// успел создать себе ключ
+func (r *SQLUserRepository) RevokeUserTokens(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		WITH keys AS (
+			UPDATE api_keys SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL)
+		UPDATE users SET token_version = token_version + 1 WHERE id = $1`,
+		id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
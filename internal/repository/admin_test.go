@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT (.+) FROM users u LEFT JOIN notes n (.+) GROUP BY u.id ORDER BY u.id LIMIT").
+		WithArgs(50, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "email", "token_version",
+			"totp_secret", "totp_enabled", "role", "disabled_at", "email_verified", "created_at", "count"}).
+			AddRow(1, "admin", "hash", nil, 0, nil, false, "admin", nil, true, now, 5).
+			AddRow(2, "alice", "hash", nil, 1, nil, false, "user", now, false, now, 0))
+
+	users, err := repo.ListUsers(context.Background(), 50, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, RoleAdmin, users[0].Role)
+	assert.Equal(t, 5, users[0].NoteCount)
+	assert.Nil(t, users[0].DisabledAt)
+	assert.NotNil(t, users[1].DisabledAt)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSetUserDisabledNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	mock.ExpectExec("UPDATE users SET disabled_at").
+		WithArgs(int64(42), true).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.SetUserDisabled(context.Background(), 42, true)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRevokeUserTokensRevokesAPIKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	mock.ExpectExec("UPDATE api_keys SET revoked_at = now\\(\\) WHERE user_id = \\$1 AND revoked_at IS NULL(.+)UPDATE users SET token_version = token_version \\+ 1 WHERE id = \\$1").
+		WithArgs(int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.RevokeUserTokens(context.Background(), 42)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAPIKeyByHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewAPIKeyRepository(db)
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT (.+) FROM api_keys WHERE key_hash = (.+) AND revoked_at IS NULL").
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "prefix", "key_hash", "scopes", "expires_at", "last_used_at", "created_at"}).
+			AddRow(3, 1, "ci", "nsk_abcdefgh", "hash", "{notes:read,notes:write}", nil, now, now))
+
+	key, err := repo.GetAPIKeyByHash(context.Background(), "hash")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"notes:read", "notes:write"}, key.Scopes)
+	assert.Nil(t, key.ExpiresAt)
+	assert.NotNil(t, key.LastUsedAt)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRevokeAPIKeyNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewAPIKeyRepository(db)
+
+	mock.ExpectExec("UPDATE api_keys SET revoked_at").
+		WithArgs(int64(3), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.RevokeAPIKey(context.Background(), 2, 3)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreateAPIKeyStoresScopes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewAPIKeyRepository(db)
+	key := &APIKey{UserID: 1, Name: "ci", Prefix: "nsk_abcdefgh", KeyHash: "hash", Scopes: []string{"notes:read"}}
+
+	mock.ExpectQuery("INSERT INTO api_keys").
+		WithArgs(int64(1), "ci", "nsk_abcdefgh", "hash", pq.Array([]string{"notes:read"}), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, time.Now()))
+
+	err = repo.CreateAPIKey(context.Background(), key)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), key.ID)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"notes-service/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrQuotaExceeded возвращается, если вложение не помещается в квоту пользователя
+var ErrQuotaExceeded = errors.New("attachment quota exceeded")
+
+const attachmentColumns = "id, note_id, user_id, filename, content_type, size, storage_key, created_at, thumbnail_status"
+
+const thumbnailColumns = "attachment_id, size, width, height, content_type, byte_size, storage_key"
+
+func scanAttachment(row interface{ Scan(...interface{}) error }) (*models.Attachment, error) {
+	var a models.Attachment
+	var thumbnailStatus sql.NullString
+	err := row.Scan(&a.ID, &a.NoteID, &a.UserID, &a.Filename, &a.ContentType, &a.Size, &a.StorageKey, &a.CreatedAt, &thumbnailStatus)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.ThumbnailStatus = thumbnailStatus.String
+	return &a, nil
+}
+
+// CheckAttachmentQuota проверяет до загрузки файла, что пользователь может
+// изменять заметку и что size байт помещаются в его квоту
+func (r *PostgresRepository) CheckAttachmentQuota(ctx context.Context, userID, noteID, size, quota int64) error {
+	note, err := r.GetNote(ctx, userID, noteID)
+	if err != nil {
+		return err
+	}
+	if note.Permission == models.PermissionRead {
+		return ErrForbidden
+	}
+
+	var used int64
+	err = r.db.QueryRowContext(ctx,
+		"SELECT COALESCE(SUM(size), 0) FROM attachments WHERE user_id = $1",
+		userID).Scan(&used)
+	if err != nil {
+		return err
+	}
+	if used+size > quota {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// CreateAttachment сохраняет вложение, уже загруженное в хранилище. Права на
+// заметку и квота проверяются повторно в том же запросе. Загрузки одного
+// пользователя сериализуются блокировкой его строки в users: иначе под READ
+// COMMITTED параллельные загрузки не видят друг друга и вместе превышают квоту.
+func (r *PostgresRepository) CreateAttachment(ctx context.Context, attachment *models.Attachment, quota int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// NO KEY UPDATE не мешает вставкам, ссылающимся на пользователя
+	if _, err := tx.ExecContext(ctx, "SELECT 1 FROM users WHERE id = $1 FOR NO KEY UPDATE", attachment.UserID); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO attachments (note_id, user_id, filename, content_type, size, storage_key, thumbnail_status)
+		SELECT n.id, $1, $3, $4, $5, $6, NULLIF($8, '') FROM notes n
+		WHERE n.id = $2 AND n.deleted_at IS NULL AND note_permission(n.id, $1) IN ('owner', 'edit')
+			AND (SELECT COALESCE(SUM(size), 0) FROM attachments WHERE user_id = $1) + $5 <= $7
+		RETURNING id, created_at`,
+		attachment.UserID, attachment.NoteID, attachment.Filename, attachment.ContentType,
+		attachment.Size, attachment.StorageKey, quota, attachment.ThumbnailStatus).
+		Scan(&attachment.ID, &attachment.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		err = r.CheckAttachmentQuota(ctx, attachment.UserID, attachment.NoteID, attachment.Size, quota)
+		if err == nil {
+			// Права и квота изменились между запросами
+			err = ErrQuotaExceeded
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListAttachments возвращает вложения заметки, доступной пользователю
+func (r *PostgresRepository) ListAttachments(ctx context.Context, userID, noteID int64) ([]*models.Attachment, error) {
+	if _, err := r.GetNote(ctx, userID, noteID); err != nil {
+		return nil, err
+	}
+	return r.NoteAttachments(ctx, noteID)
+}
+
+// NoteAttachments возвращает вложения заметки вместе с миниатюрами без
+// проверки доступа: вызывающий код уже проверил доступ к заметке
+func (r *PostgresRepository) NoteAttachments(ctx context.Context, noteID int64) ([]*models.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+attachmentColumns+" FROM attachments WHERE note_id = $1 ORDER BY id",
+		noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*models.Attachment{}
+	byID := map[int64]*models.Attachment{}
+	var ids []int64
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+		if attachment.ThumbnailStatus == models.ThumbnailReady {
+			byID[attachment.ID] = attachment
+			ids = append(ids, attachment.ID)
+		}
+	}
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return attachments, err
+	}
+
+	thumbs, err := r.db.QueryContext(ctx,
+		"SELECT "+thumbnailColumns+" FROM attachment_thumbnails WHERE attachment_id = ANY($1) ORDER BY byte_size",
+		pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer thumbs.Close()
+
+	for thumbs.Next() {
+		var attachmentID int64
+		var t models.Thumbnail
+		if err := thumbs.Scan(&attachmentID, &t.Size, &t.Width, &t.Height, &t.ContentType, &t.ByteSize, &t.StorageKey); err != nil {
+			return nil, err
+		}
+		byID[attachmentID].Thumbnails = append(byID[attachmentID].Thumbnails, &t)
+	}
+
+	return attachments, thumbs.Err()
+}
+
+// GetAttachmentBlob возвращает вложение без проверки доступа, например для
+// подписанной ссылки. Если size не пуст, StorageKey, ContentType и Size
+// относятся к миниатюре этого размера.
+func (r *PostgresRepository) GetAttachmentBlob(ctx context.Context, attachmentID int64, size string) (*models.Attachment, error) {
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx,
+		"SELECT "+attachmentColumns+" FROM attachments WHERE id = $1",
+		attachmentID))
+	if err != nil || size == "" {
+		return attachment, err
+	}
+
+	err = r.db.QueryRowContext(ctx,
+		"SELECT storage_key, content_type, byte_size FROM attachment_thumbnails WHERE attachment_id = $1 AND size = $2",
+		attachmentID, size).Scan(&attachment.StorageKey, &attachment.ContentType, &attachment.Size)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// GetAttachment возвращает вложение заметки, доступной пользователю
+func (r *PostgresRepository) GetAttachment(ctx context.Context, userID, noteID, attachmentID int64) (*models.Attachment, error) {
+	return scanAttachment(r.db.QueryRowContext(ctx, `
+		SELECT `+prefixColumns("a", attachmentColumns)+`
+		FROM attachments a
+		JOIN notes n ON n.id = a.note_id
+		WHERE a.id = $3 AND n.id = $2 AND n.deleted_at IS NULL AND note_permission(n.id, $1) IS NOT NULL`,
+		userID, noteID, attachmentID))
+}
+
+// DeleteAttachment удаляет вложение. Объект в хранилище удаляется позже
+// через orphaned_blobs.
+func (r *PostgresRepository) DeleteAttachment(ctx context.Context, userID, noteID, attachmentID int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM attachments a
+		USING notes n
+		WHERE a.note_id = n.id AND a.id = $3 AND n.id = $2 AND n.deleted_at IS NULL
+			AND note_permission(n.id, $1) IN ('owner', 'edit')`,
+		userID, noteID, attachmentID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		if _, err := r.GetAttachment(ctx, userID, noteID, attachmentID); err != nil {
+			return err
+		}
+		return ErrForbidden
+	}
+	return nil
+}
+
+// ListOrphanedBlobs возвращает ключи объектов удаленных вложений
+func (r *PostgresRepository) ListOrphanedBlobs(ctx context.Context, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT storage_key FROM orphaned_blobs ORDER BY created_at LIMIT $1",
+		limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// ForgetOrphanedBlob отмечает объект как удаленный из хранилища
+func (r *PostgresRepository) ForgetOrphanedBlob(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM orphaned_blobs WHERE storage_key = $1", key)
+	return err
+}
+
+// ClaimThumbnailJob забирает в работу самое старое изображение, ожидающее
+// миниатюр. Обработка, которая не завершилась за staleAfter, начинается
+// заново. Если ждущих изображений нет — ErrNotFound.
+func (r *PostgresRepository) ClaimThumbnailJob(ctx context.Context, staleAfter time.Duration) (*models.Attachment, error) {
+	return scanAttachment(r.db.QueryRowContext(ctx, `
+		UPDATE attachments SET thumbnail_status = 'running', thumbnail_updated_at = now()
+		WHERE id = (
+			SELECT id FROM attachments
+			WHERE thumbnail_status = 'pending' OR (thumbnail_status = 'running' AND thumbnail_updated_at < $1)
+			ORDER BY id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1)
+		RETURNING `+attachmentColumns,
+		time.Now().Add(-staleAfter)))
+}
+
+// SaveThumbnails заменяет миниатюры вложения и задает итоговое состояние
+// обработки. Объекты прежних миниатюр попадают в orphaned_blobs.
+func (r *PostgresRepository) SaveThumbnails(ctx context.Context, attachmentID int64, thumbnails []*models.Thumbnail, status string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE attachments SET thumbnail_status = $2, thumbnail_updated_at = now() WHERE id = $1`,
+		attachmentID, status)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM attachment_thumbnails WHERE attachment_id = $1", attachmentID); err != nil {
+		return err
+	}
+	for _, t := range thumbnails {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO attachment_thumbnails (`+thumbnailColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			attachmentID, t.Size, t.Width, t.Height, t.ContentType, t.ByteSize, t.StorageKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"notes-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAttachmentQuotaExceeded(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+	attachment := &models.Attachment{NoteID: 7, UserID: 1, Filename: "a.png", ContentType: "image/png", Size: 600, StorageKey: "notes/7/k"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT 1 FROM users WHERE id = \\$1 FOR NO KEY UPDATE").
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO attachments (.+) SELECT (.+) FROM notes n WHERE (.+) <= \\$7").
+		WithArgs(int64(1), int64(7), "a.png", "image/png", int64(600), "notes/7/k", int64(1000), "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+	mock.ExpectRollback()
+	mock.ExpectQuery("SELECT (.+) FROM notes WHERE id = (.+)").
+		WithArgs(int64(1), int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "workspace_id", "notebook_id", "title", "content", "content_format", "created_at", "updated_at", "deleted_at", "version", "client_id", "due_at", "remind_at", "snoozed_until", "recurrence", "reminder_timezone", "pinned", "archived", "favorite", "position", "permission"}).
+			AddRow(7, 1, nil, nil, "Note", "", models.FormatPlain, now, now, nil, 1, nil, nil, nil, nil, nil, nil, false, false, false, nil, models.PermissionOwner))
+	mock.ExpectQuery("SELECT COALESCE\\(SUM\\(size\\), 0\\) FROM attachments WHERE user_id = (.+)").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(500))
+
+	err = repo.CreateAttachment(context.Background(), attachment, 1000)
+
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAttachmentLocksUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+	attachment := &models.Attachment{NoteID: 7, UserID: 1, Filename: "a.png", ContentType: "image/png", Size: 400, StorageKey: "notes/7/k"}
+
+	// Блокировка берется до подсчета занятого места, и подсчет идет в той же
+	// транзакции, что и вставка
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT 1 FROM users WHERE id = \\$1 FOR NO KEY UPDATE").
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO attachments (.+)SUM\\(size\\)(.+) <= \\$7").
+		WithArgs(int64(1), int64(7), "a.png", "image/png", int64(400), "notes/7/k", int64(1000), "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+	mock.ExpectCommit()
+
+	err = repo.CreateAttachment(context.Background(), attachment, 1000)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), attachment.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"notes-service/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrChecklistItemNotFound возвращается, если в чек-листе заметки нет
+	// такого пункта
+	ErrChecklistItemNotFound = fmt.Errorf("checklist item %w", ErrNotFound)
+	// ErrChecklistOrder возвращается ReorderChecklist, если переданный порядок
+	// не перечисляет каждый пункт чек-листа ровно один раз
+	ErrChecklistOrder = errors.New("item_ids must list every checklist item exactly once")
+)
+
+const checklistColumns = "id, note_id, text, checked, position, created_at, updated_at"
+
+func scanChecklistItem(row interface{ Scan(...interface{}) error }) (*models.ChecklistItem, error) {
+	var item models.ChecklistItem
+	err := row.Scan(&item.ID, &item.NoteID, &item.Text, &item.Checked, &item.Position, &item.CreatedAt, &item.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChecklistItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ListChecklist возвращает пункты чек-листа заметки по порядку. Нужен любой
+// доступ к заметке.
+func (r *PostgresRepository) ListChecklist(ctx context.Context, userID, noteID int64) ([]*models.ChecklistItem, error) {
+	if _, err := r.GetNote(ctx, userID, noteID); err != nil {
+		return nil, err
+	}
+	return listChecklist(ctx, r.db, noteID)
+}
+
+func listChecklist(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}, noteID int64) ([]*models.ChecklistItem, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT "+checklistColumns+" FROM checklist_items WHERE note_id = $1 ORDER BY position", noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*models.ChecklistItem{}
+	for rows.Next() {
+		item, err := scanChecklistItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// AddChecklistItem добавляет пункт в чек-лист заметки item.NoteID на место
+// item.Position, сдвигая следующие пункты. Отрицательная или превышающая
+// длину чек-листа позиция добавляет пункт в конец. Нужен доступ owner или edit.
+func (r *PostgresRepository) AddChecklistItem(ctx context.Context, userID int64, item *models.ChecklistItem) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockNoteForEdit(ctx, tx, userID, item.NoteID); err != nil {
+		return err
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, "SELECT count(*) FROM checklist_items WHERE note_id = $1", item.NoteID).Scan(&count); err != nil {
+		return err
+	}
+	if item.Position < 0 || item.Position > count {
+		item.Position = count
+	} else if _, err := tx.ExecContext(ctx,
+		"UPDATE checklist_items SET position = position + 1 WHERE note_id = $1 AND position >= $2",
+		item.NoteID, item.Position); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO checklist_items (note_id, text, checked, position)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`,
+		item.NoteID, item.Text, item.Checked, item.Position).
+		Scan(&item.ID, &item.CreatedAt, &item.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := touchNote(ctx, tx, item.NoteID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateChecklistItem меняет текст и отметку пункта item.ID чек-листа заметки
+// item.NoteID; позиция пункта заполняется из базы. Нужен доступ owner или edit.
+func (r *PostgresRepository) UpdateChecklistItem(ctx context.Context, userID int64, item *models.ChecklistItem) error {
+	updated, err := r.changeChecklistItem(ctx, userID, item.NoteID, item.ID,
+		"text = $3, checked = $4", item.Text, item.Checked)
+	if err != nil {
+		return err
+	}
+	*item = *updated
+	return nil
+}
+
+// ToggleChecklistItem отмечает неотмеченный пункт чек-листа и снимает отметку
+// с отмеченного. Нужен доступ owner или edit.
+func (r *PostgresRepository) ToggleChecklistItem(ctx context.Context, userID, noteID, itemID int64) (*models.ChecklistItem, error) {
+	return r.changeChecklistItem(ctx, userID, noteID, itemID, "checked = NOT checked")
+}
+
+// changeChecklistItem применяет set к пункту itemID; аргументы set
+// начинаются с $3
+func (r *PostgresRepository) changeChecklistItem(ctx context.Context, userID, noteID, itemID int64, set string, args ...interface{}) (*models.ChecklistItem, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockNoteForEdit(ctx, tx, userID, noteID); err != nil {
+		return nil, err
+	}
+
+	item, err := scanChecklistItem(tx.QueryRowContext(ctx, `
+		UPDATE checklist_items SET `+set+`, updated_at = now()
+		WHERE id = $1 AND note_id = $2
+		RETURNING `+checklistColumns,
+		append([]interface{}{itemID, noteID}, args...)...))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := touchNote(ctx, tx, noteID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// DeleteChecklistItem удаляет пункт чек-листа и сдвигает следующие за ним.
+// Нужен доступ owner или edit.
+func (r *PostgresRepository) DeleteChecklistItem(ctx context.Context, userID, noteID, itemID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockNoteForEdit(ctx, tx, userID, noteID); err != nil {
+		return err
+	}
+
+	var position int
+	err = tx.QueryRowContext(ctx,
+		"DELETE FROM checklist_items WHERE id = $1 AND note_id = $2 RETURNING position",
+		itemID, noteID).Scan(&position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrChecklistItemNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE checklist_items SET position = position - 1 WHERE note_id = $1 AND position > $2",
+		noteID, position); err != nil {
+		return err
+	}
+
+	if err := touchNote(ctx, tx, noteID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReorderChecklist расставляет пункты чек-листа в порядке itemIDs и
+// возвращает чек-лист. itemIDs должен перечислять все пункты заметки ровно по
+// одному разу, иначе ErrChecklistOrder. Нужен доступ owner или edit.
+func (r *PostgresRepository) ReorderChecklist(ctx context.Context, userID, noteID int64, itemIDs []int64) ([]*models.ChecklistItem, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockNoteForEdit(ctx, tx, userID, noteID); err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, "SELECT count(*) FROM checklist_items WHERE note_id = $1", noteID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count != len(itemIDs) {
+		return nil, ErrChecklistOrder
+	}
+
+	// Повторы в itemIDs обновляют пункт один раз, поэтому затронутых строк
+	// окажется меньше, чем пунктов
+	result, err := tx.ExecContext(ctx, `
+		UPDATE checklist_items c SET position = o.ord - 1, updated_at = now()
+		FROM unnest($2::integer[]) WITH ORDINALITY AS o(id, ord)
+		WHERE c.id = o.id AND c.note_id = $1`,
+		noteID, pq.Array(itemIDs))
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err == nil && int(n) != count {
+		return nil, ErrChecklistOrder
+	}
+
+	items, err := listChecklist(ctx, tx, noteID)
+	if err != nil {
+		return nil, err
+	}
+	if err := touchNote(ctx, tx, noteID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// lockNoteForEdit блокирует заметку до конца транзакции tx, чтобы изменения
+// ее чек-листа не перемешали позиции пунктов. Без доступа owner или edit
+// возвращает ErrForbidden, а если заметка пользователю не видна — ErrNotFound.
+func lockNoteForEdit(ctx context.Context, tx *sql.Tx, userID, noteID int64) error {
+	var permission sql.NullString
+	err := tx.QueryRowContext(ctx,
+		"SELECT note_permission(id, $1) FROM notes WHERE id = $2 AND deleted_at IS NULL FOR UPDATE",
+		userID, noteID).Scan(&permission)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && !permission.Valid {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if permission.String != models.PermissionOwner && permission.String != models.PermissionEdit {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// touchNote обновляет updated_at заметки: изменение чек-листа приходит
+// клиентам событием updated заметки
+func touchNote(ctx context.Context, tx *sql.Tx, noteID int64) error {
+	_, err := tx.ExecContext(ctx, "UPDATE notes SET updated_at = now() WHERE id = $1", noteID)
+	return err
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"notes-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddChecklistItemAtPosition(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT note_permission(.+)FOR UPDATE").
+		WithArgs(int64(1), int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"note_permission"}).AddRow("edit"))
+	mock.ExpectQuery("SELECT count").
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectExec("UPDATE checklist_items SET position = position \\+ 1").
+		WithArgs(int64(7), 1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("INSERT INTO checklist_items").
+		WithArgs(int64(7), "Buy milk", false, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(11, now, now))
+	mock.ExpectExec("UPDATE notes SET updated_at").
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	item := &models.ChecklistItem{NoteID: 7, Text: "Buy milk", Position: 1}
+	err = repo.AddChecklistItem(context.Background(), 1, item)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), item.ID)
+	assert.Equal(t, 1, item.Position)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddChecklistItemAppendsPastEnd(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT note_permission").
+		WithArgs(int64(1), int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"note_permission"}).AddRow("owner"))
+	mock.ExpectQuery("SELECT count").
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery("INSERT INTO checklist_items").
+		WithArgs(int64(7), "Call mom", false, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(12, now, now))
+	mock.ExpectExec("UPDATE notes SET updated_at").
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	item := &models.ChecklistItem{NoteID: 7, Text: "Call mom", Position: -1}
+	err = repo.AddChecklistItem(context.Background(), 1, item)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, item.Position)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestToggleChecklistItemReadOnly(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT note_permission").
+		WithArgs(int64(2), int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"note_permission"}).AddRow("read"))
+	mock.ExpectRollback()
+
+	_, err = repo.ToggleChecklistItem(context.Background(), 2, 7, 3)
+
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestToggleChecklistItemMissing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT note_permission").
+		WithArgs(int64(1), int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"note_permission"}).AddRow("owner"))
+	mock.ExpectQuery("UPDATE checklist_items SET checked = NOT checked").
+		WithArgs(int64(3), int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	_, err = repo.ToggleChecklistItem(context.Background(), 1, 7, 3)
+
+	assert.ErrorIs(t, err, ErrChecklistItemNotFound)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReorderChecklistRejectsDuplicates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT note_permission").
+		WithArgs(int64(1), int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"note_permission"}).AddRow("owner"))
+	mock.ExpectQuery("SELECT count").
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectExec("UPDATE checklist_items c SET position(.+)unnest").
+		WithArgs(int64(7), pq.Array([]int64{4, 4})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	_, err = repo.ReorderChecklist(context.Background(), 1, 7, []int64{4, 4})
+
+	assert.ErrorIs(t, err, ErrChecklistOrder)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteChecklistItemShiftsFollowing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT note_permission").
+		WithArgs(int64(1), int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"note_permission"}).AddRow("edit"))
+	mock.ExpectQuery("DELETE FROM checklist_items").
+		WithArgs(int64(3), int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(1))
+	mock.ExpectExec("UPDATE checklist_items SET position = position - 1").
+		WithArgs(int64(7), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE notes SET updated_at").
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.DeleteChecklistItem(context.Background(), 1, 7, 3)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListNotesWithOpenTasks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+	openTasks := true
+
+	mock.ExpectQuery("json_agg(.+)WHERE notes.deleted_at IS NULL AND notes.user_id = \\$1 AND notes.workspace_id IS NULL AND EXISTS \\(SELECT 1 FROM checklist_items").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "workspace_id", "notebook_id", "title", "content", "content_format",
+			"created_at", "updated_at", "deleted_at", "version", "client_id", "due_at", "remind_at", "snoozed_until", "recurrence", "reminder_timezone", "pinned", "archived", "favorite", "position", "checklist"}).
+			AddRow(7, 1, nil, nil, "Groceries", "", "plain", now, now, nil, 1, nil, nil, nil, nil, nil, nil, false, false, false, nil,
+				[]byte(`[{"id":3,"note_id":7,"text":"Milk","checked":false,"position":0,"created_at":"2026-01-02T03:04:05.123456+00:00","updated_at":"2026-01-02T03:04:05.123456+00:00"}]`)).
+			AddRow(8, 1, nil, nil, "Plain", "", "plain", now, now, nil, 1, nil, nil, nil, nil, nil, nil, false, false, false, nil, nil))
+
+	notes, err := repo.ListNotes(context.Background(), 1, models.NoteFilter{HasOpenTasks: &openTasks})
+
+	assert.NoError(t, err)
+	assert.Len(t, notes, 2)
+	assert.Len(t, notes[0].Checklist, 1)
+	assert.Equal(t, "Milk", notes[0].Checklist[0].Text)
+	assert.Nil(t, notes[1].Checklist)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
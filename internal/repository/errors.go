@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound возвращается, если запись не найдена
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate возвращается при нарушении ограничения уникальности
+	ErrDuplicate = errors.New("already exists")
+	// ErrEmailTaken возвращается CreateUser, если адрес email уже занят
+	// другим пользователем; это частный случай ErrDuplicate
+	ErrEmailTaken = fmt.Errorf("email %w", ErrDuplicate)
+	// ErrInvalidCredentials возвращается ValidateUser одинаково для неизвестного
+	// пользователя и неверного пароля
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrForbidden возвращается, если запись видна пользователю, но его прав
+	// недостаточно для операции
+	ErrForbidden = errors.New("forbidden")
+	// ErrConflict возвращается, если запись изменилась после версии, которую
+	// видел клиент
+	ErrConflict = errors.New("version conflict")
+)
+
+// uniqueViolation — код ошибки PostgreSQL unique_violation
+const uniqueViolation = "23505"
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// isUniqueViolationOn сообщает, нарушено ли ограничение уникальности constraint
+func isUniqueViolationOn(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == constraint
+}
+
+// prefixColumns добавляет псевдоним таблицы к списку колонок для запросов с JOIN
+func prefixColumns(alias, columns string) string {
+	parts := strings.Split(columns, ", ")
+	for i, column := range parts {
+		parts[i] = alias + "." + column
+	}
+	return strings.Join(parts, ", ")
+}
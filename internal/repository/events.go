@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"notes-service/internal/models"
+	"strconv"
+	"time"
+)
+
+// NoteEventWatermark возвращает позицию «сейчас»: события транзакций,
+// завершенных до нее, в поток после этой позиции не попадут
+func (r *PostgresRepository) NoteEventWatermark(ctx context.Context) (models.NoteEventCursor, error) {
+	var xmin string
+	err := r.db.QueryRowContext(ctx, "SELECT pg_snapshot_xmin(pg_current_snapshot())::text").Scan(&xmin)
+	return models.NoteEventCursor{XID: parseXID(xmin)}, err
+}
+
+// OldestNoteEventXID возвращает ID транзакции самого старого хранящегося
+// события или 0. События более ранних транзакций уже удалены PruneNoteEvents.
+func (r *PostgresRepository) OldestNoteEventXID(ctx context.Context) (uint64, error) {
+	var xid sql.NullString
+	err := r.db.QueryRowContext(ctx, "SELECT xid::text FROM note_events ORDER BY xid LIMIT 1").Scan(&xid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return parseXID(xid.String), err
+}
+
+// ListNoteEvents возвращает не больше limit событий пользователя после
+// позиции after. Отдаются только события транзакций старше границы
+// pg_snapshot_xmin: все они уже завершены, и новых событий до этой границы не
+// появится. pending сообщает, что дальше есть события, которые ждут
+// завершения более старой транзакции; их нужно запросить повторно.
+func (r *PostgresRepository) ListNoteEvents(ctx context.Context, userID int64, after models.NoteEventCursor, limit int) (events []*models.NoteEvent, pending bool, err error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, note_id, type, created_at, xid::text, xid < pg_snapshot_xmin(pg_current_snapshot())
+		FROM note_events
+		WHERE user_id = $1 AND (xid, id) > ($2::xid8, $3)
+		ORDER BY xid, id
+		LIMIT $4`,
+		userID, strconv.FormatUint(after.XID, 10), after.ID, limit)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.NoteEvent
+		var xid string
+		var ready bool
+		if err := rows.Scan(&e.ID, &e.NoteID, &e.Type, &e.CreatedAt, &xid, &ready); err != nil {
+			return nil, false, err
+		}
+		if !ready {
+			// Следующие события еще новее
+			pending = true
+			break
+		}
+		e.XID = parseXID(xid)
+		events = append(events, &e)
+	}
+
+	return events, pending, rows.Err()
+}
+
+// PruneNoteEvents удаляет события старше before и возвращает их количество
+func (r *PostgresRepository) PruneNoteEvents(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM note_events WHERE created_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"notes-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListNoteEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT id, note_id, type, created_at, xid::text, xid < pg_snapshot_xmin\\(pg_current_snapshot\\(\\)\\) FROM note_events WHERE user_id = (.+) AND \\(xid, id\\) > (.+) ORDER BY xid, id").
+		WithArgs(int64(1), "100", int64(5), 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "note_id", "type", "created_at", "xid", "ready"}).
+			AddRow(6, 10, "created", now, "100", true).
+			AddRow(8, 10, "deleted", now, "102", true))
+
+	events, pending, err := repo.ListNoteEvents(context.Background(), 1, models.NoteEventCursor{XID: 100, ID: 5}, 100)
+
+	assert.NoError(t, err)
+	assert.False(t, pending)
+	assert.Len(t, events, 2)
+	assert.Equal(t, int64(6), events[0].ID)
+	assert.Equal(t, models.NoteEventCursor{XID: 102, ID: 8}, events[1].Cursor())
+	assert.Equal(t, models.NoteDeleted, events[1].Type)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListNoteEventsWaitsForOverlappingTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	// Транзакция 101 записала событие 12 и зафиксирована, а транзакция 103,
+	// получившая событие 11 раньше, еще идет: граница xmin равна 103.
+	// Событие 13 транзакции 104 отдавать нельзя, пока не видно события 11.
+	mock.ExpectQuery("FROM note_events").
+		WithArgs(int64(1), "100", int64(5), 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "note_id", "type", "created_at", "xid", "ready"}).
+			AddRow(12, 10, "updated", now, "101", true).
+			AddRow(13, 11, "updated", now, "104", false))
+
+	events, pending, err := repo.ListNoteEvents(context.Background(), 1, models.NoteEventCursor{XID: 100, ID: 5}, 100)
+
+	assert.NoError(t, err)
+	assert.True(t, pending)
+	assert.Len(t, events, 1)
+	assert.Equal(t, int64(12), events[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPruneNoteEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	before := time.Now().Add(-time.Hour)
+
+	mock.ExpectExec("DELETE FROM note_events WHERE created_at < (.+)").
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	pruned, err := repo.PruneNoteEvents(context.Background(), before)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), pruned)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
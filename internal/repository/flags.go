@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"notes-service/internal/models"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+var noteFlags = []string{models.FlagPinned, models.FlagArchived, models.FlagFavorite}
+
+// SetNoteFlag ставит или снимает отметку заметки: models.FlagPinned,
+// models.FlagArchived или models.FlagFavorite. Отметки хранятся в
+// note_user_flags и у каждого пользователя свои, поэтому их может менять
+// любой, кто видит заметку; сама заметка при этом не меняется.
+func (r *PostgresRepository) SetNoteFlag(ctx context.Context, userID, noteID int64, flag string, value bool) error {
+	if !slices.Contains(noteFlags, flag) {
+		return fmt.Errorf("unknown note flag %q", flag)
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO note_user_flags (user_id, note_id, `+flag+`)
+		SELECT $1, id, $3 FROM notes
+		WHERE id = $2 AND deleted_at IS NULL AND note_permission(id, $1) IS NOT NULL
+		ON CONFLICT (user_id, note_id) DO UPDATE SET `+flag+` = EXCLUDED.`+flag,
+		userID, noteID, value)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ReorderNotes задает ручной порядок заметок пользователя: position заметок
+// noteIDs становится равен их номеру в списке. Остальные заметки не меняются.
+// Если какая-то из заметок не найдена, повторяется или недоступна
+// пользователю, порядок не меняется и возвращается ErrNotFound.
+func (r *PostgresRepository) ReorderNotes(ctx context.Context, userID int64, noteIDs []int64) error {
+	seen := make(map[int64]bool, len(noteIDs))
+	for _, id := range noteIDs {
+		if seen[id] {
+			return ErrNotFound
+		}
+		seen[id] = true
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO note_user_flags (user_id, note_id, position)
+		SELECT $1, n.id, o.ord - 1
+		FROM unnest($2::integer[]) WITH ORDINALITY AS o(id, ord)
+		JOIN notes n ON n.id = o.id
+		WHERE n.deleted_at IS NULL AND note_permission(n.id, $1) IS NOT NULL
+		ON CONFLICT (user_id, note_id) DO UPDATE SET position = EXCLUDED.position`,
+		userID, pq.Array(noteIDs))
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && int(n) != len(noteIDs) {
+		return ErrNotFound
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"notes-service/internal/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetNoteFlag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectExec("INSERT INTO note_user_flags \\(user_id, note_id, archived\\)(.+)note_permission\\(id, \\$1\\) IS NOT NULL(.+)ON CONFLICT \\(user_id, note_id\\) DO UPDATE SET archived = EXCLUDED.archived").
+		WithArgs(int64(1), int64(7), true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.SetNoteFlag(context.Background(), 1, 7, models.FlagArchived, true)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetNoteFlagRejectsUnknownFlag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	err = repo.SetNoteFlag(context.Background(), 1, 7, "deleted_at", true)
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetNoteFlagNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectExec("INSERT INTO note_user_flags \\(user_id, note_id, pinned\\)").
+		WithArgs(int64(2), int64(7), true).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.SetNoteFlag(context.Background(), 2, 7, models.FlagPinned, true)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReorderNotesRejectsInaccessible(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO note_user_flags \\(user_id, note_id, position\\)(.+)unnest(.+)DO UPDATE SET position = EXCLUDED.position").
+		WithArgs(int64(1), pq.Array([]int64{9, 42})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	err = repo.ReorderNotes(context.Background(), 1, []int64{9, 42})
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReorderNotesRejectsDuplicates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	err = repo.ReorderNotes(context.Background(), 1, []int64{9, 42, 9})
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListNotesExcludesArchived(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	archived, favorite := false, true
+
+	mock.ExpectQuery("LEFT JOIN note_user_flags f ON f.note_id = notes.id AND f.user_id = \\$1\\s+"+
+		"WHERE notes.deleted_at IS NULL AND notes.user_id = \\$1 AND notes.workspace_id IS NULL AND "+
+		"COALESCE\\(f.archived, false\\) = \\$2 AND COALESCE\\(f.favorite, false\\) = \\$3\\s+"+
+		"ORDER BY COALESCE\\(f.pinned, false\\) DESC, f.position NULLS LAST, notes.created_at DESC").
+		WithArgs(int64(1), false, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	notes, err := repo.ListNotes(context.Background(), 1, models.NoteFilter{Archived: &archived, Favorite: &favorite})
+
+	assert.NoError(t, err)
+	assert.Empty(t, notes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+)
+
+// GetUserByIdentity возвращает пользователя, связанного с внешней учетной
+// записью subject у провайдера provider (issuer OIDC)
+func (r *SQLUserRepository) GetUserByIdentity(ctx context.Context, provider, subject string) (*User, error) {
+	return scanUser(r.db.QueryRowContext(ctx, `
+		SELECT `+prefixColumns("u", userColumns)+`
+		FROM identities i
+		JOIN users u ON u.id = i.user_id
+		WHERE i.provider = $1 AND i.subject = $2`,
+		provider, subject))
+}
+
+// CreateIdentity связывает внешнюю учетную запись с пользователем
+func (r *SQLUserRepository) CreateIdentity(ctx context.Context, userID int64, provider, subject, email string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, NULLIF($4, ''))`,
+		userID, provider, subject, email)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"notes-service/internal/models"
+	"time"
+)
+
+const importJobColumns = "id, user_id, status, format, skip_spellcheck, total, processed, imported, skipped, failed, errors, error, created_at, updated_at, finished_at"
+
+func scanImportJob(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.ImportJob, error) {
+	var job models.ImportJob
+	var errorsJSON []byte
+	var jobError sql.NullString
+	var finishedAt sql.NullTime
+	dest := []interface{}{&job.ID, &job.UserID, &job.Status, &job.Format, &job.SkipSpellcheck,
+		&job.Total, &job.Processed, &job.Imported, &job.Skipped, &job.Failed, &errorsJSON, &jobError,
+		&job.CreatedAt, &job.UpdatedAt, &finishedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(errorsJSON, &job.Errors); err != nil {
+		return nil, err
+	}
+	job.Error = jobError.String
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
+
+// CreateImportJob ставит в очередь задание импорта загруженного файла
+func (r *PostgresRepository) CreateImportJob(ctx context.Context, job *models.ImportJob, payload []byte) error {
+	job.Status = models.ImportPending
+	job.Errors = []models.ImportItemError{}
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO import_jobs (user_id, format, skip_spellcheck, payload)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`,
+		job.UserID, job.Format, job.SkipSpellcheck, payload).
+		Scan(&job.ID, &job.CreatedAt, &job.UpdatedAt)
+}
+
+// GetImportJob возвращает задание импорта пользователя
+func (r *PostgresRepository) GetImportJob(ctx context.Context, userID, jobID int64) (*models.ImportJob, error) {
+	return scanImportJob(r.db.QueryRowContext(ctx,
+		"SELECT "+importJobColumns+" FROM import_jobs WHERE id = $1 AND user_id = $2",
+		jobID, userID))
+}
+
+// ListImportJobs возвращает задания импорта пользователя, новые первыми
+func (r *PostgresRepository) ListImportJobs(ctx context.Context, userID int64) ([]*models.ImportJob, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+importJobColumns+" FROM import_jobs WHERE user_id = $1 ORDER BY id DESC",
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*models.ImportJob{}
+	for rows.Next() {
+		job, err := scanImportJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// ClaimImportJob забирает в работу самое старое ожидающее задание и
+// возвращает его вместе с загруженным файлом. Задание, которое выполняется,
+// но не обновлялось дольше staleAfter (обработчик завершился аварийно),
+// начинается заново. Если заданий нет — ErrNotFound.
+func (r *PostgresRepository) ClaimImportJob(ctx context.Context, staleAfter time.Duration) (*models.ImportJob, []byte, error) {
+	var payload []byte
+	job, err := scanImportJob(r.db.QueryRowContext(ctx, `
+		UPDATE import_jobs
+		SET status = 'running', total = 0, processed = 0, imported = 0, skipped = 0, failed = 0,
+			errors = '[]', updated_at = now()
+		WHERE id = (
+			SELECT id FROM import_jobs
+			WHERE status = 'pending' OR (status = 'running' AND updated_at < $1)
+			ORDER BY id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1)
+		RETURNING `+importJobColumns+`, payload`,
+		time.Now().Add(-staleAfter)), &payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, payload, nil
+}
+
+// SaveImportProgress сохраняет счетчики и ошибки задания. Для завершенного
+// задания загруженный файл удаляется.
+func (r *PostgresRepository) SaveImportProgress(ctx context.Context, job *models.ImportJob) error {
+	errorsJSON, err := json.Marshal(job.Errors)
+	if err != nil {
+		return err
+	}
+
+	finished := job.Status == models.ImportCompleted || job.Status == models.ImportFailed
+	return r.db.QueryRowContext(ctx, `
+		UPDATE import_jobs
+		SET status = $2, total = $3, processed = $4, imported = $5, skipped = $6, failed = $7,
+			errors = $8, error = NULLIF($9, ''), updated_at = now(),
+			finished_at = CASE WHEN $10 THEN now() END,
+			payload = CASE WHEN $10 THEN NULL ELSE payload END
+		WHERE id = $1
+		RETURNING updated_at`,
+		job.ID, job.Status, job.Total, job.Processed, job.Imported, job.Skipped, job.Failed,
+		errorsJSON, job.Error, finished).Scan(&job.UpdatedAt)
+}
+
+// NoteExists сообщает, есть ли у пользователя личная заметка с тем же
+// заголовком и текстом. Используется импортом для пропуска дубликатов.
+func (r *PostgresRepository) NoteExists(ctx context.Context, userID int64, title, content string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM notes
+			WHERE user_id = $1 AND workspace_id IS NULL AND deleted_at IS NULL
+				AND title = $2 AND md5(content) = md5($3))`,
+		userID, title, content).Scan(&exists)
+	return exists, err
+}
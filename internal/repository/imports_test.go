@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"notes-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimImportJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery("UPDATE import_jobs SET status = 'running'(.+)FOR UPDATE SKIP LOCKED(.+)RETURNING (.+), payload").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "status", "format", "skip_spellcheck", "total",
+			"processed", "imported", "skipped", "failed", "errors", "error", "created_at", "updated_at", "finished_at", "payload"}).
+			AddRow(3, 1, "running", "json", true, 0, 0, 0, 0, 0, []byte("[]"), nil, now, now, nil, []byte("[]")))
+
+	job, payload, err := repo.ClaimImportJob(context.Background(), 5*time.Minute)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), job.ID)
+	assert.Equal(t, models.ImportRunning, job.Status)
+	assert.True(t, job.SkipSpellcheck)
+	assert.Empty(t, job.Errors)
+	assert.Nil(t, job.FinishedAt)
+	assert.Equal(t, []byte("[]"), payload)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimImportJobEmptyQueue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectQuery("UPDATE import_jobs").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, _, err = repo.ClaimImportJob(context.Background(), 5*time.Minute)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// AcquireLease берет или продлевает аренду фоновой задачи name на ttl.
+// Возвращает true, если аренда принадлежит holder: она была свободна, истекла
+// или уже принадлежала ему.
+func (r *PostgresRepository) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	var owner string
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO scheduler_leases (name, holder, expires_at)
+		VALUES ($1, $2, now() + $3 * interval '1 millisecond')
+		ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE scheduler_leases.holder = EXCLUDED.holder OR scheduler_leases.expires_at < now()
+		RETURNING holder`,
+		name, holder, ttl.Milliseconds()).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
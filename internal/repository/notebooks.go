@@ -0,0 +1,415 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"notes-service/internal/models"
+	"time"
+)
+
+var (
+	// ErrNotebookNotFound возвращается, если блокнот не существует, находится
+	// в корзине или принадлежит другому пользователю
+	ErrNotebookNotFound = fmt.Errorf("notebook %w", ErrNotFound)
+	// ErrCycle возвращается при попытке переместить блокнот внутрь самого себя
+	// или своего потомка
+	ErrCycle = errors.New("notebook cannot be moved into its own subtree")
+)
+
+const notebookColumns = "id, parent_id, name, created_at, updated_at, deleted_at"
+
+// subtreeQuery выбирает блокнот $1 и всех его потомков
+const subtreeQuery = `
+	WITH RECURSIVE subtree AS (
+		SELECT id FROM notebooks WHERE id = $1
+		UNION ALL
+		SELECT n.id FROM notebooks n JOIN subtree s ON n.parent_id = s.id
+	)`
+
+func scanNotebook(row interface{ Scan(...interface{}) error }) (*models.Notebook, error) {
+	var notebook models.Notebook
+	var parentID sql.NullInt64
+	var deletedAt sql.NullTime
+	err := row.Scan(&notebook.ID, &parentID, &notebook.Name,
+		&notebook.CreatedAt, &notebook.UpdatedAt, &deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotebookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		notebook.ParentID = &parentID.Int64
+	}
+	if deletedAt.Valid {
+		notebook.DeletedAt = &deletedAt.Time
+	}
+	return &notebook, nil
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// requireNotebook проверяет, что блокнот принадлежит пользователю и не в корзине
+func requireNotebook(ctx context.Context, q querier, userID, notebookID int64) error {
+	var exists bool
+	err := q.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM notebooks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)",
+		notebookID, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotebookNotFound
+	}
+	return nil
+}
+
+// CreateNotebook создает блокнот в корне или внутри блокнота ParentID
+func (r *PostgresRepository) CreateNotebook(ctx context.Context, userID int64, notebook *models.Notebook) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO notebooks (user_id, parent_id, name)
+		SELECT $1, $2, $3
+		WHERE $2::integer IS NULL OR EXISTS (
+			SELECT 1 FROM notebooks WHERE id = $2 AND user_id = $1 AND deleted_at IS NULL)
+		RETURNING id, created_at, updated_at`,
+		userID, notebook.ParentID, notebook.Name).
+		Scan(&notebook.ID, &notebook.CreatedAt, &notebook.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotebookNotFound
+	}
+	return err
+}
+
+// ListNotebooks возвращает все блокноты пользователя вне корзины; дерево
+// восстанавливается клиентом по ParentID
+func (r *PostgresRepository) ListNotebooks(ctx context.Context, userID int64) ([]*models.Notebook, error) {
+	return r.queryNotebooks(ctx, `
+		SELECT `+notebookColumns+`
+		FROM notebooks
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY name`,
+		userID)
+}
+
+func (r *PostgresRepository) queryNotebooks(ctx context.Context, query string, args ...interface{}) ([]*models.Notebook, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notebooks := []*models.Notebook{}
+	for rows.Next() {
+		notebook, err := scanNotebook(rows)
+		if err != nil {
+			return nil, err
+		}
+		notebooks = append(notebooks, notebook)
+	}
+
+	return notebooks, rows.Err()
+}
+
+// GetNotebook возвращает блокнот пользователя вне корзины
+func (r *PostgresRepository) GetNotebook(ctx context.Context, userID, notebookID int64) (*models.Notebook, error) {
+	return scanNotebook(r.db.QueryRowContext(ctx, `
+		SELECT `+notebookColumns+`
+		FROM notebooks
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`,
+		notebookID, userID))
+}
+
+// RenameNotebook меняет название блокнота
+func (r *PostgresRepository) RenameNotebook(ctx context.Context, userID, notebookID int64, name string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE notebooks SET name = $3, updated_at = now()
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`,
+		notebookID, userID, name)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotebookNotFound
+	}
+	return nil
+}
+
+// MoveNotebook переносит блокнот внутрь parentID или в корень (parentID == nil).
+// Блокноты пользователя блокируются на время проверки, чтобы два встречных
+// перемещения не образовали цикл.
+func (r *PostgresRepository) MoveNotebook(ctx context.Context, userID, notebookID int64, parentID *int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id FROM notebooks WHERE user_id = $1 FOR UPDATE", userID)
+	if err != nil {
+		return err
+	}
+	rows.Close()
+
+	if err := requireNotebook(ctx, tx, userID, notebookID); err != nil {
+		return err
+	}
+	if parentID != nil {
+		if err := requireNotebook(ctx, tx, userID, *parentID); err != nil {
+			return err
+		}
+
+		var cycle bool
+		err := tx.QueryRowContext(ctx,
+			subtreeQuery+" SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)",
+			notebookID, *parentID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrCycle
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE notebooks SET parent_id = $2, updated_at = now() WHERE id = $1",
+		notebookID, parentID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MoveNote переносит личную заметку в блокнот notebookID или убирает ее из
+// блокнота (notebookID == nil). Заметки пространств в блокноты не раскладываются.
+func (r *PostgresRepository) MoveNote(ctx context.Context, userID, noteID int64, notebookID *int64) error {
+	if notebookID != nil {
+		if err := requireNotebook(ctx, r.db, userID, *notebookID); err != nil {
+			return err
+		}
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE notes SET notebook_id = $3, updated_at = now()
+		WHERE id = $2 AND user_id = $1 AND workspace_id IS NULL AND deleted_at IS NULL`,
+		userID, noteID, notebookID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return r.accessError(ctx, userID, noteID)
+	}
+	return nil
+}
+
+// TrashNotebook перемещает в корзину блокнот со всеми вложенными блокнотами и
+// заметками. Все элементы получают одну отметку deleted_at, по которой
+// RestoreNotebook восстанавливает их вместе.
+func (r *PostgresRepository) TrashNotebook(ctx context.Context, userID, notebookID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := requireNotebook(ctx, tx, userID, notebookID); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, subtreeQuery+`
+		UPDATE notes SET deleted_at = now()
+		WHERE notebook_id IN (SELECT id FROM subtree) AND deleted_at IS NULL`,
+		notebookID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, subtreeQuery+`
+		UPDATE notebooks SET deleted_at = now()
+		WHERE id IN (SELECT id FROM subtree) AND deleted_at IS NULL`,
+		notebookID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListTrash возвращает блокноты и заметки в корзине пользователя, включая
+// заметки пространств, где он владелец
+func (r *PostgresRepository) ListTrash(ctx context.Context, userID int64) (*models.Trash, error) {
+	notebooks, err := r.queryNotebooks(ctx, `
+		SELECT `+notebookColumns+`
+		FROM notebooks
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+noteColumns+`
+		FROM notes
+		WHERE deleted_at IS NOT NULL AND (
+			(workspace_id IS NULL AND user_id = $1) OR
+			workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1 AND role = 'owner'))
+		ORDER BY deleted_at DESC`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trash := &models.Trash{Notebooks: notebooks, Notes: []*models.Note{}}
+	for rows.Next() {
+		note, err := scanNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		trash.Notes = append(trash.Notes, note)
+	}
+
+	return trash, rows.Err()
+}
+
+// RestoreNote возвращает заметку из корзины. Если ее блокнот все еще в
+// корзине, заметка восстанавливается в корень.
+func (r *PostgresRepository) RestoreNote(ctx context.Context, userID, noteID int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE notes SET deleted_at = NULL,
+			notebook_id = CASE WHEN EXISTS (
+				SELECT 1 FROM notebooks b WHERE b.id = notes.notebook_id AND b.deleted_at IS NULL)
+				THEN notebook_id END
+		WHERE id = $2 AND deleted_at IS NOT NULL AND note_permission(id, $1) = 'owner'`,
+		userID, noteID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// trashedNotebook возвращает отметку удаления блокнота пользователя из корзины
+func trashedNotebook(ctx context.Context, q querier, userID, notebookID int64) (time.Time, error) {
+	var deletedAt time.Time
+	err := q.QueryRowContext(ctx,
+		"SELECT deleted_at FROM notebooks WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL",
+		notebookID, userID).Scan(&deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, ErrNotebookNotFound
+	}
+	return deletedAt, err
+}
+
+// RestoreNotebook возвращает из корзины блокнот вместе с теми вложенными
+// блокнотами и заметками, которые были удалены вместе с ним. Если
+// родительский блокнот все еще в корзине, блокнот восстанавливается в корень.
+func (r *PostgresRepository) RestoreNotebook(ctx context.Context, userID, notebookID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deletedAt, err := trashedNotebook(ctx, tx, userID, notebookID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, subtreeQuery+`
+		UPDATE notes SET deleted_at = NULL
+		WHERE notebook_id IN (SELECT id FROM subtree) AND deleted_at = $2`,
+		notebookID, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, subtreeQuery+`
+		UPDATE notebooks SET deleted_at = NULL
+		WHERE id IN (SELECT id FROM subtree) AND deleted_at = $2`,
+		notebookID, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE notebooks SET parent_id = NULL
+		WHERE id = $1 AND EXISTS (
+			SELECT 1 FROM notebooks p WHERE p.id = notebooks.parent_id AND p.deleted_at IS NOT NULL)`,
+		notebookID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PurgeNote окончательно удаляет заметку из корзины
+func (r *PostgresRepository) PurgeNote(ctx context.Context, userID, noteID int64) error {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM notes WHERE id = $2 AND deleted_at IS NOT NULL AND note_permission(id, $1) = 'owner'",
+		userID, noteID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// PurgeNotebook окончательно удаляет блокнот из корзины вместе с вложенными
+// блокнотами и заметками, находящимися в корзине
+func (r *PostgresRepository) PurgeNotebook(ctx context.Context, userID, notebookID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := trashedNotebook(ctx, tx, userID, notebookID); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, subtreeQuery+`
+		DELETE FROM notes
+		WHERE notebook_id IN (SELECT id FROM subtree) AND deleted_at IS NOT NULL`,
+		notebookID)
+	if err != nil {
+		return err
+	}
+
+	// Вложенные блокноты удаляются каскадно
+	if _, err := tx.ExecContext(ctx, "DELETE FROM notebooks WHERE id = $1", notebookID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PurgeTrash окончательно удаляет элементы, попавшие в корзину раньше before,
+// и возвращает их количество
+func (r *PostgresRepository) PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	notes, err := r.db.ExecContext(ctx,
+		"DELETE FROM notes WHERE deleted_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	notebooks, err := r.db.ExecContext(ctx,
+		"DELETE FROM notebooks WHERE deleted_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	deletedNotes, _ := notes.RowsAffected()
+	deletedNotebooks, _ := notebooks.RowsAffected()
+	return deletedNotes + deletedNotebooks, nil
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoveNotebookRejectsCycle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	parentID := int64(5)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM notebooks WHERE user_id = (.+) FOR UPDATE").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(5))
+	mock.ExpectQuery("SELECT EXISTS (.+) FROM notebooks WHERE id = (.+) AND deleted_at IS NULL").
+		WithArgs(int64(2), int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("SELECT EXISTS (.+) FROM notebooks WHERE id = (.+) AND deleted_at IS NULL").
+		WithArgs(int64(5), int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("WITH RECURSIVE subtree (.+) SELECT EXISTS").
+		WithArgs(int64(2), int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	err = repo.MoveNotebook(context.Background(), 1, 2, &parentID)
+
+	assert.ErrorIs(t, err, ErrCycle)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTrashNotebookTrashesSubtree(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT EXISTS (.+) FROM notebooks").
+		WithArgs(int64(2), int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("WITH RECURSIVE subtree (.+) UPDATE notes SET deleted_at = now()").
+		WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("WITH RECURSIVE subtree (.+) UPDATE notebooks SET deleted_at = now()").
+		WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err = repo.TrashNotebook(context.Background(), 1, 2)
+
+	assert.NoError(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRestoreNotebookRestoresItemsTrashedTogether(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	deletedAt := time.Now().Add(-time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT deleted_at FROM notebooks").
+		WithArgs(int64(2), int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}).AddRow(deletedAt))
+	mock.ExpectExec("UPDATE notes SET deleted_at = NULL").
+		WithArgs(int64(2), deletedAt).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("UPDATE notebooks SET deleted_at = NULL").
+		WithArgs(int64(2), deletedAt).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE notebooks SET parent_id = NULL").
+		WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err = repo.RestoreNotebook(context.Background(), 1, 2)
+
+	assert.NoError(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	before := time.Now().Add(-30 * 24 * time.Hour)
+
+	mock.ExpectExec("DELETE FROM notes WHERE deleted_at < (.+)").
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("DELETE FROM notebooks WHERE deleted_at < (.+)").
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	purged, err := repo.PurgeTrash(context.Background(), before)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), purged)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
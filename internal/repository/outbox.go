@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"notes-service/internal/models"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ClaimOutboxEvents забирает для публикации до limit самых старых
+// неопубликованных событий, время попытки которых наступило, и закрепляет их
+// за вызывающим на lease. Если публикация не подтверждена за это время,
+// события забираются снова.
+func (r *PostgresRepository) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]*models.DomainEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE outbox SET locked_until = now() + $2 * interval '1 millisecond', attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE published_at IS NULL AND next_attempt_at <= now()
+				AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY id
+			FOR UPDATE SKIP LOCKED
+			LIMIT $1)
+		RETURNING id, type, aggregate_id, payload, created_at, attempts`,
+		limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*models.DomainEvent{}
+	for rows.Next() {
+		var event models.DomainEvent
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.Type, &event.AggregateID, &payload, &event.OccurredAt, &event.Attempts); err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// UPDATE ... RETURNING не сохраняет порядок подзапроса
+	slices.SortFunc(events, func(a, b *models.DomainEvent) int { return cmp.Compare(a.ID, b.ID) })
+	return events, nil
+}
+
+// MarkOutboxPublished отмечает события опубликованными
+func (r *PostgresRepository) MarkOutboxPublished(ctx context.Context, ids []int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox SET published_at = now(), locked_until = NULL, last_error = NULL
+		WHERE id = ANY($1)`,
+		pq.Array(ids))
+	return err
+}
+
+// RetryOutboxEvents снимает закрепление с неопубликованных событий и
+// откладывает следующую попытку до retryAt
+func (r *PostgresRepository) RetryOutboxEvents(ctx context.Context, ids []int64, lastError string, retryAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox SET locked_until = NULL, next_attempt_at = $2, last_error = NULLIF($3, '')
+		WHERE id = ANY($1) AND published_at IS NULL`,
+		pq.Array(ids), retryAt, lastError)
+	return err
+}
+
+// PruneOutbox удаляет опубликованные события старше before и возвращает их число
+func (r *PostgresRepository) PruneOutbox(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM outbox WHERE published_at < $1",
+		before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimOutboxEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery("UPDATE outbox SET locked_until = (.+)published_at IS NULL(.+)FOR UPDATE SKIP LOCKED(.+)RETURNING").
+		WithArgs(100, int64(60000)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "aggregate_id", "payload", "created_at", "attempts"}).
+			AddRow(12, "note.updated", 7, []byte(`{"id":7}`), now, 1).
+			AddRow(11, "note.created", 7, []byte(`{"id":7}`), now, 2))
+
+	events, err := repo.ClaimOutboxEvents(context.Background(), 100, time.Minute)
+
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(11), events[0].ID)
+	assert.Equal(t, "note.created", events[0].Type)
+	assert.Equal(t, 2, events[0].Attempts)
+	assert.Equal(t, int64(12), events[1].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetryOutboxEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	retryAt := time.Now().Add(time.Minute)
+
+	mock.ExpectExec("UPDATE outbox SET locked_until = NULL, next_attempt_at = \\$2(.+)published_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), retryAt, "broker unavailable").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err = repo.RetryOutboxEvents(context.Background(), []int64{11, 12}, "broker unavailable", retryAt)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,7 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"notes-service/internal/models"
+	"slices"
+	"strconv"
+	"strings"
 
 	_ "github.com/lib/pq"
 )
@@ -35,48 +40,274 @@ func (r *PostgresRepository) Close() error {
 	return r.db.Close()
 }
 
-// CreateNote создает новую заметку в базе данных
+const noteColumns = "id, user_id, workspace_id, notebook_id, title, content, content_format, created_at, updated_at, deleted_at, version, client_id, due_at, remind_at, snoozed_until, recurrence, reminder_timezone"
+
+// noteFlagColumns — отметки заметки пользователя из note_user_flags f,
+// присоединенной через LEFT JOIN. Читаются scanNoteWithFlags.
+const noteFlagColumns = "COALESCE(f.pinned, false), COALESCE(f.archived, false), COALESCE(f.favorite, false), f.position"
+
+func scanNote(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.Note, error) {
+	var note models.Note
+	var workspaceID, notebookID sql.NullInt64
+	var deletedAt sql.NullTime
+	var clientID, recurrence, timezone sql.NullString
+	var dueAt, remindAt, snoozedUntil sql.NullTime
+	dest := []interface{}{&note.ID, &note.UserID, &workspaceID, &notebookID, &note.Title, &note.Content,
+		&note.ContentFormat, &note.CreatedAt, &note.UpdatedAt, &deletedAt, &note.Version, &clientID,
+		&dueAt, &remindAt, &snoozedUntil, &recurrence, &timezone}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if workspaceID.Valid {
+		note.WorkspaceID = &workspaceID.Int64
+	}
+	if notebookID.Valid {
+		note.NotebookID = &notebookID.Int64
+	}
+	if deletedAt.Valid {
+		note.DeletedAt = &deletedAt.Time
+	}
+	note.ClientID = clientID.String
+	if dueAt.Valid {
+		note.DueAt = &dueAt.Time
+	}
+	if remindAt.Valid {
+		note.RemindAt = &remindAt.Time
+	}
+	if snoozedUntil.Valid {
+		note.SnoozedUntil = &snoozedUntil.Time
+	}
+	note.Recurrence = recurrence.String
+	note.Timezone = timezone.String
+	return &note, nil
+}
+
+// scanNoteWithFlags читает заметку, выбранную как noteColumns, noteFlagColumns
+// и затем extra
+func scanNoteWithFlags(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.Note, error) {
+	var pinned, archived, favorite bool
+	var position sql.NullInt64
+	note, err := scanNote(row, append([]interface{}{&pinned, &archived, &favorite, &position}, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	note.Pinned, note.Archived, note.Favorite = pinned, archived, favorite
+	if position.Valid {
+		p := int(position.Int64)
+		note.Position = &p
+	}
+	return note, nil
+}
+
+// CreateNote создает новую заметку в базе данных. Заметку в пространстве
+// может создать только участник с ролью owner или editor, в блокноте — только
+// владелец блокнота. Заметка с уже использованным пользователем ClientID не
+// создается, возвращается ErrDuplicate.
 func (r *PostgresRepository) CreateNote(ctx context.Context, note *models.Note) error {
 	query := `
-		INSERT INTO notes (user_id, title, content, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id`
+		INSERT INTO notes (user_id, workspace_id, notebook_id, title, content, content_format, created_at, updated_at, client_id)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')
+		WHERE ($2::integer IS NULL OR EXISTS (
+			SELECT 1 FROM workspace_members
+			WHERE workspace_id = $2 AND user_id = $1 AND role IN ('owner', 'editor')))
+		AND ($3::integer IS NULL OR EXISTS (
+			SELECT 1 FROM notebooks
+			WHERE id = $3 AND user_id = $1 AND deleted_at IS NULL))
+		RETURNING id, version`
 
 	err := r.db.QueryRowContext(ctx, query,
-		note.UserID, note.Title, note.Content, note.CreatedAt, note.UpdatedAt).
-		Scan(&note.ID)
+		note.UserID, note.WorkspaceID, note.NotebookID, note.Title, note.Content, note.ContentFormat, note.CreatedAt, note.UpdatedAt, note.ClientID).
+		Scan(&note.ID, &note.Version)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		if note.WorkspaceID != nil {
+			if _, err := r.workspaceRole(ctx, note.UserID, *note.WorkspaceID); err != nil {
+				return err
+			}
+			return ErrForbidden
+		}
+		return ErrNotebookNotFound
+	}
 
 	return err
 }
 
-// ListNotes возвращает список заметок пользователя
-func (r *PostgresRepository) ListNotes(ctx context.Context, userID int64) ([]*models.Note, error) {
+// ListNotes возвращает список личных заметок пользователя или, если задан
+// filter.WorkspaceID, заметок пространства, в котором он состоит. Заметки в
+// корзине не возвращаются. Отметки заметок — свои у каждого пользователя:
+// закрепленные им заметки идут первыми, затем заметки в его ручном порядке,
+// затем остальные от новых к старым.
+func (r *PostgresRepository) ListNotes(ctx context.Context, userID int64, filter models.NoteFilter) ([]*models.Note, error) {
+	var notes []*models.Note
+	err := r.StreamNotes(ctx, userID, filter, func(note *models.Note) error {
+		notes = append(notes, note)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// StreamNotes выбирает те же заметки, что и ListNotes, но передает их в fn
+// по одной по мере чтения, не накапливая в памяти. Ошибка fn прерывает выборку.
+// Заметки отдаются вместе с чек-листами.
+func (r *PostgresRepository) StreamNotes(ctx context.Context, userID int64, filter models.NoteFilter, fn func(*models.Note) error) error {
+	conditions := []string{"notes.deleted_at IS NULL"}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	user := arg(userID)
+
+	if filter.WorkspaceID != nil {
+		if _, err := r.workspaceRole(ctx, userID, *filter.WorkspaceID); err != nil {
+			return err
+		}
+		conditions = append(conditions, "notes.workspace_id = "+arg(*filter.WorkspaceID))
+	} else {
+		conditions = append(conditions, "notes.user_id = "+user, "notes.workspace_id IS NULL")
+	}
+	if filter.NotebookID != nil {
+		conditions = append(conditions, "notes.notebook_id = "+arg(*filter.NotebookID))
+	}
+	if filter.HasOpenTasks != nil {
+		openTasks := "EXISTS (SELECT 1 FROM checklist_items c WHERE c.note_id = notes.id AND NOT c.checked)"
+		if !*filter.HasOpenTasks {
+			openTasks = "NOT " + openTasks
+		}
+		conditions = append(conditions, openTasks)
+	}
+	if filter.Archived != nil {
+		conditions = append(conditions, "COALESCE(f.archived, false) = "+arg(*filter.Archived))
+	}
+	if filter.Favorite != nil {
+		conditions = append(conditions, "COALESCE(f.favorite, false) = "+arg(*filter.Favorite))
+	}
+
 	query := `
-		SELECT id, user_id, title, content, created_at, updated_at
+		SELECT ` + prefixColumns("notes", noteColumns) + `, ` + noteFlagColumns + `,
+			(SELECT json_agg(c ORDER BY c.position) FROM checklist_items c WHERE c.note_id = notes.id) AS checklist
 		FROM notes
-		WHERE user_id = $1
-		ORDER BY created_at DESC`
+		LEFT JOIN note_user_flags f ON f.note_id = notes.id AND f.user_id = ` + user + `
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY COALESCE(f.pinned, false) DESC, f.position NULLS LAST, notes.created_at DESC`
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var notes []*models.Note
 	for rows.Next() {
-		var note models.Note
-		if err := rows.Scan(
-			&note.ID, &note.UserID, &note.Title, &note.Content,
-			&note.CreatedAt, &note.UpdatedAt); err != nil {
-			return nil, err
+		var checklist []byte
+		note, err := scanNoteWithFlags(rows, &checklist)
+		if err != nil {
+			return err
+		}
+		if checklist != nil {
+			if err := json.Unmarshal(checklist, &note.Checklist); err != nil {
+				return err
+			}
+		}
+		if err := fn(note); err != nil {
+			return err
 		}
-		notes = append(notes, &note)
 	}
 
-	if err := rows.Err(); err != nil {
+	return rows.Err()
+}
+
+// GetNote возвращает заметку, если у пользователя есть к ней доступ: он
+// владелец, участник пространства или ему открыт доступ. Недоступные заметки
+// неотличимы от несуществующих (ErrNotFound). Заметка возвращается с
+// отметками пользователя.
+func (r *PostgresRepository) GetNote(ctx context.Context, userID, noteID int64) (*models.Note, error) {
+	query := `
+		SELECT ` + prefixColumns("n", noteColumns) + `, ` + noteFlagColumns + `, permission
+		FROM (SELECT *, note_permission(id, $1) AS permission FROM notes WHERE id = $2 AND deleted_at IS NULL) n
+		LEFT JOIN note_user_flags f ON f.note_id = n.id AND f.user_id = $1
+		WHERE permission IS NOT NULL`
+
+	var permission string
+	note, err := scanNoteWithFlags(r.db.QueryRowContext(ctx, query, userID, noteID), &permission)
+	if err != nil {
 		return nil, err
 	}
+	note.Permission = permission
+	return note, nil
+}
 
-	return notes, nil
+// UpdateNote меняет заголовок, текст и формат заметки. Нужен доступ owner
+// или edit; при доступе только на чтение — ErrForbidden. Если задан
+// note.Version, а заметка с тех пор изменилась, возвращается ErrConflict.
+func (r *PostgresRepository) UpdateNote(ctx context.Context, userID int64, note *models.Note) error {
+	query := `
+		UPDATE notes SET title = $3, content = $4, content_format = $5, updated_at = $6
+		WHERE id = $2 AND deleted_at IS NULL AND note_permission(id, $1) IN ('owner', 'edit')
+		AND ($7 = 0 OR version = $7)
+		RETURNING user_id, workspace_id, notebook_id, created_at, version`
+
+	var workspaceID, notebookID sql.NullInt64
+	err := r.db.QueryRowContext(ctx, query,
+		userID, note.ID, note.Title, note.Content, note.ContentFormat, note.UpdatedAt, note.Version).
+		Scan(&note.UserID, &workspaceID, &notebookID, &note.CreatedAt, &note.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r.versionError(ctx, userID, note.ID, note.Version, models.PermissionOwner, models.PermissionEdit)
+	}
+	if err != nil {
+		return err
+	}
+	if workspaceID.Valid {
+		note.WorkspaceID = &workspaceID.Int64
+	}
+	if notebookID.Valid {
+		note.NotebookID = &notebookID.Int64
+	}
+	return nil
+}
+
+// DeleteNote перемещает заметку в корзину. Нужен доступ owner: владелец
+// личной заметки или владелец пространства.
+func (r *PostgresRepository) DeleteNote(ctx context.Context, userID, noteID int64) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE notes SET deleted_at = now() WHERE id = $2 AND deleted_at IS NULL AND note_permission(id, $1) = 'owner'",
+		userID, noteID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return r.accessError(ctx, userID, noteID)
+	}
+	return nil
+}
+
+// versionError объясняет, почему условное по версии изменение заметки не
+// затронуло ни одной строки: ErrConflict, если у пользователя достаточно прав,
+// но версия заметки другая, иначе ошибка accessError
+func (r *PostgresRepository) versionError(ctx context.Context, userID, noteID int64, version int, permissions ...string) error {
+	current, err := r.GetNote(ctx, userID, noteID)
+	if err != nil {
+		return err
+	}
+	if version != 0 && current.Version != version && slices.Contains(permissions, current.Permission) {
+		return ErrConflict
+	}
+	return ErrForbidden
+}
+
+// accessError объясняет, почему операция над заметкой не затронула ни одной
+// строки: ErrForbidden, если заметка пользователю видна, иначе ErrNotFound
+func (r *PostgresRepository) accessError(ctx context.Context, userID, noteID int64) error {
+	_, err := r.GetNote(ctx, userID, noteID)
+	if err != nil {
+		return err
+	}
+	return ErrForbidden
 }
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"notes-service/internal/models"
+	"time"
+)
+
+// SetReminder задает срок, напоминание, правило повторения и его часовой пояс
+// и отменяет отложенное напоминание. Пустой reminder убирает напоминание.
+// Нужен доступ owner или edit.
+func (r *PostgresRepository) SetReminder(ctx context.Context, userID, noteID int64, reminder models.Reminder) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE notes SET due_at = $3, remind_at = $4, recurrence = NULLIF($5, ''),
+			reminder_timezone = NULLIF($6, ''), snoozed_until = NULL
+		WHERE id = $2 AND deleted_at IS NULL AND note_permission(id, $1) IN ('owner', 'edit')`,
+		userID, noteID, reminder.DueAt, reminder.RemindAt, reminder.Recurrence, reminder.Timezone)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return r.accessError(ctx, userID, noteID)
+	}
+	return nil
+}
+
+// SnoozeReminder откладывает напоминание заметки до until. Если напоминание
+// уже сработало, оно придет еще раз в until. Нужен доступ owner или edit.
+func (r *PostgresRepository) SnoozeReminder(ctx context.Context, userID, noteID int64, until time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE notes SET snoozed_until = $3
+		WHERE id = $2 AND deleted_at IS NULL AND note_permission(id, $1) IN ('owner', 'edit')`,
+		userID, noteID, until)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return r.accessError(ctx, userID, noteID)
+	}
+	return nil
+}
+
+// DueReminders возвращает до limit заметок, напоминание которых наступило к
+// now, самые давние первыми
+func (r *PostgresRepository) DueReminders(ctx context.Context, now time.Time, limit int) ([]*models.Note, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+noteColumns+`
+		FROM notes
+		WHERE deleted_at IS NULL AND (remind_at IS NOT NULL OR snoozed_until IS NOT NULL)
+			AND COALESCE(snoozed_until, remind_at) <= $1
+		ORDER BY COALESCE(snoozed_until, remind_at), id
+		LIMIT $2`,
+		now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []*models.Note{}
+	for rows.Next() {
+		note, err := scanNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// FireReminder отмечает напоминание сработавшим: переносит напоминание и
+// срок на remindAt и dueAt, снимает отложенное напоминание и записывает
+// событие reminder для пользователей, которые видят заметку, и note.reminder в
+// outbox. Сам перенос расписания событием updated не считается. Если
+// напоминание заметки изменилось после DueReminders, возвращается ErrConflict.
+func (r *PostgresRepository) FireReminder(ctx context.Context, note *models.Note, remindAt, dueAt *time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE notes SET remind_at = $2, due_at = $3, snoozed_until = NULL
+		WHERE id = $1 AND deleted_at IS NULL
+			AND remind_at IS NOT DISTINCT FROM $4 AND snoozed_until IS NOT DISTINCT FROM $5`,
+		note.ID, remindAt, dueAt, note.RemindAt, note.SnoozedUntil)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrConflict
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"SELECT emit_note_event(n, 'reminder'), enqueue_note_outbox(n, 'reminder') FROM notes n WHERE n.id = $1",
+		note.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"notes-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFireReminder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	remindAt := time.Date(2024, 5, 6, 9, 0, 0, 0, time.UTC)
+	next := remindAt.AddDate(0, 0, 7)
+	note := &models.Note{ID: 7, RemindAt: &remindAt, Recurrence: "FREQ=WEEKLY"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE notes SET remind_at = \\$2, due_at = \\$3, snoozed_until = NULL(.+)remind_at IS NOT DISTINCT FROM \\$4").
+		WithArgs(int64(7), &next, nil, &remindAt, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SELECT emit_note_event\\(n, 'reminder'\\), enqueue_note_outbox\\(n, 'reminder'\\) FROM notes n WHERE n.id = \\$1").
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.FireReminder(context.Background(), note, &next, nil)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFireReminderChangedConcurrently(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	remindAt := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE notes SET remind_at").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = repo.FireReminder(context.Background(), &models.Note{ID: 7, RemindAt: &remindAt}, nil, nil)
+
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcquireLeaseHeldByAnotherInstance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectQuery("INSERT INTO scheduler_leases(.+)ON CONFLICT \\(name\\) DO UPDATE(.+)expires_at < now\\(\\)").
+		WithArgs("reminders", "host:1", int64(60000)).
+		WillReturnRows(sqlmock.NewRows([]string{"holder"}))
+
+	held, err := repo.AcquireLease(context.Background(), "reminders", "host:1", time.Minute)
+
+	assert.NoError(t, err)
+	assert.False(t, held)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,16 +3,185 @@ package repository
 import (
 	"context"
 	"notes-service/internal/models"
+	"time"
 )
 
 type NoteRepository interface {
 	CreateNote(ctx context.Context, note *models.Note) error
-	ListNotes(ctx context.Context, userID int64) ([]*models.Note, error)
+	ListNotes(ctx context.Context, userID int64, filter models.NoteFilter) ([]*models.Note, error)
+	GetNote(ctx context.Context, userID, noteID int64) (*models.Note, error)
+	UpdateNote(ctx context.Context, userID int64, note *models.Note) error
+	DeleteNote(ctx context.Context, userID, noteID int64) error
 	Close() error
 }
 
+type ExportRepository interface {
+	StreamNotes(ctx context.Context, userID int64, filter models.NoteFilter, fn func(*models.Note) error) error
+}
+
+type ImportRepository interface {
+	CreateImportJob(ctx context.Context, job *models.ImportJob, payload []byte) error
+	GetImportJob(ctx context.Context, userID, jobID int64) (*models.ImportJob, error)
+	ListImportJobs(ctx context.Context, userID int64) ([]*models.ImportJob, error)
+	ClaimImportJob(ctx context.Context, staleAfter time.Duration) (*models.ImportJob, []byte, error)
+	SaveImportProgress(ctx context.Context, job *models.ImportJob) error
+	NoteExists(ctx context.Context, userID int64, title, content string) (bool, error)
+}
+
+type ShareRepository interface {
+	ShareNote(ctx context.Context, ownerID, noteID int64, username, permission string) (*models.NoteShare, error)
+	ListShares(ctx context.Context, ownerID, noteID int64) ([]*models.NoteShare, error)
+	RevokeShare(ctx context.Context, userID, noteID, targetUserID int64) error
+	ListSharedWithMe(ctx context.Context, userID int64) ([]*models.Note, error)
+}
+
+type WorkspaceRepository interface {
+	CreateWorkspace(ctx context.Context, userID int64, name string) (*models.Workspace, error)
+	ListWorkspaces(ctx context.Context, userID int64) ([]*models.Workspace, error)
+	GetWorkspace(ctx context.Context, userID, workspaceID int64) (*models.Workspace, error)
+	ListMembers(ctx context.Context, userID, workspaceID int64) ([]*models.WorkspaceMember, error)
+	SetMemberRole(ctx context.Context, userID, workspaceID, memberID int64, role string) error
+	RemoveMember(ctx context.Context, userID, workspaceID, memberID int64) error
+	CreateInvitation(ctx context.Context, userID, workspaceID int64, username, role string) (*models.WorkspaceInvitation, error)
+	ListInvitations(ctx context.Context, userID int64) ([]*models.WorkspaceInvitation, error)
+	RespondToInvitation(ctx context.Context, userID, invitationID int64, accept bool) error
+}
+
+type NotebookRepository interface {
+	CreateNotebook(ctx context.Context, userID int64, notebook *models.Notebook) error
+	ListNotebooks(ctx context.Context, userID int64) ([]*models.Notebook, error)
+	GetNotebook(ctx context.Context, userID, notebookID int64) (*models.Notebook, error)
+	RenameNotebook(ctx context.Context, userID, notebookID int64, name string) error
+	MoveNotebook(ctx context.Context, userID, notebookID int64, parentID *int64) error
+	MoveNote(ctx context.Context, userID, noteID int64, notebookID *int64) error
+	TrashNotebook(ctx context.Context, userID, notebookID int64) error
+	ListTrash(ctx context.Context, userID int64) (*models.Trash, error)
+	RestoreNote(ctx context.Context, userID, noteID int64) error
+	RestoreNotebook(ctx context.Context, userID, notebookID int64) error
+	PurgeNote(ctx context.Context, userID, noteID int64) error
+	PurgeNotebook(ctx context.Context, userID, notebookID int64) error
+	PurgeTrash(ctx context.Context, before time.Time) (int64, error)
+}
+
+type ShareLinkRepository interface {
+	CreateShareLink(ctx context.Context, ownerID int64, link *models.ShareLink) error
+	ListShareLinks(ctx context.Context, ownerID, noteID int64) ([]*models.ShareLink, error)
+	RevokeShareLink(ctx context.Context, ownerID, noteID, linkID int64) error
+	GetSharedNote(ctx context.Context, tokenHash string) (*models.ShareLink, *models.Note, error)
+}
+
+type AttachmentRepository interface {
+	CheckAttachmentQuota(ctx context.Context, userID, noteID, size, quota int64) error
+	CreateAttachment(ctx context.Context, attachment *models.Attachment, quota int64) error
+	ListAttachments(ctx context.Context, userID, noteID int64) ([]*models.Attachment, error)
+	NoteAttachments(ctx context.Context, noteID int64) ([]*models.Attachment, error)
+	GetAttachmentBlob(ctx context.Context, attachmentID int64, size string) (*models.Attachment, error)
+	GetAttachment(ctx context.Context, userID, noteID, attachmentID int64) (*models.Attachment, error)
+	DeleteAttachment(ctx context.Context, userID, noteID, attachmentID int64) error
+	ListOrphanedBlobs(ctx context.Context, limit int) ([]string, error)
+	ForgetOrphanedBlob(ctx context.Context, key string) error
+}
+
+type ThumbnailRepository interface {
+	ClaimThumbnailJob(ctx context.Context, staleAfter time.Duration) (*models.Attachment, error)
+	SaveThumbnails(ctx context.Context, attachmentID int64, thumbnails []*models.Thumbnail, status string) error
+}
+
+type SyncRepository interface {
+	SyncChanges(ctx context.Context, userID int64, since uint64) (*models.SyncChanges, error)
+	GetNoteByClientID(ctx context.Context, userID int64, clientID string) (*models.Note, error)
+	TrashNote(ctx context.Context, userID, noteID int64, version int) error
+}
+
+type NoteEventRepository interface {
+	NoteEventWatermark(ctx context.Context) (models.NoteEventCursor, error)
+	OldestNoteEventXID(ctx context.Context) (uint64, error)
+	ListNoteEvents(ctx context.Context, userID int64, after models.NoteEventCursor, limit int) ([]*models.NoteEvent, bool, error)
+	PruneNoteEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	ListWebhooks(ctx context.Context, userID int64) ([]*models.Webhook, error)
+	GetWebhook(ctx context.Context, userID, webhookID int64) (*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook *models.Webhook) error
+	DeleteWebhook(ctx context.Context, userID, webhookID int64) error
+	ListWebhookDeliveries(ctx context.Context, userID, webhookID int64, status string, limit, offset int) ([]*models.WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, userID, webhookID, deliveryID int64) (*models.WebhookDelivery, error)
+	ReplayWebhookDelivery(ctx context.Context, userID, webhookID, deliveryID int64) (*models.WebhookDelivery, error)
+	ClaimWebhookDelivery(ctx context.Context, staleAfter time.Duration) (*models.WebhookDelivery, *models.Webhook, error)
+	RecordWebhookAttempt(ctx context.Context, deliveryID int64, attempt *models.WebhookAttempt, status string, nextAttemptAt time.Time) error
+	PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+type OutboxRepository interface {
+	ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]*models.DomainEvent, error)
+	MarkOutboxPublished(ctx context.Context, ids []int64) error
+	RetryOutboxEvents(ctx context.Context, ids []int64, lastError string, retryAt time.Time) error
+	PruneOutbox(ctx context.Context, before time.Time) (int64, error)
+}
+
+type ReminderRepository interface {
+	SetReminder(ctx context.Context, userID, noteID int64, reminder models.Reminder) error
+	SnoozeReminder(ctx context.Context, userID, noteID int64, until time.Time) error
+	DueReminders(ctx context.Context, now time.Time, limit int) ([]*models.Note, error)
+	FireReminder(ctx context.Context, note *models.Note, remindAt, dueAt *time.Time) error
+}
+
+type NoteFlagRepository interface {
+	SetNoteFlag(ctx context.Context, userID, noteID int64, flag string, value bool) error
+	ReorderNotes(ctx context.Context, userID int64, noteIDs []int64) error
+}
+
+type ChecklistRepository interface {
+	ListChecklist(ctx context.Context, userID, noteID int64) ([]*models.ChecklistItem, error)
+	AddChecklistItem(ctx context.Context, userID int64, item *models.ChecklistItem) error
+	UpdateChecklistItem(ctx context.Context, userID int64, item *models.ChecklistItem) error
+	ToggleChecklistItem(ctx context.Context, userID, noteID, itemID int64) (*models.ChecklistItem, error)
+	DeleteChecklistItem(ctx context.Context, userID, noteID, itemID int64) error
+	ReorderChecklist(ctx context.Context, userID, noteID int64, itemIDs []int64) ([]*models.ChecklistItem, error)
+}
+
+type LeaseRepository interface {
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+}
+
 type UserRepository interface {
-	CreateUser(ctx context.Context, username, password string) (*User, error)
+	CreateUser(ctx context.Context, username, password, email string) (*User, error)
 	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	GetUserByID(ctx context.Context, id int64) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
 	ValidateUser(ctx context.Context, username, password string) (*User, error)
+	UpdatePassword(ctx context.Context, userID int64, password string) (int, error)
+	CreatePasswordResetToken(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error
+	ResetPassword(ctx context.Context, tokenHash, password string) (int64, error)
+	CreateEmailVerificationToken(ctx context.Context, userID int64, email, tokenHash string, expiresAt time.Time) error
+	VerifyEmail(ctx context.Context, tokenHash string) (int64, error)
+	MarkEmailVerified(ctx context.Context, userID int64, email string) error
+	SetTOTPSecret(ctx context.Context, userID int64, secret string) error
+	EnableTOTP(ctx context.Context, userID int64, secret string, recoveryCodeHashes []string) error
+	DisableTOTP(ctx context.Context, userID int64) error
+	UseTOTPStep(ctx context.Context, userID int64, step int64) (bool, error)
+	ConsumeRecoveryCode(ctx context.Context, userID int64, codeHash string) error
+	GetUserByIdentity(ctx context.Context, provider, subject string) (*User, error)
+	CreateIdentity(ctx context.Context, userID int64, provider, subject, email string) error
+	GetLockedUntil(ctx context.Context, username string) (time.Time, error)
+	RecordLoginFailure(ctx context.Context, username string) (int, error)
+	LockUser(ctx context.Context, username string, until time.Time) error
+	ResetLoginFailures(ctx context.Context, userID int64) error
+}
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	ListAPIKeys(ctx context.Context, userID int64) ([]*APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id int64) error
+	TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error
+}
+
+type AdminRepository interface {
+	ListUsers(ctx context.Context, limit, offset int) ([]*UserSummary, error)
+	GetUserSummary(ctx context.Context, id int64) (*UserSummary, error)
+	SetUserDisabled(ctx context.Context, id int64, disabled bool) error
+	RevokeUserTokens(ctx context.Context, id int64) error
 }
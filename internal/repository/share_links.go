@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"notes-service/internal/models"
+)
+
+const shareLinkColumns = "l.id, l.note_id, l.token_hash, l.password_hash, l.expires_at, l.created_at"
+
+func scanShareLink(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.ShareLink, error) {
+	var link models.ShareLink
+	var passwordHash sql.NullString
+	var expiresAt sql.NullTime
+	dest := []interface{}{&link.ID, &link.NoteID, &link.TokenHash, &passwordHash, &expiresAt, &link.CreatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	link.PasswordHash = passwordHash.String
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	return &link, nil
+}
+
+// CreateShareLink сохраняет публичную ссылку на заметку. Нужен доступ owner.
+func (r *PostgresRepository) CreateShareLink(ctx context.Context, ownerID int64, link *models.ShareLink) error {
+	if err := r.requireOwner(ctx, ownerID, link.NoteID); err != nil {
+		return err
+	}
+
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO share_links (note_id, token_hash, password_hash, expires_at)
+		SELECT id, $3, NULLIF($4, ''), $5 FROM notes WHERE id = $2 AND note_permission(id, $1) = 'owner'
+		RETURNING id, created_at`,
+		ownerID, link.NoteID, link.TokenHash, link.PasswordHash, link.ExpiresAt).
+		Scan(&link.ID, &link.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ListShareLinks возвращает действующие ссылки на заметку
+func (r *PostgresRepository) ListShareLinks(ctx context.Context, ownerID, noteID int64) ([]*models.ShareLink, error) {
+	if err := r.requireOwner(ctx, ownerID, noteID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+shareLinkColumns+`
+		FROM share_links l
+		WHERE l.note_id = $1 AND l.revoked_at IS NULL
+		ORDER BY l.created_at DESC`,
+		noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*models.ShareLink{}
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// RevokeShareLink отзывает ссылку на заметку
+func (r *PostgresRepository) RevokeShareLink(ctx context.Context, ownerID, noteID, linkID int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE share_links l SET revoked_at = now()
+		FROM notes n
+		WHERE l.note_id = n.id AND l.id = $3 AND n.id = $2 AND l.revoked_at IS NULL
+			AND note_permission(n.id, $1) = 'owner'`,
+		ownerID, noteID, linkID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetSharedNote возвращает ссылку и заметку по хешу токена. Отозванные и
+// просроченные ссылки дают ErrNotFound.
+func (r *PostgresRepository) GetSharedNote(ctx context.Context, tokenHash string) (*models.ShareLink, *models.Note, error) {
+	var note models.Note
+	link, err := scanShareLink(r.db.QueryRowContext(ctx, `
+		SELECT `+shareLinkColumns+`, n.id, n.title, n.content, n.content_format, n.created_at, n.updated_at
+		FROM share_links l
+		JOIN notes n ON n.id = l.note_id
+		WHERE l.token_hash = $1 AND l.revoked_at IS NULL AND n.deleted_at IS NULL
+			AND (l.expires_at IS NULL OR l.expires_at > now())`,
+		tokenHash),
+		&note.ID, &note.Title, &note.Content, &note.ContentFormat, &note.CreatedAt, &note.UpdatedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	note.Permission = models.PermissionRead
+	return link, &note, nil
+}
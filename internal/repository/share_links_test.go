@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSharedNote(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT (.+) FROM share_links l JOIN notes n (.+) WHERE l.token_hash = (.+) AND l.revoked_at IS NULL").
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "note_id", "token_hash", "password_hash", "expires_at", "created_at",
+			"id", "title", "content", "content_format", "created_at", "updated_at"}).
+			AddRow(1, 7, "hash", nil, nil, now, 7, "Plan", "Content", "markdown", now, now))
+
+	link, note, err := repo.GetSharedNote(context.Background(), "hash")
+
+	assert.NoError(t, err)
+	assert.False(t, link.HasPassword())
+	assert.Nil(t, link.ExpiresAt)
+	assert.Equal(t, "Plan", note.Title)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRevokeShareLinkNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectExec("UPDATE share_links l SET revoked_at").
+		WithArgs(int64(2), int64(7), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.RevokeShareLink(context.Background(), 2, 7, 1)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
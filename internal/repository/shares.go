@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"notes-service/internal/models"
+)
+
+var (
+	// ErrUnknownUser возвращается, если пользователь, которому открывают
+	// доступ, не существует
+	ErrUnknownUser = errors.New("unknown user")
+	// ErrShareWithOwner возвращается при попытке открыть доступ владельцу заметки
+	ErrShareWithOwner = errors.New("cannot share a note with its owner")
+)
+
+// requireOwner проверяет, что у пользователя есть доступ owner к заметке
+func (r *PostgresRepository) requireOwner(ctx context.Context, userID, noteID int64) error {
+	note, err := r.GetNote(ctx, userID, noteID)
+	if err != nil {
+		return err
+	}
+	if note.Permission != models.PermissionOwner {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// ShareNote открывает пользователю username доступ к заметке или меняет
+// уровень уже выданного доступа. Управлять доступом может только владелец.
+func (r *PostgresRepository) ShareNote(ctx context.Context, ownerID, noteID int64, username, permission string) (*models.NoteShare, error) {
+	if err := r.requireOwner(ctx, ownerID, noteID); err != nil {
+		return nil, err
+	}
+
+	share := models.NoteShare{NoteID: noteID, Username: username, Permission: permission}
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM users WHERE username = $1", username).
+		Scan(&share.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownUser
+	}
+	if err != nil {
+		return nil, err
+	}
+	if share.UserID == ownerID {
+		return nil, ErrShareWithOwner
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO note_shares (note_id, user_id, permission)
+		SELECT id, $3, $4 FROM notes WHERE id = $2 AND note_permission(id, $1) = 'owner'
+		ON CONFLICT (note_id, user_id) DO UPDATE SET permission = EXCLUDED.permission
+		RETURNING created_at`,
+		ownerID, noteID, share.UserID, permission).Scan(&share.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// ListShares возвращает пользователей, которым открыт доступ к заметке
+func (r *PostgresRepository) ListShares(ctx context.Context, ownerID, noteID int64) ([]*models.NoteShare, error) {
+	if err := r.requireOwner(ctx, ownerID, noteID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT s.note_id, s.user_id, u.username, s.permission, s.created_at
+		FROM note_shares s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.note_id = $1
+		ORDER BY s.created_at`,
+		noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []*models.NoteShare{}
+	for rows.Next() {
+		var share models.NoteShare
+		if err := rows.Scan(&share.NoteID, &share.UserID, &share.Username,
+			&share.Permission, &share.CreatedAt); err != nil {
+			return nil, err
+		}
+		shares = append(shares, &share)
+	}
+
+	return shares, rows.Err()
+}
+
+// RevokeShare закрывает доступ пользователя targetUserID к заметке. Закрыть
+// доступ может владелец заметки или сам получатель, отказываясь от нее.
+func (r *PostgresRepository) RevokeShare(ctx context.Context, userID, noteID, targetUserID int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM note_shares s USING notes n
+		WHERE s.note_id = n.id AND n.id = $2 AND s.user_id = $3
+			AND (note_permission(n.id, $1) = 'owner' OR s.user_id = $1)`,
+		userID, noteID, targetUserID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListSharedWithMe возвращает заметки других пользователей, доступные userID
+func (r *PostgresRepository) ListSharedWithMe(ctx context.Context, userID int64) ([]*models.Note, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+prefixColumns("n", noteColumns)+`, `+noteFlagColumns+`, s.permission
+		FROM notes n
+		JOIN note_shares s ON s.note_id = n.id
+		LEFT JOIN note_user_flags f ON f.note_id = n.id AND f.user_id = s.user_id
+		WHERE s.user_id = $1 AND n.deleted_at IS NULL
+		ORDER BY n.updated_at DESC`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []*models.Note{}
+	for rows.Next() {
+		var permission string
+		note, err := scanNoteWithFlags(rows, &permission)
+		if err != nil {
+			return nil, err
+		}
+		note.Permission = permission
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
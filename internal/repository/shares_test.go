@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"notes-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func noteAccessRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "user_id", "workspace_id", "notebook_id", "title", "content", "content_format", "created_at", "updated_at", "deleted_at", "version", "client_id", "due_at", "remind_at", "snoozed_until", "recurrence", "reminder_timezone", "pinned", "archived", "favorite", "position", "permission"})
+}
+
+func TestUpdateNoteReadOnlyShare(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery("UPDATE notes SET title").
+		WithArgs(int64(2), int64(7), "Title", "Content", models.FormatMarkdown, now, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "workspace_id", "notebook_id", "created_at"}))
+	mock.ExpectQuery("SELECT (.+) note_permission\\(id, \\$1\\)").
+		WithArgs(int64(2), int64(7)).
+		WillReturnRows(noteAccessRows().AddRow(7, 1, nil, nil, "Title", "Content", "plain", now, now, nil, 1, nil, nil, nil, nil, nil, nil, false, false, false, nil, "read"))
+
+	err = repo.UpdateNote(context.Background(), 2, &models.Note{
+		ID: 7, Title: "Title", Content: "Content", ContentFormat: models.FormatMarkdown, UpdatedAt: now,
+	})
+
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetNoteWithoutAccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectQuery("SELECT (.+) note_permission\\(id, \\$1\\)").
+		WithArgs(int64(3), int64(7)).
+		WillReturnRows(noteAccessRows())
+
+	_, err = repo.GetNote(context.Background(), 3, 7)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestShareNote(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT (.+) note_permission\\(id, \\$1\\)").
+		WithArgs(int64(1), int64(7)).
+		WillReturnRows(noteAccessRows().AddRow(7, 1, nil, nil, "Title", "Content", "plain", now, now, nil, 1, nil, nil, nil, nil, nil, nil, false, false, false, nil, "owner"))
+	mock.ExpectQuery("SELECT id FROM users WHERE username").
+		WithArgs("bob").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery("INSERT INTO note_shares").
+		WithArgs(int64(1), int64(7), int64(2), "edit").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(now))
+
+	share, err := repo.ShareNote(context.Background(), 1, 7, "bob", models.PermissionEdit)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), share.UserID)
+	assert.Equal(t, models.PermissionEdit, share.Permission)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestShareNoteRequiresOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT (.+) note_permission\\(id, \\$1\\)").
+		WithArgs(int64(2), int64(7)).
+		WillReturnRows(noteAccessRows().AddRow(7, 1, nil, nil, "Title", "Content", "plain", now, now, nil, 1, nil, nil, nil, nil, nil, nil, false, false, false, nil, "edit"))
+
+	_, err = repo.ShareNote(context.Background(), 2, 7, "carol", models.PermissionRead)
+
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"notes-service/internal/models"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// SyncChanges возвращает заметки пользователя, измененные после токена since,
+// и новый токен. Токен — граница ID транзакций из note_events: в ответ
+// попадают события только тех транзакций, которые уже завершены. Если since
+// равен 0 или старше самого раннего хранящегося события, возвращаются все
+// доступные заметки с Reset.
+func (r *PostgresRepository) SyncChanges(ctx context.Context, userID int64, since uint64) (*models.SyncChanges, error) {
+	var watermark string
+	var oldest sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		SELECT pg_snapshot_xmin(pg_current_snapshot())::text,
+			(SELECT xid::text FROM note_events ORDER BY xid LIMIT 1)`).
+		Scan(&watermark, &oldest)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &models.SyncChanges{Token: watermark, Notes: []*models.Note{}, Deleted: []int64{}}
+	if since == 0 || (oldest.Valid && since < parseXID(oldest.String)) {
+		changes.Reset = true
+		changes.Notes, err = r.syncNotes(ctx, `
+			SELECT `+prefixColumns("n", noteColumns)+`, `+noteFlagColumns+`, permission
+			FROM (SELECT *, note_permission(id, $1) AS permission FROM notes WHERE deleted_at IS NULL) n
+			LEFT JOIN note_user_flags f ON f.note_id = n.id AND f.user_id = $1
+			WHERE permission IS NOT NULL
+			ORDER BY n.id`,
+			userID)
+		return changes, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT note_id FROM note_events
+		WHERE user_id = $1 AND xid >= $2::xid8 AND xid < $3::xid8
+		ORDER BY note_id`,
+		userID, strconv.FormatUint(since, 10), watermark)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return changes, nil
+	}
+
+	changes.Notes, err = r.syncNotes(ctx, `
+		SELECT `+prefixColumns("n", noteColumns)+`, `+noteFlagColumns+`, permission
+		FROM (SELECT *, note_permission(id, $1) AS permission FROM notes WHERE id = ANY($2) AND deleted_at IS NULL) n
+		LEFT JOIN note_user_flags f ON f.note_id = n.id AND f.user_id = $1
+		WHERE permission IS NOT NULL
+		ORDER BY n.id`,
+		userID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	// Заметки из журнала, которых нет среди доступных, удалены или закрыты
+	present := make(map[int64]bool, len(changes.Notes))
+	for _, note := range changes.Notes {
+		present[note.ID] = true
+	}
+	for _, id := range ids {
+		if !present[id] {
+			changes.Deleted = append(changes.Deleted, id)
+		}
+	}
+	return changes, nil
+}
+
+func (r *PostgresRepository) syncNotes(ctx context.Context, query string, args ...interface{}) ([]*models.Note, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []*models.Note{}
+	for rows.Next() {
+		var permission string
+		note, err := scanNoteWithFlags(rows, &permission)
+		if err != nil {
+			return nil, err
+		}
+		note.Permission = permission
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// parseXID разбирает ID транзакции; некорректное значение считается нулем
+func parseXID(s string) uint64 {
+	xid, _ := strconv.ParseUint(s, 10, 64)
+	return xid
+}
+
+// GetNoteByClientID возвращает заметку, которую пользователь создал с
+// клиентским ID clientID
+func (r *PostgresRepository) GetNoteByClientID(ctx context.Context, userID int64, clientID string) (*models.Note, error) {
+	var noteID int64
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id FROM notes WHERE user_id = $1 AND client_id = $2",
+		userID, clientID).Scan(&noteID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetNote(ctx, userID, noteID)
+}
+
+// TrashNote перемещает заметку в корзину, если ее версия равна version.
+// Нужен доступ owner; если заметка изменилась — ErrConflict.
+func (r *PostgresRepository) TrashNote(ctx context.Context, userID, noteID int64, version int) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE notes SET deleted_at = now()
+		WHERE id = $2 AND deleted_at IS NULL AND note_permission(id, $1) = 'owner' AND version = $3`,
+		userID, noteID, version)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return r.versionError(ctx, userID, noteID, version, models.PermissionOwner)
+	}
+	return nil
+}
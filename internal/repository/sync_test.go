@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncChanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT pg_snapshot_xmin\\(pg_current_snapshot\\(\\)\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"xmin", "oldest"}).AddRow("1250", "900"))
+	mock.ExpectQuery("SELECT DISTINCT note_id FROM note_events WHERE user_id = (.+) AND xid >= (.+) AND xid < (.+)").
+		WithArgs(int64(1), "1200", "1250").
+		WillReturnRows(sqlmock.NewRows([]string{"note_id"}).AddRow(3).AddRow(5))
+	mock.ExpectQuery("SELECT (.+) FROM \\(SELECT \\*, note_permission\\(id, \\$1\\) AS permission FROM notes WHERE id = ANY").
+		WithArgs(int64(1), pq.Array([]int64{3, 5})).
+		WillReturnRows(noteAccessRows().AddRow(3, 1, nil, nil, "Title", "Content", "plain", now, now, nil, 4, "local-3", nil, nil, nil, nil, nil, false, false, false, nil, "owner"))
+
+	changes, err := repo.SyncChanges(context.Background(), 1, 1200)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1250", changes.Token)
+	assert.False(t, changes.Reset)
+	if assert.Len(t, changes.Notes, 1) {
+		assert.Equal(t, 4, changes.Notes[0].Version)
+		assert.Equal(t, "local-3", changes.Notes[0].ClientID)
+	}
+	assert.Equal(t, []int64{5}, changes.Deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSyncChangesExpiredToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectQuery("SELECT pg_snapshot_xmin").
+		WillReturnRows(sqlmock.NewRows([]string{"xmin", "oldest"}).AddRow("1250", "900"))
+	mock.ExpectQuery("SELECT (.+) FROM notes WHERE deleted_at IS NULL\\) n LEFT JOIN note_user_flags f ON f.note_id = n.id AND f.user_id = \\$1 WHERE permission IS NOT NULL").
+		WithArgs(int64(1)).
+		WillReturnRows(noteAccessRows())
+
+	changes, err := repo.SyncChanges(context.Background(), 1, 500)
+
+	assert.NoError(t, err)
+	assert.True(t, changes.Reset)
+	assert.Empty(t, changes.Notes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTrashNoteVersionConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectExec("UPDATE notes SET deleted_at = now\\(\\)(.+)AND version = \\$3").
+		WithArgs(int64(1), int64(7), 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT (.+) note_permission\\(id, \\$1\\)").
+		WithArgs(int64(1), int64(7)).
+		WillReturnRows(noteAccessRows().AddRow(7, 1, nil, nil, "Title", "Content", "plain", now, now, nil, 3, nil, nil, nil, nil, nil, nil, false, false, false, nil, "owner"))
+
+	err = repo.TrashNote(context.Background(), 1, 7, 2)
+
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// SetTOTPSecret сохраняет секрет для подключения 2FA. Пока 2FA не включена
+// вызовом EnableTOTP, секрет не используется при входе.
+func (r *SQLUserRepository) SetTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE users SET totp_secret = $2, totp_last_step = 0 WHERE id = $1 AND NOT totp_enabled",
+		userID, secret)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// EnableTOTP включает 2FA с секретом secret, по которому проверен код, и
+// заменяет коды восстановления новыми. Если секрет с тех пор заменен новым
+// вызовом SetTOTPSecret или 2FA уже включена, возвращается ErrConflict:
+// иначе 2FA включилась бы с секретом, которого пользователь не видел.
+func (r *SQLUserRepository) EnableTOTP(ctx context.Context, userID int64, secret string, recoveryCodeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE users SET totp_enabled = TRUE WHERE id = $1 AND totp_secret = $2 AND NOT totp_enabled",
+		userID, secret)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrConflict
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)",
+			userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DisableTOTP отключает 2FA, удаляя секрет и коды восстановления
+func (r *SQLUserRepository) DisableTOTP(ctx context.Context, userID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE users SET totp_secret = NULL, totp_enabled = FALSE, totp_last_step = 0 WHERE id = $1",
+		userID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep запоминает временной шаг последнего принятого кода. Возвращает
+// false, если код этого или более позднего шага уже использовался — это
+// защищает от повторного использования перехваченного кода.
+func (r *SQLUserRepository) UseTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2",
+		userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// ConsumeRecoveryCode помечает код восстановления использованным.
+// Неизвестные и уже использованные коды дают ErrNotFound.
+func (r *SQLUserRepository) ConsumeRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE recovery_codes SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+		RETURNING id`,
+		userID, codeHash).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
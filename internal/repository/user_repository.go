@@ -4,68 +4,350 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"log"
+	"notes-service/internal/password"
+	"sync"
+	"time"
+)
 
-	"golang.org/x/crypto/bcrypt"
+// Роли пользователей
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
 )
 
 type User struct {
 	ID       int64  `json:"id"`
 	Username string `json:"username"`
-	Password string `json:"-"` // Пароль не должен сериализоваться в JSON
+	Email    string `json:"email,omitempty"`
+	// EmailVerified — пользователь подтвердил Email по ссылке из письма или
+	// через провайдера OIDC
+	EmailVerified bool       `json:"email_verified"`
+	Password      string     `json:"-"` // Пароль не должен сериализоваться в JSON
+	TokenVersion  int        `json:"-"` // Увеличивается при смене пароля, отзывая выданные токены
+	TOTPSecret    string     `json:"-"` // Секрет TOTP в base32; задан и во время подключения 2FA
+	TOTPEnabled   bool       `json:"totp_enabled"`
+	Role          string     `json:"role"`
+	DisabledAt    *time.Time `json:"disabled_at,omitempty"`
 }
 
 type SQLUserRepository struct {
-	db *sql.DB
+	db     *sql.DB
+	hasher *password.Hasher
+
+	dummyHashOnce sync.Once
+	dummyHash     string
 }
 
-func NewUserRepository(db *sql.DB) *SQLUserRepository {
-	return &SQLUserRepository{db: db}
+// UserRepositoryOption настраивает SQLUserRepository
+type UserRepositoryOption func(*SQLUserRepository)
+
+// WithHasher задает алгоритм хеширования паролей. Хеши, полученные с другими
+// параметрами, перехешируются при успешном входе.
+func WithHasher(hasher *password.Hasher) UserRepositoryOption {
+	return func(r *SQLUserRepository) {
+		r.hasher = hasher
+	}
 }
 
-func (r *SQLUserRepository) CreateUser(ctx context.Context, username, password string) (*User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return nil, err
+func NewUserRepository(db *sql.DB, opts ...UserRepositoryOption) *SQLUserRepository {
+	r := &SQLUserRepository{db: db, hasher: password.DefaultHasher()}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
+}
 
+const userColumns = "id, username, password, email, token_version, totp_secret, totp_enabled, role, disabled_at, email_verified"
+
+func scanUser(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*User, error) {
 	var user User
-	err = r.db.QueryRowContext(ctx,
-		"INSERT INTO users (username, password) VALUES ($1, $2) RETURNING id, username",
-		username, hashedPassword).Scan(&user.ID, &user.Username)
+	var email, totpSecret sql.NullString
+	var disabledAt sql.NullTime
+	dest := []interface{}{&user.ID, &user.Username, &user.Password, &email, &user.TokenVersion,
+		&totpSecret, &user.TOTPEnabled, &user.Role, &disabledAt, &user.EmailVerified}
+	err := row.Scan(append(dest, extra...)...)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
-
+	user.Email = email.String
+	user.TOTPSecret = totpSecret.String
+	if disabledAt.Valid {
+		user.DisabledAt = &disabledAt.Time
+	}
 	return &user, nil
 }
 
-func (r *SQLUserRepository) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+func (r *SQLUserRepository) CreateUser(ctx context.Context, username, password, email string) (*User, error) {
+	hashedPassword, err := r.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+
 	var user User
-	err := r.db.QueryRowContext(ctx,
-		"SELECT id, username, password FROM users WHERE username = $1",
-		username).Scan(&user.ID, &user.Username, &user.Password)
+	err = r.db.QueryRowContext(ctx,
+		"INSERT INTO users (username, password, email) VALUES ($1, $2, NULLIF($3, '')) RETURNING id, username, role",
+		username, hashedPassword, email).Scan(&user.ID, &user.Username, &user.Role)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil // Пользователь не найден
+		if isUniqueViolationOn(err, "idx_users_email") {
+			return nil, ErrEmailTaken
+		}
+		if isUniqueViolation(err) {
+			return nil, ErrDuplicate
 		}
 		return nil, err
 	}
+	user.Email = email
+
 	return &user, nil
 }
 
+func (r *SQLUserRepository) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE username = $1",
+		username))
+}
+
+// GetUserByID возвращает пользователя по идентификатору
+func (r *SQLUserRepository) GetUserByID(ctx context.Context, id int64) (*User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE id = $1",
+		id))
+}
+
+// GetUserByEmail возвращает пользователя по адресу электронной почты
+func (r *SQLUserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return scanUser(r.db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users WHERE lower(email) = lower($1)",
+		email))
+}
+
+// ValidateUser проверяет имя пользователя и пароль. Для неизвестного
+// пользователя пароль сравнивается с фиктивным хешем, чтобы время ответа не
+// выдавало, существует ли такое имя. Если хеш получен с устаревшими
+// параметрами, пароль перехешируется.
 func (r *SQLUserRepository) ValidateUser(ctx context.Context, username, password string) (*User, error) {
 	user, err := r.GetUserByUsername(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		r.dummyHashOnce.Do(func() {
+			r.dummyHash, _ = r.hasher.Hash("dummy-password")
+		})
+		r.hasher.Verify(r.dummyHash, password)
+		return nil, ErrInvalidCredentials
+	}
 	if err != nil {
 		return nil, err
 	}
-	if user == nil {
-		return nil, nil // Пользователь не найден
+
+	ok, err := r.hasher.Verify(user.Password, password)
+	if err != nil || !ok {
+		return nil, ErrInvalidCredentials
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if err != nil {
-		return nil, err // Неверный пароль
+	if r.hasher.NeedsRehash(user.Password) {
+		r.rehash(ctx, user, password)
 	}
 
 	return user, nil
 }
+
+func (r *SQLUserRepository) rehash(ctx context.Context, user *User, password string) {
+	hashedPassword, err := r.hasher.Hash(password)
+	if err != nil {
+		log.Printf("Failed to rehash password for user %d: %v", user.ID, err)
+		return
+	}
+
+	// Условие на старый хеш защищает от гонки с одновременной сменой пароля
+	_, err = r.db.ExecContext(ctx,
+		"UPDATE users SET password = $2 WHERE id = $1 AND password = $3",
+		user.ID, hashedPassword, user.Password)
+	if err != nil {
+		log.Printf("Failed to rehash password for user %d: %v", user.ID, err)
+		return
+	}
+	user.Password = hashedPassword
+}
+
+// UpdatePassword меняет пароль, отзывает все выданные токены, ключи API и
+// неиспользованные ссылки на сброс пароля. Возвращает новую версию токенов.
+func (r *SQLUserRepository) UpdatePassword(ctx context.Context, userID int64, password string) (int, error) {
+	hashedPassword, err := r.hasher.Hash(password)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	tokenVersion, err := setPassword(ctx, tx, userID, hashedPassword)
+	if err != nil {
+		return 0, err
+	}
+	return tokenVersion, tx.Commit()
+}
+
+// CreatePasswordResetToken сохраняет хеш одноразового токена сброса пароля
+func (r *SQLUserRepository) CreatePasswordResetToken(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)",
+		userID, tokenHash, expiresAt)
+	return err
+}
+
+// ResetPassword по одноразовому токену сброса устанавливает новый пароль и
+// возвращает ID пользователя. Токен помечается использованным в той же
+// транзакции, что и смена пароля: если пароль не изменился, токен остается
+// действительным. Просроченные и уже использованные токены дают ErrNotFound.
+func (r *SQLUserRepository) ResetPassword(ctx context.Context, tokenHash, password string) (int64, error) {
+	hashedPassword, err := r.hasher.Hash(password)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	err = tx.QueryRowContext(ctx, `
+		UPDATE password_reset_tokens SET used_at = now()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING user_id`,
+		tokenHash).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	if _, err := setPassword(ctx, tx, userID, hashedPassword); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
+
+// setPassword сохраняет хеш нового пароля, увеличивает версию токенов,
+// отзывает ключи API и удаляет неиспользованные ссылки на сброс пароля: ключ,
+// созданный тем, кто узнал старый пароль, не должен пережить его смену
+func setPassword(ctx context.Context, tx *sql.Tx, userID int64, hashedPassword string) (int, error) {
+	var tokenVersion int
+	err := tx.QueryRowContext(ctx,
+		"UPDATE users SET password = $2, token_version = token_version + 1 WHERE id = $1 RETURNING token_version",
+		userID, hashedPassword).Scan(&tokenVersion)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM password_reset_tokens WHERE user_id = $1 AND used_at IS NULL",
+		userID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE api_keys SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL",
+		userID)
+	if err != nil {
+		return 0, err
+	}
+	return tokenVersion, nil
+}
+
+// CreateEmailVerificationToken сохраняет хеш одноразового токена
+// подтверждения адреса email пользователя
+func (r *SQLUserRepository) CreateEmailVerificationToken(ctx context.Context, userID int64, email, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		"INSERT INTO email_verification_tokens (user_id, email, token_hash, expires_at) VALUES ($1, $2, $3, $4)",
+		userID, email, tokenHash, expiresAt)
+	return err
+}
+
+// VerifyEmail по одноразовому токену отмечает адрес пользователя
+// подтвержденным и возвращает ID пользователя. Просроченные и использованные
+// токены, а также токены для адреса, который пользователь уже сменил, дают
+// ErrNotFound.
+func (r *SQLUserRepository) VerifyEmail(ctx context.Context, tokenHash string) (int64, error) {
+	var userID int64
+	err := r.db.QueryRowContext(ctx, `
+		WITH token AS (
+			UPDATE email_verification_tokens SET used_at = now()
+			WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+			RETURNING user_id, email)
+		UPDATE users u SET email_verified = true
+		FROM token
+		WHERE u.id = token.user_id AND lower(u.email) = lower(token.email)
+		RETURNING u.id`,
+		tokenHash).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+// MarkEmailVerified отмечает подтвержденным адрес email пользователя, если
+// он не изменился. Вызывается, когда адрес подтвердил провайдер OIDC.
+func (r *SQLUserRepository) MarkEmailVerified(ctx context.Context, userID int64, email string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET email_verified = true WHERE id = $1 AND lower(email) = lower($2)",
+		userID, email)
+	return err
+}
+
+// GetLockedUntil возвращает время, до которого вход пользователя заблокирован.
+// Для неизвестных и незаблокированных пользователей возвращается нулевое время.
+func (r *SQLUserRepository) GetLockedUntil(ctx context.Context, username string) (time.Time, error) {
+	var lockedUntil sql.NullTime
+	err := r.db.QueryRowContext(ctx,
+		"SELECT locked_until FROM users WHERE username = $1",
+		username).Scan(&lockedUntil)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, err
+	}
+	return lockedUntil.Time, nil
+}
+
+// RecordLoginFailure увеличивает счетчик неудачных попыток входа и возвращает
+// его новое значение (0 для неизвестного пользователя)
+func (r *SQLUserRepository) RecordLoginFailure(ctx context.Context, username string) (int, error) {
+	var failures int
+	err := r.db.QueryRowContext(ctx,
+		"UPDATE users SET failed_login_attempts = failed_login_attempts + 1 WHERE username = $1 RETURNING failed_login_attempts",
+		username).Scan(&failures)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	return failures, nil
+}
+
+// LockUser блокирует вход пользователя до until
+func (r *SQLUserRepository) LockUser(ctx context.Context, username string, until time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET locked_until = $2 WHERE username = $1",
+		username, until)
+	return err
+}
+
+// ResetLoginFailures сбрасывает счетчик неудачных попыток и блокировку после успешного входа
+func (r *SQLUserRepository) ResetLoginFailures(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET failed_login_attempts = 0, locked_until = NULL WHERE id = $1 AND (failed_login_attempts > 0 OR locked_until IS NOT NULL)",
+		userID)
+	return err
+}
@@ -2,12 +2,21 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"notes-service/internal/password"
+	"strings"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
+func userRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "username", "password", "email", "token_version", "totp_secret", "totp_enabled", "role", "disabled_at", "email_verified"})
+}
+
 func TestCreateUser(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -18,11 +27,11 @@ func TestCreateUser(t *testing.T) {
 	repo := NewUserRepository(db)
 
 	mock.ExpectQuery("INSERT INTO users").
-		WithArgs("testuser", sqlmock.AnyArg()).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).
-			AddRow(1, "testuser"))
+		WithArgs("testuser", sqlmock.AnyArg(), "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "role"}).
+			AddRow(1, "testuser", "user"))
 
-	user, err := repo.CreateUser(context.Background(), "testuser", "password")
+	user, err := repo.CreateUser(context.Background(), "testuser", "password", "")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
@@ -43,8 +52,8 @@ func TestGetUserByUsername(t *testing.T) {
 
 	repo := NewUserRepository(db)
 
-	rows := sqlmock.NewRows([]string{"id", "username", "password"}).
-		AddRow(1, "testuser", "hashedpassword")
+	rows := userRows().
+		AddRow(1, "testuser", "hashedpassword", nil, 0, nil, false, "user", nil, false)
 
 	mock.ExpectQuery("SELECT (.+) FROM users WHERE username = ?").
 		WithArgs("testuser").
@@ -72,10 +81,11 @@ func TestValidateUser(t *testing.T) {
 	repo := NewUserRepository(db)
 
 	// Хешированный пароль "password"
-	hashedPassword := "$2a$10$1234567890123456789012abcdefghijklmnopqrstuvwxyzABCDEF"
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	assert.NoError(t, err)
 
-	rows := sqlmock.NewRows([]string{"id", "username", "password"}).
-		AddRow(1, "testuser", hashedPassword)
+	rows := userRows().
+		AddRow(1, "testuser", string(hashedPassword), nil, 0, nil, false, "user", nil, false)
 
 	mock.ExpectQuery("SELECT (.+) FROM users WHERE username = ?").
 		WithArgs("testuser").
@@ -92,3 +102,271 @@ func TestValidateUser(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestCreateUserDuplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	mock.ExpectQuery("INSERT INTO users").
+		WithArgs("testuser", sqlmock.AnyArg(), "").
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	user, err := repo.CreateUser(context.Background(), "testuser", "password", "")
+
+	assert.ErrorIs(t, err, ErrDuplicate)
+	assert.Nil(t, user)
+}
+
+func TestCreateUserEmailTaken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	mock.ExpectQuery("INSERT INTO users").
+		WithArgs("testuser", sqlmock.AnyArg(), "user@example.com").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "idx_users_email"})
+
+	_, err = repo.CreateUser(context.Background(), "testuser", "password", "user@example.com")
+
+	assert.ErrorIs(t, err, ErrEmailTaken)
+	assert.ErrorIs(t, err, ErrDuplicate)
+}
+
+func TestGetUserByUsernameNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE username = ?").
+		WithArgs("nobody").
+		WillReturnRows(userRows())
+
+	user, err := repo.GetUserByUsername(context.Background(), "nobody")
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, user)
+}
+
+func TestValidateUserInvalidCredentials(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	hasher, err := password.NewHasher(password.Bcrypt, bcrypt.MinCost)
+	assert.NoError(t, err)
+	repo := NewUserRepository(db, WithHasher(hasher))
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE username = ?").
+		WithArgs("testuser").
+		WillReturnRows(userRows().
+			AddRow(1, "testuser", string(hashedPassword), nil, 0, nil, false, "user", nil, false))
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE username = ?").
+		WithArgs("nobody").
+		WillReturnRows(userRows())
+
+	// Неверный пароль и неизвестный пользователь неотличимы для вызывающего
+	_, wrongPasswordErr := repo.ValidateUser(context.Background(), "testuser", "wrong")
+	_, unknownUserErr := repo.ValidateUser(context.Background(), "nobody", "password")
+
+	assert.ErrorIs(t, wrongPasswordErr, ErrInvalidCredentials)
+	assert.ErrorIs(t, unknownUserErr, ErrInvalidCredentials)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestValidateUserRehashesOutdatedHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	hasher, err := password.NewHasher(password.Argon2id, bcrypt.DefaultCost)
+	assert.NoError(t, err)
+	repo := NewUserRepository(db, WithHasher(hasher))
+
+	oldHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE username = ?").
+		WithArgs("testuser").
+		WillReturnRows(userRows().AddRow(1, "testuser", string(oldHash), nil, 0, nil, false, "user", nil, false))
+	mock.ExpectExec("UPDATE users SET password").
+		WithArgs(int64(1), sqlmock.AnyArg(), string(oldHash)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	user, err := repo.ValidateUser(context.Background(), "testuser", "password")
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(user.Password, "$argon2id$"))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE password_reset_tokens SET used_at").
+		WithArgs("valid").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectQuery("UPDATE users SET password").
+		WithArgs(int64(1), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"token_version"}).AddRow(2))
+	mock.ExpectExec("DELETE FROM password_reset_tokens").
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE api_keys SET revoked_at = now\\(\\) WHERE user_id = \\$1 AND revoked_at IS NULL").
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	userID, err := repo.ResetPassword(context.Background(), "valid", "new-password")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), userID)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestResetPasswordUsedToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE password_reset_tokens SET used_at").
+		WithArgs("used").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectRollback()
+
+	_, err = repo.ResetPassword(context.Background(), "used", "new-password")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestResetPasswordKeepsTokenOnFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE password_reset_tokens SET used_at").
+		WithArgs("valid").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectQuery("UPDATE users SET password").
+		WithArgs(int64(1), sqlmock.AnyArg()).
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	_, err = repo.ResetPassword(context.Background(), "valid", "new-password")
+	assert.Error(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	mock.ExpectQuery("UPDATE email_verification_tokens SET used_at .* UPDATE users u SET email_verified = true").
+		WithArgs("valid").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	userID, err := repo.VerifyEmail(context.Background(), "valid")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), userID)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVerifyEmailUsedToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	mock.ExpectQuery("UPDATE email_verification_tokens SET used_at").
+		WithArgs("used").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = repo.VerifyEmail(context.Background(), "used")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestEnableTOTPSecretReplaced(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET totp_enabled = TRUE WHERE id = \\$1 AND totp_secret = \\$2 AND NOT totp_enabled").
+		WithArgs(int64(1), "OLDSECRET").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = repo.EnableTOTP(context.Background(), 1, "OLDSECRET", []string{"hash"})
+
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"notes-service/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Секрет вебхука не входит в webhookColumns: он нужен только при доставке
+const webhookColumns = "id, user_id, url, events, active, created_at"
+
+const deliveryColumns = "id, webhook_id, event, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at"
+
+func scanWebhook(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.Webhook, error) {
+	var webhook models.Webhook
+	dest := []interface{}{&webhook.ID, &webhook.UserID, &webhook.URL, pq.Array(&webhook.Events),
+		&webhook.Active, &webhook.CreatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func scanDelivery(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var payload []byte
+	var nextAttemptAt time.Time
+	var statusCode sql.NullInt64
+	var lastError sql.NullString
+	var deliveredAt sql.NullTime
+	dest := []interface{}{&delivery.ID, &delivery.WebhookID, &delivery.Event, &payload, &delivery.Status,
+		&delivery.Attempts, &nextAttemptAt, &statusCode, &lastError, &delivery.CreatedAt, &deliveredAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	delivery.Payload = payload
+	if delivery.Status == models.DeliveryPending || delivery.Status == models.DeliveryDelivering {
+		delivery.NextAttemptAt = &nextAttemptAt
+	}
+	if statusCode.Valid {
+		code := int(statusCode.Int64)
+		delivery.LastStatusCode = &code
+	}
+	delivery.LastError = lastError.String
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return &delivery, nil
+}
+
+// CreateWebhook создает подписку пользователя на события заметок
+func (r *PostgresRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO webhooks (user_id, url, secret, events, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		webhook.UserID, webhook.URL, webhook.Secret, pq.Array(webhook.Events), webhook.Active).
+		Scan(&webhook.ID, &webhook.CreatedAt)
+}
+
+// ListWebhooks возвращает вебхуки пользователя
+func (r *PostgresRepository) ListWebhooks(ctx context.Context, userID int64) ([]*models.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+webhookColumns+" FROM webhooks WHERE user_id = $1 ORDER BY id",
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetWebhook возвращает вебхук пользователя
+func (r *PostgresRepository) GetWebhook(ctx context.Context, userID, webhookID int64) (*models.Webhook, error) {
+	return scanWebhook(r.db.QueryRowContext(ctx,
+		"SELECT "+webhookColumns+" FROM webhooks WHERE id = $1 AND user_id = $2",
+		webhookID, userID))
+}
+
+// UpdateWebhook меняет адрес, события и активность вебхука. Секрет не меняется.
+func (r *PostgresRepository) UpdateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE webhooks SET url = $3, events = $4, active = $5
+		WHERE id = $1 AND user_id = $2
+		RETURNING created_at`,
+		webhook.ID, webhook.UserID, webhook.URL, pq.Array(webhook.Events), webhook.Active).
+		Scan(&webhook.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// DeleteWebhook удаляет вебхук вместе с его доставками
+func (r *PostgresRepository) DeleteWebhook(ctx context.Context, userID, webhookID int64) error {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM webhooks WHERE id = $1 AND user_id = $2",
+		webhookID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListWebhookDeliveries возвращает страницу доставок вебхука, новые первыми.
+// Пустой status — доставки в любом состоянии.
+func (r *PostgresRepository) ListWebhookDeliveries(ctx context.Context, userID, webhookID int64, status string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+prefixColumns("d", deliveryColumns)+`
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.webhook_id = $1 AND w.user_id = $2 AND ($3 = '' OR d.status = $3)
+		ORDER BY d.id DESC
+		LIMIT $4 OFFSET $5`,
+		webhookID, userID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetWebhookDelivery возвращает доставку вебхука пользователя вместе с журналом попыток
+func (r *PostgresRepository) GetWebhookDelivery(ctx context.Context, userID, webhookID, deliveryID int64) (*models.WebhookDelivery, error) {
+	delivery, err := scanDelivery(r.db.QueryRowContext(ctx, `
+		SELECT `+prefixColumns("d", deliveryColumns)+`
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.id = $1 AND d.webhook_id = $2 AND w.user_id = $3`,
+		deliveryID, webhookID, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT status_code, error, duration_ms, created_at
+		FROM webhook_attempts WHERE delivery_id = $1
+		ORDER BY id`,
+		deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	delivery.AttemptLog = []*models.WebhookAttempt{}
+	for rows.Next() {
+		var attempt models.WebhookAttempt
+		var statusCode sql.NullInt64
+		var attemptError sql.NullString
+		if err := rows.Scan(&statusCode, &attemptError, &attempt.DurationMS, &attempt.CreatedAt); err != nil {
+			return nil, err
+		}
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			attempt.StatusCode = &code
+		}
+		attempt.Error = attemptError.String
+		delivery.AttemptLog = append(delivery.AttemptLog, &attempt)
+	}
+	return delivery, rows.Err()
+}
+
+// ReplayWebhookDelivery ставит доставку в очередь заново со сброшенным
+// счетчиком попыток. Журнал прежних попыток сохраняется. Если доставка
+// выполняется прямо сейчас — ErrConflict.
+func (r *PostgresRepository) ReplayWebhookDelivery(ctx context.Context, userID, webhookID, deliveryID int64) (*models.WebhookDelivery, error) {
+	delivery, err := scanDelivery(r.db.QueryRowContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET status = 'pending', attempts = 0, next_attempt_at = now(), updated_at = now(), delivered_at = NULL
+		FROM webhooks w
+		WHERE d.id = $1 AND d.webhook_id = $2 AND w.id = d.webhook_id AND w.user_id = $3
+			AND d.status <> 'delivering'
+		RETURNING `+prefixColumns("d", deliveryColumns),
+		deliveryID, webhookID, userID))
+	if !errors.Is(err, ErrNotFound) {
+		return delivery, err
+	}
+	if _, err := r.GetWebhookDelivery(ctx, userID, webhookID, deliveryID); err != nil {
+		return nil, err
+	}
+	return nil, ErrConflict
+}
+
+// ClaimWebhookDelivery забирает в работу самую старую доставку активного
+// вебхука, время попытки которой наступило, и увеличивает счетчик попыток.
+// Возвращает доставку и вебхук с секретом. Доставка, которая выполняется
+// дольше staleAfter, начинается заново. Если доставок нет — ErrNotFound.
+func (r *PostgresRepository) ClaimWebhookDelivery(ctx context.Context, staleAfter time.Duration) (*models.WebhookDelivery, *models.Webhook, error) {
+	webhook := &models.Webhook{Active: true}
+	delivery, err := scanDelivery(r.db.QueryRowContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET status = 'delivering', attempts = d.attempts + 1, updated_at = now()
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id = (
+			SELECT dd.id FROM webhook_deliveries dd JOIN webhooks ww ON ww.id = dd.webhook_id
+			WHERE ww.active AND (
+				(dd.status = 'pending' AND dd.next_attempt_at <= now())
+				OR (dd.status = 'delivering' AND dd.updated_at < $1))
+			ORDER BY dd.next_attempt_at, dd.id
+			FOR UPDATE OF dd SKIP LOCKED
+			LIMIT 1)
+		RETURNING `+prefixColumns("d", deliveryColumns)+`, w.user_id, w.url, w.secret`,
+		time.Now().Add(-staleAfter)), &webhook.UserID, &webhook.URL, &webhook.Secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	webhook.ID = delivery.WebhookID
+	return delivery, webhook, nil
+}
+
+// RecordWebhookAttempt записывает попытку доставки и задает итоговое
+// состояние доставки. Для DeliveryPending следующая попытка — nextAttemptAt.
+func (r *PostgresRepository) RecordWebhookAttempt(ctx context.Context, deliveryID int64, attempt *models.WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO webhook_attempts (delivery_id, status_code, error, duration_ms)
+		VALUES ($1, $2, NULLIF($3, ''), $4)`,
+		deliveryID, attempt.StatusCode, attempt.Error, attempt.DurationMS)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, next_attempt_at = $3, last_status_code = $4, last_error = NULLIF($5, ''),
+			updated_at = now(), delivered_at = CASE WHEN $2 = 'succeeded' THEN now() END
+		WHERE id = $1`,
+		deliveryID, status, nextAttemptAt, attempt.StatusCode, attempt.Error)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+
+	return tx.Commit()
+}
+
+// PruneWebhookDeliveries удаляет завершенные доставки старше before и
+// возвращает их число
+func (r *PostgresRepository) PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM webhook_deliveries
+		WHERE status IN ('succeeded', 'failed') AND updated_at < $1`,
+		before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"notes-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var deliveryRowColumns = []string{"id", "webhook_id", "event", "payload", "status", "attempts", "next_attempt_at",
+	"last_status_code", "last_error", "created_at", "delivered_at"}
+
+func TestClaimWebhookDelivery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery("UPDATE webhook_deliveries d SET status = 'delivering', attempts = d.attempts \\+ 1(.+)WHERE ww.active(.+)FOR UPDATE OF dd SKIP LOCKED(.+)RETURNING (.+), w.user_id, w.url, w.secret").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(append(deliveryRowColumns, "user_id", "url", "secret")).
+			AddRow(10, 5, "note.updated", []byte(`{"note_id":7}`), "delivering", 3, now, 503, "503 Service Unavailable", now, nil,
+				1, "https://ci.example.com/hook", "0123456789abcdef"))
+
+	delivery, webhook, err := repo.ClaimWebhookDelivery(context.Background(), time.Minute)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), delivery.ID)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.JSONEq(t, `{"note_id":7}`, string(delivery.Payload))
+	assert.NotNil(t, delivery.NextAttemptAt)
+	assert.Equal(t, 503, *delivery.LastStatusCode)
+	assert.Equal(t, int64(5), webhook.ID)
+	assert.Equal(t, "https://ci.example.com/hook", webhook.URL)
+	assert.Equal(t, "0123456789abcdef", webhook.Secret)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimWebhookDeliveryEmptyQueue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectQuery("UPDATE webhook_deliveries").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, _, err = repo.ClaimWebhookDelivery(context.Background(), time.Minute)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordWebhookAttempt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	next := time.Now().Add(time.Minute)
+	code := 500
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO webhook_attempts").
+		WithArgs(int64(10), &code, "500 Internal Server Error", int64(12)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE webhook_deliveries SET status = \\$2, next_attempt_at = \\$3").
+		WithArgs(int64(10), models.DeliveryPending, next, &code, "500 Internal Server Error").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.RecordWebhookAttempt(context.Background(), 10,
+		&models.WebhookAttempt{StatusCode: &code, Error: "500 Internal Server Error", DurationMS: 12},
+		models.DeliveryPending, next)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReplayWebhookDeliveryInProgress(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery("UPDATE webhook_deliveries d SET status = 'pending', attempts = 0(.+)d.status <> 'delivering'").
+		WithArgs(int64(10), int64(5), int64(1)).
+		WillReturnRows(sqlmock.NewRows(deliveryRowColumns))
+	mock.ExpectQuery("SELECT (.+) FROM webhook_deliveries d JOIN webhooks w").
+		WithArgs(int64(10), int64(5), int64(1)).
+		WillReturnRows(sqlmock.NewRows(deliveryRowColumns).
+			AddRow(10, 5, "note.created", []byte(`{}`), "delivering", 1, now, nil, nil, now, nil))
+	mock.ExpectQuery("SELECT status_code, error, duration_ms, created_at FROM webhook_attempts").
+		WithArgs(int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"status_code", "error", "duration_ms", "created_at"}))
+
+	_, err = repo.ReplayWebhookDelivery(context.Background(), 1, 5, 10)
+
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetWebhookDeliveryWithAttempts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT (.+) FROM webhook_deliveries d JOIN webhooks w(.+)w.user_id = \\$3").
+		WithArgs(int64(10), int64(5), int64(1)).
+		WillReturnRows(sqlmock.NewRows(deliveryRowColumns).
+			AddRow(10, 5, "note.created", []byte(`{}`), "succeeded", 2, now, 200, nil, now, now))
+	mock.ExpectQuery("SELECT status_code, error, duration_ms, created_at FROM webhook_attempts").
+		WithArgs(int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"status_code", "error", "duration_ms", "created_at"}).
+			AddRow(nil, "connection refused", 3, now).
+			AddRow(200, nil, 40, now))
+
+	delivery, err := repo.GetWebhookDelivery(context.Background(), 1, 5, 10)
+
+	require.NoError(t, err)
+	assert.Nil(t, delivery.NextAttemptAt)
+	assert.NotNil(t, delivery.DeliveredAt)
+	require.Len(t, delivery.AttemptLog, 2)
+	assert.Nil(t, delivery.AttemptLog[0].StatusCode)
+	assert.Equal(t, "connection refused", delivery.AttemptLog[0].Error)
+	assert.Equal(t, 200, *delivery.AttemptLog[1].StatusCode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,339 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"notes-service/internal/models"
+)
+
+// ErrLastOwner возвращается при попытке понизить или удалить последнего
+// владельца пространства
+var ErrLastOwner = errors.New("workspace must keep at least one owner")
+
+// workspaceRole возвращает роль пользователя в пространстве. Пространства,
+// в которых пользователь не состоит, неотличимы от несуществующих (ErrNotFound).
+func (r *PostgresRepository) workspaceRole(ctx context.Context, userID, workspaceID int64) (string, error) {
+	var role string
+	err := r.db.QueryRowContext(ctx,
+		"SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2",
+		workspaceID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return role, err
+}
+
+// requireWorkspaceOwner проверяет, что пользователь — владелец пространства
+func (r *PostgresRepository) requireWorkspaceOwner(ctx context.Context, userID, workspaceID int64) error {
+	role, err := r.workspaceRole(ctx, userID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if role != models.WorkspaceOwner {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// CreateWorkspace создает пространство, его создатель становится владельцем
+func (r *PostgresRepository) CreateWorkspace(ctx context.Context, userID int64, name string) (*models.Workspace, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	workspace := models.Workspace{Name: name, Role: models.WorkspaceOwner}
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO workspaces (name, created_by) VALUES ($1, $2) RETURNING id, created_at",
+		name, userID).Scan(&workspace.ID, &workspace.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, $3)",
+		workspace.ID, userID, models.WorkspaceOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	return &workspace, tx.Commit()
+}
+
+// ListWorkspaces возвращает пространства, в которых состоит пользователь
+func (r *PostgresRepository) ListWorkspaces(ctx context.Context, userID int64) ([]*models.Workspace, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT w.id, w.name, w.created_at, m.role
+		FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE m.user_id = $1
+		ORDER BY w.name`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workspaces := []*models.Workspace{}
+	for rows.Next() {
+		var workspace models.Workspace
+		if err := rows.Scan(&workspace.ID, &workspace.Name, &workspace.CreatedAt, &workspace.Role); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, &workspace)
+	}
+
+	return workspaces, rows.Err()
+}
+
+// GetWorkspace возвращает пространство, в котором состоит пользователь
+func (r *PostgresRepository) GetWorkspace(ctx context.Context, userID, workspaceID int64) (*models.Workspace, error) {
+	var workspace models.Workspace
+	err := r.db.QueryRowContext(ctx, `
+		SELECT w.id, w.name, w.created_at, m.role
+		FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE w.id = $1 AND m.user_id = $2`,
+		workspaceID, userID).Scan(&workspace.ID, &workspace.Name, &workspace.CreatedAt, &workspace.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// ListMembers возвращает участников пространства; доступно любому участнику
+func (r *PostgresRepository) ListMembers(ctx context.Context, userID, workspaceID int64) ([]*models.WorkspaceMember, error) {
+	if _, err := r.workspaceRole(ctx, userID, workspaceID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT m.user_id, u.username, m.role, m.created_at
+		FROM workspace_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = $1
+		ORDER BY m.created_at`,
+		workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*models.WorkspaceMember{}
+	for rows.Next() {
+		var member models.WorkspaceMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Role, &member.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, &member)
+	}
+
+	return members, rows.Err()
+}
+
+// lockMembers блокирует строки участников пространства до конца транзакции
+// и возвращает их роли
+func lockMembers(ctx context.Context, tx *sql.Tx, workspaceID int64) (map[int64]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT user_id, role FROM workspace_members WHERE workspace_id = $1 FOR UPDATE",
+		workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := map[int64]string{}
+	for rows.Next() {
+		var userID int64
+		var role string
+		if err := rows.Scan(&userID, &role); err != nil {
+			return nil, err
+		}
+		roles[userID] = role
+	}
+	return roles, rows.Err()
+}
+
+// lastOwner сообщает, что memberID — единственный владелец пространства
+func lastOwner(roles map[int64]string, memberID int64) bool {
+	if roles[memberID] != models.WorkspaceOwner {
+		return false
+	}
+	for userID, role := range roles {
+		if userID != memberID && role == models.WorkspaceOwner {
+			return false
+		}
+	}
+	return true
+}
+
+// SetMemberRole меняет роль участника. Доступно только владельцам; последнего
+// владельца понизить нельзя (ErrLastOwner).
+func (r *PostgresRepository) SetMemberRole(ctx context.Context, userID, workspaceID, memberID int64, role string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	roles, err := lockMembers(ctx, tx, workspaceID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case roles[userID] == "":
+		return ErrNotFound
+	case roles[userID] != models.WorkspaceOwner:
+		return ErrForbidden
+	case roles[memberID] == "":
+		return ErrNotFound
+	case role != models.WorkspaceOwner && lastOwner(roles, memberID):
+		return ErrLastOwner
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE workspace_members SET role = $3 WHERE workspace_id = $1 AND user_id = $2",
+		workspaceID, memberID, role)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveMember исключает участника из пространства. Исключать может владелец,
+// а любой участник может выйти сам. Последний владелец выйти не может.
+func (r *PostgresRepository) RemoveMember(ctx context.Context, userID, workspaceID, memberID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	roles, err := lockMembers(ctx, tx, workspaceID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case roles[userID] == "":
+		return ErrNotFound
+	case userID != memberID && roles[userID] != models.WorkspaceOwner:
+		return ErrForbidden
+	case roles[memberID] == "":
+		return ErrNotFound
+	case lastOwner(roles, memberID):
+		return ErrLastOwner
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2",
+		workspaceID, memberID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateInvitation приглашает пользователя username в пространство с ролью
+// role. Приглашать может только владелец. Если пользователь уже участник или
+// у него есть ожидающее приглашение — ErrDuplicate.
+func (r *PostgresRepository) CreateInvitation(ctx context.Context, userID, workspaceID int64, username, role string) (*models.WorkspaceInvitation, error) {
+	if err := r.requireWorkspaceOwner(ctx, userID, workspaceID); err != nil {
+		return nil, err
+	}
+
+	invitation := models.WorkspaceInvitation{WorkspaceID: workspaceID, Username: username, Role: role}
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM users WHERE username = $1", username).
+		Scan(&invitation.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownUser
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.workspaceRole(ctx, invitation.UserID, workspaceID); err == nil {
+		return nil, ErrDuplicate
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO workspace_invitations (workspace_id, user_id, role, invited_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		workspaceID, invitation.UserID, role, userID).Scan(&invitation.ID, &invitation.CreatedAt)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicate
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// ListInvitations возвращает ожидающие приглашения пользователя
+func (r *PostgresRepository) ListInvitations(ctx context.Context, userID int64) ([]*models.WorkspaceInvitation, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT i.id, i.workspace_id, w.name, i.user_id, i.role, i.created_at
+		FROM workspace_invitations i
+		JOIN workspaces w ON w.id = i.workspace_id
+		WHERE i.user_id = $1 AND i.responded_at IS NULL
+		ORDER BY i.created_at DESC`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*models.WorkspaceInvitation{}
+	for rows.Next() {
+		var invitation models.WorkspaceInvitation
+		if err := rows.Scan(&invitation.ID, &invitation.WorkspaceID, &invitation.WorkspaceName,
+			&invitation.UserID, &invitation.Role, &invitation.CreatedAt); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, &invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+// RespondToInvitation принимает или отклоняет приглашение пользователя
+func (r *PostgresRepository) RespondToInvitation(ctx context.Context, userID, invitationID int64, accept bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var workspaceID int64
+	var role string
+	err = tx.QueryRowContext(ctx, `
+		UPDATE workspace_invitations SET responded_at = now()
+		WHERE id = $1 AND user_id = $2 AND responded_at IS NULL
+		RETURNING workspace_id, role`,
+		invitationID, userID).Scan(&workspaceID, &role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if accept {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO workspace_members (workspace_id, user_id, role) VALUES ($1, $2, $3)
+			ON CONFLICT (workspace_id, user_id) DO NOTHING`,
+			workspaceID, userID, role)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"notes-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMemberRoleKeepsLastOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT user_id, role FROM workspace_members WHERE workspace_id = (.+) FOR UPDATE").
+		WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role"}).
+			AddRow(1, "owner").
+			AddRow(2, "editor"))
+	mock.ExpectRollback()
+
+	err = repo.SetMemberRole(context.Background(), 1, 3, 1, models.WorkspaceEditor)
+
+	assert.ErrorIs(t, err, ErrLastOwner)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRemoveMemberRequiresOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT user_id, role FROM workspace_members").
+		WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role"}).
+			AddRow(1, "owner").
+			AddRow(2, "editor").
+			AddRow(4, "viewer"))
+	mock.ExpectRollback()
+
+	err = repo.RemoveMember(context.Background(), 2, 3, 4)
+
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreateNoteInWorkspaceAsViewer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	workspaceID := int64(3)
+	now := time.Now()
+
+	mock.ExpectQuery("INSERT INTO notes").
+		WithArgs(int64(4), &workspaceID, nil, "Title", "Content", models.FormatPlain, now, now, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version"}))
+	mock.ExpectQuery("SELECT role FROM workspace_members").
+		WithArgs(int64(3), int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("viewer"))
+
+	err = repo.CreateNote(context.Background(), &models.Note{
+		UserID: 4, WorkspaceID: &workspaceID, Title: "Title", Content: "Content", ContentFormat: models.FormatPlain,
+		CreatedAt: now, UpdatedAt: now,
+	})
+
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestListNotesInWorkspaceRequiresMembership(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresRepository{db: db}
+	workspaceID := int64(3)
+
+	mock.ExpectQuery("SELECT role FROM workspace_members").
+		WithArgs(int64(3), int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}))
+
+	_, err = repo.ListNotes(context.Background(), 5, models.NoteFilter{WorkspaceID: &workspaceID})
+
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
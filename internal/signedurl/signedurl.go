@@ -0,0 +1,75 @@
+// Package signedurl выдает и проверяет временные подписанные ссылки на
+// вложения, которые открываются без аутентификации
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidSignature возвращается для ссылки с неверной подписью
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrExpired возвращается для ссылки с истекшим сроком действия
+	ErrExpired = errors.New("link expired")
+)
+
+// Signer подписывает ссылки вида {baseURL}/files/{id}?size=&expires=&signature=
+type Signer struct {
+	secret  []byte
+	baseURL string
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// NewSigner создает новый экземпляр Signer. Ссылки действуют не меньше ttl.
+func NewSigner(secret []byte, baseURL string, ttl time.Duration) *Signer {
+	return &Signer{secret: secret, baseURL: strings.TrimSuffix(baseURL, "/"), ttl: ttl, now: time.Now}
+}
+
+// AttachmentURL возвращает подписанную ссылку на вложение или, если size не
+// пуст, на его миниатюру. Срок действия округляется вверх до ttl, чтобы
+// ссылка не менялась при каждом показе заметки и кешировалась браузером.
+func (s *Signer) AttachmentURL(attachmentID int64, size string) string {
+	expires := s.now().Add(s.ttl).Truncate(s.ttl).Add(s.ttl).Unix()
+
+	query := url.Values{}
+	if size != "" {
+		query.Set("size", size)
+	}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(attachmentID, size, expires))
+	return s.baseURL + "/files/" + strconv.FormatInt(attachmentID, 10) + "?" + query.Encode()
+}
+
+// Verify проверяет подпись и срок действия ссылки на вложение
+func (s *Signer) Verify(attachmentID int64, size, expires, signature string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	want, _ := hex.DecodeString(s.sign(attachmentID, size, exp))
+	if !hmac.Equal(given, want) {
+		return ErrInvalidSignature
+	}
+	if s.now().Unix() > exp {
+		return ErrExpired
+	}
+	return nil
+}
+
+func (s *Signer) sign(attachmentID int64, size string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("attachment:" + strconv.FormatInt(attachmentID, 10) + ":" + size + ":" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
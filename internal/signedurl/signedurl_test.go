@@ -0,0 +1,34 @@
+package signedurl
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachmentURL(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := NewSigner([]byte("secret"), "https://notes.example.com/", time.Hour)
+	s.now = func() time.Time { return now }
+
+	link := s.AttachmentURL(42, "small")
+	assert.True(t, strings.HasPrefix(link, "https://notes.example.com/files/42?"))
+	// Ссылка не меняется в пределах окна округления
+	s.now = func() time.Time { return now.Add(10 * time.Minute) }
+	assert.Equal(t, link, s.AttachmentURL(42, "small"))
+
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	q := u.Query()
+	assert.NoError(t, s.Verify(42, "small", q.Get("expires"), q.Get("signature")))
+	assert.ErrorIs(t, s.Verify(42, "", q.Get("expires"), q.Get("signature")), ErrInvalidSignature)
+	assert.ErrorIs(t, s.Verify(43, "small", q.Get("expires"), q.Get("signature")), ErrInvalidSignature)
+	assert.ErrorIs(t, s.Verify(42, "small", "9999999999", q.Get("signature")), ErrInvalidSignature)
+
+	s.now = func() time.Time { return now.Add(3 * time.Hour) }
+	assert.ErrorIs(t, s.Verify(42, "small", q.Get("expires"), q.Get("signature")), ErrExpired)
+}
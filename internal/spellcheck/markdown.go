@@ -0,0 +1,67 @@
+package spellcheck
+
+import (
+	"notes-service/internal/markdown"
+	"notes-service/internal/models"
+	"strings"
+)
+
+// Фрагменты, скрытые от проверки, заменяются символами из области частного
+// использования Unicode, которые спеллер не считает словами. Символы этой
+// области, которые уже есть в тексте (например, значки шрифтов иконок),
+// заменителями не служат.
+const (
+	placeholderFirst = 0xE000
+	placeholderLast  = 0xF8FF
+)
+
+// CheckMarkdown проверяет орфографию текста в Markdown, не затрагивая код,
+// HTML и адреса
+func CheckMarkdown(checker Spellchecker, text string) (string, error) {
+	ranges := markdown.ProtectedRanges(text)
+	if len(ranges) == 0 {
+		return checker.CheckSpelling(text)
+	}
+
+	used := make(map[rune]bool)
+	for _, c := range text {
+		if c >= placeholderFirst && c <= placeholderLast {
+			used[c] = true
+		}
+	}
+	if len(ranges) > placeholderLast-placeholderFirst+1-len(used) {
+		// Почти весь текст — код, исправлять в нем нечего
+		return text, nil
+	}
+
+	var masked strings.Builder
+	restore := make([]string, 0, 2*len(ranges))
+	next := rune(placeholderFirst)
+	last := 0
+	for _, r := range ranges {
+		for used[next] {
+			next++
+		}
+		placeholder := string(next)
+		next++
+		masked.WriteString(text[last:r[0]])
+		masked.WriteString(placeholder)
+		restore = append(restore, placeholder, text[r[0]:r[1]])
+		last = r[1]
+	}
+	masked.WriteString(text[last:])
+
+	corrected, err := checker.CheckSpelling(masked.String())
+	if err != nil {
+		return "", err
+	}
+	return strings.NewReplacer(restore...).Replace(corrected), nil
+}
+
+// Check проверяет орфографию текста заметки с учетом ее формата
+func Check(checker Spellchecker, format, text string) (string, error) {
+	if format == models.FormatMarkdown {
+		return CheckMarkdown(checker, text)
+	}
+	return checker.CheckSpelling(text)
+}
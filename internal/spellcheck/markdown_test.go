@@ -0,0 +1,42 @@
+package spellcheck
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replaceChecker исправляет опечатки по словарю и запоминает переданный текст
+type replaceChecker struct {
+	fixes   *strings.Replacer
+	checked string
+}
+
+func (c *replaceChecker) CheckSpelling(text string) (string, error) {
+	c.checked = text
+	return c.fixes.Replace(text), nil
+}
+
+func TestCheckMarkdownSkipsCode(t *testing.T) {
+	checker := &replaceChecker{fixes: strings.NewReplacer("teh", "the")}
+
+	corrected, err := CheckMarkdown(checker, "teh `teh` and https://example.com/teh")
+
+	require.NoError(t, err)
+	assert.Equal(t, "the `teh` and https://example.com/teh", corrected)
+	assert.NotContains(t, checker.checked, "`teh`")
+}
+
+func TestCheckMarkdownKeepsPrivateUseCharacters(t *testing.T) {
+	checker := &replaceChecker{fixes: strings.NewReplacer("teh", "the")}
+	// U+E000 и U+E001 — значки шрифта иконок, вставленные из другого приложения
+	text := "\ue000 teh `code` \ue001 and `more`"
+
+	corrected, err := CheckMarkdown(checker, text)
+
+	require.NoError(t, err)
+	assert.Equal(t, "\ue000 the `code` \ue001 and `more`", corrected)
+	assert.NotContains(t, checker.checked, "`code`")
+}
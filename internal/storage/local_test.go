@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	key := NewKey("notes/1")
+	require.NoError(t, store.Put(ctx, key, strings.NewReader("hello world"), 11, "text/plain"))
+
+	body, err := store.Get(ctx, key, 6, 3)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "wor", string(data))
+
+	require.NoError(t, store.Delete(ctx, key))
+	_, err = store.Get(ctx, key, 0, -1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, store.Delete(ctx, key))
+}
+
+func TestLocalStoreShortUpload(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	err = store.Put(context.Background(), "k", strings.NewReader("abc"), 10, "text/plain")
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	_, err = store.Get(context.Background(), "k", 0, -1)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalStoreRejectsTraversal(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	err = store.Put(context.Background(), "../escape", strings.NewReader("x"), 1, "text/plain")
+	assert.ErrorContains(t, err, "invalid key")
+}
@@ -0,0 +1,93 @@
+// Package storage хранит содержимое вложений вне базы: в локальной файловой
+// системе или в S3-совместимом хранилище
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// ErrNotFound возвращается, если объекта с таким ключом нет
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore хранит объекты по ключу. Ключ — путь из сегментов через "/".
+type BlobStore interface {
+	// Put сохраняет size байт из r под ключом key
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get читает length байт объекта начиная с offset; при length < 0 — до конца
+	Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Delete удаляет объект; отсутствие объекта ошибкой не считается
+	Delete(ctx context.Context, key string) error
+}
+
+// NewKey возвращает случайный ключ для нового объекта
+func NewKey(prefix string) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return prefix + "/" + hex.EncodeToString(b)
+}
+
+// objectReader читает объект известного размера через io.ReadSeeker, открывая
+// его с нужного места при первом чтении после Seek
+type objectReader struct {
+	ctx    context.Context
+	store  BlobStore
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+// NewReader возвращает io.ReadSeekCloser для объекта размером size. Его
+// можно передать в http.ServeContent: запросы Range читают из хранилища
+// только нужную часть объекта.
+func NewReader(ctx context.Context, store BlobStore, key string, size int64) io.ReadSeekCloser {
+	return &objectReader{ctx: ctx, store: store, key: key, size: size}
+}
+
+func (o *objectReader) Read(p []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+	if o.body == nil {
+		body, err := o.store.Get(o.ctx, o.key, o.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		o.body = body
+	}
+	n, err := o.body.Read(p)
+	o.offset += int64(n)
+	return n, err
+}
+
+func (o *objectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += o.size
+	}
+	if offset < 0 {
+		return 0, errors.New("storage: negative position")
+	}
+	if offset != o.offset {
+		o.Close()
+		o.offset = offset
+	}
+	return offset, nil
+}
+
+func (o *objectReader) Close() error {
+	if o.body == nil {
+		return nil
+	}
+	err := o.body.Close()
+	o.body = nil
+	return err
+}
@@ -0,0 +1,102 @@
+// Package thumbnail создает уменьшенные копии изображений из вложений
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // декодер GIF для image.Decode
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// maxPixels ограничивает размер декодируемого изображения, чтобы небольшой
+// файл с огромными размерами не занял всю память
+const maxPixels = 50_000_000
+
+// jpegQuality — качество JPEG-миниатюр
+const jpegQuality = 85
+
+// ErrTooLarge возвращается для изображений больше maxPixels
+var ErrTooLarge = errors.New("image is too large")
+
+// Variant — вариант миниатюры: изображение вписывается в квадрат MaxSize
+type Variant struct {
+	Name    string
+	MaxSize int
+}
+
+// Variants — создаваемые варианты миниатюр
+var Variants = []Variant{
+	{Name: "small", MaxSize: 256},
+	{Name: "large", MaxSize: 1024},
+}
+
+// Supported сообщает, создаются ли миниатюры для файлов типа contentType
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// Decode декодирует JPEG, PNG или GIF (первый кадр) и возвращает формат
+func Decode(data []byte) (image.Image, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, "", fmt.Errorf("invalid image size %dx%d", cfg.Width, cfg.Height)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return nil, "", ErrTooLarge
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return img, format, nil
+}
+
+// Resize уменьшает изображение так, чтобы большая сторона не превышала
+// maxSize. Изображения меньше maxSize не увеличиваются.
+func Resize(img image.Image, maxSize int) image.Image {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width <= maxSize && height <= maxSize {
+		return img
+	}
+	if width >= height {
+		height = max(1, height*maxSize/width)
+		width = maxSize
+	} else {
+		width = max(1, width*maxSize/height)
+		height = maxSize
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// Encode кодирует миниатюру: фотографии — в JPEG, PNG и GIF — в PNG, чтобы
+// сохранить прозрачность. Возвращает тип содержимого.
+func Encode(img image.Image, format string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
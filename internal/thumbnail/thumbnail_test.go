@@ -0,0 +1,105 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"notes-service/internal/models"
+	"notes-service/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.NRGBA{R: 255, A: 128})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestResize(t *testing.T) {
+	img, format, err := Decode(encodePNG(t, 2000, 500))
+	require.NoError(t, err)
+	assert.Equal(t, "png", format)
+
+	small := Resize(img, 256)
+	assert.Equal(t, image.Rect(0, 0, 256, 64), small.Bounds())
+
+	// Маленькие изображения не увеличиваются
+	tiny, _, err := Decode(encodePNG(t, 10, 20))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 10, 20), Resize(tiny, 256).Bounds())
+}
+
+func TestDecodeRejectsHugeImage(t *testing.T) {
+	// Заголовок PNG с размерами 100000x100000 без данных изображения
+	data := encodePNG(t, 1, 1)
+	copy(data[16:24], []byte{0, 1, 0x86, 0xa0, 0, 1, 0x86, 0xa0})
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+
+	_, _, err := Decode(data)
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+// fakeRepo запоминает сохраненные миниатюры
+type fakeRepo struct {
+	thumbnails []*models.Thumbnail
+	status     string
+}
+
+func (r *fakeRepo) ClaimThumbnailJob(ctx context.Context, staleAfter time.Duration) (*models.Attachment, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) SaveThumbnails(ctx context.Context, attachmentID int64, thumbnails []*models.Thumbnail, status string) error {
+	r.thumbnails, r.status = thumbnails, status
+	return nil
+}
+
+func TestWorkerProcess(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	data := encodePNG(t, 2048, 1024)
+	require.NoError(t, store.Put(context.Background(), "notes/7/img", bytes.NewReader(data), int64(len(data)), "image/png"))
+
+	repo := &fakeRepo{}
+	w := NewWorker(repo, store)
+	w.Process(context.Background(), &models.Attachment{ID: 3, Size: int64(len(data)), StorageKey: "notes/7/img"})
+
+	assert.Equal(t, models.ThumbnailReady, repo.status)
+	require.Len(t, repo.thumbnails, 2)
+	assert.Equal(t, "small", repo.thumbnails[0].Size)
+	assert.Equal(t, 256, repo.thumbnails[0].Width)
+	assert.Equal(t, 128, repo.thumbnails[0].Height)
+	assert.Equal(t, "image/png", repo.thumbnails[0].ContentType)
+	assert.Equal(t, 1024, repo.thumbnails[1].Width)
+
+	body, err := store.Get(context.Background(), repo.thumbnails[0].StorageKey, 0, -1)
+	require.NoError(t, err)
+	defer body.Close()
+	cfg, err := png.DecodeConfig(body)
+	require.NoError(t, err)
+	assert.Equal(t, 256, cfg.Width)
+}
+
+func TestWorkerProcessNotAnImage(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, store.Put(context.Background(), "notes/7/img", bytes.NewReader([]byte("GIF89a broken")), 13, "image/gif"))
+
+	repo := &fakeRepo{}
+	NewWorker(repo, store).Process(context.Background(), &models.Attachment{ID: 3, Size: 13, StorageKey: "notes/7/img"})
+
+	assert.Equal(t, models.ThumbnailFailed, repo.status)
+	assert.Empty(t, repo.thumbnails)
+}
@@ -0,0 +1,146 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"notes-service/internal/storage"
+	"strconv"
+	"time"
+)
+
+const (
+	// pollInterval — как часто Worker проверяет очередь без уведомлений
+	pollInterval = 30 * time.Second
+	// staleAfter — через сколько незавершенная обработка начинается заново
+	staleAfter = 5 * time.Minute
+)
+
+// Worker создает миниатюры для загруженных изображений
+type Worker struct {
+	repo   repository.ThumbnailRepository
+	store  storage.BlobStore
+	notify chan struct{}
+}
+
+// NewWorker создает новый экземпляр Worker
+func NewWorker(repo repository.ThumbnailRepository, store storage.BlobStore) *Worker {
+	return &Worker{repo: repo, store: store, notify: make(chan struct{}, 1)}
+}
+
+// Notify сообщает Worker о новом изображении, чтобы не ждать следующего опроса
+func (w *Worker) Notify() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run обрабатывает изображения, пока не отменен ctx
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for w.runNext(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.notify:
+		}
+	}
+}
+
+// runNext обрабатывает одно изображение из очереди и сообщает, было ли оно
+func (w *Worker) runNext(ctx context.Context) bool {
+	attachment, err := w.repo.ClaimThumbnailJob(ctx, staleAfter)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to claim thumbnail job: %v", err)
+		return false
+	}
+
+	w.Process(ctx, attachment)
+	return true
+}
+
+// Process создает все варианты миниатюр вложения. Если файл не читается как
+// изображение, обработка завершается с ThumbnailFailed. При ошибках хранилища
+// состояние не меняется, и изображение обрабатывается снова через staleAfter.
+func (w *Worker) Process(ctx context.Context, attachment *models.Attachment) {
+	data, err := w.read(ctx, attachment)
+	if err != nil {
+		log.Printf("Failed to read attachment %d: %v", attachment.ID, err)
+		return
+	}
+
+	img, format, err := Decode(data)
+	if err != nil {
+		log.Printf("Failed to decode image in attachment %d: %v", attachment.ID, err)
+		w.save(ctx, attachment.ID, nil, models.ThumbnailFailed)
+		return
+	}
+
+	var thumbnails []*models.Thumbnail
+	for _, variant := range Variants {
+		resized := Resize(img, variant.MaxSize)
+		encoded, contentType, err := Encode(resized, format)
+		if err == nil {
+			thumbnail := &models.Thumbnail{
+				Size:        variant.Name,
+				Width:       resized.Bounds().Dx(),
+				Height:      resized.Bounds().Dy(),
+				ContentType: contentType,
+				ByteSize:    int64(len(encoded)),
+				StorageKey:  storage.NewKey("thumbnails/" + strconv.FormatInt(attachment.ID, 10)),
+			}
+			err = w.store.Put(ctx, thumbnail.StorageKey, bytes.NewReader(encoded), thumbnail.ByteSize, contentType)
+			if err == nil {
+				thumbnails = append(thumbnails, thumbnail)
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to create %s thumbnail for attachment %d: %v", variant.Name, attachment.ID, err)
+			w.discard(ctx, thumbnails)
+			return
+		}
+	}
+
+	if !w.save(ctx, attachment.ID, thumbnails, models.ThumbnailReady) {
+		w.discard(ctx, thumbnails)
+	}
+}
+
+func (w *Worker) read(ctx context.Context, attachment *models.Attachment) ([]byte, error) {
+	body, err := w.store.Get(ctx, attachment.StorageKey, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(io.LimitReader(body, attachment.Size))
+}
+
+func (w *Worker) save(ctx context.Context, attachmentID int64, thumbnails []*models.Thumbnail, status string) bool {
+	if err := w.repo.SaveThumbnails(ctx, attachmentID, thumbnails, status); err != nil {
+		log.Printf("Failed to save thumbnails for attachment %d: %v", attachmentID, err)
+		return false
+	}
+	return true
+}
+
+// discard удаляет из хранилища миниатюры, которые не попали в базу
+func (w *Worker) discard(ctx context.Context, thumbnails []*models.Thumbnail) {
+	for _, t := range thumbnails {
+		if err := w.store.Delete(ctx, t.StorageKey); err != nil {
+			log.Printf("Failed to delete blob %s: %v", t.StorageKey, err)
+		}
+	}
+}
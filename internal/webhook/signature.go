@@ -0,0 +1,46 @@
+// Package webhook доставляет события заметок на адреса вебхуков
+// пользователей: подписывает запросы и повторяет неудачные доставки
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Заголовки запроса доставки
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// signaturePrefix указывает алгоритм подписи в заголовке X-Webhook-Signature
+const signaturePrefix = "sha256="
+
+// Sign подписывает тело запроса: HMAC-SHA256 с секретом вебхука от строки
+// "<timestamp>.<body>". Метка времени в подписи не дает повторить старый
+// запрос.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись запроса и то, что метка времени отличается от now
+// не больше чем на tolerance. Предназначена для получателей вебхуков.
+func Verify(secret, signature, timestamp string, body []byte, tolerance time.Duration, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if diff := now.Sub(time.Unix(ts, 0)); diff > tolerance || diff < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, ts, body)))
+}
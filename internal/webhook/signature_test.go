@@ -0,0 +1,31 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignKnownValue(t *testing.T) {
+	// echo -n '1700000000.{"event":"note.created"}' | openssl dgst -sha256 -hmac secret
+	sig := Sign("secret", 1700000000, []byte(`{"event":"note.created"}`))
+	assert.Equal(t, "sha256=11e3e930d1f3c99c147eac9fe7cef7a07dbf05fddf8388764801864c9fd4ff6d", sig)
+	assert.NotEqual(t, sig, Sign("other", 1700000000, []byte(`{"event":"note.created"}`)))
+	assert.NotEqual(t, sig, Sign("secret", 1700000001, []byte(`{"event":"note.created"}`)))
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"note_id":1}`)
+	sig := Sign("secret", now.Unix(), body)
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	assert.True(t, Verify("secret", sig, ts, body, 5*time.Minute, now))
+	assert.True(t, Verify("secret", sig, ts, body, 5*time.Minute, now.Add(4*time.Minute)))
+	assert.False(t, Verify("secret", sig, ts, body, 5*time.Minute, now.Add(6*time.Minute)), "stale timestamp")
+	assert.False(t, Verify("wrong", sig, ts, body, 5*time.Minute, now))
+	assert.False(t, Verify("secret", sig, ts, []byte(`{"note_id":2}`), 5*time.Minute, now))
+	assert.False(t, Verify("secret", sig, "not-a-number", body, 5*time.Minute, now))
+}
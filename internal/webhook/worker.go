@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// pollInterval — как часто Worker проверяет очередь без уведомлений.
+	// Доставки ставит в очередь триггер базы, поэтому опрос частый.
+	pollInterval = 5 * time.Second
+	// staleAfter — через сколько незавершенная доставка начинается заново
+	staleAfter = time.Minute
+	// MaxAttempts — после стольких неудачных попыток доставка считается failed
+	MaxAttempts = 8
+	// retryBase и retryMax задают экспоненциальную задержку между попытками
+	retryBase = 30 * time.Second
+	retryMax  = 6 * time.Hour
+	// maxErrorBytes ограничивает сохраняемую часть ответа получателя
+	maxErrorBytes = 512
+)
+
+// Worker отправляет доставки вебхуков из очереди
+type Worker struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+	notify chan struct{}
+}
+
+// NewWorker создает новый экземпляр Worker
+func NewWorker(repo repository.WebhookRepository, client *http.Client) *Worker {
+	return &Worker{repo: repo, client: client, notify: make(chan struct{}, 1)}
+}
+
+// Notify сообщает Worker о доставке, поставленной в очередь вручную
+func (w *Worker) Notify() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run отправляет доставки, пока не отменен ctx
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for w.runNext(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.notify:
+		}
+	}
+}
+
+// runNext отправляет одну доставку из очереди и сообщает, была ли она
+func (w *Worker) runNext(ctx context.Context) bool {
+	delivery, webhook, err := w.repo.ClaimWebhookDelivery(ctx, staleAfter)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to claim webhook delivery: %v", err)
+		return false
+	}
+
+	w.Deliver(ctx, delivery, webhook)
+	return true
+}
+
+// Deliver выполняет попытку доставки и записывает ее результат. Ответ 2xx —
+// успех; иначе доставка повторяется с экспоненциальной задержкой, пока не
+// исчерпано MaxAttempts попыток.
+func (w *Worker) Deliver(ctx context.Context, delivery *models.WebhookDelivery, webhook *models.Webhook) {
+	start := time.Now()
+	statusCode, err := w.send(ctx, delivery, webhook)
+	attempt := &models.WebhookAttempt{DurationMS: time.Since(start).Milliseconds()}
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	status, next := models.DeliverySucceeded, time.Now()
+	if err != nil {
+		status = models.DeliveryFailed
+		if delivery.Attempts < MaxAttempts {
+			status, next = models.DeliveryPending, next.Add(Backoff(delivery.Attempts))
+		}
+	}
+	if err := w.repo.RecordWebhookAttempt(ctx, delivery.ID, attempt, status, next); err != nil {
+		log.Printf("Failed to record webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// send отправляет подписанный запрос и возвращает код ответа получателя
+func (w *Worker) send(ctx context.Context, delivery *models.WebhookDelivery, webhook *models.Webhook) (int, error) {
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "notes-service-webhooks")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message := resp.Status
+		if len(body) > 0 {
+			message += ": " + strings.ToValidUTF8(string(body), "")
+		}
+		return resp.StatusCode, errors.New(message)
+	}
+	return resp.StatusCode, nil
+}
+
+// Backoff возвращает задержку перед повтором после attempts неудачных
+// попыток: 30s, 1m, 2m, ... но не больше 6h
+func Backoff(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts && delay < retryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMax)
+}
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"notes-service/internal/models"
+	"notes-service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepo отдает одну доставку и запоминает записанные попытки
+type fakeRepo struct {
+	delivery *models.WebhookDelivery
+	webhook  *models.Webhook
+	recorded []recordedAttempt
+}
+
+type recordedAttempt struct {
+	attempt *models.WebhookAttempt
+	status  string
+	next    time.Time
+}
+
+func (r *fakeRepo) CreateWebhook(ctx context.Context, webhook *models.Webhook) error { return nil }
+
+func (r *fakeRepo) ListWebhooks(ctx context.Context, userID int64) ([]*models.Webhook, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) GetWebhook(ctx context.Context, userID, webhookID int64) (*models.Webhook, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) UpdateWebhook(ctx context.Context, webhook *models.Webhook) error { return nil }
+
+func (r *fakeRepo) DeleteWebhook(ctx context.Context, userID, webhookID int64) error { return nil }
+
+func (r *fakeRepo) ListWebhookDeliveries(ctx context.Context, userID, webhookID int64, status string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) GetWebhookDelivery(ctx context.Context, userID, webhookID, deliveryID int64) (*models.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) ReplayWebhookDelivery(ctx context.Context, userID, webhookID, deliveryID int64) (*models.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (r *fakeRepo) ClaimWebhookDelivery(ctx context.Context, staleAfter time.Duration) (*models.WebhookDelivery, *models.Webhook, error) {
+	if r.delivery == nil {
+		return nil, nil, repository.ErrNotFound
+	}
+	delivery := r.delivery
+	r.delivery = nil
+	delivery.Attempts++
+	return delivery, r.webhook, nil
+}
+
+func (r *fakeRepo) RecordWebhookAttempt(ctx context.Context, deliveryID int64, attempt *models.WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	r.recorded = append(r.recorded, recordedAttempt{attempt: attempt, status: status, next: nextAttemptAt})
+	return nil
+}
+
+func (r *fakeRepo) PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func newDelivery(attempts int) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		ID:       42,
+		Event:    models.WebhookNoteUpdated,
+		Payload:  json.RawMessage(`{"event":"note.updated","note_id":7}`),
+		Attempts: attempts,
+	}
+}
+
+func TestWorkerDeliversSignedRequest(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	repo := &fakeRepo{
+		delivery: newDelivery(0),
+		webhook:  &models.Webhook{ID: 3, URL: receiver.URL + "/hook", Secret: "0123456789abcdef"},
+	}
+	worker := NewWorker(repo, NewClient(true))
+
+	assert.True(t, worker.runNext(context.Background()))
+	assert.False(t, worker.runNext(context.Background()))
+
+	require.NotNil(t, got)
+	assert.Equal(t, http.MethodPost, got.Method)
+	assert.Equal(t, "/hook", got.URL.Path)
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, models.WebhookNoteUpdated, got.Header.Get(HeaderEvent))
+	assert.Equal(t, "42", got.Header.Get(HeaderDelivery))
+	assert.JSONEq(t, `{"event":"note.updated","note_id":7}`, string(body))
+	assert.True(t, Verify("0123456789abcdef", got.Header.Get(HeaderSignature), got.Header.Get(HeaderTimestamp),
+		body, time.Minute, time.Now()))
+
+	require.Len(t, repo.recorded, 1)
+	assert.Equal(t, models.DeliverySucceeded, repo.recorded[0].status)
+	require.NotNil(t, repo.recorded[0].attempt.StatusCode)
+	assert.Equal(t, http.StatusNoContent, *repo.recorded[0].attempt.StatusCode)
+	assert.Empty(t, repo.recorded[0].attempt.Error)
+}
+
+func TestWorkerRetriesFailedDelivery(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "try later", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	repo := &fakeRepo{
+		delivery: newDelivery(2),
+		webhook:  &models.Webhook{ID: 3, URL: receiver.URL, Secret: "0123456789abcdef"},
+	}
+	start := time.Now()
+	NewWorker(repo, NewClient(true)).runNext(context.Background())
+
+	require.Len(t, repo.recorded, 1)
+	rec := repo.recorded[0]
+	assert.Equal(t, models.DeliveryPending, rec.status)
+	assert.Equal(t, http.StatusServiceUnavailable, *rec.attempt.StatusCode)
+	assert.Contains(t, rec.attempt.Error, "try later")
+	// Третья попытка не удалась: следующая через 30s·2²
+	assert.WithinDuration(t, start.Add(2*time.Minute), rec.next, 5*time.Second)
+}
+
+func TestWorkerGivesUpAfterMaxAttempts(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	repo := &fakeRepo{
+		delivery: newDelivery(MaxAttempts - 1),
+		webhook:  &models.Webhook{ID: 3, URL: receiver.URL, Secret: "0123456789abcdef"},
+	}
+	NewWorker(repo, NewClient(true)).runNext(context.Background())
+
+	require.Len(t, repo.recorded, 1)
+	assert.Equal(t, models.DeliveryFailed, repo.recorded[0].status)
+}
+
+func TestWorkerDoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/elsewhere" {
+			followed = true
+			return
+		}
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer receiver.Close()
+
+	repo := &fakeRepo{
+		delivery: newDelivery(0),
+		webhook:  &models.Webhook{ID: 3, URL: receiver.URL, Secret: "0123456789abcdef"},
+	}
+	NewWorker(repo, NewClient(true)).runNext(context.Background())
+
+	assert.False(t, followed)
+	require.Len(t, repo.recorded, 1)
+	assert.Equal(t, models.DeliveryPending, repo.recorded[0].status)
+	assert.Equal(t, http.StatusFound, *repo.recorded[0].attempt.StatusCode)
+}
+
+func TestClientRejectsPrivateAddresses(t *testing.T) {
+	called := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	repo := &fakeRepo{
+		delivery: newDelivery(0),
+		webhook:  &models.Webhook{ID: 3, URL: receiver.URL, Secret: "0123456789abcdef"},
+	}
+	NewWorker(repo, NewClient(false)).runNext(context.Background())
+
+	assert.False(t, called)
+	require.Len(t, repo.recorded, 1)
+	assert.Nil(t, repo.recorded[0].attempt.StatusCode)
+	assert.Contains(t, repo.recorded[0].attempt.Error, ErrPrivateAddress.Error())
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 4*time.Minute, Backoff(4))
+	assert.Equal(t, 6*time.Hour, Backoff(20))
+}
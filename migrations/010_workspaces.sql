@@ -0,0 +1,51 @@
+-- Рабочие пространства: заметки команды с ролями участников
+CREATE TABLE IF NOT EXISTS workspaces (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(8) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members(user_id);
+
+CREATE TABLE IF NOT EXISTS workspace_invitations (
+    id SERIAL PRIMARY KEY,
+    workspace_id INTEGER NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(8) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Одно ожидающее приглашение на пользователя в пространство
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workspace_invitations_pending
+    ON workspace_invitations(workspace_id, user_id) WHERE responded_at IS NULL;
+
+-- Заметка принадлежит либо пользователю (workspace_id IS NULL), либо
+-- пространству; user_id остается автором
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS workspace_id INTEGER REFERENCES workspaces(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_notes_workspace_id ON notes(workspace_id);
+
+-- note_permission возвращает доступ пользователя к заметке: owner, edit, read
+-- или NULL. Учитываются владение, роль в пространстве и note_shares;
+-- выбирается наибольший доступ.
+CREATE OR REPLACE FUNCTION note_permission(p_note_id INTEGER, p_user_id INTEGER) RETURNS TEXT AS $$
+    SELECT CASE
+        WHEN (n.workspace_id IS NULL AND n.user_id = p_user_id) OR m.role = 'owner' THEN 'owner'
+        WHEN m.role = 'editor' OR s.permission = 'edit' THEN 'edit'
+        WHEN m.role = 'viewer' OR s.permission = 'read' THEN 'read'
+    END
+    FROM notes n
+    LEFT JOIN note_shares s ON s.note_id = n.id AND s.user_id = p_user_id
+    LEFT JOIN workspace_members m ON m.workspace_id = n.workspace_id AND m.user_id = p_user_id
+    WHERE n.id = p_note_id
+$$ LANGUAGE SQL STABLE;
@@ -0,0 +1,20 @@
+-- Блокноты (папки) с вложенностью и корзина для заметок и блокнотов
+CREATE TABLE IF NOT EXISTS notebooks (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id INTEGER REFERENCES notebooks(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    CHECK (parent_id IS NULL OR parent_id <> id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notebooks_user_id ON notebooks(user_id);
+CREATE INDEX IF NOT EXISTS idx_notebooks_parent_id ON notebooks(parent_id);
+
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS notebook_id INTEGER REFERENCES notebooks(id) ON DELETE SET NULL;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_notes_notebook_id ON notes(notebook_id);
+CREATE INDEX IF NOT EXISTS idx_notes_deleted_at ON notes(deleted_at) WHERE deleted_at IS NOT NULL;
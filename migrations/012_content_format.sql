@@ -0,0 +1,3 @@
+-- Формат текста заметки: простой текст или Markdown
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS content_format VARCHAR(16) NOT NULL DEFAULT 'plain'
+    CHECK (content_format IN ('plain', 'markdown'));
@@ -0,0 +1,32 @@
+-- Вложения заметок. Содержимое хранится в хранилище объектов под storage_key.
+CREATE TABLE IF NOT EXISTS attachments (
+    id SERIAL PRIMARY KEY,
+    note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL CHECK (size >= 0),
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_note_id ON attachments(note_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_user_id ON attachments(user_id);
+
+-- Объекты удаленных вложений (в том числе при каскадном удалении заметок и
+-- пользователей) удаляются из хранилища фоновой задачей
+CREATE TABLE IF NOT EXISTS orphaned_blobs (
+    storage_key VARCHAR(255) PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE OR REPLACE FUNCTION queue_orphaned_blob() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO orphaned_blobs (storage_key) VALUES (OLD.storage_key) ON CONFLICT DO NOTHING;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS attachments_orphaned_blob ON attachments;
+CREATE TRIGGER attachments_orphaned_blob AFTER DELETE ON attachments
+    FOR EACH ROW EXECUTE FUNCTION queue_orphaned_blob();
@@ -0,0 +1,24 @@
+-- Миниатюры изображений во вложениях. thumbnail_status задан только для
+-- JPEG, PNG и GIF: pending — ждет обработки, running — обрабатывается с
+-- thumbnail_updated_at, ready или failed — обработка завершена.
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS thumbnail_status VARCHAR(16)
+    CHECK (thumbnail_status IN ('pending', 'running', 'ready', 'failed'));
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS thumbnail_updated_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_attachments_thumbnail_pending
+    ON attachments(id) WHERE thumbnail_status IN ('pending', 'running');
+
+CREATE TABLE IF NOT EXISTS attachment_thumbnails (
+    attachment_id INTEGER NOT NULL REFERENCES attachments(id) ON DELETE CASCADE,
+    size VARCHAR(16) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    byte_size BIGINT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    PRIMARY KEY (attachment_id, size)
+);
+
+DROP TRIGGER IF EXISTS attachment_thumbnails_orphaned_blob ON attachment_thumbnails;
+CREATE TRIGGER attachment_thumbnails_orphaned_blob AFTER DELETE ON attachment_thumbnails
+    FOR EACH ROW EXECUTE FUNCTION queue_orphaned_blob();
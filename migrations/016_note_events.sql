@@ -0,0 +1,79 @@
+-- Журнал изменений заметок для потока GET /events. Событие записывается для
+-- каждого пользователя, который видит заметку; после записи в канал
+-- note_events отправляется NOTIFY с ID пользователя. user_id без внешнего
+-- ключа: события пишутся и при каскадном удалении заметок вместе с владельцем.
+CREATE TABLE IF NOT EXISTS note_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    note_id INTEGER NOT NULL,
+    type VARCHAR(16) NOT NULL CHECK (type IN ('created', 'updated', 'deleted')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_events_user_id ON note_events(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_note_events_created_at ON note_events(created_at);
+
+-- emit_note_event записывает событие для автора личной заметки, участников
+-- пространства и пользователей, которым заметка открыта
+CREATE OR REPLACE FUNCTION emit_note_event(p_note notes, p_type TEXT) RETURNS VOID AS $$
+DECLARE
+    recipient INTEGER;
+BEGIN
+    FOR recipient IN
+        SELECT p_note.user_id WHERE p_note.workspace_id IS NULL
+        UNION SELECT m.user_id FROM workspace_members m WHERE m.workspace_id = p_note.workspace_id
+        UNION SELECT s.user_id FROM note_shares s WHERE s.note_id = p_note.id
+    LOOP
+        INSERT INTO note_events (user_id, note_id, type) VALUES (recipient, p_note.id, p_type);
+        PERFORM pg_notify('note_events', recipient::text);
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Перемещение в корзину — deleted, восстановление — created. Окончательное
+-- удаление заметки из корзины событий не порождает.
+CREATE OR REPLACE FUNCTION notes_emit_events() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        PERFORM emit_note_event(NEW, 'created');
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            PERFORM emit_note_event(NEW, 'deleted');
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            PERFORM emit_note_event(NEW, 'created');
+        ELSIF NEW.deleted_at IS NULL AND NEW IS DISTINCT FROM OLD THEN
+            PERFORM emit_note_event(NEW, 'updated');
+        END IF;
+    ELSIF OLD.deleted_at IS NULL THEN
+        PERFORM emit_note_event(OLD, 'deleted');
+    END IF;
+    RETURN COALESCE(NEW, OLD);
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notes_events ON notes;
+CREATE TRIGGER notes_events AFTER INSERT OR UPDATE ON notes
+    FOR EACH ROW EXECUTE FUNCTION notes_emit_events();
+
+-- При удалении получатели определяются до каскадного удаления note_shares
+DROP TRIGGER IF EXISTS notes_events_delete ON notes;
+CREATE TRIGGER notes_events_delete BEFORE DELETE ON notes
+    FOR EACH ROW EXECUTE FUNCTION notes_emit_events();
+
+-- Открытие доступа к заметке — created для получателя, отзыв — deleted
+CREATE OR REPLACE FUNCTION note_shares_emit_events() RETURNS TRIGGER AS $$
+DECLARE
+    share note_shares := COALESCE(NEW, OLD);
+BEGIN
+    IF EXISTS (SELECT 1 FROM notes WHERE id = share.note_id AND deleted_at IS NULL) THEN
+        INSERT INTO note_events (user_id, note_id, type)
+        VALUES (share.user_id, share.note_id, CASE WHEN TG_OP = 'INSERT' THEN 'created' ELSE 'deleted' END);
+        PERFORM pg_notify('note_events', share.user_id::text);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS note_shares_events ON note_shares;
+CREATE TRIGGER note_shares_events AFTER INSERT OR DELETE ON note_shares
+    FOR EACH ROW EXECUTE FUNCTION note_shares_emit_events();
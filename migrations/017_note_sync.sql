@@ -0,0 +1,31 @@
+-- Версия заметки растет при каждом изменении и служит для обнаружения
+-- конфликтов при синхронизации. client_id — ID, который офлайн-клиент
+-- присвоил созданной им заметке; повторная отправка того же создания не
+-- порождает дубликат.
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS client_id VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notes_client_id ON notes(user_id, client_id) WHERE client_id IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION notes_bump_version() RETURNS TRIGGER AS $$
+BEGIN
+    IF (NEW.title, NEW.content, NEW.content_format, NEW.workspace_id, NEW.notebook_id, NEW.deleted_at)
+        IS DISTINCT FROM (OLD.title, OLD.content, OLD.content_format, OLD.workspace_id, OLD.notebook_id, OLD.deleted_at) THEN
+        NEW.version := OLD.version + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notes_version ON notes;
+CREATE TRIGGER notes_version BEFORE UPDATE ON notes
+    FOR EACH ROW EXECUTE FUNCTION notes_bump_version();
+
+-- Токен синхронизации — граница по ID транзакций: события транзакций с
+-- меньшими ID уже зафиксированы или отменены, поэтому незавершенная на момент
+-- запроса транзакция не будет пропущена, даже если ее событие получит меньший
+-- id, чем уже отданные.
+ALTER TABLE note_events ADD COLUMN IF NOT EXISTS xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE INDEX IF NOT EXISTS idx_note_events_user_xid ON note_events(user_id, xid);
+CREATE INDEX IF NOT EXISTS idx_note_events_xid ON note_events(xid);
@@ -0,0 +1,79 @@
+-- Исходящие вебхуки: подписка пользователя на события его заметок
+CREATE TABLE IF NOT EXISTS webhooks (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id);
+
+-- Доставки — исходящая очередь (outbox) и журнал. Строка добавляется
+-- триггером в той же транзакции, что и изменение заметки, поэтому событие
+-- не теряется и не отправляется для отмененного изменения.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(32) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'delivering', 'succeeded', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INTEGER,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at)
+    WHERE status IN ('pending', 'delivering');
+
+-- Каждая попытка доставки с ответом получателя
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    status_code INTEGER,
+    error TEXT,
+    duration_ms INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_delivery_id ON webhook_attempts(delivery_id);
+
+-- Событие из note_events ставится в очередь каждому активному вебхуку
+-- получателя, подписанному на этот тип. Для удаленных заметок текст не
+-- передается.
+CREATE OR REPLACE FUNCTION note_events_enqueue_webhooks() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO webhook_deliveries (webhook_id, event, payload)
+    SELECT w.id, 'note.' || NEW.type, jsonb_build_object(
+        'event', 'note.' || NEW.type,
+        'note_id', NEW.note_id,
+        'occurred_at', NEW.created_at,
+        'note', (
+            SELECT jsonb_build_object(
+                'id', n.id,
+                'workspace_id', n.workspace_id,
+                'notebook_id', n.notebook_id,
+                'title', n.title,
+                'content', n.content,
+                'content_format', n.content_format,
+                'version', n.version,
+                'created_at', n.created_at,
+                'updated_at', n.updated_at)
+            FROM notes n WHERE n.id = NEW.note_id AND NEW.type <> 'deleted'))
+    FROM webhooks w
+    WHERE w.user_id = NEW.user_id AND w.active AND 'note.' || NEW.type = ANY(w.events);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS note_events_webhooks ON note_events;
+CREATE TRIGGER note_events_webhooks AFTER INSERT ON note_events
+    FOR EACH ROW EXECUTE FUNCTION note_events_enqueue_webhooks();
@@ -0,0 +1,65 @@
+-- Исходящая очередь доменных событий для брокера сообщений (outbox).
+-- События пишутся триггером в той же транзакции, что и изменение заметки,
+-- и публикуются обработчиком очереди не меньше одного раза. Обработчик
+-- забирает пачку событий на locked_until; если он завершился аварийно, пачка
+-- публикуется снова после истечения срока.
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    aggregate_id BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_unpublished ON outbox(next_attempt_at, id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_published_at ON outbox(published_at) WHERE published_at IS NOT NULL;
+
+-- Одно событие на изменение заметки, независимо от числа пользователей, которые
+-- ее видят. Типы совпадают с событиями note_events.
+CREATE OR REPLACE FUNCTION notes_outbox() RETURNS TRIGGER AS $$
+DECLARE
+    event_type TEXT;
+    note notes;
+BEGIN
+    note := COALESCE(NEW, OLD);
+    IF TG_OP = 'INSERT' THEN
+        event_type := 'created';
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            event_type := 'deleted';
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            event_type := 'created';
+        ELSIF NEW.deleted_at IS NULL AND NEW IS DISTINCT FROM OLD THEN
+            event_type := 'updated';
+        END IF;
+    ELSIF OLD.deleted_at IS NULL THEN
+        event_type := 'deleted';
+    END IF;
+
+    IF event_type IS NOT NULL THEN
+        INSERT INTO outbox (type, aggregate_id, payload)
+        VALUES ('note.' || event_type, note.id, jsonb_build_object(
+            'id', note.id,
+            'user_id', note.user_id,
+            'workspace_id', note.workspace_id,
+            'notebook_id', note.notebook_id,
+            'title', note.title,
+            'content', note.content,
+            'content_format', note.content_format,
+            'version', note.version,
+            'created_at', note.created_at,
+            'updated_at', note.updated_at,
+            'deleted_at', note.deleted_at));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notes_outbox ON notes;
+CREATE TRIGGER notes_outbox AFTER INSERT OR UPDATE OR DELETE ON notes
+    FOR EACH ROW EXECUTE FUNCTION notes_outbox();
@@ -0,0 +1,105 @@
+-- Сроки и напоминания. remind_at — очередное время напоминания, recurrence —
+-- правило повторения RRULE относительно него. Правило вычисляется в часовом
+-- поясе reminder_timezone (имя из базы IANA, например Europe/Moscow), чтобы
+-- напоминание на 9 утра приходило в 9 утра по местному времени и после
+-- перехода на летнее время; NULL — UTC. snoozed_until откладывает текущее
+-- напоминание, не меняя расписания повторений, или повторяет уже сработавшее.
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS due_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS remind_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS snoozed_until TIMESTAMP WITH TIME ZONE;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS recurrence TEXT;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS reminder_timezone VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_notes_reminders ON notes((COALESCE(snoozed_until, remind_at)))
+    WHERE deleted_at IS NULL AND (remind_at IS NOT NULL OR snoozed_until IS NOT NULL);
+
+-- Сработавшее напоминание — событие reminder в note_events: оно приходит в
+-- поток GET /events и вебхукам, подписанным на note.reminder
+ALTER TABLE note_events DROP CONSTRAINT IF EXISTS note_events_type_check;
+ALTER TABLE note_events ADD CONSTRAINT note_events_type_check
+    CHECK (type IN ('created', 'updated', 'deleted', 'reminder'));
+
+-- Аренда фоновой задачи: задачу выполняет только экземпляр, который держит
+-- неистекшую аренду, и продлевает ее, пока работает
+CREATE TABLE IF NOT EXISTS scheduler_leases (
+    name VARCHAR(64) PRIMARY KEY,
+    holder VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Событие updated — только об изменении самой заметки. Расписание
+-- напоминаний (due_at, remind_at, snoozed_until, recurrence,
+-- reminder_timezone) меняется без событий в note_events и outbox: иначе
+-- каждое срабатывание напоминания выглядело бы для клиентов, вебхуков и
+-- брокера как правка заметки. Сравниваются те же поля, что в
+-- notes_bump_version, и updated_at, который обновляют изменения чек-листа.
+CREATE OR REPLACE FUNCTION note_content_changed(p_old notes, p_new notes) RETURNS BOOLEAN AS $$
+    SELECT (p_new.user_id, p_new.title, p_new.content, p_new.content_format, p_new.workspace_id, p_new.notebook_id, p_new.updated_at)
+        IS DISTINCT FROM (p_old.user_id, p_old.title, p_old.content, p_old.content_format, p_old.workspace_id, p_old.notebook_id, p_old.updated_at);
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION notes_emit_events() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        PERFORM emit_note_event(NEW, 'created');
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            PERFORM emit_note_event(NEW, 'deleted');
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            PERFORM emit_note_event(NEW, 'created');
+        ELSIF NEW.deleted_at IS NULL AND note_content_changed(OLD, NEW) THEN
+            PERFORM emit_note_event(NEW, 'updated');
+        END IF;
+    ELSIF OLD.deleted_at IS NULL THEN
+        PERFORM emit_note_event(OLD, 'deleted');
+    END IF;
+    RETURN COALESCE(NEW, OLD);
+END;
+$$ LANGUAGE plpgsql;
+
+-- enqueue_note_outbox записывает в outbox событие note.<p_type> со снимком
+-- заметки. Вызывается триггером notes_outbox и при срабатывании напоминания.
+CREATE OR REPLACE FUNCTION enqueue_note_outbox(p_note notes, p_type TEXT) RETURNS VOID AS $$
+BEGIN
+    INSERT INTO outbox (type, aggregate_id, payload)
+    VALUES ('note.' || p_type, p_note.id, jsonb_build_object(
+        'id', p_note.id,
+        'user_id', p_note.user_id,
+        'workspace_id', p_note.workspace_id,
+        'notebook_id', p_note.notebook_id,
+        'title', p_note.title,
+        'content', p_note.content,
+        'content_format', p_note.content_format,
+        'version', p_note.version,
+        'created_at', p_note.created_at,
+        'updated_at', p_note.updated_at,
+        'deleted_at', p_note.deleted_at,
+        'due_at', p_note.due_at,
+        'remind_at', p_note.remind_at));
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION notes_outbox() RETURNS TRIGGER AS $$
+DECLARE
+    event_type TEXT;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        event_type := 'created';
+    ELSIF TG_OP = 'UPDATE' THEN
+        IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+            event_type := 'deleted';
+        ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+            event_type := 'created';
+        ELSIF NEW.deleted_at IS NULL AND note_content_changed(OLD, NEW) THEN
+            event_type := 'updated';
+        END IF;
+    ELSIF OLD.deleted_at IS NULL THEN
+        event_type := 'deleted';
+    END IF;
+
+    IF event_type IS NOT NULL THEN
+        PERFORM enqueue_note_outbox(COALESCE(NEW, OLD), event_type);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,16 @@
+-- Чек-лист заметки. position — порядковый номер пункта с нуля; номера
+-- пунктов одной заметки идут подряд, их поддерживает репозиторий.
+CREATE TABLE IF NOT EXISTS checklist_items (
+    id SERIAL PRIMARY KEY,
+    note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    checked BOOLEAN NOT NULL DEFAULT false,
+    position INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_checklist_items_note_id ON checklist_items(note_id, position);
+
+-- Для фильтра ListNotes ?has_open_tasks
+CREATE INDEX IF NOT EXISTS idx_checklist_items_open ON checklist_items(note_id) WHERE NOT checked;
@@ -0,0 +1,21 @@
+-- Закрепленные, архивные и избранные заметки и ручной порядок. Это личные
+-- настройки пользователя, а не свойства заметки: у каждого, кто видит общую
+-- заметку, они свои. Отметки хранятся отдельно от notes, поэтому их
+-- изменение не меняет заметку и не создает событий updated, вебхуков и
+-- записей outbox. Строки нет — заметка не закреплена, не в архиве и не в
+-- избранном.
+--
+-- В списке заметок закрепленные идут первыми, затем заметки с position по
+-- возрастанию, затем остальные от новых к старым. Архивные заметки в список
+-- по умолчанию не попадают.
+CREATE TABLE IF NOT EXISTS note_user_flags (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    pinned BOOLEAN NOT NULL DEFAULT false,
+    archived BOOLEAN NOT NULL DEFAULT false,
+    favorite BOOLEAN NOT NULL DEFAULT false,
+    position INTEGER,
+    PRIMARY KEY (user_id, note_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_user_flags_note_id ON note_user_flags(note_id);